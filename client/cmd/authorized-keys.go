@@ -12,12 +12,15 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/sysarmor/guard/server/pkg/apis"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
 )
 
 type authorizedKeys struct {
 	guard
 
 	authorizedKeysPath string
+	// received is the digest of the authorized keys from server
+	received string
 }
 
 func newAuthorizedKeys(_ *Config, guard apis.Guard) *cobra.Command {
@@ -45,6 +48,8 @@ func newAuthorizedKeys(_ *Config, guard apis.Guard) *cobra.Command {
 				)
 			}
 
+			publicKey.report(cmd.Context(), dto.ArtifactAuthorizedKeys, publicKey.received, publicKey.applied, err)
+
 			return err
 		},
 	}
@@ -63,6 +68,7 @@ func (r *authorizedKeys) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get remote authorized keys: %w", err)
 	}
+	r.received = dto.DigestAuthorizedKeys(remoteAuthorizedKeys)
 
 	same, err := r.compareAuthorizedKeys(remoteAuthorizedKeys)
 	if err != nil {
@@ -84,16 +90,23 @@ func (r *authorizedKeys) overwriteAuthorizedKeys(remote []string) error {
 	}
 	defer fd.Close()
 
-	for _, key := range remote {
-		_, err = fd.WriteString(key + "\n")
-		if err != nil {
-			return fmt.Errorf("failed to write authorized key: %w", err)
-		}
+	_, err = fd.Write(dto.AuthorizedKeysContent(remote))
+	if err != nil {
+		return fmt.Errorf("failed to write authorized keys: %w", err)
 	}
 
 	return nil
 }
 
+// applied returns the digest of the local authorized keys file
+func (r *authorizedKeys) applied() (string, error) {
+	body, err := os.ReadFile(r.authorizedKeysPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read authorized keys file: %w", err)
+	}
+	return dto.Digest(body), nil
+}
+
 func (r *authorizedKeys) getRemoteAuthorizedKeys(ctx context.Context) ([]string, error) {
 	return r.guard.Guard.GetAuthorizedKeys(ctx)
 }
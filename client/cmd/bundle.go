@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
+)
+
+// bundle applies all the files of the node from one response, the files
+// are read by the server from one snapshot, so they are consistent
+type bundle struct {
+	guard
+
+	// the files are managed as the commands of them do
+	ca             ca
+	principals     principals
+	revokedKeys    revokedKeys
+	authorizedKeys authorizedKeys
+}
+
+func newBundle(config *Config, guard apis.Guard) *cobra.Command {
+	bundle := &bundle{}
+
+	command := &cobra.Command{
+		Use:   "bundle",
+		Short: "Update the CA, principals, revoked keys and authorized keys at once",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			bundle.ca.trustedUserCAKeys = config.TrustedUserCAKeys
+			if bundle.ca.trustedUserCAKeys == "" {
+				return fmt.Errorf("trusted user ca keys is empty")
+			}
+
+			bundle.principals.authorizedPrincipalsFile = strings.TrimSuffix(config.AuthorizedPrincipalsFile, "%u")
+			if bundle.principals.authorizedPrincipalsFile == "" {
+				return fmt.Errorf("authorized principals file is empty")
+			}
+
+			bundle.revokedKeys.revokeKeys = config.RevokeKeys
+			if bundle.revokedKeys.revokeKeys == "" {
+				return fmt.Errorf("revoked keys file is empty")
+			}
+
+			bundle.guard.Guard = guard
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := bundle.run(cmd.Context())
+			if err != nil {
+				slog.Error("Failed to apply bundle",
+					"error", err,
+				)
+			}
+
+			artifacts := []*dto.ArtifactReport{
+				artifactReport(dto.ArtifactCA, bundle.ca.received, bundle.ca.applied, err),
+				artifactReport(dto.ArtifactPrincipals, bundle.principals.received, bundle.principals.applied, err),
+				artifactReport(dto.ArtifactKRL, bundle.revokedKeys.received, bundle.revokedKeys.applied, err),
+			}
+			if bundle.authorizedKeys.authorizedKeysPath != "" {
+				artifacts = append(artifacts, artifactReport(dto.ArtifactAuthorizedKeys,
+					bundle.authorizedKeys.received, bundle.authorizedKeys.applied, err))
+			}
+			bundle.send(cmd.Context(), artifacts...)
+
+			return err
+		},
+	}
+
+	flags := command.PersistentFlags()
+	flags.StringVarP(&bundle.authorizedKeys.authorizedKeysPath, "authorized-keys-path", "", "", "The authorized keys path, empty skips the authorized keys")
+
+	return command
+}
+
+func (b *bundle) run(ctx context.Context) error {
+	slog.Info("Start to apply bundle")
+	defer slog.Info("Finish apply bundle")
+
+	remote, err := b.GetBundle(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get bundle from server: %w", err)
+	}
+
+	var krl []byte
+	if remote.KRL != "" {
+		krl, err = base64.StdEncoding.DecodeString(remote.KRL)
+		if err != nil {
+			return fmt.Errorf("failed to decode revoked keys: %w", err)
+		}
+	}
+
+	b.ca.received = dto.DigestCA(remote.CA)
+	b.principals.received = dto.DigestPrincipals(remote.Principals)
+	b.revokedKeys.received = dto.DigestKRL(krl)
+
+	files := []*bundleFile{
+		{path: b.ca.trustedUserCAKeys, content: []byte(remote.CA)},
+		{path: b.revokedKeys.revokeKeys, content: krl},
+	}
+
+	roles := make([]string, 0, len(remote.Principals))
+	for _, p := range remote.Principals {
+		roles = append(roles, p.Role)
+		files = append(files, &bundleFile{
+			path:    filepath.Join(b.principals.authorizedPrincipalsFile, p.Role),
+			content: []byte(fmt.Sprintf(defaultPrincipalsComment, p.Role) + b.principals.serializePrincipals(p.Principals)),
+		})
+	}
+
+	if b.authorizedKeys.authorizedKeysPath != "" {
+		b.authorizedKeys.received = dto.DigestAuthorizedKeys(remote.AuthorizedKeys)
+		files = append(files, &bundleFile{
+			path:    b.authorizedKeys.authorizedKeysPath,
+			content: dto.AuthorizedKeysContent(remote.AuthorizedKeys),
+		})
+	}
+
+	stale, err := b.stalePrincipals(roles)
+	if err != nil {
+		return fmt.Errorf("failed to find stale principals: %w", err)
+	}
+
+	if err := writeFiles(files); err != nil {
+		return err
+	}
+
+	// the roles no longer served are removed after the new files are in
+	// place, a failure leaves them and they are reported as drift
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale principals: %w", err)
+		}
+		slog.Info("principals removed",
+			"file", path,
+		)
+	}
+
+	slog.Info("Bundle applied",
+		"version", remote.Version,
+		"digest", remote.Digest,
+	)
+	return nil
+}
+
+// stalePrincipals returns the principals files written by guard for the
+// roles not in roles, the other files in the directory are kept
+func (b *bundle) stalePrincipals(roles []string) ([]string, error) {
+	entries, err := os.ReadDir(b.principals.authorizedPrincipalsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read authorized principals directory: %w", err)
+	}
+
+	stale := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || slices.Contains(roles, entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(b.principals.authorizedPrincipalsFile, entry.Name())
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		if bytes.HasPrefix(body, []byte(fmt.Sprintf(defaultPrincipalsComment, entry.Name()))) {
+			stale = append(stale, path)
+		}
+	}
+
+	return stale, nil
+}
+
+// bundleFile is a file of the bundle to write, tmp is the temporary file
+// holding the content until it's renamed to path
+type bundleFile struct {
+	path    string
+	content []byte
+	tmp     string
+}
+
+// writeFiles replaces the changed files as a whole: all of them are
+// written to temporary files in their directories first, and renamed to
+// their paths only if all are written, so a failure keeps the old files.
+// The renames are atomic, sshd never reads a partially written file.
+func writeFiles(files []*bundleFile) (err error) {
+	changed := make([]*bundleFile, 0, len(files))
+	for _, file := range files {
+		local, err := os.ReadFile(file.path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read file %s: %w", file.path, err)
+		}
+
+		if err == nil && bytes.Equal(local, file.content) {
+			continue
+		}
+		changed = append(changed, file)
+	}
+
+	defer func() {
+		for _, file := range changed {
+			if file.tmp != "" {
+				err = errors.Join(err, os.Remove(file.tmp))
+			}
+		}
+	}()
+
+	for _, file := range changed {
+		if err := writeTemp(file); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range changed {
+		if err := os.Rename(file.tmp, file.path); err != nil {
+			return fmt.Errorf("failed to rename file %s: %w", file.path, err)
+		}
+		file.tmp = ""
+
+		slog.Info("file updated",
+			"file", file.path,
+		)
+	}
+
+	return nil
+}
+
+func writeTemp(file *bundleFile) error {
+	dir := filepath.Dir(file.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	fd, err := os.CreateTemp(dir, "."+filepath.Base(file.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file of %s: %w", file.path, err)
+	}
+	file.tmp = fd.Name()
+	defer fd.Close()
+
+	if _, err := fd.Write(file.content); err != nil {
+		return fmt.Errorf("failed to write temporary file of %s: %w", file.path, err)
+	}
+
+	if err := fd.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to chmod temporary file of %s: %w", file.path, err)
+	}
+
+	if err := fd.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file of %s: %w", file.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readDir returns the names of the files in the directory
+func readDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pub")
+	principals := filepath.Join(dir, "principals", "root")
+
+	files := []*bundleFile{
+		{path: ca, content: []byte("ca")},
+		{path: principals, content: []byte("alice\n")},
+	}
+	if err := writeFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		body, err := os.ReadFile(file.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != string(file.content) {
+			t.Fatalf("expected %q in %s, got %q", file.content, file.path, body)
+		}
+	}
+
+	// the unchanged file is not rewritten, its mode is kept
+	if err := os.Chmod(ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles([]*bundleFile{{path: ca, content: []byte("ca")}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the unchanged file kept, got mode %v", info.Mode())
+	}
+
+	if names := readDir(t, dir); len(names) != 2 {
+		t.Fatalf("expected no temporary file left, got %v", names)
+	}
+}
+
+func TestWriteFilesAtomic(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pub")
+	if err := os.WriteFile(ca, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the directory of the second file can not be created, it's a file
+	err := writeFiles([]*bundleFile{
+		{path: ca, content: []byte("new")},
+		{path: filepath.Join(ca, "root"), content: []byte("alice\n")},
+	})
+	if err == nil {
+		t.Fatal("expected the error of the second file")
+	}
+
+	body, err := os.ReadFile(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "old" {
+		t.Fatalf("expected the old file kept, got %q", body)
+	}
+
+	if names := readDir(t, dir); len(names) != 1 {
+		t.Fatalf("expected the temporary files removed, got %v", names)
+	}
+}
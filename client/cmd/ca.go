@@ -9,11 +9,14 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/sysarmor/guard/server/pkg/apis"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
 )
 
 // manage the ca File
 type ca struct {
 	trustedUserCAKeys string
+	// received is the digest of the guard.pub from server
+	received string
 
 	guard
 }
@@ -40,6 +43,8 @@ func newCA(config *Config, guard apis.Guard) *cobra.Command {
 					"error", err,
 				)
 			}
+
+			ca.report(cmd.Context(), dto.ArtifactCA, ca.received, ca.applied, err)
 			return err
 		},
 	}
@@ -52,6 +57,7 @@ func (ca *ca) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get guard.pub from server: %w", err)
 	}
+	ca.received = dto.DigestCA(remoteCAPub)
 
 	localCAPub, err := ca.getCAFromLocal()
 	if err != nil {
@@ -99,6 +105,15 @@ func (ca *ca) getCAFromLocal() (string, error) {
 	return buf.String(), nil
 }
 
+// applied returns the digest of the local guard.pub
+func (ca *ca) applied() (string, error) {
+	caPub, err := ca.getCAFromLocal()
+	if err != nil {
+		return "", err
+	}
+	return dto.DigestCA(caPub), nil
+}
+
 func (ca *ca) updateCA(caPub string) error {
 	fd, err := os.OpenFile(ca.trustedUserCAKeys, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
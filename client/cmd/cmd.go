@@ -25,16 +25,16 @@ func New() *cobra.Command {
 		Short: "A client tool for managing SSH configurations and Guard operation",
 
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun {
+				guard.Guard = &apis.FakeGuard{}
+			}
+
 			if daemon.isNeedDaemonize() {
-				if err := daemon.daemonize(cmd.Context()); err != nil {
+				if err := daemon.daemonize(cmd.Context(), guard); err != nil {
 					return fmt.Errorf("failed to daemonize: %w", err)
 				}
 			}
 
-			if dryRun {
-				guard.Guard = &apis.FakeGuard{}
-			}
-
 			path := filepath.Join(sshdConfigDir, fileName)
 
 			// Create the directory if it does not exist
@@ -61,6 +61,7 @@ func New() *cobra.Command {
 	root.AddCommand(newPrincipals(sshdConfig, guard))
 	root.AddCommand(newRevokedKeys(sshdConfig, guard))
 	root.AddCommand(newAuthorizedKeys(sshdConfig, guard))
+	root.AddCommand(newBundle(sshdConfig, guard))
 
 	flags := root.PersistentFlags()
 	flags.StringVarP(&sshdConfigDir, "sshd-config-dir", "", "/etc/ssh/sshd_config.d/", "The directory of sshd config files, default is /etc/ssh/sshd_config.d/")
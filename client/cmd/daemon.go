@@ -7,20 +7,27 @@ import (
 	"os/exec"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/robfig/cron"
 	flag "github.com/spf13/pflag"
+	"github.com/sysarmor/guard/server/pkg/apis"
 )
 
 type daemon struct {
 	daemon bool
 
-	cron string
+	cron  string
+	watch bool
 }
 
+// watchRetry is the wait before watching again after a failure
+const watchRetry = 30 * time.Second
+
 func (d *daemon) PersistentFlags(flagSet *flag.FlagSet) {
 	flagSet.BoolVarP(&d.daemon, "daemon", "d", false, "Run the daemon, default is false")
 	flagSet.StringVarP(&d.cron, "cron", "c", "0 0/5 * * *", "The cron expression to run the daemon, default is every 5 minutes")
+	flagSet.BoolVar(&d.watch, "watch", true, "Run immediately when the server notifies a change, the cron is the fallback, default is true")
 }
 
 func (d *daemon) isDaemon() bool {
@@ -36,7 +43,7 @@ func (d *daemon) isNeedDaemonize() bool {
 }
 
 // daemonize daemonize the process
-func (d *daemon) daemonize(ctx context.Context) error {
+func (d *daemon) daemonize(ctx context.Context, guard apis.Guard) error {
 	name := os.Args[0]
 	arg := os.Args[1:]
 
@@ -48,7 +55,12 @@ func (d *daemon) daemonize(ctx context.Context) error {
 		return nil
 	}
 
-	d.cronRun(ctx, name, d.trimDaemonArgs(arg)...)
+	arg = d.trimDaemonArgs(arg)
+	if d.watch {
+		go d.watchRun(ctx, guard, name, arg...)
+	}
+
+	d.cronRun(ctx, name, arg...)
 	return nil
 }
 
@@ -69,6 +81,44 @@ func (d *daemon) cronRun(ctx context.Context, name string, arg ...string) {
 	slog.Info("Stop cron job")
 }
 
+// watchRun runs the command when the server notifies that the files of
+// the node changed, the watches are held by the server until a change
+// or a timeout, so the revocations reach the node in seconds
+func (d *daemon) watchRun(ctx context.Context, guard apis.Guard, name string, arg ...string) {
+	slog.Info("Start watching the server")
+	defer slog.Info("Stop watching the server")
+
+	var version string
+	for ctx.Err() == nil {
+		watch, err := guard.Watch(ctx, version)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("Failed to watch the server",
+				"error", err,
+				"retry", watchRetry,
+			)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(watchRetry):
+			}
+			continue
+		}
+
+		if watch.Changed {
+			slog.Info("Files changed on the server",
+				"version", watch.Version,
+			)
+			d.run(name, arg...)
+		}
+
+		version = watch.Version
+	}
+}
+
 func (d *daemon) run(name string, arg ...string) {
 	cmd := exec.Command(name, arg...)
 	cmd.Stdout = os.Stdout
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
 	flag "github.com/spf13/pflag"
 	"github.com/sysarmor/guard/server/pkg/apis"
@@ -15,12 +17,15 @@ type guard struct {
 	address    string
 	nodeID     string
 	nodeSecret string
+	// cacheDir keeps the responses with the etags between the runs
+	cacheDir string
 }
 
 func (g *guard) PersistentFlags(flagSet *flag.FlagSet) {
 	flagSet.StringVar(&g.address, "address", "", "Address of the guard server")
 	flagSet.StringVar(&g.nodeID, "node-id", "", "Node ID")
 	flagSet.StringVar(&g.nodeSecret, "node-secret", "", "Node secret")
+	flagSet.StringVar(&g.cacheDir, "cache-dir", "/var/cache/guard-client", "The directory to cache the responses, the unchanged ones are not downloaded again, empty disables it")
 }
 
 // initEndpoint initializes the guard endpoint
@@ -30,11 +35,15 @@ func (g *guard) initEndpoint() error {
 			return err
 		}
 
-		var err error
-		g.Guard, err = apis.NewHTTPGuard(g.address, g.nodeID, g.nodeSecret)
+		guard, err := apis.NewHTTPGuard(g.address, g.nodeID, g.nodeSecret)
 		if err != nil {
 			return fmt.Errorf("failed to create guard: %w", err)
 		}
+
+		if g.cacheDir != "" {
+			guard.SetCache(&apis.FileCache{Dir: g.cacheDir})
+		}
+		g.Guard = guard
 	}
 
 	return nil
@@ -86,3 +95,70 @@ func (g *guard) GetAuthorizedKeys(ctx context.Context) ([]string, error) {
 	}
 	return g.Guard.GetAuthorizedKeys(ctx)
 }
+
+func (g *guard) GetBundle(ctx context.Context) (*dto.Bundle, error) {
+	err := g.initEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	return g.Guard.GetBundle(ctx)
+}
+
+func (g *guard) Report(ctx context.Context, report *dto.Report) error {
+	err := g.initEndpoint()
+	if err != nil {
+		return err
+	}
+	return g.Guard.Report(ctx, report)
+}
+
+func (g *guard) Watch(ctx context.Context, version string) (*dto.Watch, error) {
+	err := g.initEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	return g.Guard.Watch(ctx, version)
+}
+
+// report reports the digest received from the server and the digest of
+// the file read back after the run, so that the server can tell whether
+// the node is up to date. The failure of the report does not fail the run.
+func (g *guard) report(ctx context.Context, kind dto.Artifact, received string, applied func() (string, error), runErr error) {
+	g.send(ctx, artifactReport(kind, received, applied, runErr))
+}
+
+// artifactReport returns the report of one file, see report
+func artifactReport(kind dto.Artifact, received string, applied func() (string, error), runErr error) *dto.ArtifactReport {
+	artifact := &dto.ArtifactReport{
+		Kind:     kind,
+		Received: received,
+	}
+
+	digest, err := applied()
+	if err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+	artifact.Applied = digest
+
+	if runErr != nil {
+		artifact.Error = runErr.Error()
+	}
+
+	return artifact
+}
+
+// send reports the files in one request, the failure is logged only
+func (g *guard) send(ctx context.Context, artifacts ...*dto.ArtifactReport) {
+	err := g.Report(ctx, &dto.Report{Artifacts: artifacts})
+	if err != nil {
+		kinds := make([]dto.Artifact, 0, len(artifacts))
+		for _, artifact := range artifacts {
+			kinds = append(kinds, artifact.Kind)
+		}
+
+		slog.Warn("Failed to report",
+			"kind", kinds,
+			"error", err,
+		)
+	}
+}
@@ -18,6 +18,8 @@ type principals struct {
 	guard
 
 	authorizedPrincipalsFile string
+	// received is the digest of the principals from server
+	received string
 }
 
 func newPrincipals(config *Config, guard apis.Guard) *cobra.Command {
@@ -44,6 +46,8 @@ func newPrincipals(config *Config, guard apis.Guard) *cobra.Command {
 				)
 			}
 
+			principals.report(cmd.Context(), dto.ArtifactPrincipals, principals.received, principals.applied, err)
+
 			return err
 		},
 	}
@@ -59,6 +63,7 @@ func (r *principals) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get remote principals: %w", err)
 	}
+	r.received = dto.DigestPrincipals(remotePrincipals)
 
 	localPrincipals, err := r.getLocalPrincipals()
 	if err != nil {
@@ -179,6 +184,21 @@ func (r *principals) getLocalPrincipals() ([]dto.Principals, error) {
 	return principals, nil
 }
 
+// applied returns the digest of the local principals, the files of the
+// roles no longer served are included, they are reported as drift
+func (r *principals) applied() (string, error) {
+	local, err := r.getLocalPrincipals()
+	if err != nil {
+		return "", err
+	}
+
+	list := make(dto.PrincipalList, 0, len(local))
+	for i := range local {
+		list = append(list, &local[i])
+	}
+	return dto.DigestPrincipals(list), nil
+}
+
 // parsePrincipals parse the body of authorized principals file
 // and return the principals
 func (r *principals) parsePrincipals(body string) []string {
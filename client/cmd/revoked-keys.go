@@ -9,12 +9,15 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/sysarmor/guard/server/pkg/apis"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
 )
 
 type revokedKeys struct {
 	guard
 
 	revokeKeys string
+	// received is the digest of the KRL from server
+	received string
 }
 
 func newRevokedKeys(config *Config, guard apis.Guard) *cobra.Command {
@@ -41,6 +44,8 @@ func newRevokedKeys(config *Config, guard apis.Guard) *cobra.Command {
 				)
 			}
 
+			revokedKeys.report(cmd.Context(), dto.ArtifactKRL, revokedKeys.received, revokedKeys.applied, err)
+
 			return err
 		},
 	}
@@ -56,6 +61,7 @@ func (r *revokedKeys) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get remote revoked keys: %w", err)
 	}
+	r.received = dto.DigestKRL(remoteRevokedKeys)
 
 	fd, err := os.OpenFile(r.revokeKeys, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
@@ -71,6 +77,15 @@ func (r *revokedKeys) run(ctx context.Context) error {
 	return nil
 }
 
+// applied returns the digest of the local revoked keys file
+func (r *revokedKeys) applied() (string, error) {
+	body, err := os.ReadFile(r.revokeKeys)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read revoked keys file: %w", err)
+	}
+	return dto.DigestKRL(body), nil
+}
+
 func (r *revokedKeys) getRemoteRevokedKeys(ctx context.Context) ([]byte, error) {
 	revokedKeys, err := r.GetKRL(ctx)
 	if err != nil {
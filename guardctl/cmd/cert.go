@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+)
+
+func newCert(ctl *guardctl) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "cert",
+		Short: "Grant and revoke the ssh certificates of the users",
+	}
+
+	var effect time.Duration
+	grant := &cobra.Command{
+		Use:   "grant USER",
+		Short: "Grant a certificate to the user and print it",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			resp, err := ctl.grantCert(cmd.Context(), args[0], effect)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"CERT"}
+				t.add(strings.TrimSpace(resp.Cert))
+			})
+		}),
+	}
+	grant.Flags().DurationVar(&effect, "effect", 8*time.Hour, "Lifetime of the certificate")
+
+	var key string
+	var force bool
+	issue := &cobra.Command{
+		Use:   "issue USER",
+		Short: "Grant a certificate and write it next to the private key, e.g. ~/.ssh/id_ed25519-cert.pub",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			user, err := ctl.user(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			// the cert is signed for the public key on the server, it's
+			// useless if the local key is a different one
+			if local, err := os.ReadFile(key + ".pub"); err == nil && !force && !samePublicKey(string(local), user.PubKey) {
+				return fmt.Errorf("%s.pub is not the public key of %s, use --force to write the cert anyway", key, user.Email)
+			}
+
+			resp, err := ctl.GrantCert(cmd.Context(), &admin.GrantCertRequest{UserID: user.ID, Effect: int64(effect.Seconds())})
+			if err != nil {
+				return err
+			}
+
+			path := key + "-cert.pub"
+			if err := os.WriteFile(path, []byte(resp.Cert), 0644); err != nil {
+				return fmt.Errorf("failed to write cert: %w", err)
+			}
+
+			fmt.Fprintf(ctl.w, "cert of %s written to %s, valid for %s\n", user.Email, path, effect)
+			return nil
+		}),
+	}
+	issue.Flags().DurationVar(&effect, "effect", 8*time.Hour, "Lifetime of the certificate")
+	issue.Flags().StringVar(&key, "key", defaultKeyPath(), "Private key path, the cert is written to <key>-cert.pub")
+	issue.Flags().BoolVar(&force, "force", false, "Write the cert even if the local public key is different")
+
+	list := &cobra.Command{
+		Use:   "list USER",
+		Short: "List the certs granted to the user",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			ids, err := ctl.userIDs(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+
+			resp, err := ctl.ListUserCert(cmd.Context(), ids[0])
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "REVOKED", "CREATED"}
+				for _, c := range resp.Certs {
+					t.add(c.ID, c.IsRevoked, formatTime(c.CreatedAt))
+				}
+			})
+		}),
+	}
+
+	var certIDs []int64
+	revoke := &cobra.Command{
+		Use:   "revoke USER",
+		Short: "Revoke the certs of the user, all certs are revoked if --cert is not given",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			ids, err := ctl.userIDs(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.RevokeCert(cmd.Context(), &admin.RevokeCertRequest{UserID: ids[0], CertIDs: certIDs}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "certs of %s revoked\n", args[0])
+			return nil
+		}),
+	}
+	revoke.Flags().Int64SliceVar(&certIDs, "cert", nil, "IDs of the certs to revoke")
+
+	command.AddCommand(grant, issue, list, revoke)
+	return command
+}
+
+func (ctl *guardctl) grantCert(ctx context.Context, s string, effect time.Duration) (*admin.GrantCertResponse, error) {
+	ids, err := ctl.userIDs(ctx, []string{s})
+	if err != nil {
+		return nil, err
+	}
+
+	return ctl.GrantCert(ctx, &admin.GrantCertRequest{UserID: ids[0], Effect: int64(effect.Seconds())})
+}
+
+func defaultKeyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "id_ed25519"
+	}
+	return filepath.Join(home, ".ssh", "id_ed25519")
+}
+
+// samePublicKey compares the type and the key of the authorized keys,
+// the comments are ignored
+func samePublicKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	if len(fa) < 2 || len(fb) < 2 {
+		return false
+	}
+	return fa[0] == fb[0] && fa[1] == fb[1]
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// guardctl is the shared state of the subcommands
+type guardctl struct {
+	client
+	printer
+}
+
+func New() *cobra.Command {
+	ctl := &guardctl{}
+
+	root := cobra.Command{
+		Use:          "guardctl",
+		Short:        "A command-line tool for the Guard management API",
+		SilenceUsage: true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			ctl.printer.w = cmd.OutOrStdout()
+			return ctl.printer.validate()
+		},
+	}
+
+	root.AddCommand(newProfile(ctl))
+	root.AddCommand(newSpace(ctl))
+	root.AddCommand(newNode(ctl))
+	root.AddCommand(newRole(ctl))
+	root.AddCommand(newUser(ctl))
+	root.AddCommand(newCert(ctl))
+
+	flags := root.PersistentFlags()
+	flags.StringVarP(&ctl.output, "output", "o", outputTable, "Output format, one of table, json and yaml")
+	ctl.client.PersistentFlags(flags)
+
+	return &root
+}
+
+// run wraps the RunE of the commands calling the server, the client
+// is created from the profile before fn
+func (ctl *guardctl) run(fn func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := ctl.client.init(); err != nil {
+			return err
+		}
+		return fn(cmd, args)
+	}
+}
+
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return id, nil
+}
+
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseLabels parses the labels in the form of k=v
+func parseLabels(list []string) (map[string]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(list))
+	for _, s := range list {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q, must be k=v", s)
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
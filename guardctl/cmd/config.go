@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the config file of guardctl, it keeps the profiles of the
+// guard servers, e.g.
+//
+//	current: prod
+//	profiles:
+//	  prod:
+//	    server: https://guard.example.com
+//	    token: <TOKEN>
+type Config struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile is the address and the api token of a guard server
+type Profile struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token"`
+}
+
+// defaultConfigPath returns ~/.config/guardctl/config.yaml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "guardctl.yaml"
+	}
+	return filepath.Join(dir, "guardctl", "config.yaml")
+}
+
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config %s: %w", path, err)
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+	return cfg, nil
+}
+
+// save writes the config, it's only readable by the owner because
+// it contains the tokens
+func (c *Config) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	return nil
+}
+
+// client is the admin client built from the profile and the flags,
+// the flags take precedence over the profile
+type client struct {
+	admin.Guard
+
+	configPath string
+	profile    string
+	server     string
+	token      string
+}
+
+func (c *client) PersistentFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.configPath, "config", defaultConfigPath(), "Config file of the profiles")
+	flagSet.StringVar(&c.profile, "profile", "", "Profile to use, default is the current profile")
+	flagSet.StringVar(&c.server, "server", "", "Address of the guard server, overrides the profile")
+	flagSet.StringVar(&c.token, "token", "", "API token, overrides the profile, default is $GUARD_TOKEN")
+}
+
+// init creates the admin client
+func (c *client) init() error {
+	if c.Guard != nil {
+		return nil
+	}
+
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	name := c.profile
+	if name == "" {
+		name = cfg.Current
+	}
+
+	profile := &Profile{}
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %s not found in %s", name, c.configPath)
+		}
+		profile = p
+	}
+
+	server := c.server
+	if server == "" {
+		server = profile.Server
+	}
+	if server == "" {
+		return fmt.Errorf("guard server address is required, use --server or a profile")
+	}
+
+	token := c.token
+	if token == "" {
+		token = os.Getenv("GUARD_TOKEN")
+	}
+	if token == "" {
+		token = profile.Token
+	}
+
+	c.Guard, err = admin.NewHTTPGuard(server, token)
+	if err != nil {
+		return fmt.Errorf("failed to create guard: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+)
+
+func newNode(ctl *guardctl) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "node",
+		Short: "Manage the nodes of a space",
+	}
+
+	var spaceID int64
+	command.PersistentFlags().Int64Var(&spaceID, "space", 0, "Space ID of the nodes")
+	command.MarkPersistentFlagRequired("space") // nolint
+
+	var createReq admin.CreateNodeRequest
+	var labels []string
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a node, the unique id and the secret are only shown once",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			var err error
+			if createReq.Labels, err = parseLabels(labels); err != nil {
+				return err
+			}
+			createReq.SpaceID, createReq.Name = spaceID, args[0]
+
+			resp, err := ctl.CreateNode(cmd.Context(), &createReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "UNIQUE ID", "SECRET"}
+				t.add(resp.ID, resp.UniqueID, resp.Secret)
+			})
+		}),
+	}
+	create.Flags().StringVar(&createReq.IP, "ip", "", "IP address of the node")
+	create.Flags().StringVar(&createReq.Description, "description", "", "Description of the node")
+	create.Flags().StringSliceVar(&createReq.Accounts, "account", nil, "Accounts of the node, default is root")
+	create.Flags().StringSliceVar(&labels, "label", nil, "Labels of the node, k=v")
+
+	var listReq admin.ListNodeRequest
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the nodes",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			listReq.SpaceID = spaceID
+			resp, err := ctl.ListNode(cmd.Context(), &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "UNIQUE ID", "IP", "ACCOUNTS", "LABELS", "HEALTH", "LAST HEARTBEAT"}
+				for _, n := range resp.Nodes {
+					t.add(n.ID, n.Name, n.UniqueID, n.IP, strings.Join(n.Accounts, ","),
+						orDash(n.Labels.String()), n.Health, formatTime(n.LastHeartbeat))
+				}
+			})
+		}),
+	}
+	list.Flags().StringVar(&listReq.Name, "name", "", "Filter by the name substring")
+	list.Flags().StringVar(&listReq.Account, "account", "", "Filter by the account")
+	list.Flags().StringVar(&listReq.Labels, "labels", "", "Filter by the labels, k1=v1,k2=v2")
+	list.Flags().StringVar(&listReq.Health, "health", "", "Filter by the health, healthy, stale or offline")
+	pageFlags(list, &listReq.PageRequest)
+
+	remove := &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete a node",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.DeleteNode(cmd.Context(), spaceID, id); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "node %d deleted\n", id)
+			return nil
+		}),
+	}
+
+	heartbeat := &cobra.Command{
+		Use:   "heartbeat ID",
+		Short: "Show the health and the recent successful syncs of a node",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			resp, err := ctl.ListNodeHeartbeat(cmd.Context(), &admin.ListNodeHeartbeatRequest{SpaceID: spaceID, NodeID: id})
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				// the health is shown in the first row, the heartbeats newest first
+				t.header = []string{"HEALTH", "STALE AFTER", "OFFLINE AFTER", "HEARTBEAT"}
+				row := []any{resp.Health, formatSeconds(resp.StaleAfter), formatSeconds(resp.OfflineAfter)}
+				if len(resp.Heartbeats) == 0 {
+					t.add(append(row, "-")...)
+				}
+				for _, at := range resp.Heartbeats {
+					t.add(append(row, formatTime(at))...)
+					row = []any{"", "", ""}
+				}
+			})
+		}),
+	}
+
+	var syncReq admin.ListSpaceSyncRequest
+	sync := &cobra.Command{
+		Use:   "sync",
+		Short: "Compare the files reported by the nodes with what the server serves now",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			syncReq.SpaceID = spaceID
+			resp, err := ctl.ListSpaceSync(cmd.Context(), &syncReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "KIND", "STATUS", "CURRENT", "APPLIED", "REPORTED AT", "ERROR"}
+				for _, n := range resp.Nodes {
+					for _, a := range n.Artifacts {
+						t.add(n.NodeID, n.Name, a.Kind, a.Status, shortDigest(a.Current), shortDigest(a.Applied),
+							formatTime(a.ReportedAt), orDash(a.Error))
+					}
+				}
+			})
+		}),
+	}
+	sync.Flags().Int64Var(&syncReq.NodeID, "node", 0, "Filter by the node id")
+	sync.Flags().StringVar(&syncReq.Kind, "kind", "", "Filter by the file, ca, principals, krl or authorized_keys")
+	sync.Flags().StringVar(&syncReq.Status, "status", "", "Filter the nodes with a file in the status, synced, lagging, drift or unknown")
+
+	command.AddCommand(create, list, remove, heartbeat, sync)
+	return command
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer writes the responses in the output format
+type printer struct {
+	output string
+	w      io.Writer
+}
+
+func (p *printer) validate() error {
+	switch p.output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("output must be one of table, json and yaml")
+	}
+}
+
+// table is the columns of the table output
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(row ...any) {
+	cols := make([]string, 0, len(row))
+	for _, col := range row {
+		cols = append(cols, fmt.Sprint(col))
+	}
+	t.rows = append(t.rows, cols)
+}
+
+// print writes v as json or yaml, or the table built by fn
+func (p *printer) print(v any, fn func(t *table)) error {
+	switch p.output {
+	case outputJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		return p.yaml(v)
+	}
+
+	t := &table{}
+	fn(t)
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// yaml writes v with the json field names in the json field order
+func (p *printer) yaml(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+
+	// json is yaml, decoding into a node keeps the order of the fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return enc.Close()
+}
+
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// formatTime formats the unix seconds, 0 is shown as "-"
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format(time.DateTime)
+}
+
+// formatSeconds formats the seconds as a duration, 0 is shown as "default"
+func formatSeconds(seconds int64) string {
+	if seconds == 0 {
+		return "default"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// shortDigest returns the prefix of the digest like the short git hashes
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return orDash(digest)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
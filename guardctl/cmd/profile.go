@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newProfile(ctl *guardctl) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the profiles of the guard servers",
+	}
+
+	var profile Profile
+	set := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a profile, the first profile becomes the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(ctl.configPath)
+			if err != nil {
+				return err
+			}
+
+			p, ok := cfg.Profiles[args[0]]
+			if !ok {
+				p = &Profile{}
+				cfg.Profiles[args[0]] = p
+			}
+			if cmd.Flags().Changed("server") {
+				p.Server = profile.Server
+			}
+			if cmd.Flags().Changed("token") {
+				p.Token = profile.Token
+			}
+			if p.Server == "" {
+				return fmt.Errorf("server is required")
+			}
+
+			if cfg.Current == "" {
+				cfg.Current = args[0]
+			}
+			return cfg.save(ctl.configPath)
+		},
+	}
+	// the flags shadow the persistent --server and --token of the root
+	set.Flags().StringVar(&profile.Server, "server", "", "Address of the guard server")
+	set.Flags().StringVar(&profile.Token, "token", "", "API token")
+
+	use := &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(ctl.configPath)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %s not found", args[0])
+			}
+
+			cfg.Current = args[0]
+			return cfg.save(ctl.configPath)
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the profiles, the tokens are not shown",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(ctl.configPath)
+			if err != nil {
+				return err
+			}
+
+			type profileVO struct {
+				Name    string `json:"name"`
+				Server  string `json:"server"`
+				Current bool   `json:"current"`
+			}
+
+			profiles := make([]*profileVO, 0, len(cfg.Profiles))
+			for name, p := range cfg.Profiles {
+				profiles = append(profiles, &profileVO{Name: name, Server: p.Server, Current: name == cfg.Current})
+			}
+			slices.SortFunc(profiles, func(a, b *profileVO) int { return strings.Compare(a.Name, b.Name) })
+
+			return ctl.print(profiles, func(t *table) {
+				t.header = []string{"CURRENT", "NAME", "SERVER"}
+				for _, p := range profiles {
+					current := ""
+					if p.Current {
+						current = "*"
+					}
+					t.add(current, p.Name, p.Server)
+				}
+			})
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(ctl.configPath)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %s not found", args[0])
+			}
+
+			delete(cfg.Profiles, args[0])
+			if cfg.Current == args[0] {
+				cfg.Current = ""
+			}
+			return cfg.save(ctl.configPath)
+		},
+	}
+
+	command.AddCommand(set, use, list, remove)
+	return command
+}
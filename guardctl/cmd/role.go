@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+)
+
+func newRole(ctl *guardctl) *cobra.Command {
+	var spaceID int64
+	var global bool
+
+	command := &cobra.Command{
+		Use:   "role",
+		Short: "Manage the roles of a space or the global roles",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if global == (spaceID > 0) {
+				return fmt.Errorf("one of --space and --global is required")
+			}
+			return cmd.Root().PersistentPreRunE(cmd, args)
+		},
+	}
+	command.PersistentFlags().Int64Var(&spaceID, "space", 0, "Space ID of the roles")
+	command.PersistentFlags().BoolVar(&global, "global", false, "Manage the global roles")
+
+	var createReq admin.CreateRoleRequest
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a role",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			createReq.SpaceID, createReq.Global, createReq.Name = spaceID, global, args[0]
+			id, err := ctl.CreateRole(cmd.Context(), &createReq)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "role %s created, id %d\n", args[0], id)
+			return nil
+		}),
+	}
+	create.Flags().StringVar(&createReq.Description, "description", "", "Description of the role")
+	create.Flags().BoolVar(&createReq.BreakGlass, "break-glass", false, "Allow the emergency access of the role")
+
+	var listReq admin.ListRoleRequest
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the roles",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			listReq.SpaceID, listReq.Global = spaceID, global
+			resp, err := ctl.ListRole(cmd.Context(), &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "DESCRIPTION", "BREAK GLASS", "GLOBAL", "CREATED"}
+				for _, r := range resp.Roles {
+					t.add(r.ID, r.Name, orDash(r.Description), r.BreakGlass, r.Global, formatTime(r.CreatedAt))
+				}
+			})
+		}),
+	}
+	list.Flags().StringVar(&listReq.Name, "name", "", "Filter by the name substring")
+	pageFlags(list, &listReq.PageRequest)
+
+	remove := &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete a role",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.DeleteRole(cmd.Context(), spaceID, id); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "role %d deleted\n", id)
+			return nil
+		}),
+	}
+
+	command.AddCommand(create, list, remove)
+	command.AddCommand(newRoleUser(ctl, &spaceID)...)
+	command.AddCommand(newRoleNode(ctl, &spaceID)...)
+	return command
+}
+
+func newRoleUser(ctl *guardctl, spaceID *int64) []*cobra.Command {
+	var validFor time.Duration
+	add := &cobra.Command{
+		Use:   "add-user ROLE_ID USER...",
+		Short: "Add the users to the role, the users are ids or emails",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			roleID, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			userIDs, err := ctl.userIDs(cmd.Context(), args[1:])
+			if err != nil {
+				return err
+			}
+
+			req := &admin.AddUserToRoleRequest{RoleID: roleID, UserIDs: userIDs}
+			if validFor > 0 {
+				req.ValidUntil = time.Now().Add(validFor).Unix()
+			}
+
+			if err := ctl.AddUserToRole(cmd.Context(), *spaceID, req); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "%d users added to role %d\n", len(userIDs), roleID)
+			return nil
+		}),
+	}
+	add.Flags().DurationVar(&validFor, "valid-for", 0, "Duration of a temporary membership, e.g. 8h")
+
+	remove := &cobra.Command{
+		Use:   "remove-user ROLE_ID USER...",
+		Short: "Remove the users from the role, the users are ids or emails",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			roleID, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			userIDs, err := ctl.userIDs(cmd.Context(), args[1:])
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.RemoveUserFromRole(cmd.Context(), *spaceID,
+				&admin.RemoveUserFromRoleRequest{RoleID: roleID, UserIDs: userIDs}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "%d users removed from role %d\n", len(userIDs), roleID)
+			return nil
+		}),
+	}
+
+	var listReq admin.ListRoleUserRequest
+	list := &cobra.Command{
+		Use:   "users ROLE_ID",
+		Short: "List the users of the role",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			var err error
+			if listReq.RoleID, err = parseID(args[0]); err != nil {
+				return err
+			}
+
+			resp, err := ctl.ListRoleUser(cmd.Context(), *spaceID, &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "USERNAME", "EMAIL", "STATE", "DIRECT", "GROUPS", "VALID UNTIL"}
+				for _, u := range resp.Users {
+					t.add(u.ID, u.Username, u.Email, u.State, u.Direct,
+						orDash(strings.Join(u.Groups, ",")), formatTime(u.ValidUntil))
+				}
+			})
+		}),
+	}
+	pageFlags(list, &listReq.PageRequest)
+
+	return []*cobra.Command{add, remove, list}
+}
+
+func newRoleNode(ctl *guardctl, spaceID *int64) []*cobra.Command {
+	add := &cobra.Command{
+		Use:   "add-node ROLE_ID NODE[:ACCOUNT]...",
+		Short: "Add the nodes to the role, the nodes are ids or unique ids",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			roleID, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			req := &admin.AddNodeToRoleRequest{RoleID: roleID}
+			for _, arg := range args[1:] {
+				node, account, _ := strings.Cut(arg, ":")
+				n := admin.RoleNodeRequest{Account: account}
+				if id, err := parseID(node); err == nil {
+					n.NodeID = id
+				} else {
+					n.UniqueID = node
+				}
+				req.Nodes = append(req.Nodes, n)
+			}
+
+			if err := ctl.AddNodeToRole(cmd.Context(), *spaceID, req); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "%d nodes added to role %d\n", len(req.Nodes), roleID)
+			return nil
+		}),
+	}
+
+	remove := &cobra.Command{
+		Use:   "remove-node ROLE_ID NODE_ID...",
+		Short: "Remove the nodes from the role",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			roleID, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			nodeIDs, err := parseIDs(args[1:])
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.RemoveNodeFromRole(cmd.Context(), *spaceID,
+				&admin.RemoveNodeFromRoleRequest{RoleID: roleID, NodeIDs: nodeIDs}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "%d nodes removed from role %d\n", len(nodeIDs), roleID)
+			return nil
+		}),
+	}
+
+	var listReq admin.ListRoleNodeRequest
+	list := &cobra.Command{
+		Use:   "nodes ROLE_ID",
+		Short: "List the nodes of the role, including the nodes bound by the label selectors",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			var err error
+			if listReq.RoleID, err = parseID(args[0]); err != nil {
+				return err
+			}
+
+			resp, err := ctl.ListRoleNode(cmd.Context(), *spaceID, &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "SPACE", "ACCOUNT", "IP", "DYNAMIC", "HEALTH", "LAST HEARTBEAT"}
+				for _, n := range resp.Nodes {
+					t.add(n.ID, n.Name, n.SpaceID, n.Account, n.IP, n.Dynamic, n.Health, formatTime(n.LastHeartbeat))
+				}
+			})
+		}),
+	}
+	pageFlags(list, &listReq.PageRequest)
+
+	return []*cobra.Command{add, remove, list}
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+)
+
+func newSpace(ctl *guardctl) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "space",
+		Short: "Manage the spaces",
+	}
+
+	var description string
+	var staleAfter, offlineAfter time.Duration
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a space",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := ctl.CreateSpace(cmd.Context(), &admin.CreateSpaceRequest{Name: args[0], Description: description,
+				StaleAfter: seconds(staleAfter), OfflineAfter: seconds(offlineAfter)})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "space %s created, id %d\n", args[0], id)
+			return nil
+		}),
+	}
+	create.Flags().StringVar(&description, "description", "", "Description of the space")
+	thresholdFlags(create, &staleAfter, &offlineAfter)
+
+	var name string
+	update := &cobra.Command{
+		Use:   "update ID",
+		Short: "Update the name, description or heartbeat thresholds of a space",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			req := &admin.UpdateSpaceRequest{SpaceID: id}
+			flags := cmd.Flags()
+			if flags.Changed("name") {
+				req.Name = &name
+			}
+			if flags.Changed("description") {
+				req.Description = &description
+			}
+			if flags.Changed("stale-after") {
+				v := seconds(staleAfter)
+				req.StaleAfter = &v
+			}
+			if flags.Changed("offline-after") {
+				v := seconds(offlineAfter)
+				req.OfflineAfter = &v
+			}
+
+			if err := ctl.UpdateSpace(cmd.Context(), req); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "space %d updated\n", id)
+			return nil
+		}),
+	}
+	update.Flags().StringVar(&name, "name", "", "Name of the space")
+	update.Flags().StringVar(&description, "description", "", "Description of the space")
+	thresholdFlags(update, &staleAfter, &offlineAfter)
+
+	var listReq admin.ListSpaceRequest
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the spaces",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			resp, err := ctl.ListSpace(cmd.Context(), &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "DESCRIPTION", "STALE AFTER", "OFFLINE AFTER", "CREATED"}
+				for _, s := range resp.Spaces {
+					t.add(s.ID, s.Name, orDash(s.Description), formatSeconds(s.StaleAfter),
+						formatSeconds(s.OfflineAfter), formatTime(s.CreatedAt))
+				}
+			})
+		}),
+	}
+	list.Flags().StringVar(&listReq.Name, "name", "", "Filter by the name substring")
+	pageFlags(list, &listReq.PageRequest)
+
+	var force bool
+	remove := &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete a space",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.DeleteSpace(cmd.Context(), &admin.DeleteSpaceRequest{SpaceID: id, Force: force}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "space %d deleted\n", id)
+			return nil
+		}),
+	}
+	remove.Flags().BoolVar(&force, "force", false, "Delete the nodes and roles of the space")
+
+	health := &cobra.Command{
+		Use:   "health",
+		Short: "Count the nodes of every space by the health",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			resp, err := ctl.FleetHealth(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "NAME", "STALE AFTER", "OFFLINE AFTER", "TOTAL", "HEALTHY", "STALE", "OFFLINE"}
+				for _, s := range resp.Spaces {
+					t.add(s.SpaceID, s.SpaceName, formatSeconds(s.StaleAfter), formatSeconds(s.OfflineAfter),
+						s.Total, s.Healthy, s.Stale, s.Offline)
+				}
+			})
+		}),
+	}
+
+	command.AddCommand(create, update, list, remove, health)
+	return command
+}
+
+// thresholdFlags adds the heartbeat threshold flags of the spaces
+func thresholdFlags(cmd *cobra.Command, staleAfter, offlineAfter *time.Duration) {
+	cmd.Flags().DurationVar(staleAfter, "stale-after", 0,
+		"Duration without heartbeat before the nodes are stale, 0 means the server default")
+	cmd.Flags().DurationVar(offlineAfter, "offline-after", 0,
+		"Duration without heartbeat before the nodes are offline, 0 means the server default")
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+// pageFlags adds the paging flags of the list commands
+func pageFlags(cmd *cobra.Command, page *admin.PageRequest) {
+	cmd.Flags().Int64Var(&page.Page, "page", 1, "Page number, start from 1")
+	cmd.Flags().Int64Var(&page.Limit, "limit", 100, "Number of items per page, max 1000")
+	cmd.Flags().StringVar(&page.Sort, "sort", "", "Sort field, prefix - means descending, e.g. -created_at")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sysarmor/guard/server/pkg/apis/admin"
+)
+
+// user returns the user by the id or the email
+func (ctl *guardctl) user(ctx context.Context, s string) (*admin.GetUserResponse, error) {
+	if id, err := parseID(s); err == nil {
+		return ctl.GetUser(ctx, id)
+	}
+	return ctl.GetUserByEmail(ctx, s)
+}
+
+// userIDs returns the ids of the users, the emails are looked up
+func (ctl *guardctl) userIDs(ctx context.Context, list []string) ([]int64, error) {
+	ids := make([]int64, 0, len(list))
+	for _, s := range list {
+		if id, err := parseID(s); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+
+		user, err := ctl.GetUserByEmail(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user %s: %w", s, err)
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+func newUser(ctl *guardctl) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "user",
+		Short: "Manage the users",
+	}
+
+	var createReq admin.CreateUserRequest
+	var publicKeyFile string
+	var expiresIn time.Duration
+	create := &cobra.Command{
+		Use:   "create EMAIL",
+		Short: "Create a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			key, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read public key: %w", err)
+			}
+
+			createReq.Email, createReq.PublicKey = args[0], strings.TrimSpace(string(key))
+			if createReq.Username == "" {
+				createReq.Username, _, _ = strings.Cut(args[0], "@")
+			}
+			if expiresIn > 0 {
+				createReq.ExpiresAt = time.Now().Add(expiresIn).Unix()
+			}
+
+			id, err := ctl.CreateUser(cmd.Context(), &createReq)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "user %s created, id %d\n", args[0], id)
+			return nil
+		}),
+	}
+	create.Flags().StringVar(&createReq.Username, "username", "", "Username, default is the local part of the email")
+	create.Flags().StringVar(&publicKeyFile, "public-key", "", "Public key file of the user, e.g. id_ed25519.pub")
+	create.Flags().DurationVar(&expiresIn, "expires-in", 0, "Expire the user after the duration, e.g. 720h")
+	create.MarkFlagRequired("public-key") // nolint
+
+	var listReq admin.ListUserRequest
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the users",
+		Args:  cobra.NoArgs,
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			resp, err := ctl.ListUser(cmd.Context(), &listReq)
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(resp, func(t *table) {
+				t.header = []string{"ID", "USERNAME", "EMAIL", "STATE", "EXPIRES", "CREATED"}
+				for _, u := range resp.Users {
+					t.add(u.ID, u.Username, u.Email, u.State, formatTime(u.ExpiresAt), formatTime(u.CreatedAt))
+				}
+			})
+		}),
+	}
+	list.Flags().StringVar(&listReq.Name, "name", "", "Filter by the username substring")
+	list.Flags().StringVar(&listReq.Email, "email", "", "Filter by the email substring")
+	list.Flags().StringVar(&listReq.State, "state", "", "Filter by the state, one of active, suspended, expired and banned")
+	pageFlags(list, &listReq.PageRequest)
+
+	get := &cobra.Command{
+		Use:   "get USER",
+		Short: "Get a user by the id or the email",
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			user, err := ctl.user(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return ctl.print(user, func(t *table) {
+				t.header = []string{"ID", "USERNAME", "EMAIL", "STATE", "EXPIRES", "PUBLIC KEY"}
+				t.add(user.ID, user.Username, user.Email, user.State, formatTime(user.ExpiresAt), user.PubKey)
+			})
+		}),
+	}
+
+	command.AddCommand(create, list, get)
+	command.AddCommand(
+		newUserAction(ctl, "delete", "Delete the user, the certs of the user are revoked", admin.Guard.DeleteUser),
+		newUserAction(ctl, "ban", "Ban the user, the user is removed from all roles", admin.Guard.BanUser),
+		newUserAction(ctl, "unban", "Unban the user", admin.Guard.UnbanUser),
+		newUserAction(ctl, "suspend", "Suspend the user, it can be resumed", admin.Guard.SuspendUser),
+		newUserAction(ctl, "resume", "Resume the suspended user", admin.Guard.ResumeUser),
+	)
+	return command
+}
+
+// newUserAction creates the command changing a user, e.g. "ban USER"
+func newUserAction(ctl *guardctl, name, short string, action func(g admin.Guard, ctx context.Context, id int64) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " USER",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: ctl.run(func(cmd *cobra.Command, args []string) error {
+			ids, err := ctl.userIDs(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+
+			if err := action(ctl.Guard, cmd.Context(), ids[0]); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(ctl.w, "user %s: %s done\n", args[0], name)
+			return nil
+		}),
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/backup"
+	"github.com/sysarmor/guard/server/internal/repo/postgres"
+)
+
+// passphraseEnv is the environment variable of the backup passphrase,
+// it's never accepted as a flag to keep it out of the shell history
+const passphraseEnv = "GUARD_BACKUP_PASSPHRASE"
+
+// readPassphrase reads the passphrase from the file, or the environment
+// variable if the file is not set
+func readPassphrase(file string) ([]byte, error) {
+	if file == "" {
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("passphrase is required, set %s or use -passphrase-file", passphraseEnv)
+		}
+		return []byte(passphrase), nil
+	}
+
+	passphrase, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase file: %w", err)
+	}
+
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase file %s is empty", file)
+	}
+
+	return passphrase, nil
+}
+
+// backupCommand writes all tables, the CA keypair and the schema
+// version into one encrypted archive
+func backupCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "config file path")
+	output := fs.String("o", "", "archive file path")
+	passphraseFile := fs.String("passphrase-file", "", "file of the passphrase, default $"+passphraseEnv)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return fmt.Errorf("archive file is required, use -o")
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("get config: %w", err)
+	}
+
+	archive := &backup.Archive{Version: backup.Version, CreatedAt: time.Now().UTC()}
+	if archive.CA.PublicKey, err = os.ReadFile(cfg.Services.PubKeyPath); err != nil {
+		return fmt.Errorf("read public key: %w", err)
+	}
+	if archive.CA.PrivateKey, err = os.ReadFile(cfg.Services.PrivKeyPath); err != nil {
+		return fmt.Errorf("read private key: %w", err)
+	}
+
+	db, err := postgres.Open(ctx, &cfg.Postgres)
+	if err != nil {
+		return fmt.Errorf("open postgres: %w", err)
+	}
+	defer db.Close()
+
+	if archive.Dump, err = postgres.DumpAll(ctx, db); err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	fd, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("create archive file: %w", err)
+	}
+	defer fd.Close()
+
+	if err := backup.Write(fd, archive, passphrase); err != nil {
+		os.Remove(*output) // nolint
+		return err
+	}
+
+	rows := 0
+	for _, table := range archive.Tables {
+		rows += len(table.Rows)
+	}
+	fmt.Printf("backup %s: schema version %d, %d tables, %d rows\n",
+		*output, archive.SchemaVersion, len(archive.Tables), rows)
+	return nil
+}
+
+// restoreCommand restores the archive into an empty database and writes
+// the CA keypair to the paths in the config
+func restoreCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "config file path")
+	file := fs.String("f", "", "archive file path")
+	passphraseFile := fs.String("passphrase-file", "", "file of the passphrase, default $"+passphraseEnv)
+	verify := fs.Bool("verify", false, "only verify the archive without restoring")
+	force := fs.Bool("force", false, "overwrite the existing CA keypair if it is different")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("archive file is required, use -f")
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("open archive file: %w", err)
+	}
+	defer fd.Close()
+
+	archive, err := backup.Read(fd, passphrase)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("archive %s: created at %s, schema version %d, %d tables\n",
+		*file, archive.CreatedAt.Format(time.RFC3339), archive.SchemaVersion, len(archive.Tables))
+	if *verify {
+		return nil
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("get config: %w", err)
+	}
+
+	keys := []struct {
+		path string
+		data []byte
+		mode os.FileMode
+	}{
+		{cfg.Services.PubKeyPath, archive.CA.PublicKey, 0644},
+		{cfg.Services.PrivKeyPath, archive.CA.PrivateKey, 0600},
+	}
+
+	// check the keypair before the database is changed
+	for _, key := range keys {
+		existing, err := os.ReadFile(key.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", key.path, err)
+		}
+
+		if !bytes.Equal(existing, key.data) && !*force {
+			return fmt.Errorf("%s is different from the archive, use -force to overwrite it", key.path)
+		}
+	}
+
+	db, err := postgres.Open(ctx, &cfg.Postgres)
+	if err != nil {
+		return fmt.Errorf("open postgres: %w", err)
+	}
+	defer db.Close()
+
+	if err := postgres.Restore(ctx, db, archive.Dump); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	for _, key := range keys {
+		if err := os.MkdirAll(filepath.Dir(key.path), 0700); err != nil {
+			return fmt.Errorf("create directory of %s: %w", key.path, err)
+		}
+
+		if err := os.WriteFile(key.path, key.data, key.mode); err != nil {
+			return fmt.Errorf("write %s: %w", key.path, err)
+		}
+	}
+
+	fmt.Printf("restored into database %s\n", cfg.Postgres.Database)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sysarmor/guard/server/internal/repo/postgres"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// commands are the subcommands of the server binary, e.g.
+// "guard-server apply -f state.yaml", the server runs without one
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"apply":   applyCommand,
+	"plan":    applyCommand,
+	"export":  exportCommand,
+	"backup":  backupCommand,
+	"restore": restoreCommand,
+}
+
+// cliOperator is the operator of the subcommands, they run with
+// the database access so they are trusted as a super admin
+var cliOperator = &service.Operator{Name: "cli", SuperAdmin: true}
+
+// newService creates the service from the config file for the subcommands
+func newService(ctx context.Context, configPath string) (service.Guard, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("get config: %w", err)
+	}
+
+	repo, err := postgres.New(ctx, &cfg.Postgres)
+	if err != nil {
+		return nil, fmt.Errorf("new postgres: %w", err)
+	}
+
+	svc, err := service.New(cfg.Services, repo)
+	if err != nil {
+		return nil, fmt.Errorf("new service: %w", err)
+	}
+
+	return svc, nil
+}
+
+// applyCommand applies the state file, "plan" is the same as "apply -plan"
+func applyCommand(ctx context.Context, args []string) error {
+	name := "apply"
+	if os.Args[1] == "plan" {
+		name = "plan"
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "config file path")
+	file := fs.String("f", "", "state file path, - for stdin")
+	plan := fs.Bool("plan", name == "plan", "print the changes without applying")
+	prune := fs.Bool("prune", false, "remove the objects and bindings not in the state")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("state file is required, use -f")
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		fd, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("open state file: %w", err)
+		}
+		defer fd.Close()
+		r = fd
+	}
+
+	state, err := service.DecodeState(r)
+	if err != nil {
+		return err
+	}
+
+	req := &service.ApplyRequest{State: state, Plan: *plan, Prune: *prune}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	svc, err := newService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := svc.Apply(service.WithOperator(ctx, cliOperator), req)
+	if err != nil {
+		return fmt.Errorf("apply: %w", err)
+	}
+
+	printApply(os.Stdout, resp)
+	return nil
+}
+
+// printApply prints the changes, the principals diff in the format of
+// guard-client principals, and the secrets of the created nodes
+func printApply(w io.Writer, resp *service.ApplyResponse) {
+	for _, change := range resp.Changes {
+		fmt.Fprintln(w, change)
+	}
+
+	if len(resp.Diffs) > 0 {
+		fmt.Fprintln(w, "\nprincipals:")
+	}
+	for _, diff := range resp.Diffs {
+		fmt.Fprintf(w, "  node=%s node_id=%d role=%s diff=[%s]\n",
+			diff.NodeName, diff.NodeID, diff.Role, strings.Join(diff.Diff, " "))
+	}
+
+	if len(resp.Nodes) > 0 {
+		fmt.Fprintln(w, "\nnodes:")
+	}
+	for _, node := range resp.Nodes {
+		fmt.Fprintf(w, "  %s/%s unique_id=%s secret=%s\n", node.Space, node.Name, node.UniqueID, node.Secret)
+	}
+
+	if resp.Plan {
+		fmt.Fprintf(w, "\nPlan: %d changes, nothing is applied\n", len(resp.Changes))
+		return
+	}
+	fmt.Fprintf(w, "\nApplied %d changes\n", len(resp.Changes))
+}
+
+// exportCommand writes the current state as yaml
+func exportCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "config file path")
+	output := fs.String("o", "-", "output file path, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, err := newService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+
+	state, err := svc.Export(service.WithOperator(ctx, cliOperator))
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		fd, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("open output file: %w", err)
+		}
+		defer fd.Close()
+		w = fd
+	}
+
+	return state.Encode(w)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/sysarmor/guard/server/internal/controller"
 	"github.com/sysarmor/guard/server/internal/repo/postgres"
 	"github.com/sysarmor/guard/server/internal/service"
 	"gopkg.in/yaml.v3"
@@ -15,6 +17,31 @@ type config struct {
 	Postgres postgres.Config `yaml:"postgres"`
 
 	Services service.Config `yaml:"services"`
+
+	Controller controller.Config `yaml:"controller"`
+
+	Shutdown shutdownConfig `yaml:"shutdown"`
+}
+
+// shutdownConfig is the configuration of the graceful shutdown
+type shutdownConfig struct {
+	// Delay is the time the server keeps serving after the readiness
+	// fails, so that the load balancer stops sending the new requests,
+	// default 0
+	Delay time.Duration `yaml:"delay"`
+	// Timeout is the max time to drain the in-flight requests after the
+	// delay, the remaining connections are closed, default 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *shutdownConfig) Validate() {
+	if c.Delay < 0 {
+		c.Delay = 0
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
 }
 
 func (c *config) Validate() error {
@@ -30,6 +57,12 @@ func (c *config) Validate() error {
 		return fmt.Errorf("services: %w", err)
 	}
 
+	if err := c.Controller.Validate(); err != nil {
+		return fmt.Errorf("controller: %w", err)
+	}
+
+	c.Shutdown.Validate()
+
 	return nil
 }
 
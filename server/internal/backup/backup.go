@@ -0,0 +1,182 @@
+// Package backup is the encrypted archive of the guard server, it contains
+// all tables, the CA keypair and the schema version
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/repo/postgres"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Version is the format version of the archive
+const Version = 1
+
+// magic is the header of the archive file
+const magic = "GUARDBK1"
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// scrypt parameters of the key derivation
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrDecrypt is returned if the passphrase is wrong or the archive is modified
+var ErrDecrypt = errors.New("failed to decrypt archive, wrong passphrase or corrupted archive")
+
+// CA is the keypair of the certificate authority, the private key is kept
+// as it is on the disk, which is encrypted by the ca passphrase
+type CA struct {
+	PublicKey  []byte `json:"public_key"`
+	PrivateKey []byte `json:"private_key"`
+}
+
+// Archive is the content of the backup
+type Archive struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	CA        CA        `json:"ca"`
+
+	*postgres.Dump
+}
+
+// Verify checks the version and the checksums of the archive
+func (a *Archive) Verify() error {
+	if a.Version != Version {
+		return fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	if a.Dump == nil {
+		return fmt.Errorf("archive has no tables")
+	}
+
+	if len(a.CA.PublicKey) == 0 || len(a.CA.PrivateKey) == 0 {
+		return fmt.Errorf("archive has no ca keypair")
+	}
+
+	return a.Dump.Verify()
+}
+
+// Write encrypts the archive with the passphrase into w. The file is the
+// magic, the scrypt salt, the AES-GCM nonce and the sealed gzip json.
+func Write(w io.Writer, archive *Archive, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return fmt.Errorf("failed to encode archive: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := append(append([]byte(magic), salt...), nonce...)
+	// the header is authenticated with the content
+	sealed := aead.Seal(nil, nonce, plain.Bytes(), header)
+
+	if _, err := w.Write(append(header, sealed...)); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return nil
+}
+
+// Read decrypts the archive from r and verifies it
+func Read(r io.Reader, passphrase []byte) (*Archive, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, fmt.Errorf("not a guard backup archive")
+	}
+
+	if len(data) < len(magic)+saltSize {
+		return nil, ErrDecrypt
+	}
+	salt := data[len(magic) : len(magic)+saltSize]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(magic) + saltSize + aead.NonceSize()
+	if len(data) < headerSize {
+		return nil, ErrDecrypt
+	}
+
+	plain, err := aead.Open(nil, data[len(magic)+saltSize:headerSize], data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer zr.Close()
+
+	archive := new(Archive)
+	if err := json.NewDecoder(zr).Decode(archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+
+	if err := archive.Verify(); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func newAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
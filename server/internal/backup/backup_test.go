@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/repo/postgres"
+)
+
+func newArchive() *Archive {
+	table := postgres.NewTable("user", []string{`{"id":1,"email":"a@example.com"}`})
+	return &Archive{
+		Version:   Version,
+		CreatedAt: time.Unix(1000, 0).UTC(),
+		CA:        CA{PublicKey: []byte("public"), PrivateKey: []byte("private")},
+		Dump:      &postgres.Dump{SchemaVersion: 8, Tables: []*postgres.Table{table}},
+	}
+}
+
+func TestArchive(t *testing.T) {
+	passphrase := []byte("passphrase")
+
+	archive := newArchive()
+	var buf bytes.Buffer
+	if err := Write(&buf, archive, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	t.Run("Roundtrip", func(t *testing.T) {
+		got, err := Read(bytes.NewReader(data), passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.SchemaVersion != 8 || len(got.Tables) != 1 || got.Tables[0].Rows[0] != archive.Tables[0].Rows[0] {
+			t.Fatalf("unexpected archive: %+v", got)
+		}
+
+		if string(got.CA.PrivateKey) != "private" {
+			t.Fatalf("unexpected ca: %+v", got.CA)
+		}
+	})
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		if _, err := Read(bytes.NewReader(data), []byte("wrong")); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := bytes.Clone(data)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := Read(bytes.NewReader(tampered), passphrase); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		bad := newArchive()
+		bad.Tables[0].Checksum = "bad"
+
+		var buf bytes.Buffer
+		if err := Write(&buf, bad, passphrase); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Read(&buf, passphrase); err == nil {
+			t.Fatalf("checksum mismatch should fail")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+// Package console is the web admin console embedded in the server. The
+// static files have no external assets, so it works in the air-gapped
+// networks. The files contain no data and are served without the
+// authentication, the console calls the admin api with the token entered
+// by the user, the same as the other api clients.
+package console
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the console under the prefix, e.g. /console. The
+// route must have the *filepath wildcard.
+func Handler(prefix string) gin.HandlerFunc {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	fileServer := http.StripPrefix(prefix, http.FileServer(http.FS(files)))
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		// only the embedded files are allowed, so that the token can not
+		// be sent anywhere else by an injected script
+		header.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,374 @@
+'use strict';
+
+// The console of the guard admin api. It keeps the api token in the
+// session storage and renders the views with the DOM api, the data is
+// always set as text, never as html.
+
+const API = '/api/v1/guard';
+const TOKEN_KEY = 'guard.token';
+
+const main = document.getElementById('main');
+const errorBox = document.getElementById('error');
+
+// ==== api ====
+
+async function request(method, path, body) {
+  const headers = {};
+  const token = sessionStorage.getItem(TOKEN_KEY);
+  if (token) {
+    headers.Authorization = 'Bearer ' + token;
+  }
+  if (body !== undefined) {
+    headers['Content-Type'] = 'application/json';
+  }
+
+  const resp = await fetch(API + path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  if (resp.status === 401) {
+    sessionStorage.removeItem(TOKEN_KEY);
+    location.hash = '#/login';
+    throw new Error('the token is invalid or expired, please sign in again');
+  }
+
+  const text = await resp.text();
+  let data = null;
+  try {
+    data = text ? JSON.parse(text) : null;
+  } catch (e) {
+    // not a json body, e.g. the bind errors
+  }
+
+  if (!resp.ok) {
+    throw new Error(data && data.message ? data.message : resp.status + ' ' + resp.statusText);
+  }
+  return data;
+}
+
+const get = (path) => request('GET', path);
+const post = (path, body) => request('POST', path, body === undefined ? {} : body);
+
+function query(params) {
+  const q = new URLSearchParams({ page: 1, limit: 1000 });
+  for (const [k, v] of Object.entries(params || {})) {
+    if (v !== '' && v !== undefined) {
+      q.set(k, v);
+    }
+  }
+  return '?' + q.toString();
+}
+
+function rolePath(spaceID) {
+  return spaceID ? `/space/${spaceID}/role` : '/global_role';
+}
+
+// ==== dom ====
+
+// h creates an element, the functions in attrs are the event handlers
+// and the children are the nodes or the strings
+function h(tag, attrs, ...children) {
+  const el = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (typeof v === 'function') {
+      el.addEventListener(k.replace(/^on/, ''), v);
+    } else if (k === 'class') {
+      el.className = v;
+    } else if (v !== undefined && v !== false) {
+      el.setAttribute(k, v === true ? '' : v);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child !== null && child !== undefined) {
+      el.append(child instanceof Node ? child : String(child));
+    }
+  }
+  return el;
+}
+
+function table(header, rows, empty) {
+  if (rows.length === 0) {
+    return h('p', { class: 'muted' }, empty || 'Nothing here.');
+  }
+  return h('table', {},
+    h('thead', {}, h('tr', {}, header.map((name) => h('th', {}, name)))),
+    h('tbody', {}, rows.map((row) => h('tr', {}, row.map((cell) => h('td', {}, cell))))));
+}
+
+function badge(text, cls) {
+  return h('span', { class: 'badge ' + (cls || '') }, text);
+}
+
+function link(href, text) {
+  return h('a', { href }, text);
+}
+
+function show(...children) {
+  main.replaceChildren(...children);
+}
+
+function showError(err) {
+  errorBox.textContent = err ? err.message || String(err) : '';
+  errorBox.hidden = !err;
+}
+
+// action runs fn after the confirmation and re-renders the current view
+function action(message, fn) {
+  return async () => {
+    if (message && !confirm(message)) {
+      return;
+    }
+    try {
+      await fn();
+      await route();
+    } catch (err) {
+      showError(err);
+    }
+  };
+}
+
+// ==== format ====
+
+function formatTime(ts) {
+  return ts ? new Date(ts * 1000).toLocaleString() : '-';
+}
+
+function ago(seconds) {
+  if (seconds < 60) return Math.max(0, Math.floor(seconds)) + 's ago';
+  if (seconds < 3600) return Math.floor(seconds / 60) + 'm ago';
+  if (seconds < 86400) return Math.floor(seconds / 3600) + 'h ago';
+  return Math.floor(seconds / 86400) + 'd ago';
+}
+
+// health shows the health computed by the server and the age of the
+// last successful sync of the node
+function health(n) {
+  const seen = n.last_heartbeat ? ago(Date.now() / 1000 - n.last_heartbeat) : 'never synced';
+  return h('span', { title: formatTime(n.last_heartbeat) }, badge(n.health, n.health), ' ', seen);
+}
+
+function formatLabels(labels) {
+  return Object.keys(labels || {}).sort().map((k) => k + '=' + labels[k]).join(', ') || '-';
+}
+
+// ==== views ====
+
+function login() {
+  const form = document.getElementById('login').content.firstElementChild.cloneNode(true);
+  form.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    sessionStorage.setItem(TOKEN_KEY, form.elements.token.value.trim());
+    try {
+      // check the token with a cheap request
+      await get('/space' + query({ limit: 1 }));
+      location.hash = '#/spaces';
+    } catch (err) {
+      showError(err);
+    }
+  });
+  show(form);
+}
+
+async function spaces() {
+  const [resp, fleet] = await Promise.all([get('/space' + query()), get('/fleet/health')]);
+  const counts = new Map(fleet.spaces.map((s) => [s.space_id, s]));
+  const count = (s, key, cls) => {
+    const n = counts.has(s.id) ? counts.get(s.id)[key] : 0;
+    return n ? badge(String(n), cls) : '0';
+  };
+  show(
+    h('h2', {}, 'Spaces'),
+    table(['Name', 'Description', 'Healthy', 'Stale', 'Offline', 'Created'], resp.spaces.map((s) => [
+      link(`#/space/${s.id}`, s.name), s.description || '-',
+      count(s, 'healthy', 'healthy'), count(s, 'stale', 'stale'), count(s, 'offline', 'offline'),
+      formatTime(s.created_at),
+    ]), 'No spaces yet.'));
+}
+
+async function space(spaceID) {
+  const [spaceList, nodes, roles] = await Promise.all([
+    get('/space' + query()),
+    get(`/space/${spaceID}/node` + query()),
+    get(rolePath(spaceID) + query()),
+  ]);
+  const s = spaceList.spaces.find((s) => s.id === spaceID);
+
+  show(
+    h('h2', {}, s ? s.name : `Space ${spaceID}`),
+    s && s.description ? h('p', { class: 'muted' }, s.description) : null,
+    h('h3', {}, `Nodes (${nodes.total})`),
+    table(['Name', 'Unique ID', 'IP', 'Accounts', 'Labels', 'Health'], nodes.nodes.map((n) => [
+      n.name, n.unique_id, n.ip, (n.accounts || []).join(', '), formatLabels(n.labels), health(n),
+    ]), 'No nodes in the space.'),
+    h('h3', {}, `Roles (${roles.total})`),
+    roleTable(roles.roles, spaceID));
+}
+
+async function globalRoles() {
+  const roles = await get(rolePath(0) + query());
+  show(h('h2', {}, 'Global roles'), roleTable(roles.roles, 0));
+}
+
+function roleTable(roles, spaceID) {
+  const base = spaceID ? `#/space/${spaceID}/role/` : '#/global/';
+  return table(['Name', 'Description', 'Break glass', 'Created'], roles.map((r) => [
+    link(base + r.id, r.name), r.description || '-', r.break_glass ? 'yes' : 'no', formatTime(r.created_at),
+  ]), 'No roles yet.');
+}
+
+async function role(spaceID, roleID) {
+  const path = `${rolePath(spaceID)}/${roleID}`;
+  const [roles, users, nodes, groups] = await Promise.all([
+    get(rolePath(spaceID) + query()),
+    get(path + '/user' + query()),
+    get(path + '/node' + query()),
+    get(path + '/group'),
+  ]);
+  const r = roles.roles.find((r) => r.id === roleID);
+
+  show(
+    h('h2', {}, (r ? r.name : `Role ${roleID}`) + (spaceID ? '' : ' (global)')),
+    r && r.description ? h('p', { class: 'muted' }, r.description) : null,
+    h('h3', {}, `Users (${users.total})`),
+    table(['Email', 'Username', 'State', 'Membership', 'Valid until'], users.users.map((u) => [
+      link(`#/user/${u.id}`, u.email), u.username, badge(u.state, u.state),
+      [u.direct ? 'direct' : null, ...(u.groups || []).map((g) => 'group ' + g)].filter(Boolean).join(', '),
+      formatTime(u.valid_until),
+    ]), 'No users in the role.'),
+    h('h3', {}, `Groups (${(groups.groups || []).length})`),
+    table(['Name', 'Description'], (groups.groups || []).map((g) => [g.name, g.description || '-']),
+      'No groups in the role.'),
+    h('h3', {}, `Nodes (${nodes.total})`),
+    table(['Name', 'Account', 'IP', 'Bound by', 'Health'], nodes.nodes.map((n) => [
+      n.name, n.account, n.ip, n.dynamic ? 'selector' : 'node', health(n),
+    ]), 'No nodes in the role.'));
+}
+
+async function users(params) {
+  const resp = await get('/users' + query(params));
+
+  const email = h('input', { name: 'email', placeholder: 'Email', value: params.email || '' });
+  const state = h('select', { name: 'state' },
+    ['', 'active', 'suspended', 'expired', 'banned'].map((s) =>
+      h('option', { value: s, selected: s === (params.state || '') }, s || 'any state')));
+  const search = h('form', {
+    class: 'toolbar',
+    onsubmit: (e) => {
+      e.preventDefault();
+      const q = new URLSearchParams({ email: email.value.trim(), state: state.value });
+      location.hash = '#/users?' + q.toString();
+    },
+  }, email, state, h('button', { type: 'submit' }, 'Search'));
+
+  show(
+    h('h2', {}, `Users (${resp.total})`),
+    search,
+    table(['Email', 'Username', 'State', 'Expires', 'Created', ''], resp.users.map((u) => [
+      link(`#/user/${u.id}`, u.email), u.username, badge(u.state, u.state),
+      formatTime(u.expires_at), formatTime(u.created_at), banButton(u),
+    ]), 'No users found.'));
+}
+
+function banButton(u) {
+  if (u.state === 'banned') {
+    return h('button', { onclick: action(`Unban ${u.email}?`, () => post(`/user/${u.id}/unban`)) }, 'Unban');
+  }
+  return h('button', {
+    class: 'danger',
+    onclick: action(`Ban ${u.email}? The user is removed from all roles and groups, and all certs are revoked.`,
+      () => post(`/user/${u.id}/ban`)),
+  }, 'Ban');
+}
+
+async function user(userID) {
+  const [u, certs] = await Promise.all([
+    get(`/user/${userID}`),
+    get(`/user/${userID}/cert`),
+  ]);
+
+  const hours = h('input', { type: 'number', min: '1', value: '8', size: '4' });
+  const output = h('textarea', { readonly: true, hidden: true });
+  const grant = async () => {
+    try {
+      const resp = await post(`/user/${userID}/cert`, { effect: Number(hours.value) * 3600 });
+      output.value = resp.cert;
+      output.hidden = false;
+      output.select();
+      showError(null);
+    } catch (err) {
+      showError(err);
+    }
+  };
+
+  show(
+    h('h2', {}, u.email),
+    table(['Username', 'State', 'Expires', 'Created', ''], [[
+      u.username, badge(u.state, u.state), formatTime(u.expires_at), formatTime(u.created_at), banButton(u),
+    ]]),
+    h('h3', {}, 'Public key'),
+    h('textarea', { readonly: true }, u.public_key),
+    h('h3', {}, 'Grant a certificate'),
+    h('div', { class: 'toolbar' }, hours, h('span', {}, 'hours'), h('button', { onclick: grant }, 'Grant')),
+    output,
+    h('h3', {}, 'Certificates'),
+    h('div', { class: 'toolbar' }, h('button', {
+      class: 'danger',
+      onclick: action(`Revoke all certs of ${u.email}?`, () => post(`/user/${userID}/cert/revoke`, { cert_ids: [] })),
+    }, 'Revoke all')),
+    table(['ID', 'State', 'Granted', ''], certs.certs.map((c) => [
+      c.id,
+      c.is_revoked ? badge('revoked', 'revoked') : badge('valid', 'active'),
+      formatTime(c.created_at),
+      c.is_revoked ? '' : h('button', {
+        class: 'danger',
+        onclick: action(`Revoke cert ${c.id}?`, () => post(`/user/${userID}/cert/revoke`, { cert_ids: [c.id] })),
+      }, 'Revoke'),
+    ]), 'No certs granted.'));
+}
+
+// ==== router ====
+
+async function route() {
+  const [path, search] = location.hash.replace(/^#/, '').split('?');
+  const parts = path.split('/').filter(Boolean);
+  const params = Object.fromEntries(new URLSearchParams(search || ''));
+  const signedIn = sessionStorage.getItem(TOKEN_KEY) !== null;
+
+  document.getElementById('nav').hidden = !signedIn;
+  if (parts[0] === 'logout') {
+    sessionStorage.removeItem(TOKEN_KEY);
+    location.hash = '#/login';
+    return;
+  }
+  if (!signedIn || parts[0] === 'login') {
+    login();
+    return;
+  }
+
+  const id = (i) => Number(parts[i]);
+  try {
+    if (parts[0] === 'space' && parts[2] === 'role') {
+      await role(id(1), id(3));
+    } else if (parts[0] === 'space') {
+      await space(id(1));
+    } else if (parts[0] === 'global' && parts[1]) {
+      await role(0, id(1));
+    } else if (parts[0] === 'global') {
+      await globalRoles();
+    } else if (parts[0] === 'users') {
+      await users(params);
+    } else if (parts[0] === 'user') {
+      await user(id(1));
+    } else {
+      await spaces();
+    }
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+window.addEventListener('hashchange', () => route());
+route();
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary ListNodeAccess
+// @Description List who can log into the node, with the role and membership granting each access
+// @Tags access
+// @Param nodeID path int true "Node ID"
+// @Param account query string false "account of the node"
+// @Param format query string false "response format" Enums(json, csv)
+// @Param page query int false "page of the json response, start from 1"
+// @Param limit query int false "limit of the json response, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, one of email, node_name, account, role_name, until"
+// @Success 200 {object} service.ListEffectiveAccessResponse
+// @Router /api/v1/guard/access/node/{nodeID} [get]
+func (g *Guard) ListNodeAccess(c *gin.Context) {
+	var req service.ListEffectiveAccessRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.NodeID, err = getNodeID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	g.listAccess(c, &req, fmt.Sprintf("node-%d", req.NodeID))
+}
+
+// @Summary ListUserAccess
+// @Description List the nodes and accounts the user can reach, with the role and membership granting each access
+// @Tags access
+// @Param userID path int true "User ID"
+// @Param space_id query int false "space of the nodes"
+// @Param account query string false "account of the nodes"
+// @Param format query string false "response format" Enums(json, csv)
+// @Param page query int false "page of the json response, start from 1"
+// @Param limit query int false "limit of the json response, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, one of email, node_name, account, role_name, until"
+// @Success 200 {object} service.ListEffectiveAccessResponse
+// @Router /api/v1/guard/access/user/{userID} [get]
+func (g *Guard) ListUserAccess(c *gin.Context) {
+	var req service.ListEffectiveAccessRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.UserID, err = getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	g.listAccess(c, &req, fmt.Sprintf("user-%d", req.UserID))
+}
+
+// listAccess writes the access list as json, or as a csv attachment
+// named after the subject
+func (g *Guard) listAccess(c *gin.Context, req *service.ListEffectiveAccessRequest, subject string) {
+	ctx := c.Request.Context()
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	resp, err := g.svc.ListEffectiveAccess(ctx, req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if req.Format != service.AccessFormatCSV {
+		response(c, resp, nil)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="access-%s.csv"`, subject))
+	c.Status(http.StatusOK)
+	if err := resp.WriteCSV(c.Writer); err != nil {
+		slog.ErrorContext(ctx, "failed to write access csv", "error", err)
+	}
+}
@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary CreateAccessRequest
+// @Description File an access request for a role, if the api token is bound to a user, the request is filed for the user itself
+// @Tags access request
+// @Param body body service.CreateAccessRequestRequest true "Create access request request"
+// @Success 200 {object} int64
+// @Router /api/v1/guard/access_request [post]
+func (g *Guard) CreateAccessRequest(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.CreateAccessRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	id, err := g.svc.CreateAccessRequest(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, id, nil)
+}
+
+// @Summary ListAccessRequest
+// @Description List access requests
+// @Tags access request
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param role_id query int false "role id"
+// @Param user_id query int false "user id of the requester"
+// @Param state query string false "request state" Enums(pending, approved, denied, cancelled)
+// @Param reviewable query bool false "only the requests which the operator can approve"
+// @Success 200 {object} service.ListAccessRequestResponse
+// @Router /api/v1/guard/access_request [get]
+func (g *Guard) ListAccessRequest(c *gin.Context) {
+	ctx := c.Request.Context()
+	req := service.ListAccessRequestRequest{}
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	requests, err := g.svc.ListAccessRequest(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, requests, nil)
+}
+
+// @Summary GetAccessRequest
+// @Description Get access request with its state transitions
+// @Tags access request
+// @Param requestID path int true "Access request ID"
+// @Success 200 {object} service.GetAccessRequestResponse
+// @Router /api/v1/guard/access_request/{requestID} [get]
+func (g *Guard) GetAccessRequest(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getRequestID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	request, err := g.svc.GetAccessRequest(ctx, id)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, request, nil)
+}
+
+// @Summary ApproveAccessRequest
+// @Description Approve access request, the operator must be an approver of the role
+// @Tags access request
+// @Param requestID path int true "Access request ID"
+// @Param body body service.ReviewAccessRequestRequest false "Review access request request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/access_request/{requestID}/approve [post]
+func (g *Guard) ApproveAccessRequest(c *gin.Context) {
+	g.reviewAccessRequest(c, g.svc.ApproveAccessRequest)
+}
+
+// @Summary DenyAccessRequest
+// @Description Deny access request, the operator must be an approver of the role
+// @Tags access request
+// @Param requestID path int true "Access request ID"
+// @Param body body service.ReviewAccessRequestRequest false "Review access request request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/access_request/{requestID}/deny [post]
+func (g *Guard) DenyAccessRequest(c *gin.Context) {
+	g.reviewAccessRequest(c, g.svc.DenyAccessRequest)
+}
+
+// @Summary CancelAccessRequest
+// @Description Cancel access request, the operator must be the requester or a token not bound to a user
+// @Tags access request
+// @Param requestID path int true "Access request ID"
+// @Param body body service.ReviewAccessRequestRequest false "Review access request request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/access_request/{requestID}/cancel [post]
+func (g *Guard) CancelAccessRequest(c *gin.Context) {
+	g.reviewAccessRequest(c, g.svc.CancelAccessRequest)
+}
+
+// reviewAccessRequest binds the review request and calls fn
+func (g *Guard) reviewAccessRequest(c *gin.Context, fn func(context.Context, *service.ReviewAccessRequestRequest) error) {
+	ctx := c.Request.Context()
+	var req service.ReviewAccessRequestRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var err error
+	req.RequestID, err = getRequestID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := fn(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary ListRoleApprover
+// @Description List the approvers of the role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Success 200 {object} service.ListRoleApproverResponse
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/approver [get]
+func (g *Guard) ListRoleApprover(c *gin.Context) {
+	ctx := c.Request.Context()
+	roleID, err := getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	approvers, err := g.svc.ListRoleApprover(ctx, roleID)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, approvers, nil)
+}
+
+// @Summary AddApproverToRole
+// @Description Add approvers to role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body []int64 true "User IDs"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/approver [post]
+func (g *Guard) AddApproverToRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AddApproverToRoleRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.AddApproverToRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary BatchRemoveApproverFromRole
+// @Description Batch remove approvers from role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body []int64 true "User IDs"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/approver/batch/delete [post]
+func (g *Guard) BatchRemoveApproverFromRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RemoveApproverFromRoleRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.RemoveApproverFromRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// accessService returns one access of the node and keeps the request
+type accessService struct {
+	service.Guard
+	in *service.ListEffectiveAccessRequest
+}
+
+func (s *accessService) ListEffectiveAccess(ctx context.Context, in *service.ListEffectiveAccessRequest) (*service.ListEffectiveAccessResponse, error) {
+	s.in = in
+	return &service.ListEffectiveAccessResponse{
+		Total: 1,
+		Accesses: []*service.AccessVO{{UserID: 1, Username: "alice", Email: "alice@example.com",
+			NodeID: in.NodeID, NodeName: "web, primary", SpaceID: 1, Account: "root", RoleID: 2, RoleName: "ops",
+			Via: "direct", Binding: "explicit"}},
+	}, nil
+}
+
+func TestListNodeAccessCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := &accessService{}
+	g := New(Config{}, svc)
+	e := gin.New()
+	e.GET("/api/v1/guard/access/node/:nodeID", g.ListNodeAccess)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/guard/access/node/3"+query, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?format=csv&account=root")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+	if svc.in.NodeID != 3 || svc.in.Account != "root" {
+		t.Fatalf("unexpected request: %+v", svc.in)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("expected the csv content type, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="access-node-3.csv"` {
+		t.Fatalf("unexpected content disposition: %q", cd)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"user_id", "username", "email", "node_id", "node_name", "space_id", "account",
+			"role_id", "role_name", "global", "via", "binding", "valid_until", "expires_at", "until"},
+		{"1", "alice", "alice@example.com", "3", "web, primary", "1", "root", "2", "ops", "false", "direct", "explicit", "0", "0", "0"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("expected %q, got %q", expected, records)
+	}
+
+	// json by default
+	w = get("")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("expected the json response, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	if w := get("?format=xml"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the unknown format rejected, got %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary Apply
+// @Description Apply the declarative state of the users, groups, spaces, nodes and roles in yaml or json.
+// @Description The objects and bindings not in the state are removed only with prune.
+// @Tags apply
+// @Accept x-yaml
+// @Param plan query bool false "report the changes and the principals diff without applying"
+// @Param prune query bool false "remove the objects and bindings not in the state"
+// @Param body body service.State true "state"
+// @Success 200 {object} service.ApplyResponse
+// @Router /api/v1/guard/apply [post]
+func (g *Guard) Apply(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ApplyRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.State, err = service.DecodeState(c.Request.Body)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	resp, err := g.svc.Apply(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	slog.InfoContext(ctx, "state applied", "operator", getOperator(c), "plan", req.Plan,
+		"prune", req.Prune, "changes", len(resp.Changes))
+	response(c, resp, nil)
+}
+
+// @Summary Export
+// @Description Export the current state as yaml, the temporary memberships are not included
+// @Tags apply
+// @Produce x-yaml
+// @Success 200 {object} service.State
+// @Router /api/v1/guard/export [get]
+func (g *Guard) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	state, err := g.svc.Export(ctx)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/yaml; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := state.Encode(c.Writer); err != nil {
+		slog.ErrorContext(ctx, "failed to write state", "error", err)
+	}
+}
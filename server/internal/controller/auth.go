@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+
+	// ctxKeyOperator is the key of the authenticated token name in the gin context
+	ctxKeyOperator = "operator"
+)
+
+// AuthConfig is the configuration of the admin api authentication
+type AuthConfig struct {
+	// Tokens is the api token list, if it is empty, the admin api
+	// is not protected
+	Tokens []Token `yaml:"tokens"`
+}
+
+// Token is an api token
+type Token struct {
+	// Name is the name of the token owner, it's used in logs
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	// Email binds the token to a guard user, the user acts as itself,
+	// e.g. filing or approving the access requests
+	Email string `yaml:"email"`
+	// SuperAdmin allows the token to manage the global roles
+	SuperAdmin bool `yaml:"super_admin"`
+}
+
+func (c *AuthConfig) Validate() error {
+	names := make(map[string]struct{}, len(c.Tokens))
+	for i, token := range c.Tokens {
+		if token.Name == "" {
+			return fmt.Errorf("name is required for token %d", i)
+		}
+
+		if token.Token == "" {
+			return fmt.Errorf("token is required for %s", token.Name)
+		}
+
+		if _, ok := names[token.Name]; ok {
+			return fmt.Errorf("duplicate token name %s", token.Name)
+		}
+		names[token.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// index returns the operator indexed by the token
+func (c *AuthConfig) index() map[string]*service.Operator {
+	tokens := make(map[string]*service.Operator, len(c.Tokens))
+	for _, token := range c.Tokens {
+		tokens[token.Token] = &service.Operator{
+			Name:       token.Name,
+			Email:      token.Email,
+			SuperAdmin: token.SuperAdmin,
+		}
+	}
+	return tokens
+}
+
+// lookupToken returns the operator of the token, it compares all tokens
+// in constant time to avoid leaking the token by timing
+func (g *Guard) lookupToken(token string) (*service.Operator, bool) {
+	var operator *service.Operator
+	for t, o := range g.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			operator = o
+		}
+	}
+	return operator, operator != nil
+}
+
+// IsAdmin is a middleware to authenticate the admin api by the
+// bearer token in the Authorization header
+func (g *Guard) IsAdmin(c *gin.Context) {
+	if len(g.tokens) == 0 {
+		c.Next()
+		return
+	}
+
+	ip := c.ClientIP()
+	if g.isLocked(c, lockoutKeyIP(ip)) {
+		return
+	}
+
+	token := strings.TrimPrefix(c.GetHeader(HeaderAuthorization), "Bearer ")
+	operator, ok := g.lookupToken(token)
+	if !ok {
+		g.recordFailure(c, lockoutKeyIP(ip))
+		slog.WarnContext(c.Request.Context(), "invalid api token", "ip", ip)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	c.Set(ctxKeyOperator, operator.Name)
+	c.Request = c.Request.WithContext(service.WithOperator(c.Request.Context(), operator))
+	c.Next()
+}
+
+// getOperator returns the name of the authenticated token
+func getOperator(c *gin.Context) string {
+	return c.GetString(ctxKeyOperator)
+}
+
+// IsSuperAdmin is a middleware to allow only the super admins, it's used
+// after IsAdmin by the api changing the objects of all spaces
+func (g *Guard) IsSuperAdmin(c *gin.Context) {
+	if len(g.tokens) > 0 && !service.OperatorFrom(c.Request.Context()).SuperAdmin {
+		response(c, nil, errors.ErrForbidden)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
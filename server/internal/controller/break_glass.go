@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary BreakGlass
+// @Description Grant the emergency access to a break-glass role without approval, the cert and the membership are short-lived, the admins of the space are notified
+// @Tags break glass
+// @Param body body service.BreakGlassRequest true "Break glass request"
+// @Success 200 {object} service.BreakGlassResponse
+// @Router /api/v1/guard/break_glass [post]
+func (g *Guard) BreakGlass(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.BreakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	resp, err := g.svc.BreakGlass(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, resp, nil)
+}
+
+// @Summary ListBreakGlass
+// @Description List the break-glass review items
+// @Tags break glass
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param space_id query int false "space id"
+// @Param role_id query int false "role id"
+// @Param user_id query int false "user id"
+// @Param acked query bool false "acknowledged or pending"
+// @Success 200 {object} service.ListBreakGlassResponse
+// @Router /api/v1/guard/break_glass [get]
+func (g *Guard) ListBreakGlass(c *gin.Context) {
+	ctx := c.Request.Context()
+	req := service.ListBreakGlassRequest{}
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	list, err := g.svc.ListBreakGlass(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, list, nil)
+}
+
+// @Summary AckBreakGlass
+// @Description Acknowledge the review of the break-glass, the operator must be an admin of the space
+// @Tags break glass
+// @Param id path int true "Break glass ID"
+// @Param body body service.AckBreakGlassRequest true "Ack break glass request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/break_glass/{id}/ack [post]
+func (g *Guard) AckBreakGlass(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AckBreakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.ID, err = strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.AckBreakGlass(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary ListSpaceAdmin
+// @Description List the admins of the space
+// @Tags space
+// @Param spaceID path int true "Space ID"
+// @Success 200 {object} service.ListSpaceAdminResponse
+// @Router /api/v1/guard/space/{spaceID}/admin [get]
+func (g *Guard) ListSpaceAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	spaceID, err := getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	admins, err := g.svc.ListSpaceAdmin(ctx, spaceID)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, admins, nil)
+}
+
+// @Summary AddSpaceAdmin
+// @Description Add admins to the space, the admins are notified of the break-glass access
+// @Tags space
+// @Param spaceID path int true "Space ID"
+// @Param body body []int64 true "User IDs"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/admin [post]
+func (g *Guard) AddSpaceAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AddSpaceAdminRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.AddSpaceAdmin(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary BatchRemoveSpaceAdmin
+// @Description Batch remove admins from the space
+// @Tags space
+// @Param spaceID path int true "Space ID"
+// @Param body body []int64 true "User IDs"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/admin/batch/delete [post]
+func (g *Guard) BatchRemoveSpaceAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RemoveSpaceAdminRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.RemoveSpaceAdmin(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
@@ -1,12 +1,15 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
 	"github.com/sysarmor/guard/server/pkg/errors"
 	"github.com/sysarmor/guard/server/pkg/signature"
 )
@@ -20,6 +23,29 @@ type ctxIn struct {
 	secret string
 
 	*gin.Context
+	// request is the context of the request, the gin context is never
+	// done, the cancellation and the deadline come from the request. It
+	// carries the node, so that the handlers do not get it again.
+	request context.Context
+}
+
+func (c *ctxIn) Value(key any) any {
+	if v := c.request.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+func (c *ctxIn) Deadline() (time.Time, bool) {
+	return c.request.Deadline()
+}
+
+func (c *ctxIn) Done() <-chan struct{} {
+	return c.request.Done()
+}
+
+func (c *ctxIn) Err() error {
+	return c.request.Err()
 }
 
 func (g *Guard) IsAllowedNode(c *gin.Context) {
@@ -31,12 +57,14 @@ func (g *Guard) IsAllowedNode(c *gin.Context) {
 
 	timeStamp := c.GetHeader(HeaderTimestamp)
 	if timeStamp == "" {
+		signatureFailures.With(signatureMissing).Inc()
 		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("timestamp is required"))
 		return
 	}
 
 	sign := c.GetHeader(HeaderSignature)
 	if sign == "" {
+		signatureFailures.With(signatureMissing).Inc()
 		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("signature is required"))
 		return
 	}
@@ -50,6 +78,8 @@ func (g *Guard) IsAllowedNode(c *gin.Context) {
 	}
 
 	if node == nil {
+		signatureFailures.With(signatureUnknown).Inc()
+		g.recordFailure(c, lockoutKeyIP(c.ClientIP()))
 		c.AbortWithError(http.StatusNotFound, fmt.Errorf("node not found"))
 		return
 	}
@@ -57,14 +87,22 @@ func (g *Guard) IsAllowedNode(c *gin.Context) {
 	expectedSign := signature.SimpleSignature(signature.SimpleString(timeStamp), []byte(node.Secret))
 
 	if expectedSign != sign {
+		signatureFailures.With(signatureInvalid).Inc()
 		slog.Debug("expected sign: %s, actual sign: %s", expectedSign, sign)
+		g.recordFailure(c, lockoutKeyIP(c.ClientIP()))
+		g.recordFailure(c, nodeIPKey(nodeID, c.ClientIP()))
 		c.AbortWithError(http.StatusForbidden, fmt.Errorf("signature is invalid"))
 		return
 	}
 
+	if !g.allowNode(c, nodeID) {
+		return
+	}
+
 	ctxIn := &ctxIn{
 		secret:  node.Secret,
 		Context: c,
+		request: service.WithNode(c.Request.Context(), node),
 	}
 
 	c.Request = c.Request.WithContext(ctxIn)
@@ -85,6 +123,15 @@ func (w *writer) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// WriteHeader signs the etag of the 304 responses, they have no body
+func (w *writer) WriteHeader(code int) {
+	if code == http.StatusNotModified {
+		sign := signature.SimpleSignature(signature.SimpleString(w.Header().Get(HeaderETag)), []byte(w.secret))
+		w.Header().Set(HeaderSignature, sign)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
 // Signature is a middleware to sign the response
 // with the secret of the node
 func (g *Guard) Signature(c *gin.Context) {
@@ -101,9 +148,16 @@ func (g *Guard) Signature(c *gin.Context) {
 	c.Next()
 }
 
+// UpdateNodeLastHeartbeat records the heartbeat of the node after the
+// handler, only the successful syncs count, a node failing to sync
+// becomes stale and then offline
 func (g *Guard) UpdateNodeLastHeartbeat(c *gin.Context) {
 	c.Next()
 
+	if c.IsAborted() || c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+
 	nodeID := c.Query("nodeID")
 	if nodeID == "" {
 		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("node id is required"))
@@ -150,6 +204,14 @@ func getRoleID(c *gin.Context) (int64, error) {
 	return roleID, nil
 }
 
+func getRequestID(c *gin.Context) (int64, error) {
+	requestID, err := strconv.ParseInt(c.Param("requestID"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse request id: %w", err)
+	}
+	return requestID, nil
+}
+
 // response is a helper function to send response to the client
 func response(c *gin.Context, data interface{}, err error) {
 	if err != nil {
@@ -176,3 +238,25 @@ func response(c *gin.Context, data interface{}, err error) {
 
 	c.JSON(http.StatusOK, data)
 }
+
+func getGroupID(c *gin.Context) (int64, error) {
+	groupID, err := strconv.ParseInt(c.Param("groupID"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse group id: %w", err)
+	}
+	return groupID, nil
+}
+
+// dryRun previews the mutation in fn if dry_run=true is in the query, the
+// principals diff of the nodes and users in scope is written as the response.
+// It returns false if the request is not a dry run, then the caller runs the
+// mutation as usual.
+func (g *Guard) dryRun(c *gin.Context, scope *service.DryRunScope, fn func(svc service.Guard) error) bool {
+	if dry, _ := strconv.ParseBool(c.Query("dry_run")); !dry {
+		return false
+	}
+
+	resp, err := g.svc.DryRun(c.Request.Context(), scope, fn)
+	response(c, resp, err)
+	return true
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// etagOf returns the strong etag of the digest
+func etagOf(digest string) string {
+	return `"` + digest + `"`
+}
+
+// notModified sets the etag, and writes 304 if the client has it in
+// If-None-Match. The 304 has no body, the etag is signed instead.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header(HeaderETag, etag)
+	if !matchETag(c.GetHeader(HeaderIfNoneMatch), etag) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// matchETag compares the etags of If-None-Match weakly as RFC 9110 says
+func matchETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// responseETag writes the data as json with the strong etag of the
+// body, or 304 if the client has the same body
+func responseETag(c *gin.Context, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if notModified(c, etagOf(dto.Digest(body))) {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+	"github.com/sysarmor/guard/server/pkg/signature"
+)
+
+// nodeService serves the CA to the node with the secret
+type nodeService struct {
+	service.Guard
+}
+
+func (s *nodeService) GetNodeByUniqueID(ctx context.Context, uniqueID string) (*service.Node, error) {
+	return &service.Node{UniqueID: uniqueID, Secret: "secret"}, nil
+}
+
+func (s *nodeService) GetCA(ctx context.Context) []byte {
+	return []byte("ssh-ed25519 AAAA")
+}
+
+func TestETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	g := New(Config{}, &nodeService{})
+	e := gin.New()
+	e.GET("/api/v1/guard/ca", g.IsAllowedNode, g.Signature, g.GetCA)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/guard/ca?nodeID=node", nil)
+		req.Header.Set(HeaderTimestamp, "1700000000")
+		req.Header.Set(HeaderSignature, signature.SimpleSignature(signature.SimpleString("1700000000"), []byte("secret")))
+		if etag != "" {
+			req.Header.Set(HeaderIfNoneMatch, etag)
+		}
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	etag := w.Header().Get(HeaderETag)
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("unexpected response: %d, etag %q", w.Code, etag)
+	}
+
+	w = get(`"other", ` + etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("unexpected response: %d, body %q", w.Code, w.Body.String())
+	}
+
+	sign := signature.SimpleSignature(signature.SimpleString(etag), []byte("secret"))
+	if w.Header().Get(HeaderSignature) != sign {
+		t.Fatalf("the etag of 304 is not signed")
+	}
+
+	if w = get(`"other"`); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary ListEvent
+// @Description List events, e.g. the expiry of the users and the temporary memberships
+// @Tags event
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param type query string false "event type, e.g. role_user.expired"
+// @Param space_id query int false "space id"
+// @Param role_id query int false "role id"
+// @Param user_id query int false "user id"
+// @Param node_id query int false "node id"
+// @Param from query int false "created after, unix seconds"
+// @Param to query int false "created before, unix seconds"
+// @Success 200 {object} service.ListEventResponse
+// @Router /api/v1/guard/event [get]
+func (g *Guard) ListEvent(c *gin.Context) {
+	ctx := c.Request.Context()
+	req := service.ListEventRequest{}
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	events, err := g.svc.ListEvent(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, events, nil)
+}
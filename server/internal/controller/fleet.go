@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary FleetHealth
+// @Description Count the nodes of every space by the health, healthy, stale or offline
+// @Tags node
+// @Success 200 {object} service.FleetHealthResponse
+// @Router /api/v1/guard/fleet/health [get]
+func (g *Guard) FleetHealth(c *gin.Context) {
+	fleet, err := g.svc.FleetHealth(c.Request.Context())
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, fleet, nil)
+}
+
+// @Summary ListNodeHeartbeat
+// @Description Get the health, the thresholds and the recent successful syncs of a node
+// @Tags node
+// @Param spaceID path int true "Space ID"
+// @Param nodeID path int true "Node ID"
+// @Success 200 {object} service.ListNodeHeartbeatResponse
+// @Router /api/v1/guard/space/{spaceID}/node/{nodeID}/heartbeat [get]
+func (g *Guard) ListNodeHeartbeat(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ListNodeHeartbeatRequest
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	req.NodeID, err = getNodeID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	heartbeats, err := g.svc.ListNodeHeartbeat(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, heartbeats, nil)
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// RoleInSpace is a middleware to check the role in the path belongs to the
+// space in the path, the global roles are only managed by the global role api
+func (g *Guard) RoleInSpace(c *gin.Context) {
+	if c.Param("roleID") == "" {
+		c.Next()
+		return
+	}
+
+	spaceID, err := getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		c.Abort()
+		return
+	}
+
+	role, err := g.pathRole(c)
+	if err != nil {
+		response(c, nil, err)
+		c.Abort()
+		return
+	}
+
+	if role.Global || role.SpaceID != spaceID {
+		response(c, nil, errors.ErrRoleNotFound)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// GlobalRole is a middleware of the global role api, the role in the path
+// must be global, and only the super admins can change the global roles
+func (g *Guard) GlobalRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.Request.Method != http.MethodGet && len(g.tokens) > 0 && !service.OperatorFrom(ctx).SuperAdmin {
+		response(c, nil, errors.ErrForbidden)
+		c.Abort()
+		return
+	}
+
+	if c.Param("roleID") == "" {
+		c.Next()
+		return
+	}
+
+	role, err := g.pathRole(c)
+	if err != nil {
+		response(c, nil, err)
+		c.Abort()
+		return
+	}
+
+	if !role.Global {
+		response(c, nil, errors.ErrRoleNotFound)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// pathRole returns the role of the roleID in the path
+func (g *Guard) pathRole(c *gin.Context) (*service.ListRoleVO, error) {
+	roleID, err := getRoleID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.svc.GetRole(c.Request.Context(), roleID)
+}
+
+// @Summary CreateGlobalRole
+// @Description Create a global role, it binds the nodes of all spaces. Only the super admins can create it
+// @Tags global role
+// @Param body body service.CreateRoleRequest true "Create role request"
+// @Success 200 {object} int64
+// @Router /api/v1/guard/global_role [post]
+func (g *Guard) CreateGlobalRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	req.Global = true
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	id, err := g.svc.CreateRole(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, id, nil)
+}
+
+// @Summary ListGlobalRole
+// @Description List global roles
+// @Tags global role
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "role name substring"
+// @Param break_glass query bool false "break-glass roles or normal roles"
+// @Success 200 {object} service.ListRoleResponse
+// @Router /api/v1/guard/global_role [get]
+func (g *Guard) ListGlobalRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ListRoleRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	req.Global = true
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	roles, err := g.svc.ListRole(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, roles, nil)
+}
+
+// @Summary UpdateGlobalRole
+// @Description Update global role
+// @Tags global role
+// @Param roleID path int true "Role ID"
+// @Param body body service.UpdateRoleRequest true "Update role request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/global_role/{roleID} [patch]
+func (g *Guard) UpdateGlobalRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.UpdateRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary CreateGroup
+// @Description Create group
+// @Tags group
+// @Param body body service.CreateGroupRequest true "Create group request"
+// @Success 200 {object} int64
+// @Router /api/v1/guard/group [post]
+func (g *Guard) CreateGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	id, err := g.svc.CreateGroup(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, id, nil)
+}
+
+// @Summary ListGroup
+// @Description List groups
+// @Tags group
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "group name substring"
+// @Success 200 {object} service.ListGroupResponse
+// @Router /api/v1/guard/group [get]
+func (g *Guard) ListGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ListGroupRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	groups, err := g.svc.ListGroup(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, groups, nil)
+}
+
+// @Summary UpdateGroup
+// @Description Update group
+// @Tags group
+// @Param groupID path int true "Group ID"
+// @Param body body service.UpdateGroupRequest true "Update group request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/group/{groupID} [patch]
+func (g *Guard) UpdateGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.GroupID, err = getGroupID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.UpdateGroup(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary DeleteGroup
+// @Description Delete group, the members lose the roles inherited from the group
+// @Tags group
+// @Param groupID path int true "Group ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/group/{groupID} [delete]
+func (g *Guard) DeleteGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getGroupID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{GroupID: id}, func(svc service.Guard) error {
+		return svc.DeleteGroup(ctx, id)
+	}) {
+		return
+	}
+
+	if err := g.svc.DeleteGroup(ctx, id); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary AddUserToGroup
+// @Description Add users to group, the users inherit the roles of the group
+// @Tags group
+// @Param groupID path int true "Group ID"
+// @Param body body []int64 true "User IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/group/{groupID}/user [post]
+func (g *Guard) AddUserToGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AddUserToGroupRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.GroupID, err = getGroupID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: req.UserIDs}, func(svc service.Guard) error {
+		return svc.AddUserToGroup(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.AddUserToGroup(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary ListGroupUser
+// @Description List group users
+// @Tags group
+// @Param groupID path int true "Group ID"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "username substring"
+// @Param email query string false "email substring"
+// @Success 200 {object} service.ListGroupUserResponse
+// @Router /api/v1/guard/group/{groupID}/user [get]
+func (g *Guard) ListGroupUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ListGroupUserRequest
+	var err error
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	req.GroupID, err = getGroupID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	users, err := g.svc.ListGroupUser(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, users, nil)
+}
+
+// @Summary BatchRemoveUserFromGroup
+// @Description Batch remove users from group
+// @Tags group
+// @Param groupID path int true "Group ID"
+// @Param body body []int64 true "User IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/group/{groupID}/user/batch/delete [post]
+func (g *Guard) BatchRemoveUserFromGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RemoveUserFromGroupRequest
+	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.GroupID, err = getGroupID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: req.UserIDs}, func(svc service.Guard) error {
+		return svc.RemoveUserFromGroup(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.RemoveUserFromGroup(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary AddGroupToRole
+// @Description Add groups to role, the members of the groups inherit the role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body []int64 true "Group IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/group [post]
+func (g *Guard) AddGroupToRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AddGroupToRoleRequest
+	if err := c.ShouldBindJSON(&req.GroupIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID}, func(svc service.Guard) error {
+		return svc.AddGroupToRole(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.AddGroupToRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary ListRoleGroup
+// @Description List the groups of the role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Success 200 {object} service.ListRoleGroupResponse
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/group [get]
+func (g *Guard) ListRoleGroup(c *gin.Context) {
+	ctx := c.Request.Context()
+	roleID, err := getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	groups, err := g.svc.ListRoleGroup(ctx, roleID)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, groups, nil)
+}
+
+// @Summary BatchRemoveGroupFromRole
+// @Description Batch remove groups from role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body []int64 true "Group IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/group/batch/delete [post]
+func (g *Guard) BatchRemoveGroupFromRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RemoveGroupFromRoleRequest
+	if err := c.ShouldBindJSON(&req.GroupIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID}, func(svc service.Guard) error {
+		return svc.RemoveGroupFromRole(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.RemoveGroupFromRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
@@ -4,18 +4,53 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sysarmor/guard/server/internal/service"
 	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/metrics"
 )
 
+// Config is the configuration of the controller
+type Config struct {
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+func (c *Config) Validate() error {
+	if err := c.Auth.Validate(); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return c.RateLimit.Validate()
+}
+
 type Guard struct {
 	svc service.Guard
+
+	tokens  map[string]*service.Operator
+	limiter *limiter
+	metrics *metrics.Registry
+
+	// draining is set when the server is shutting down
+	draining atomic.Bool
 }
 
-func New(svc service.Guard) *Guard {
-	return &Guard{svc: svc}
+func New(cfg Config, svc service.Guard) *Guard {
+	g := &Guard{
+		svc:     svc,
+		tokens:  cfg.Auth.index(),
+		limiter: newLimiter(&cfg.RateLimit),
+	}
+
+	g.metrics = newMetrics(g)
+
+	if len(g.tokens) == 0 {
+		slog.Warn("no api token configured, the admin api is not protected")
+	}
+
+	return g
 }
 
 // @Summary GetCA
@@ -24,14 +59,16 @@ func New(svc service.Guard) *Guard {
 // @Param node_id query string true "Node ID"
 // @Param X-Timestamp header string true "unix timestamp, seconds"
 // @Param X-Signature header string true "signature"
+// @Param If-None-Match header string false "ETag of the last response"
 // @Router /api/v1/guard/ca [get]
 // @Router /api/v1/guard/ca [get]
 // @Success 200 {string} string "CA certificate"
+// @Success 304 "CA certificate is not modified"
 func (g *Guard) GetCA(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	ca := g.svc.GetCA(ctx)
-	response(c, string(ca), nil)
+	responseETag(c, string(ca))
 }
 
 // @Summary GetPrincipals
@@ -40,7 +77,9 @@ func (g *Guard) GetCA(c *gin.Context) {
 // @Param node_id query string true "Node ID"
 // @Param X-Timestamp header string true "unix timestamp, seconds"
 // @Param X-Signature header string true "signature"
+// @Param If-None-Match header string false "ETag of the last response"
 // @Success 200 {object} service.PrincipalList "principals"
+// @Success 304 "principals are not modified"
 // @Router /api/v1/guard/principals [get]
 func (g *Guard) GetPrincipals(c *gin.Context) {
 	nodeID := c.Query("nodeID")
@@ -62,7 +101,7 @@ func (g *Guard) GetPrincipals(c *gin.Context) {
 		return
 	}
 
-	response(c, principals, nil)
+	responseETag(c, principals)
 }
 
 // @Summary GetKRL
@@ -71,7 +110,9 @@ func (g *Guard) GetPrincipals(c *gin.Context) {
 // @Param node_id query string true "Node ID"
 // @Param X-Timestamp header string true "unix timestamp, seconds"
 // @Param X-Signature header string true "signature"
+// @Param If-None-Match header string false "ETag of the last response"
 // @Success 200 {object} []byte "KRL"
+// @Success 304 "KRL is not modified"
 // @Router /api/v1/guard/krl [get]
 func (g *Guard) GetKRL(c *gin.Context) {
 	nodeID := c.Query("nodeID")
@@ -80,7 +121,20 @@ func (g *Guard) GetKRL(c *gin.Context) {
 		return
 	}
 
+	// the KRL has the generated date, the etag is the version of the
+	// revoked keys, so the KRL is not generated if it's not modified
 	ctx := c.Request.Context()
+	version, err := g.svc.GetKRLVersion(ctx, nodeID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error(), "node id", nodeID)
+		response(c, nil, err)
+		return
+	}
+
+	if notModified(c, etagOf(version)) {
+		return
+	}
+
 	krl, err := g.svc.GetKRL(ctx, nodeID)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error(), "node id", nodeID)
@@ -97,7 +151,9 @@ func (g *Guard) GetKRL(c *gin.Context) {
 // @Param node_id query string true "Node ID"
 // @Param X-Timestamp header string true "unix timestamp, seconds"
 // @Param X-Signature header string true "signature"
+// @Param If-None-Match header string false "ETag of the last response"
 // @Success 200 {object} []string "Authorized keys"
+// @Success 304 "Authorized keys are not modified"
 // @Router /api/v1/guard/authorizedKeys [get]
 func (g *Guard) GetAuthorizedKeys(c *gin.Context) {
 	nodeID := c.Query("nodeID")
@@ -114,7 +170,43 @@ func (g *Guard) GetAuthorizedKeys(c *gin.Context) {
 		return
 	}
 
-	response(c, keys, nil)
+	responseETag(c, keys)
+}
+
+// @Summary GetBundle
+// @Description Get the CA, principals, KRL and authorized keys of the node from one snapshot
+// @Tags Guard
+// @Param node_id query string true "Node ID"
+// @Param X-Timestamp header string true "unix timestamp, seconds"
+// @Param X-Signature header string true "signature"
+// @Param If-None-Match header string false "ETag of the last response"
+// @Success 200 {object} service.Bundle "bundle"
+// @Success 304 "bundle is not modified"
+// @Router /api/v1/guard/bundle [get]
+func (g *Guard) GetBundle(c *gin.Context) {
+	nodeID := c.Query("nodeID")
+	if nodeID == "" {
+		c.AbortWithError(http.StatusBadRequest, errors.ErrNodeNotFound)
+		return
+	}
+
+	// the etag is the digest of the bundle, the KRL has the generated
+	// date, it's not generated if the bundle is not modified
+	ctx := c.Request.Context()
+	bundle, err := g.svc.GetBundle(ctx, nodeID, func(digest string) bool {
+		return !notModified(c, etagOf(digest))
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error(), "node id", nodeID)
+		response(c, nil, err)
+		return
+	}
+
+	if bundle == nil {
+		return
+	}
+
+	response(c, bundle, nil)
 }
 
 // @Summary CreateUser
@@ -143,14 +235,18 @@ func (g *Guard) CreateUser(c *gin.Context) {
 // @Summary ListUser
 // @Description List users
 // @Tags user
-// @Param page query int true "page"
-// @Param limit query int tue "limit"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "username substring"
+// @Param email query string false "email substring"
+// @Param state query string false "user state" Enums(active, suspended, expired, banned)
 // @Success 200 {object} service.ListUserResponse
-// @Router /api/v1/guard/user [get]
+// @Router /api/v1/guard/users [get]
 func (g *Guard) ListUser(c *gin.Context) {
 	ctx := c.Request.Context()
 	req := service.ListUserRequest{}
-	if err := c.BindQuery(&req.PageRequest); err != nil {
+	if err := c.BindQuery(&req); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
@@ -219,6 +315,7 @@ func (g *Guard) QueryUser(c *gin.Context) {
 // @Description Ban user
 // @Tags user
 // @Param userID path int true "User ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/user/{userID}/ban [post]
 func (g *Guard) BanUser(c *gin.Context) {
@@ -229,6 +326,12 @@ func (g *Guard) BanUser(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{id}}, func(svc service.Guard) error {
+		return svc.BanUser(ctx, id)
+	}) {
+		return
+	}
+
 	if err := g.svc.BanUser(ctx, id); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response(c, nil, err)
@@ -238,6 +341,164 @@ func (g *Guard) BanUser(c *gin.Context) {
 	response(c, nil, nil)
 }
 
+// @Summary UnbanUser
+// @Description Unban user, the revoked certs and role memberships are not restored
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID}/unban [post]
+func (g *Guard) UnbanUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{id}}, func(svc service.Guard) error {
+		return svc.UnbanUser(ctx, id)
+	}) {
+		return
+	}
+
+	if err := g.svc.UnbanUser(ctx, id); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary SuspendUser
+// @Description Suspend user, all certs of the user are revoked, the role memberships are kept
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID}/suspend [post]
+func (g *Guard) SuspendUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{id}}, func(svc service.Guard) error {
+		return svc.SuspendUser(ctx, id)
+	}) {
+		return
+	}
+
+	if err := g.svc.SuspendUser(ctx, id); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary ResumeUser
+// @Description Resume suspended user, the revoked certs are not restored
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID}/resume [post]
+func (g *Guard) ResumeUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{id}}, func(svc service.Guard) error {
+		return svc.ResumeUser(ctx, id)
+	}) {
+		return
+	}
+
+	if err := g.svc.ResumeUser(ctx, id); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary UpdateUser
+// @Description Update user, changing the email revokes all certs of the user
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param body body service.UpdateUserRequest true "Update user request"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID} [patch]
+func (g *Guard) UpdateUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.UserID, err = getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{req.UserID}}, func(svc service.Guard) error {
+		return svc.UpdateUser(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.UpdateUser(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary DeleteUser
+// @Description Delete user, the user is removed from all roles and all certs are revoked
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID} [delete]
+func (g *Guard) DeleteUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{UserIDs: []int64{id}}, func(svc service.Guard) error {
+		return svc.DeleteUser(ctx, id)
+	}) {
+		return
+	}
+
+	if err := g.svc.DeleteUser(ctx, id); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
 // @Summary UpdateUserPublicKey
 // @Description Update user public key
 // @Tags user
@@ -309,6 +570,64 @@ func (g *Guard) GrantCert(c *gin.Context) {
 	response(c, cert, nil)
 }
 
+// @Summary ListUserCert
+// @Description List the certs granted to the user, the newest first
+// @Tags user
+// @Param userID path int true "User ID"
+// @Success 200 {object} service.ListUserCertResponse
+// @Router /api/v1/guard/user/{userID}/cert [get]
+func (g *Guard) ListUserCert(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	resp, err := g.svc.ListUserCert(ctx, id)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, resp, nil)
+}
+
+// @Summary RevokeCert
+// @Description Revoke the certs of the user, all certs are revoked if cert_ids is empty
+// @Tags user
+// @Param userID path int true "User ID"
+// @Param body body service.RevokeCertRequest true "Revoke certificate request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/user/{userID}/cert/revoke [post]
+func (g *Guard) RevokeCert(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RevokeCertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.UserID, err = getUserID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.RevokeCert(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
 // @Summary CreateSpace
 // @Description Create space
 // @Tags space
@@ -340,11 +659,26 @@ func (g *Guard) CreateSpace(c *gin.Context) {
 // @Summary ListSpace
 // @Description List spaces
 // @Tags space
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "space name substring"
 // @Success 200 {object} service.ListSpaceResponse
 // @Router /api/v1/guard/space [get]
 func (g *Guard) ListSpace(c *gin.Context) {
 	ctx := c.Request.Context()
-	spaces, err := g.svc.ListSpace(ctx)
+	var req service.ListSpaceRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	spaces, err := g.svc.ListSpace(ctx, &req)
 	if err != nil {
 		response(c, nil, err)
 		return
@@ -353,6 +687,77 @@ func (g *Guard) ListSpace(c *gin.Context) {
 	response(c, spaces, nil)
 }
 
+// @Summary UpdateSpace
+// @Description Update space
+// @Tags space
+// @Param spaceID path int true "Space ID"
+// @Param body body service.UpdateSpaceRequest true "Update space request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID} [patch]
+func (g *Guard) UpdateSpace(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateSpaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.UpdateSpace(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary DeleteSpace
+// @Description Delete space, a space with nodes or roles is only deleted with force,
+// @Description then its nodes and roles are deleted too
+// @Tags space
+// @Param spaceID path int true "Space ID"
+// @Param force query bool false "delete the nodes and roles of the space"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID} [delete]
+func (g *Guard) DeleteSpace(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.DeleteSpaceRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.DeleteSpace(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
 // @Summary CreateNode
 // @Description Create node
 // @Tags node
@@ -392,15 +797,22 @@ func (g *Guard) CreateNode(c *gin.Context) {
 // @Description List nodes
 // @Tags node
 // @Param spaceID path int true "Space ID"
-// @Param page query int true "page"
-// @Param limit query int true "limit"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "node name substring"
+// @Param account query string false "node account"
+// @Param heartbeat_from query int false "last heartbeat from, unix seconds"
+// @Param heartbeat_to query int false "last heartbeat to, unix seconds"
+// @Param labels query string false "labels the nodes must have, e.g. env=prod,service=db"
+// @Param health query string false "node health, one of healthy, stale and offline"
 // @Success 200 {object} service.ListNodeResponse
 // @Router /api/v1/guard/space/{spaceID}/node [get]
 func (g *Guard) ListNode(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req service.ListNodeRequest
 	var err error
-	if err := c.BindQuery(&req.PageRequest); err != nil {
+	if err := c.BindQuery(&req); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
@@ -425,22 +837,94 @@ func (g *Guard) ListNode(c *gin.Context) {
 	response(c, nodes, nil)
 }
 
+// @Summary UpdateNode
+// @Description Update node, removing an account bound to roles requires force
+// @Tags node
+// @Param spaceID path int true "Space ID"
+// @Param nodeID path int true "Node ID"
+// @Param force query bool false "remove the role bindings of the removed accounts"
+// @Param body body service.UpdateNodeRequest true "Update node request"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/node/{nodeID} [patch]
+func (g *Guard) UpdateNode(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	req.NodeID, err = getNodeID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	req.Force = c.Query("force") == "true"
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{NodeIDs: []int64{req.NodeID}}, func(svc service.Guard) error {
+		return svc.UpdateNode(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.UpdateNode(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
 // @Summary DeleteNode
 // @Description Delete node
 // @Tags node
 // @Param spaceID path int true "Space ID"
 // @Param nodeID path int true "Node ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/node/{nodeID} [delete]
 func (g *Guard) DeleteNode(c *gin.Context) {
 	ctx := c.Request.Context()
-	id, err := getNodeID(c)
+	var req service.DeleteNodeRequest
+	var err error
+	req.SpaceID, err = getSpaceID(c)
 	if err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		response(c, nil, err)
 		return
 	}
 
-	if err := g.svc.DeleteNode(ctx, id); err != nil {
+	req.NodeID, err = getNodeID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{NodeIDs: []int64{req.NodeID}}, func(svc service.Guard) error {
+		return svc.DeleteNode(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.DeleteNode(ctx, &req); err != nil {
 		response(c, nil, err)
 		return
 	}
@@ -484,21 +968,35 @@ func (g *Guard) CreateRole(c *gin.Context) {
 }
 
 // @Summary ListRole
-// @Description List roles
+// @Description List roles of the space, including the global roles which bind the nodes of the space
 // @Tags role
 // @Param spaceID path int true "Space ID"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "role name substring"
 // @Success 200 {object} service.ListRoleResponse
 // @Router /api/v1/guard/space/{spaceID}/role [get]
 func (g *Guard) ListRole(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req service.ListRoleRequest
 	var err error
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
 	req.SpaceID, err = getSpaceID(c)
 	if err != nil {
 		response(c, nil, err)
 		return
 	}
 
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
 	roles, err := g.svc.ListRole(ctx, &req)
 	if err != nil {
 		response(c, nil, err)
@@ -507,11 +1005,54 @@ func (g *Guard) ListRole(c *gin.Context) {
 	response(c, roles, err)
 }
 
+// @Summary UpdateRole
+// @Description Update role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body service.UpdateRoleRequest true "Update role request"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID} [patch]
+func (g *Guard) UpdateRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := g.svc.UpdateRole(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
 // @Summary DeleteRole
 // @Description Delete role
 // @Tags role
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID} [delete]
 func (g *Guard) DeleteRole(c *gin.Context) {
@@ -522,6 +1063,12 @@ func (g *Guard) DeleteRole(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, &service.DryRunScope{RoleID: id}, func(svc service.Guard) error {
+		return svc.DeleteRole(ctx, id)
+	}) {
+		return
+	}
+
 	if err := g.svc.DeleteRole(ctx, id); err != nil {
 		response(c, nil, err)
 		return
@@ -536,6 +1083,7 @@ func (g *Guard) DeleteRole(c *gin.Context) {
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
 // @Param body body service.RoleNodeListRequest true "node list"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/node [post]
 func (g *Guard) AddNodeToRole(c *gin.Context) {
@@ -558,6 +1106,12 @@ func (g *Guard) AddNodeToRole(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, req.Nodes.DryRunScope(), func(svc service.Guard) error {
+		return svc.AddNodeToRole(ctx, &req)
+	}) {
+		return
+	}
+
 	if err := g.svc.AddNodeToRole(ctx, &req); err != nil {
 		response(c, nil, err)
 		return
@@ -567,10 +1121,18 @@ func (g *Guard) AddNodeToRole(c *gin.Context) {
 }
 
 // @Summary ListRoleNode
-// @Description List role nodes
+// @Description List role nodes, including the nodes matched by the label selectors of the role
 // @Tags role
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "node name substring"
+// @Param account query string false "bound account"
+// @Param heartbeat_from query int false "last heartbeat from, unix seconds"
+// @Param heartbeat_to query int false "last heartbeat to, unix seconds"
+// @Param dynamic query bool false "bound by label selectors or explicitly"
 // @Success 200 {object} service.ListRoleNodeResponse
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/node [get]
 func (g *Guard) ListRoleNode(c *gin.Context) {
@@ -578,7 +1140,7 @@ func (g *Guard) ListRoleNode(c *gin.Context) {
 	var req service.ListRoleNodeRequest
 	var err error
 
-	if err := c.ShouldBind(&req); err != nil {
+	if err := c.BindQuery(&req); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
@@ -589,6 +1151,11 @@ func (g *Guard) ListRoleNode(c *gin.Context) {
 		return
 	}
 
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
 	nodes, err := g.svc.ListRoleNode(ctx, &req)
 	if err != nil {
 		response(c, nil, err)
@@ -604,6 +1171,7 @@ func (g *Guard) ListRoleNode(c *gin.Context) {
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
 // @Param body body []int64 true "Node IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/node/batch/delete [post]
 func (g *Guard) BatchRemoveNodeFromRole(c *gin.Context) {
@@ -628,6 +1196,12 @@ func (g *Guard) BatchRemoveNodeFromRole(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, &service.DryRunScope{NodeIDs: req.NodeIDs}, func(svc service.Guard) error {
+		return svc.RemoveNodeFromRole(ctx, &req)
+	}) {
+		return
+	}
+
 	if err := g.svc.RemoveNodeFromRole(ctx, &req); err != nil {
 		response(c, nil, err)
 		return
@@ -637,16 +1211,24 @@ func (g *Guard) BatchRemoveNodeFromRole(c *gin.Context) {
 }
 
 // @Summary AddUserToRole
-// @Description Add user to role
+// @Description Add user to role, valid_from and valid_until limit the membership to a time window
 // @Tags role
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
 // @Param body body []int64 true "User IDs"
+// @Param valid_from query int false "start of the membership, unix seconds"
+// @Param valid_until query int false "end of the membership, unix seconds"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/user [post]
 func (g *Guard) AddUserToRole(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req service.AddUserToRoleRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
 	if err := c.ShouldBindJSON(&req.UserIDs); err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
@@ -664,6 +1246,12 @@ func (g *Guard) AddUserToRole(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID, UserIDs: req.UserIDs}, func(svc service.Guard) error {
+		return svc.AddUserToRole(ctx, &req)
+	}) {
+		return
+	}
+
 	if err := g.svc.AddUserToRole(ctx, &req); err != nil {
 		response(c, nil, err)
 		return
@@ -677,18 +1265,34 @@ func (g *Guard) AddUserToRole(c *gin.Context) {
 // @Tags role
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
+// @Param page query int false "page, start from 1"
+// @Param limit query int false "limit, default is 100, max is 1000"
+// @Param sort query string false "sort field, prefix - means descending, e.g. -created_at"
+// @Param name query string false "username substring"
+// @Param email query string false "email substring"
+// @Param state query string false "user state" Enums(active, suspended, expired, banned)
 // @Success 200 {object} service.ListRoleUserResponse
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/user [get]
 func (g *Guard) ListRoleUser(c *gin.Context) {
 	ctx := c.Request.Context()
 	var req service.ListRoleUserRequest
 	var err error
+	if err := c.BindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
 	req.RoleID, err = getRoleID(c)
 	if err != nil {
 		response(c, nil, err)
 		return
 	}
 
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
 	users, err := g.svc.ListRoleUser(ctx, &req)
 	if err != nil {
 		response(c, nil, err)
@@ -704,6 +1308,7 @@ func (g *Guard) ListRoleUser(c *gin.Context) {
 // @Param spaceID path int true "Space ID"
 // @Param roleID path int true "Role ID"
 // @Param body body []int64 true "User IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
 // @Success 200 {object} nil
 // @Router /api/v1/guard/space/{spaceID}/role/{roleID}/user/batch/delete [post]
 func (g *Guard) BatchRemoveUserFromRole(c *gin.Context) {
@@ -728,6 +1333,12 @@ func (g *Guard) BatchRemoveUserFromRole(c *gin.Context) {
 		return
 	}
 
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID, UserIDs: req.UserIDs}, func(svc service.Guard) error {
+		return svc.RemoveUserFromRole(ctx, &req)
+	}) {
+		return
+	}
+
 	if err := g.svc.RemoveUserFromRole(ctx, &req); err != nil {
 		response(c, nil, err)
 		return
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// Drain marks the server as draining, the readiness check fails from
+// now on, so that the load balancer stops sending the new requests
+// while the in-flight ones are finished
+func (g *Guard) Drain() {
+	g.draining.Store(true)
+}
+
+// @Summary Healthz
+// @Description Liveness of the process, it's always ok while the server is running
+// @Tags health
+// @Success 200 {object} nil
+// @Router /healthz [get]
+func (g *Guard) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary Readyz
+// @Description Readiness of the server, the database must be reachable and the CA usable, it fails during the shutdown
+// @Tags health
+// @Success 200 {object} service.ReadyResponse
+// @Failure 503 {object} service.ReadyResponse
+// @Router /readyz [get]
+func (g *Guard) Readyz(c *gin.Context) {
+	if g.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, &service.ReadyResponse{
+			Checks: map[string]string{"server": "draining"},
+		})
+		return
+	}
+
+	resp := g.svc.Ready(c.Request.Context())
+	if !resp.Ready {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// readyService fails the database check if down is set
+type readyService struct {
+	service.Guard
+	down bool
+}
+
+func (s *readyService) Ready(ctx context.Context) *service.ReadyResponse {
+	if s.down {
+		return &service.ReadyResponse{Checks: map[string]string{"database": "failed", "ca": "ok"}}
+	}
+	return &service.ReadyResponse{Ready: true, Checks: map[string]string{"database": "ok", "ca": "ok"}}
+}
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		down     bool
+		drain    bool
+		code     int
+		expected map[string]string
+	}{
+		{name: "ready", code: http.StatusOK, expected: map[string]string{"database": "ok", "ca": "ok"}},
+		{name: "database down", down: true, code: http.StatusServiceUnavailable,
+			expected: map[string]string{"database": "failed", "ca": "ok"}},
+		{name: "draining", drain: true, code: http.StatusServiceUnavailable,
+			expected: map[string]string{"server": "draining"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(Config{}, &readyService{down: tt.down})
+			if tt.drain {
+				g.Drain()
+			}
+			e := gin.New()
+			e.GET("/readyz", g.Readyz)
+			e.GET("/healthz", g.Healthz)
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, w.Code)
+			}
+
+			var resp service.ReadyResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Ready != (tt.code == http.StatusOK) || !reflect.DeepEqual(resp.Checks, tt.expected) {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+
+			// the liveness does not depend on the checks
+			w = httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected the liveness ok, got %d", w.Code)
+			}
+		})
+	}
+}
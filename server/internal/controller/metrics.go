@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/pkg/metrics"
+)
+
+// the reasons of the signature failures of the node requests
+const (
+	signatureMissing = "missing"
+	signatureUnknown = "unknown_node"
+	signatureInvalid = "invalid_signature"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("guard_http_requests_total",
+		"Number of the http requests, by the route and the status", "method", "route", "status")
+	httpDuration = metrics.NewHistogramVec("guard_http_request_duration_seconds",
+		"Latency of the http requests in seconds, by the route and the status", metrics.DefaultBuckets,
+		"method", "route", "status")
+	signatureFailures = metrics.NewCounterVec("guard_node_signature_failures_total",
+		"Number of the node requests failing the signature check, by the reason", "reason")
+)
+
+func init() {
+	for _, reason := range []string{signatureMissing, signatureUnknown, signatureInvalid} {
+		signatureFailures.With(reason)
+	}
+
+	metrics.Register(httpRequests, httpDuration, signatureFailures)
+}
+
+// newMetrics returns the registry of the /metrics endpoint, it has the
+// metrics of the packages and the nodes counted at the scrape time
+func newMetrics(g *Guard) *metrics.Registry {
+	r := metrics.NewRegistry()
+	r.Register(metrics.Default, metrics.NewGaugeFunc("guard_nodes",
+		"Number of the nodes, by the space and the health computed from the last heartbeat",
+		[]string{"space", "health"}, g.collectNodes))
+	return r
+}
+
+func (g *Guard) collectNodes(ctx context.Context, observe func(v float64, values ...string)) error {
+	fleet, err := g.svc.FleetHealth(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range fleet.Spaces {
+		observe(float64(s.Healthy), s.SpaceName, string(model.NodeHealthy))
+		observe(float64(s.Stale), s.SpaceName, string(model.NodeStale))
+		observe(float64(s.Offline), s.SpaceName, string(model.NodeOffline))
+	}
+	return nil
+}
+
+// Instrument is a middleware to record the count and the latency of the
+// requests by the route template, e.g. /api/v1/guard/user/:userID
+func (g *Guard) Instrument(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	method, route := c.Request.Method, c.FullPath()
+	if route == "" {
+		// the unmatched paths and methods are arbitrary, keep them out
+		// of the labels
+		method, route = "", "unmatched"
+	}
+
+	status := strconv.Itoa(c.Writer.Status())
+	httpRequests.With(method, route, status).Inc()
+	httpDuration.With(method, route, status).Observe(time.Since(start).Seconds())
+}
+
+// @Summary Metrics
+// @Description Metrics in the prometheus text format
+// @Tags metrics
+// @Produce plain
+// @Success 200 {string} string "metrics"
+// @Router /metrics [get]
+func (g *Guard) Metrics(c *gin.Context) {
+	g.metrics.ServeHTTP(c.Writer, c.Request)
+}
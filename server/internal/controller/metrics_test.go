@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// stubService implements the methods used by the metrics, the others
+// panic on the nil embedded interface
+type stubService struct {
+	service.Guard
+}
+
+func (s *stubService) GetNodeByUniqueID(ctx context.Context, uniqueID string) (*service.Node, error) {
+	return nil, nil
+}
+
+func (s *stubService) FleetHealth(ctx context.Context) (*service.FleetHealthResponse, error) {
+	return &service.FleetHealthResponse{Spaces: []*service.SpaceHealthVO{
+		{SpaceID: 1, SpaceName: "prod", Total: 4, Healthy: 3, Stale: 1},
+	}}, nil
+}
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	g := New(Config{}, &stubService{})
+	e := gin.New()
+	e.Use(g.Instrument)
+	e.GET("/api/v1/guard/ca", g.IsAllowedNode, g.GetCA)
+	e.GET("/metrics", g.IsAdmin, g.Metrics)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/guard/ca?nodeID=unknown", nil)
+	req.Header.Set(HeaderTimestamp, "1700000000")
+	req.Header.Set(HeaderSignature, "sign")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		`guard_http_requests_total{method="GET",route="/api/v1/guard/ca",status="404"} 1`,
+		`guard_http_request_duration_seconds_count{method="GET",route="/api/v1/guard/ca",status="404"} 1`,
+		`guard_node_signature_failures_total{reason="unknown_node"} 1`,
+		`guard_node_signature_failures_total{reason="invalid_signature"} 0`,
+		`guard_certs_issued_total 0`,
+		`guard_nodes{space="prod",health="healthy"} 3`,
+		`guard_nodes{space="prod",health="stale"} 1`,
+		`guard_nodes{space="prod",health="offline"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %s in:\n%s", line, body)
+		}
+	}
+}
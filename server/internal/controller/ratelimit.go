@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/ratelimit"
+)
+
+const HeaderRetryAfter = "Retry-After"
+
+// RateLimitConfig is the configuration of the rate limit, the zero value of
+// each item uses the default value, a negative rate or threshold disables it
+type RateLimitConfig struct {
+	// Disable disables all rate limits and lockouts
+	Disable bool `yaml:"disable"`
+
+	// Node limits the requests per node id, the requests before the
+	// signature is verified are limited per node id and source ip
+	Node ratelimit.Config `yaml:"node"`
+	// IP limits the requests per source ip
+	IP ratelimit.Config `yaml:"ip"`
+	// Token limits the requests per api token
+	Token ratelimit.Config `yaml:"token"`
+
+	// Lockout locks the source ip, or the node id from the source ip,
+	// temporarily after repeated signature or token failures
+	Lockout ratelimit.LockoutConfig `yaml:"lockout"`
+}
+
+func (c *RateLimitConfig) Validate() error {
+	limits := []struct {
+		name string
+		cfg  *ratelimit.Config
+		def  ratelimit.Config
+	}{
+		{"node", &c.Node, ratelimit.Config{Rate: 1, Burst: 10}},
+		{"ip", &c.IP, ratelimit.Config{Rate: 50, Burst: 200}},
+		{"token", &c.Token, ratelimit.Config{Rate: 20, Burst: 100}},
+	}
+	for _, limit := range limits {
+		switch {
+		case limit.cfg.Rate == 0 && limit.cfg.Burst == 0:
+			*limit.cfg = limit.def
+		case limit.cfg.Rate < 0:
+			// disabled
+			*limit.cfg = ratelimit.Config{}
+		}
+
+		if err := limit.cfg.Validate(); err != nil {
+			return fmt.Errorf("rate limit %s: %w", limit.name, err)
+		}
+	}
+
+	switch {
+	case c.Lockout.Threshold == 0:
+		c.Lockout.Threshold = 10
+	case c.Lockout.Threshold < 0:
+		// disabled
+		c.Lockout.Threshold = 0
+	}
+
+	if err := c.Lockout.Validate(); err != nil {
+		return fmt.Errorf("rate limit lockout: %w", err)
+	}
+
+	return nil
+}
+
+type limiter struct {
+	// nodeIP limits the unauthenticated requests per node id and source
+	// ip, node limits the authenticated ones per node id
+	nodeIP  *ratelimit.Limiter
+	node    *ratelimit.Limiter
+	ip      *ratelimit.Limiter
+	token   *ratelimit.Limiter
+	lockout *ratelimit.Lockout
+}
+
+func newLimiter(cfg *RateLimitConfig) *limiter {
+	if cfg.Disable {
+		return &limiter{}
+	}
+
+	return &limiter{
+		nodeIP:  ratelimit.New(cfg.Node),
+		node:    ratelimit.New(cfg.Node),
+		ip:      ratelimit.New(cfg.IP),
+		token:   ratelimit.New(cfg.Token),
+		lockout: ratelimit.NewLockout(cfg.Lockout),
+	}
+}
+
+func lockoutKeyIP(ip string) string {
+	return "ip:" + ip
+}
+
+// nodeIPKey is the key of a node id from a source ip, the failures and
+// the requests from the other ips can not lock out or throttle the node
+func nodeIPKey(nodeID, ip string) string {
+	return "node:" + nodeID + "@" + ip
+}
+
+// tooManyRequests aborts the request with 429 and the Retry-After header
+func tooManyRequests(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header(HeaderRetryAfter, strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, errors.ErrTooManyRequests)
+}
+
+// isLocked aborts the request if the key is locked
+func (g *Guard) isLocked(c *gin.Context, key string) bool {
+	locked, wait := g.limiter.lockout.Locked(key)
+	if locked {
+		tooManyRequests(c, wait)
+	}
+	return locked
+}
+
+// recordFailure records a failure of the key, the key will be
+// locked temporarily after repeated failures
+func (g *Guard) recordFailure(c *gin.Context, key string) {
+	if g.limiter.lockout.Fail(key) {
+		slog.WarnContext(c.Request.Context(), "too many failures, locked", "key", key)
+	}
+}
+
+// RateLimitIP is a middleware to limit the requests per source ip
+func (g *Guard) RateLimitIP(c *gin.Context) {
+	ip := c.ClientIP()
+	if ok, wait := g.limiter.ip.Allow(ip); !ok {
+		slog.DebugContext(c.Request.Context(), "rate limited", "ip", ip)
+		tooManyRequests(c, wait)
+		return
+	}
+
+	c.Next()
+}
+
+// RateLimitNode is a middleware to limit the requests per node id and
+// source ip, it runs before the node is looked up, so random node ids can
+// not hammer the database. The node id is not verified yet, so the requests
+// from the other ips can not drain the bucket of the node.
+func (g *Guard) RateLimitNode(c *gin.Context) {
+	nodeID := c.Query("nodeID")
+	if nodeID == "" {
+		c.Next()
+		return
+	}
+
+	ip := c.ClientIP()
+	key := nodeIPKey(nodeID, ip)
+	if g.isLocked(c, lockoutKeyIP(ip)) || g.isLocked(c, key) {
+		return
+	}
+
+	if ok, wait := g.limiter.nodeIP.Allow(key); !ok {
+		slog.DebugContext(c.Request.Context(), "rate limited", "nodeID", nodeID, "ip", ip)
+		tooManyRequests(c, wait)
+		return
+	}
+
+	c.Next()
+}
+
+// allowNode limits the requests per node id after the signature is
+// verified, it aborts the request if the node is throttled
+func (g *Guard) allowNode(c *gin.Context, nodeID string) bool {
+	ok, wait := g.limiter.node.Allow(nodeID)
+	if !ok {
+		slog.DebugContext(c.Request.Context(), "rate limited", "nodeID", nodeID)
+		tooManyRequests(c, wait)
+	}
+	return ok
+}
+
+// RateLimitToken is a middleware to limit the requests per api token,
+// it must run after IsAdmin
+func (g *Guard) RateLimitToken(c *gin.Context) {
+	operator := getOperator(c)
+	if operator == "" {
+		c.Next()
+		return
+	}
+
+	if ok, wait := g.limiter.token.Allow(operator); !ok {
+		slog.DebugContext(c.Request.Context(), "rate limited", "operator", operator)
+		tooManyRequests(c, wait)
+		return
+	}
+
+	c.Next()
+}
+
+// RateLimitState is the state of the rate limiters
+type RateLimitState struct {
+	NodeIP  []ratelimit.BucketState `json:"node_ip"`
+	Node    []ratelimit.BucketState `json:"node"`
+	IP      []ratelimit.BucketState `json:"ip"`
+	Token   []ratelimit.BucketState `json:"token"`
+	Lockout []ratelimit.LockState   `json:"lockout"`
+}
+
+// @Summary GetRateLimitState
+// @Description Get the state of the rate limiters, only the throttled keys are returned
+// @Tags admin
+// @Success 200 {object} RateLimitState
+// @Router /api/v1/guard/ratelimit [get]
+func (g *Guard) GetRateLimitState(c *gin.Context) {
+	response(c, &RateLimitState{
+		NodeIP:  g.limiter.nodeIP.Snapshot(),
+		Node:    g.limiter.node.Snapshot(),
+		IP:      g.limiter.ip.Snapshot(),
+		Token:   g.limiter.token.Snapshot(),
+		Lockout: g.limiter.lockout.Snapshot(),
+	}, nil)
+}
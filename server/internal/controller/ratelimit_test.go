@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/pkg/ratelimit"
+	"github.com/sysarmor/guard/server/pkg/signature"
+)
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RateLimitConfig
+		wantErr bool
+	}{
+		{name: "default", cfg: RateLimitConfig{}},
+		{name: "disabled", cfg: RateLimitConfig{Node: ratelimit.Config{Rate: -1}, Lockout: ratelimit.LockoutConfig{Threshold: -1}}},
+		{name: "negative burst", cfg: RateLimitConfig{IP: ratelimit.Config{Rate: 1, Burst: -1}}, wantErr: true},
+		{name: "burst without rate", cfg: RateLimitConfig{Token: ratelimit.Config{Burst: 10}}, wantErr: true},
+		{name: "negative window", cfg: RateLimitConfig{Lockout: ratelimit.LockoutConfig{Window: -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestLockoutNode checks the invalid signatures from one ip do not lock
+// out the node from the other ips
+func TestLockoutNode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := Config{RateLimit: RateLimitConfig{Lockout: ratelimit.LockoutConfig{Threshold: 2}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(cfg, &nodeService{})
+	e := gin.New()
+	e.GET("/api/v1/guard/ca", g.RateLimitNode, g.IsAllowedNode, g.Signature, g.GetCA)
+
+	get := func(ip, secret string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/guard/ca?nodeID=node", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(HeaderTimestamp, "1700000000")
+		req.Header.Set(HeaderSignature, signature.SimpleSignature(signature.SimpleString("1700000000"), []byte(secret)))
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := get("192.0.2.1", "wrong"); code != http.StatusForbidden {
+			t.Fatalf("unexpected status: %d", code)
+		}
+	}
+
+	if code := get("192.0.2.1", "secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the attacker locked, got %d", code)
+	}
+
+	if code := get("192.0.2.2", "secret"); code != http.StatusOK {
+		t.Fatalf("expected the node allowed from its ip, got %d", code)
+	}
+}
+
+// TestRateLimitNode checks the requests with the node id from one ip do
+// not drain the bucket of the node from the other ips
+func TestRateLimitNode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := Config{RateLimit: RateLimitConfig{
+		Node:    ratelimit.Config{Rate: 0.001, Burst: 2},
+		Lockout: ratelimit.LockoutConfig{Threshold: -1},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(cfg, &nodeService{})
+	e := gin.New()
+	e.GET("/api/v1/guard/ca", g.RateLimitNode, g.IsAllowedNode, g.Signature, g.GetCA)
+
+	get := func(ip, secret string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/guard/ca?nodeID=node", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(HeaderTimestamp, "1700000000")
+		req.Header.Set(HeaderSignature, signature.SimpleSignature(signature.SimpleString("1700000000"), []byte(secret)))
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := get("192.0.2.1", "wrong"); code != http.StatusForbidden {
+			t.Fatalf("unexpected status: %d", code)
+		}
+	}
+
+	if code := get("192.0.2.1", "wrong"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the attacker limited, got %d", code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := get("192.0.2.2", "secret"); code != http.StatusOK {
+			t.Fatalf("expected the node allowed from its ip, got %d", code)
+		}
+	}
+
+	// the verified requests are limited per node id from every ip
+	if code := get("192.0.2.3", "secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the node limited, got %d", code)
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// @Summary AddRoleSelector
+// @Description Add a label selector to role, the nodes of the space with all the labels are bound to the role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body service.AddRoleSelectorRequest true "Add role selector request"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} int64
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/selector [post]
+func (g *Guard) AddRoleSelector(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.AddRoleSelectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID, Selector: true}, func(svc service.Guard) error {
+		_, err := svc.AddRoleSelector(ctx, &req)
+		return err
+	}) {
+		return
+	}
+
+	id, err := g.svc.AddRoleSelector(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, id, nil)
+}
+
+// @Summary ListRoleSelector
+// @Description List the label selectors of the role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Success 200 {object} service.ListRoleSelectorResponse
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/selector [get]
+func (g *Guard) ListRoleSelector(c *gin.Context) {
+	ctx := c.Request.Context()
+	roleID, err := getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	selectors, err := g.svc.ListRoleSelector(ctx, roleID)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, selectors, nil)
+}
+
+// @Summary BatchRemoveRoleSelector
+// @Description Batch remove label selectors from role
+// @Tags role
+// @Param spaceID path int true "Space ID"
+// @Param roleID path int true "Role ID"
+// @Param body body []int64 true "Selector IDs"
+// @Param dry_run query bool false "preview the principals diff without changing anything"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/space/{spaceID}/role/{roleID}/selector/batch/delete [post]
+func (g *Guard) BatchRemoveRoleSelector(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.RemoveRoleSelectorRequest
+	if err := c.ShouldBindJSON(&req.SelectorIDs); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.RoleID, err = getRoleID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if g.dryRun(c, &service.DryRunScope{RoleID: req.RoleID}, func(svc service.Guard) error {
+		return svc.RemoveRoleSelector(ctx, &req)
+	}) {
+		return
+	}
+
+	if err := g.svc.RemoveRoleSelector(ctx, &req); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
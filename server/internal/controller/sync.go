@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/service"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// @Summary Report
+// @Description Report the digests of the files managed by the client after a run
+// @Tags Guard
+// @Param node_id query string true "Node ID"
+// @Param X-Timestamp header string true "unix timestamp, seconds"
+// @Param X-Signature header string true "signature"
+// @Param body body dto.Report true "Report"
+// @Success 200 {object} nil
+// @Router /api/v1/guard/report [post]
+func (g *Guard) Report(c *gin.Context) {
+	nodeID := c.Query("nodeID")
+	if nodeID == "" {
+		c.AbortWithError(http.StatusBadRequest, errors.ErrNodeNotFound)
+		return
+	}
+
+	var req service.ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	req.UniqueID = nodeID
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := g.svc.Report(ctx, &req); err != nil {
+		slog.ErrorContext(ctx, err.Error(), "node id", nodeID)
+		response(c, nil, err)
+		return
+	}
+
+	response(c, nil, nil)
+}
+
+// @Summary Watch
+// @Description Wait until the files served to the node change or the watch times out, the version of the response is passed to the next watch
+// @Tags Guard
+// @Param node_id query string true "Node ID"
+// @Param version query string false "Version returned by the last watch, empty returns the current version"
+// @Param X-Timestamp header string true "unix timestamp, seconds"
+// @Param X-Signature header string true "signature"
+// @Success 200 {object} dto.Watch
+// @Failure 503 {object} errors.Error "the server is shutting down"
+// @Router /api/v1/guard/watch [get]
+func (g *Guard) Watch(c *gin.Context) {
+	nodeID := c.Query("nodeID")
+	if nodeID == "" {
+		c.AbortWithError(http.StatusBadRequest, errors.ErrNodeNotFound)
+		return
+	}
+
+	req := service.WatchRequest{UniqueID: nodeID, Version: c.Query("version")}
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	watch, err := g.svc.Watch(ctx, &req)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, err.Error(), "node id", nodeID)
+		}
+		response(c, nil, err)
+		return
+	}
+
+	response(c, watch, nil)
+}
+
+// @Summary ListSpaceSync
+// @Description Compare the files reported by the nodes of the space with what the server serves now, synced, lagging, drift or unknown
+// @Tags node
+// @Param spaceID path int true "Space ID"
+// @Param node_id query int false "Node ID"
+// @Param kind query string false "ca, principals, krl or authorized_keys"
+// @Param status query string false "synced, lagging, drift or unknown"
+// @Success 200 {object} service.ListSpaceSyncResponse
+// @Router /api/v1/guard/space/{spaceID}/sync [get]
+func (g *Guard) ListSpaceSync(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ListSpaceSyncRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	req.SpaceID, err = getSpaceID(c)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	sync, err := g.svc.ListSpaceSync(ctx, &req)
+	if err != nil {
+		response(c, nil, err)
+		return
+	}
+
+	response(c, sync, nil)
+}
@@ -0,0 +1,41 @@
+package model
+
+// Access is an effective access of a user to a node with an account,
+// it tells via which role and membership the access exists
+type Access struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+
+	NodeID   int64  `json:"node_id"`
+	NodeName string `json:"node_name"`
+	SpaceID  int64  `json:"space_id"`
+	Account  string `json:"account"`
+
+	RoleID   int64  `json:"role_id"`
+	RoleName string `json:"role_name"`
+	Global   bool   `json:"global"`
+
+	// Group is the group which the user inherits the role from,
+	// it's empty if the user is added to the role directly
+	Group string `json:"group"`
+	// Dynamic is true if the node is bound by a label selector
+	Dynamic bool `json:"dynamic"`
+
+	// ValidUntil is the end of the membership, ExpiresAt is the
+	// expiry of the user, 0 means no limit
+	ValidUntil int64 `json:"valid_until"`
+	ExpiresAt  int64 `json:"expires_at"`
+}
+
+// Until returns the time when the access ends, 0 means no limit
+func (a *Access) Until() int64 {
+	switch {
+	case a.ValidUntil == 0:
+		return a.ExpiresAt
+	case a.ExpiresAt == 0:
+		return a.ValidUntil
+	default:
+		return min(a.ValidUntil, a.ExpiresAt)
+	}
+}
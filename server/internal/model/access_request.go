@@ -0,0 +1,58 @@
+package model
+
+// AccessRequestState is the state of the access request
+type AccessRequestState string
+
+const (
+	AccessRequestPending   AccessRequestState = "pending"
+	AccessRequestApproved  AccessRequestState = "approved"
+	AccessRequestDenied    AccessRequestState = "denied"
+	AccessRequestCancelled AccessRequestState = "cancelled"
+)
+
+// Valid reports whether the state is a known state
+func (s AccessRequestState) Valid() bool {
+	switch s {
+	case AccessRequestPending, AccessRequestApproved, AccessRequestDenied, AccessRequestCancelled:
+		return true
+	}
+	return false
+}
+
+// AccessRequest is the request of a user to join a role temporarily,
+// the membership is created when one of the approvers of the role
+// approves the request
+type AccessRequest struct {
+	ID     int64  `json:"id"`
+	RoleID int64  `json:"role_id"`
+	UserID int64  `json:"user_id"`
+	Reason string `json:"reason"`
+	// Duration is the requested duration of the membership in seconds
+	Duration int64              `json:"duration"`
+	State    AccessRequestState `json:"state"`
+	// Reviewer is the email of the approver who approved or denied the request
+	Reviewer  string `json:"reviewer"`
+	Comment   string `json:"comment"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// AccessRequestLog records a state transition of the access request
+type AccessRequestLog struct {
+	ID        int64              `json:"id"`
+	RequestID int64              `json:"request_id"`
+	FromState AccessRequestState `json:"from_state"`
+	ToState   AccessRequestState `json:"to_state"`
+	// Operator is the email or the token name of the operator
+	Operator  string `json:"operator"`
+	Comment   string `json:"comment"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// RoleApprover is the user who can approve the access requests of the role
+type RoleApprover struct {
+	ID        int64 `json:"id"`
+	RoleID    int64 `json:"role_id"`
+	UserID    int64 `json:"user_id"`
+	CreatedAt int64 `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// BreakGlass is an emergency access of a user to a break-glass role,
+// it's also the review item which must be acknowledged afterwards
+type BreakGlass struct {
+	ID      int64  `json:"id"`
+	RoleID  int64  `json:"role_id"`
+	SpaceID int64  `json:"space_id"`
+	UserID  int64  `json:"user_id"`
+	Reason  string `json:"reason"`
+	// Operator is the operator who triggered the break-glass
+	Operator string `json:"operator"`
+	// ExpiresAt is the end of the membership and the cert
+	ExpiresAt int64 `json:"expires_at"`
+	// AckedBy is the operator who acknowledged the review,
+	// it's empty if the review is pending
+	AckedBy    string `json:"acked_by"`
+	AckedAt    int64  `json:"acked_at"`
+	AckComment string `json:"ack_comment"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// IsAcked reports whether the review is acknowledged
+func (b *BreakGlass) IsAcked() bool {
+	return b.AckedAt > 0
+}
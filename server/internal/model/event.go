@@ -0,0 +1,40 @@
+package model
+
+// EventType is the type of the event
+type EventType string
+
+const (
+	// EventUserExpired is emitted when the user passes the expires_at
+	EventUserExpired EventType = "user.expired"
+	// EventRoleUserExpired is emitted when the temporary
+	// membership of the user in the role ends
+	EventRoleUserExpired EventType = "role_user.expired"
+	// EventBreakGlassGranted is emitted when a user gets the emergency access
+	EventBreakGlassGranted EventType = "break_glass.granted"
+	// EventBreakGlassAcked is emitted when the review of the
+	// emergency access is acknowledged
+	EventBreakGlassAcked EventType = "break_glass.acked"
+	// EventNodeHealthy, EventNodeStale and EventNodeOffline are
+	// emitted when the health of the node changes
+	EventNodeHealthy EventType = "node.healthy"
+	EventNodeStale   EventType = "node.stale"
+	EventNodeOffline EventType = "node.offline"
+	// EventNodeDrift is emitted when a managed file on the node
+	// becomes different from what the node received
+	EventNodeDrift EventType = "node.drift"
+)
+
+// Event is the model of the event, it records the changes
+// made by the server itself, e.g. the expiry of the memberships
+type Event struct {
+	ID   int64     `json:"id"`
+	Type EventType `json:"type"`
+	// SpaceID, RoleID, UserID and NodeID are the related
+	// objects of the event, 0 means not related
+	SpaceID   int64  `json:"space_id"`
+	RoleID    int64  `json:"role_id"`
+	UserID    int64  `json:"user_id"`
+	NodeID    int64  `json:"node_id"`
+	Message   string `json:"message"`
+	CreatedAt int64  `json:"created_at"`
+}
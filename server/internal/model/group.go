@@ -0,0 +1,26 @@
+package model
+
+// Group is the model of the group, a team of users. The members
+// of the group inherit the roles which the group is bound to.
+type Group struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// GroupUser is the relation of the group and the user
+type GroupUser struct {
+	ID        int64 `json:"id"`
+	GroupID   int64 `json:"group_id"`
+	UserID    int64 `json:"user_id"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+// RoleGroup is the relation of the role and the group
+type RoleGroup struct {
+	ID        int64 `json:"id"`
+	RoleID    int64 `json:"role_id"`
+	GroupID   int64 `json:"group_id"`
+	CreatedAt int64 `json:"created_at"`
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels is the key/value labels of the node, e.g. env=prod, it's
+// also used as the selector of the role, a node matches the selector
+// if it has all the labels of the selector
+type Labels map[string]string
+
+// ParseLabels parses the labels in the form of "k1=v1,k2=v2", a key
+// can not be given twice
+func ParseLabels(s string) (Labels, error) {
+	labels := Labels{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q, should be key=value", pair)
+		}
+
+		k = strings.TrimSpace(k)
+		if _, ok := labels[k]; ok {
+			return nil, fmt.Errorf("duplicate label %q", k)
+		}
+		labels[k] = strings.TrimSpace(v)
+	}
+
+	return labels, labels.Validate()
+}
+
+// Validate checks the keys and values can be written as "k1=v1,k2=v2"
+func (l Labels) Validate() error {
+	for k, v := range l {
+		if k == "" {
+			return fmt.Errorf("label key can not be empty")
+		}
+		if strings.ContainsAny(k, "=,") || strings.Contains(v, ",") {
+			return fmt.Errorf("label %q contains invalid characters", k)
+		}
+	}
+
+	return nil
+}
+
+// String returns the labels in the form of "k1=v1,k2=v2" sorted by key
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+l[k])
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// Value implements the driver.Valuer, the labels are stored as jsonb. It's
+// a string, the []byte is sent as bytea by the driver.
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner
+func (l *Labels) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = Labels{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type of labels: %T", src)
+	}
+
+	return json.Unmarshal(data, l)
+}
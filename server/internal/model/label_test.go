@@ -0,0 +1,99 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected Labels
+		wantErr  bool
+	}{
+		{name: "empty", s: "", expected: Labels{}},
+		{name: "blank pairs", s: " , ,", expected: Labels{}},
+		{name: "one", s: "env=prod", expected: Labels{"env": "prod"}},
+		{name: "many", s: " env = prod ,team=db", expected: Labels{"env": "prod", "team": "db"}},
+		{name: "empty value", s: "canary=", expected: Labels{"canary": ""}},
+		{name: "equal sign in value", s: "expr=a=b", expected: Labels{"expr": "a=b"}},
+		{name: "no value", s: "env", wantErr: true},
+		{name: "empty key", s: "=prod", wantErr: true},
+		{name: "duplicate", s: "env=prod,env=dev", wantErr: true},
+		{name: "duplicate same value", s: "env=prod, env =prod", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := ParseLabels(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(labels, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, labels)
+			}
+		})
+	}
+}
+
+func TestLabelsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  Labels
+		wantErr bool
+	}{
+		{name: "nil", labels: nil},
+		{name: "empty", labels: Labels{}},
+		{name: "valid", labels: Labels{"env": "prod", "canary": ""}},
+		{name: "equal sign in value", labels: Labels{"expr": "a=b"}},
+		{name: "empty key", labels: Labels{"": "prod"}, wantErr: true},
+		{name: "equal sign in key", labels: Labels{"a=b": "prod"}, wantErr: true},
+		{name: "comma in key", labels: Labels{"a,b": "prod"}, wantErr: true},
+		{name: "comma in value", labels: Labels{"env": "prod,dev"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.labels.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestLabelsString checks the valid labels are parsed back from the string
+func TestLabelsString(t *testing.T) {
+	labels := Labels{"team": "db", "env": "prod", "expr": "a=b", "canary": ""}
+	s := labels.String()
+	if expected := "canary=,env=prod,expr=a=b,team=db"; s != expected {
+		t.Fatalf("expected %q, got %q", expected, s)
+	}
+
+	parsed, err := ParseLabels(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, labels) {
+		t.Fatalf("expected %v, got %v", labels, parsed)
+	}
+}
+
+// TestLabelsValue checks the labels are scanned back from the stored
+// value, the nil labels are stored as the empty ones
+func TestLabelsValue(t *testing.T) {
+	for _, labels := range []Labels{nil, {}, {"env": "prod"}} {
+		value, err := labels.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var scanned Labels
+		if err := scanned.Scan(value); err != nil {
+			t.Fatal(err)
+		}
+		if scanned == nil || len(scanned) != len(labels) || scanned["env"] != labels["env"] {
+			t.Fatalf("expected %v, got %v", labels, scanned)
+		}
+	}
+}
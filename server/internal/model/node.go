@@ -28,6 +28,81 @@ type Node struct {
 	// on the node. default use the first account as the default
 	Accounts []string `json:"accounts"`
 
+	// Labels is the key/value labels of the node, the roles
+	// bind the nodes by the label selectors
+	Labels Labels `json:"labels"`
+
+	// Health is computed from the last heartbeat by the sweep
+	Health NodeHealth `json:"health"`
+
 	CreatedAt int64 `json:"created_at"`
 	UpdatedAt int64 `json:"updated_at"`
 }
+
+// NodeHealth is the health of the node by its last heartbeat
+type NodeHealth string
+
+const (
+	// NodeHealthy means the node synced within the stale threshold
+	NodeHealthy NodeHealth = "healthy"
+	// NodeStale means the node missed the stale threshold but
+	// not the offline one
+	NodeStale NodeHealth = "stale"
+	// NodeOffline means the node missed the offline threshold,
+	// or never synced
+	NodeOffline NodeHealth = "offline"
+)
+
+// Valid reports whether the health is a known health
+func (h NodeHealth) Valid() bool {
+	switch h {
+	case NodeHealthy, NodeStale, NodeOffline:
+		return true
+	}
+	return false
+}
+
+// NodeArtifact is the state of a file managed by the client on the node,
+// reported after each run
+type NodeArtifact struct {
+	ID     int64  `json:"id"`
+	NodeID int64  `json:"node_id"`
+	Kind   string `json:"kind"`
+	// Received is the digest of the content served to the node,
+	// Applied is the digest of the file read back after the run
+	Received   string `json:"received"`
+	Applied    string `json:"applied"`
+	Error      string `json:"error"`
+	ReportedAt int64  `json:"reported_at"`
+}
+
+// Drifted reports whether the file differs from what the node received
+func (a *NodeArtifact) Drifted() bool {
+	return a.Received != "" && a.Applied != a.Received
+}
+
+// SyncStatus is the state of a managed file compared with what the
+// server serves now
+type SyncStatus string
+
+const (
+	// SyncSynced means the file is what the server serves now
+	SyncSynced SyncStatus = "synced"
+	// SyncLagging means the file is what the node received, but the
+	// server has changed it since
+	SyncLagging SyncStatus = "lagging"
+	// SyncDrift means the file is not what the node received, e.g. it
+	// failed to write or the file was edited
+	SyncDrift SyncStatus = "drift"
+	// SyncUnknown means the node never reported the file
+	SyncUnknown SyncStatus = "unknown"
+)
+
+// Valid reports whether the status is a known status
+func (s SyncStatus) Valid() bool {
+	switch s {
+	case SyncSynced, SyncLagging, SyncDrift, SyncUnknown:
+		return true
+	}
+	return false
+}
@@ -6,7 +6,21 @@ type Role struct {
 	SpaceID     int64  `json:"space_id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	CreatedAt   int64  `json:"created_at"`
+	// BreakGlass marks the role for the emergency access, the users
+	// can join it without approval for a short time
+	BreakGlass bool `json:"break_glass"`
+	// Global role binds the nodes of all spaces, its SpaceID is 0.
+	// It's only managed by the super admins.
+	Global    bool  `json:"global"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+// NodeMember is an account of the node and an active user allowed to log
+// in with it, Email and PublicKey are empty if the account has no member
+type NodeMember struct {
+	Account   string `json:"account"`
+	Email     string `json:"email"`
+	PublicKey string `json:"public_key"`
 }
 
 // RoleNode is the relation of the role and the node
@@ -19,16 +33,46 @@ type RoleNode struct {
 	// to access the node
 	Account   string `json:"account"`
 	CreatedAt int64  `json:"created_at"`
+	// Dynamic is true if the node is bound by a label selector of
+	// the role rather than added to the role explicitly
+	Dynamic bool `json:"dynamic"`
+}
+
+// RoleSelector binds the nodes of the role's space which match the
+// selector, the nodes enrolled later are bound without touching the role
+type RoleSelector struct {
+	ID       int64  `json:"id"`
+	RoleID   int64  `json:"role_id"`
+	Selector Labels `json:"selector"`
+	// Account is the account used to access the matched nodes, if it's
+	// empty, the default account (the first one) of the node is used.
+	// The nodes without the account are not matched.
+	Account   string `json:"account"`
+	CreatedAt int64  `json:"created_at"`
 }
 
 // RoleUser is the relation of the role and the user
 // It's subset of the space_user. Only the user who has the role
 // can access the node in the role.
 type RoleUser struct {
-	ID        int64 `json:"id"`
-	RoleID    int64 `json:"role_id"`
-	UserID    int64 `json:"user_id"`
-	CreatedAt int64 `json:"created_at"`
+	ID     int64 `json:"id"`
+	RoleID int64 `json:"role_id"`
+	UserID int64 `json:"user_id"`
+	// ValidFrom and ValidUntil limit the membership to a time window,
+	// 0 means no limit. The membership is removed after ValidUntil.
+	ValidFrom  int64 `json:"valid_from"`
+	ValidUntil int64 `json:"valid_until"`
+	CreatedAt  int64 `json:"created_at"`
+}
+
+// IsTemporary reports whether the membership has an end
+func (ru *RoleUser) IsTemporary() bool {
+	return ru.ValidUntil > 0
+}
+
+// ValidAt reports whether the membership is valid at the time
+func (ru *RoleUser) ValidAt(now int64) bool {
+	return ru.ValidFrom <= now && (ru.ValidUntil == 0 || ru.ValidUntil > now)
 }
 
 type RoleNodeView struct {
@@ -36,4 +80,19 @@ type RoleNodeView struct {
 
 	Account   string `json:"account"`
 	CreatedAt int64  `json:"created_at"`
+	// Dynamic is true if the node is bound by a label selector
+	Dynamic bool `json:"dynamic"`
+}
+
+type RoleUserView struct {
+	User
+
+	ValidFrom  int64 `json:"valid_from"`
+	ValidUntil int64 `json:"valid_until"`
+	// JoinedAt is the time when the user is added to the role
+	JoinedAt int64 `json:"joined_at"`
+	// Direct is true if the user is added to the role directly
+	Direct bool `json:"direct"`
+	// Groups are the names of the groups the user inherits the role from
+	Groups []string `json:"groups"`
 }
@@ -5,7 +5,11 @@ type Space struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	CreatedAt   int64  `json:"created_at"`
+	// StaleAfter and OfflineAfter are the seconds without heartbeat
+	// before the nodes are stale or offline, 0 means the server default
+	StaleAfter   int64 `json:"stale_after"`
+	OfflineAfter int64 `json:"offline_after"`
+	CreatedAt    int64 `json:"created_at"`
 }
 
 // SpaceUser is the relation of the space and the user
@@ -1,14 +1,53 @@
 package model
 
+// UserState is the lifecycle state of the user
+type UserState string
+
+const (
+	// UserStateActive is the normal state, only active users
+	// can be granted certs and appear in the principals
+	UserStateActive UserState = "active"
+	// UserStateSuspended is set by the admin, it's reversible
+	UserStateSuspended UserState = "suspended"
+	// UserStateExpired is set when the user passes the expires_at,
+	// it's reversible by extending the expires_at
+	UserStateExpired UserState = "expired"
+	// UserStateBanned is set by the admin, the user is removed from
+	// all roles, unban does not restore the roles
+	UserStateBanned UserState = "banned"
+)
+
+// Valid reports whether the state is a known state
+func (s UserState) Valid() bool {
+	switch s {
+	case UserStateActive, UserStateSuspended, UserStateExpired, UserStateBanned:
+		return true
+	}
+	return false
+}
+
 // User is the model of the user
 type User struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	PubKey    string `json:"pub_key"`
-	Ban       bool   `json:"ban"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID       int64     `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	PubKey   string    `json:"pub_key"`
+	State    UserState `json:"state"`
+	// ExpiresAt is the time when the user will be expired,
+	// if it is 0, the user never expires
+	ExpiresAt int64 `json:"expires_at"`
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// IsBanned reports whether the user is banned
+func (u *User) IsBanned() bool {
+	return u.State == UserStateBanned
+}
+
+// IsActive reports whether the user is active at the time
+func (u *User) IsActive(now int64) bool {
+	return u.State == UserStateActive && (u.ExpiresAt == 0 || u.ExpiresAt > now)
 }
 
 // UserCert is the model of the user cert
@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// AccessRequestRepo is the interface that provides access request methods.
+type AccessRequestRepo interface {
+	Create(ctx context.Context, request *model.AccessRequest) error
+	GetByID(ctx context.Context, id int64) (*model.AccessRequest, error)
+	List(ctx context.Context, filter *AccessRequestFilter, opt *ListOption) ([]*model.AccessRequest, int64, error)
+	// UpdateState updates the state, reviewer and comment of the request if
+	// it's still in the from state, it returns false if it's not
+	UpdateState(ctx context.Context, request *model.AccessRequest, from model.AccessRequestState) (bool, error)
+
+	CreateLog(ctx context.Context, log *model.AccessRequestLog) error
+	ListLog(ctx context.Context, requestID int64) ([]*model.AccessRequestLog, error)
+
+	ListApprover(ctx context.Context, roleID int64) ([]*model.User, error)
+	AddApprover(ctx context.Context, roleID, userID int64) error
+	// RemoveApprover removes approvers from the role, if userIDs is empty,
+	// remove all approvers from the role
+	RemoveApprover(ctx context.Context, roleID int64, userIDs ...int64) error
+	RemoveApproverByUserID(ctx context.Context, userID int64) error
+	IsApprover(ctx context.Context, roleID, userID int64) (bool, error)
+}
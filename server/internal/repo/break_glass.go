@@ -0,0 +1,18 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// BreakGlassRepo is the interface that provides break-glass methods.
+type BreakGlassRepo interface {
+	Create(ctx context.Context, breakGlass *model.BreakGlass) error
+	GetByID(ctx context.Context, id int64) (*model.BreakGlass, error)
+	List(ctx context.Context, filter *BreakGlassFilter, opt *ListOption) ([]*model.BreakGlass, int64, error)
+	// Ack acknowledges the review
+	Ack(ctx context.Context, breakGlass *model.BreakGlass) error
+	// CountByUserID counts the break-glass of the user since the time
+	CountByUserID(ctx context.Context, userID, since int64) (int64, error)
+}
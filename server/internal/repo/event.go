@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// EventRepo is the interface that provides event methods.
+type EventRepo interface {
+	Create(ctx context.Context, event *model.Event) error
+	List(ctx context.Context, filter *EventFilter, opt *ListOption) ([]*model.Event, int64, error)
+}
@@ -0,0 +1,36 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// GroupRepo is the interface that provides group methods.
+type GroupRepo interface {
+	List(ctx context.Context, filter *GroupFilter, opt *ListOption) ([]*model.Group, int64, error)
+	GetByID(ctx context.Context, id int64) (*model.Group, error)
+	GetByName(ctx context.Context, name string) (*model.Group, error)
+	Create(ctx context.Context, group *model.Group) error
+	Update(ctx context.Context, group *model.Group) error
+	Delete(ctx context.Context, id int64) error
+
+	ListUser(ctx context.Context, filter *GroupUserFilter, opt *ListOption) ([]*model.User, int64, error)
+	AddUser(ctx context.Context, groupID, userID int64) error
+	// RemoveUser removes users from the group, if userIDs is empty,
+	// remove all users from the group
+	RemoveUser(ctx context.Context, groupID int64, userIDs ...int64) error
+	RemoveUserByUserID(ctx context.Context, userID int64) error
+
+	// ListByRoleID lists the groups bound to the role
+	ListByRoleID(ctx context.Context, roleID int64) ([]*model.Group, error)
+	AddRole(ctx context.Context, roleID, groupID int64) error
+	// RemoveRole removes groups from the role, if groupIDs is empty,
+	// remove all groups from the role
+	RemoveRole(ctx context.Context, roleID int64, groupIDs ...int64) error
+	RemoveRoleByGroupID(ctx context.Context, groupID int64) error
+	// HasGlobalRole reports whether the group is bound to a global role
+	HasGlobalRole(ctx context.Context, groupID int64) (bool, error)
+	// ListRoleByUserID lists the ids of the roles which the user inherits from groups
+	ListRoleByUserID(ctx context.Context, userID int64) ([]int64, error)
+}
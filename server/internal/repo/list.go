@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"strings"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// ListOption is the common option of the list methods
+type ListOption struct {
+	Offset int64
+	// Limit is the max number of items, if it is 0, all items are returned
+	Limit int64
+	// Sort is the field to sort by, prefix "-" means descending,
+	// e.g. "-created_at". Unknown fields use the default order.
+	Sort string
+}
+
+// SortField returns the sort field and whether it's descending
+func (o *ListOption) SortField() (string, bool) {
+	if o == nil {
+		return "", false
+	}
+
+	if strings.HasPrefix(o.Sort, "-") {
+		return strings.TrimPrefix(o.Sort, "-"), true
+	}
+
+	return o.Sort, false
+}
+
+// SpaceFilter is the filter of the space list
+type SpaceFilter struct {
+	// Name matches the space name by substring
+	Name string
+}
+
+// UserFilter is the filter of the user list
+type UserFilter struct {
+	// Name matches the username by substring
+	Name string
+	// Email matches the email by substring
+	Email string
+	// State filters the users by state, empty means all
+	State string
+}
+
+// NodeFilter is the filter of the node list
+type NodeFilter struct {
+	SpaceID int64
+	// Name matches the node name by substring
+	Name string
+	// Account filters the nodes which have the account
+	Account string
+	// HeartbeatFrom and HeartbeatTo filter the nodes by the last
+	// heartbeat, unix seconds, 0 means no limit
+	HeartbeatFrom int64
+	HeartbeatTo   int64
+	// Labels filters the nodes which have all the labels
+	Labels model.Labels
+	// Health filters the nodes by the health, empty means all
+	Health string
+}
+
+// RoleFilter is the filter of the role list
+type RoleFilter struct {
+	SpaceID int64
+	// Global lists the global roles, the SpaceID is ignored
+	Global bool
+	// IncludeGlobal lists the global roles bound to the
+	// nodes of the space besides the roles of the space
+	IncludeGlobal bool
+	// Name matches the role name by substring
+	Name string
+	// BreakGlass filters the break-glass roles or the normal roles
+	BreakGlass *bool
+}
+
+// RoleNodeFilter is the filter of the nodes in a role
+type RoleNodeFilter struct {
+	RoleID int64
+	// Name matches the node name by substring
+	Name string
+	// Account filters the nodes bound with the account
+	Account       string
+	HeartbeatFrom int64
+	HeartbeatTo   int64
+	// Dynamic filters the nodes bound by label selectors or explicitly
+	Dynamic *bool
+}
+
+// RoleUserFilter is the filter of the users in a role
+type RoleUserFilter struct {
+	RoleID int64
+	Name   string
+	Email  string
+	State  string
+}
+
+// AccessFilter is the filter of the effective access, the zero
+// value of each field means no limit
+type AccessFilter struct {
+	NodeID  int64
+	UserID  int64
+	SpaceID int64
+	Account string
+	// NodeIDs and UserIDs limit the access to any of the nodes and users
+	NodeIDs []int64
+	UserIDs []int64
+}
+
+// EventFilter is the filter of the event list, the zero
+// value of each field means no limit
+type EventFilter struct {
+	Type    string
+	SpaceID int64
+	RoleID  int64
+	UserID  int64
+	NodeID  int64
+	// From and To filter the events by the creation time, unix seconds
+	From int64
+	To   int64
+}
+
+// AccessRequestFilter is the filter of the access request list
+type AccessRequestFilter struct {
+	RoleID int64
+	UserID int64
+	State  string
+	// ApproverID filters the requests of the roles which
+	// the user is an approver of
+	ApproverID int64
+}
+
+// BreakGlassFilter is the filter of the break-glass list
+type BreakGlassFilter struct {
+	SpaceID int64
+	RoleID  int64
+	UserID  int64
+	// Acked filters the acknowledged or pending reviews
+	Acked *bool
+}
+
+// GroupFilter is the filter of the group list
+type GroupFilter struct {
+	// Name matches the group name by substring
+	Name string
+}
+
+// GroupUserFilter is the filter of the users in a group
+type GroupUserFilter struct {
+	GroupID int64
+	Name    string
+	Email   string
+}
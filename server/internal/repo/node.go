@@ -10,7 +10,50 @@ type NodeRepo interface {
 	GetByUniqueID(ctx context.Context, uniqueID string) (*model.Node, error)
 	GetByID(ctx context.Context, id int64) (*model.Node, error)
 	Create(ctx context.Context, node *model.Node) error
+	// Update updates the name, description, ip and accounts of the node
+	Update(ctx context.Context, node *model.Node) error
 	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context, spaceID int64, offset, limit int64) ([]*model.Node, int64, error)
+	List(ctx context.Context, filter *NodeFilter, opt *ListOption) ([]*model.Node, int64, error)
+	// UpdateLastHeartbeat updates the last heartbeat of the node and
+	// records it in the heartbeat history
 	UpdateLastHeartbeat(ctx context.Context, uniqueID string) error
+	// ListHeartbeat lists the recorded heartbeats of the node, newest first
+	ListHeartbeat(ctx context.Context, nodeID int64, limit int) ([]int64, error)
+	// PruneHeartbeat keeps the newest keep heartbeats of every node
+	PruneHeartbeat(ctx context.Context, keep int) (int64, error)
+	// UpdateHealth computes the health of all nodes at now by the
+	// thresholds of their spaces, staleAfter and offlineAfter are the
+	// defaults in seconds, and returns the nodes whose health changed
+	UpdateHealth(ctx context.Context, now, staleAfter, offlineAfter int64) ([]*HealthChange, error)
+	// CountByHealth counts the nodes of every space by the health,
+	// the spaces without nodes are included
+	CountByHealth(ctx context.Context) ([]*HealthCount, error)
+
+	// ListArtifact lists the reported managed files of the nodes
+	ListArtifact(ctx context.Context, nodeIDs ...int64) ([]*model.NodeArtifact, error)
+	// SaveArtifact creates or replaces the report of the managed file
+	SaveArtifact(ctx context.Context, artifact *model.NodeArtifact) error
+}
+
+// HealthChange is a node whose health is changed by UpdateHealth
+type HealthChange struct {
+	NodeID        int64
+	SpaceID       int64
+	Name          string
+	LastHeartbeat int64
+	From          model.NodeHealth
+	To            model.NodeHealth
+}
+
+// HealthCount is the number of the nodes of a space by the health,
+// StaleAfter and OfflineAfter are the thresholds of the space, 0
+// means the server default
+type HealthCount struct {
+	SpaceID      int64
+	SpaceName    string
+	StaleAfter   int64
+	OfflineAfter int64
+	Healthy      int64
+	Stale        int64
+	Offline      int64
 }
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+type accessRequest struct {
+	*baseRepo
+}
+
+func NewAccessRequest(br *baseRepo) repo.AccessRequestRepo {
+	return &accessRequest{
+		baseRepo: br,
+	}
+}
+
+// accessRequestFields is the column list scanned by scan
+const accessRequestFields = `id, role_id, user_id, reason, duration, state, reviewer, comment, created_at, updated_at`
+
+func (a *accessRequest) scan(row scanner) (*model.AccessRequest, error) {
+	request := &model.AccessRequest{}
+
+	var updated sql.NullInt64
+
+	err := row.Scan(&request.ID, &request.RoleID, &request.UserID, &request.Reason, &request.Duration,
+		&request.State, &request.Reviewer, &request.Comment, &request.CreatedAt, &updated)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan access request: %w", err)
+	}
+
+	request.UpdatedAt = updated.Int64
+
+	return request, nil
+}
+
+// Create creates a new access request
+func (a *accessRequest) Create(ctx context.Context, request *model.AccessRequest) error {
+	request.CreatedAt = time.Now().Unix()
+
+	err := a.queryRowContext(ctx, `
+		INSERT INTO access_request (role_id, user_id, reason, duration, state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+	`, request.RoleID, request.UserID, request.Reason, request.Duration, request.State, request.CreatedAt).
+		Scan(&request.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	return nil
+}
+
+func (a *accessRequest) GetByID(ctx context.Context, id int64) (*model.AccessRequest, error) {
+	request, err := a.scan(a.queryRowContext(ctx,
+		`SELECT `+accessRequestFields+` FROM access_request WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request by id: %w", err)
+	}
+
+	return request, nil
+}
+
+var accessRequestColumns = map[string]string{
+	"id":         "id",
+	"state":      "state",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// List lists the access requests
+func (a *accessRequest) List(ctx context.Context, filter *repo.AccessRequestFilter, opt *repo.ListOption) ([]*model.AccessRequest, int64, error) {
+	c := &conditions{}
+	if filter.RoleID > 0 {
+		c.add("role_id = ?", filter.RoleID)
+	}
+	if filter.UserID > 0 {
+		c.add("user_id = ?", filter.UserID)
+	}
+	if filter.State != "" {
+		c.add("state = ?", filter.State)
+	}
+	if filter.ApproverID > 0 {
+		c.add("role_id IN (SELECT role_id FROM role_approver WHERE user_id = ?)", filter.ApproverID)
+	}
+
+	var total int64
+	if err := a.queryRowContext(ctx, `SELECT COUNT(id) FROM access_request`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count access requests: %w", err)
+	}
+
+	page, args := c.page(opt, accessRequestColumns, "id DESC")
+	rows, err := a.queryContext(ctx, `SELECT `+accessRequestFields+` FROM access_request`+c.where()+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list access requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := make([]*model.AccessRequest, 0)
+	for rows.Next() {
+		request, err := a.scan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, total, nil
+}
+
+// UpdateState updates the state, reviewer and comment of the request if
+// it's still in the from state. The concurrent updates wait for the row
+// lock and check the state again, so only one of them moves the request.
+func (a *accessRequest) UpdateState(ctx context.Context, request *model.AccessRequest, from model.AccessRequestState) (bool, error) {
+	request.UpdatedAt = time.Now().Unix()
+
+	result, err := a.execContext(ctx, `
+		UPDATE access_request
+		SET state = $1,
+			reviewer = $2,
+			comment = $3,
+			updated_at = $4
+		WHERE id = $5 AND state = $6
+	`, request.State, request.Reviewer, request.Comment, request.UpdatedAt, request.ID, from)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to update access request state: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// CreateLog records a state transition of the request
+func (a *accessRequest) CreateLog(ctx context.Context, log *model.AccessRequestLog) error {
+	log.CreatedAt = time.Now().Unix()
+
+	err := a.queryRowContext(ctx, `
+		INSERT INTO access_request_log (request_id, from_state, to_state, operator, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+	`, log.RequestID, log.FromState, log.ToState, log.Operator, log.Comment, log.CreatedAt).
+		Scan(&log.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create access request log: %w", err)
+	}
+
+	return nil
+}
+
+// ListLog lists the state transitions of the request in order
+func (a *accessRequest) ListLog(ctx context.Context, requestID int64) ([]*model.AccessRequestLog, error) {
+	rows, err := a.queryContext(ctx, `
+		SELECT id, request_id, from_state, to_state, operator, comment, created_at
+		FROM access_request_log WHERE request_id = $1 ORDER BY id`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access request logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]*model.AccessRequestLog, 0)
+	for rows.Next() {
+		log := &model.AccessRequestLog{}
+		if err := rows.Scan(&log.ID, &log.RequestID, &log.FromState, &log.ToState, &log.Operator,
+			&log.Comment, &log.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan access request log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// ListApprover lists the approvers of the role
+func (a *accessRequest) ListApprover(ctx context.Context, roleID int64) ([]*model.User, error) {
+	rows, err := a.queryContext(ctx, `
+		SELECT u.id, u.username, u.email, u.state FROM role_approver ra
+		JOIN "user" u ON u.id = ra.user_id
+		WHERE ra.role_id = $1 ORDER BY ra.id`, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvers: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		user := &model.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.State); err != nil {
+			return nil, fmt.Errorf("failed to scan approver: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// AddApprover adds the approver to the role, it's ignored if
+// the user is already an approver
+func (a *accessRequest) AddApprover(ctx context.Context, roleID, userID int64) error {
+	_, err := a.execContext(ctx, `INSERT INTO role_approver (role_id, user_id, created_at)
+		VALUES ($1, $2, $3) ON CONFLICT (role_id, user_id) DO NOTHING`,
+		roleID, userID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to add approver: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveApprover removes approvers from the role
+func (a *accessRequest) RemoveApprover(ctx context.Context, roleID int64, userIDs ...int64) error {
+	sql := "DELETE FROM role_approver WHERE role_id = $1"
+	values := []interface{}{roleID}
+
+	if len(userIDs) != 0 {
+		sql += " AND user_id = ANY($2)"
+		values = append(values, pq.Array(userIDs))
+	}
+
+	if _, err := a.execContext(ctx, sql, values...); err != nil {
+		return fmt.Errorf("failed to remove approver: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveApproverByUserID removes the user from the approvers of all roles
+func (a *accessRequest) RemoveApproverByUserID(ctx context.Context, userID int64) error {
+	if _, err := a.execContext(ctx, `DELETE FROM role_approver WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to remove approver by user id: %w", err)
+	}
+
+	return nil
+}
+
+// IsApprover reports whether the user is an approver of the role
+func (a *accessRequest) IsApprover(ctx context.Context, roleID, userID int64) (bool, error) {
+	var exists bool
+	err := a.queryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM role_approver WHERE role_id = $1 AND user_id = $2)`,
+		roleID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check approver: %w", err)
+	}
+
+	return exists, nil
+}
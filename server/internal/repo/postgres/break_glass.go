@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+type breakGlass struct {
+	*baseRepo
+}
+
+func NewBreakGlass(br *baseRepo) repo.BreakGlassRepo {
+	return &breakGlass{
+		baseRepo: br,
+	}
+}
+
+// breakGlassFields is the column list scanned by scan
+const breakGlassFields = `id, role_id, space_id, user_id, reason, operator, expires_at, 
+	acked_by, acked_at, ack_comment, created_at`
+
+func (b *breakGlass) scan(row scanner) (*model.BreakGlass, error) {
+	bg := &model.BreakGlass{}
+
+	err := row.Scan(&bg.ID, &bg.RoleID, &bg.SpaceID, &bg.UserID, &bg.Reason, &bg.Operator, &bg.ExpiresAt,
+		&bg.AckedBy, &bg.AckedAt, &bg.AckComment, &bg.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan break glass: %w", err)
+	}
+
+	return bg, nil
+}
+
+// Create creates a new break-glass
+func (b *breakGlass) Create(ctx context.Context, bg *model.BreakGlass) error {
+	bg.CreatedAt = time.Now().Unix()
+
+	err := b.queryRowContext(ctx, `
+		INSERT INTO break_glass (role_id, space_id, user_id, reason, operator, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`, bg.RoleID, bg.SpaceID, bg.UserID, bg.Reason, bg.Operator, bg.ExpiresAt, bg.CreatedAt).
+		Scan(&bg.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create break glass: %w", err)
+	}
+
+	return nil
+}
+
+func (b *breakGlass) GetByID(ctx context.Context, id int64) (*model.BreakGlass, error) {
+	bg, err := b.scan(b.queryRowContext(ctx, `SELECT `+breakGlassFields+` FROM break_glass WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get break glass by id: %w", err)
+	}
+
+	return bg, nil
+}
+
+var breakGlassColumns = map[string]string{
+	"id":         "id",
+	"expires_at": "expires_at",
+	"acked_at":   "acked_at",
+	"created_at": "created_at",
+}
+
+// List lists the break-glass
+func (b *breakGlass) List(ctx context.Context, filter *repo.BreakGlassFilter, opt *repo.ListOption) ([]*model.BreakGlass, int64, error) {
+	c := &conditions{}
+	if filter.SpaceID > 0 {
+		c.add("space_id = ?", filter.SpaceID)
+	}
+	if filter.RoleID > 0 {
+		c.add("role_id = ?", filter.RoleID)
+	}
+	if filter.UserID > 0 {
+		c.add("user_id = ?", filter.UserID)
+	}
+	if filter.Acked != nil {
+		if *filter.Acked {
+			c.clauses = append(c.clauses, "acked_at > 0")
+		} else {
+			c.clauses = append(c.clauses, "acked_at = 0")
+		}
+	}
+
+	var total int64
+	if err := b.queryRowContext(ctx, `SELECT COUNT(id) FROM break_glass`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count break glass: %w", err)
+	}
+
+	page, args := c.page(opt, breakGlassColumns, "id DESC")
+	rows, err := b.queryContext(ctx, `SELECT `+breakGlassFields+` FROM break_glass`+c.where()+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list break glass: %w", err)
+	}
+	defer rows.Close()
+
+	list := make([]*model.BreakGlass, 0)
+	for rows.Next() {
+		bg, err := b.scan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, bg)
+	}
+
+	return list, total, nil
+}
+
+// Ack acknowledges the review
+func (b *breakGlass) Ack(ctx context.Context, bg *model.BreakGlass) error {
+	bg.AckedAt = time.Now().Unix()
+
+	_, err := b.execContext(ctx, `
+		UPDATE break_glass
+		SET acked_by = $1,
+			acked_at = $2,
+			ack_comment = $3
+		WHERE id = $4
+	`, bg.AckedBy, bg.AckedAt, bg.AckComment, bg.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to ack break glass: %w", err)
+	}
+
+	return nil
+}
+
+// CountByUserID counts the break-glass of the user since the time
+func (b *breakGlass) CountByUserID(ctx context.Context, userID, since int64) (int64, error) {
+	var count int64
+	err := b.queryRowContext(ctx, `SELECT COUNT(id) FROM break_glass WHERE user_id = $1 AND created_at >= $2`,
+		userID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count break glass: %w", err)
+	}
+
+	return count, nil
+}
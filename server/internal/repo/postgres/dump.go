@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// restoreBatch is the number of rows inserted by one statement
+const restoreBatch = 500
+
+// Table is the rows of a table, each row is a json object of the columns
+type Table struct {
+	Name string   `json:"name"`
+	Rows []string `json:"rows"`
+	// Checksum is the sha256 of the rows, it's checked before the restore
+	Checksum string `json:"checksum"`
+}
+
+// NewTable creates the table with the checksum of the rows
+func NewTable(name string, rows []string) *Table {
+	table := &Table{Name: name, Rows: rows}
+	table.Checksum = table.sum()
+	return table
+}
+
+func (t *Table) sum() string {
+	h := sha256.New()
+	for _, row := range t.Rows {
+		h.Write([]byte(row))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Dump is the snapshot of all tables at the schema version
+type Dump struct {
+	SchemaVersion int      `json:"schema_version"`
+	Tables        []*Table `json:"tables"`
+}
+
+// Verify checks the checksums of the tables
+func (d *Dump) Verify() error {
+	for _, table := range d.Tables {
+		if table.sum() != table.Checksum {
+			return fmt.Errorf("checksum of table %s mismatch", table.Name)
+		}
+	}
+
+	return nil
+}
+
+// DumpAll dumps all tables and the schema version in one read only
+// transaction, so that they are consistent with each other
+func DumpAll(ctx context.Context, db *sql.DB) (*Dump, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // nolint
+
+	// the version is read in the snapshot, a migration meanwhile does
+	// not change it
+	version, err := SchemaVersion(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := listTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &Dump{SchemaVersion: version, Tables: make([]*Table, 0, len(tables))}
+	for _, name := range tables {
+		table, err := dumpTable(ctx, tx, name)
+		if err != nil {
+			return nil, err
+		}
+		dump.Tables = append(dump.Tables, table)
+	}
+
+	return dump, nil
+}
+
+func dumpTable(ctx context.Context, tx *sql.Tx, name string) (*Table, error) {
+	ident := pq.QuoteIdentifier(name)
+	rows, err := tx.QueryContext(ctx, `SELECT row_to_json(t)::TEXT FROM `+ident+` t ORDER BY t.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump table %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	list := make([]string, 0)
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return nil, fmt.Errorf("failed to scan row of table %s: %w", name, err)
+		}
+		list = append(list, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to dump table %s: %w", name, err)
+	}
+
+	return NewTable(name, list), nil
+}
+
+// Restore restores the dump into an empty database in one transaction.
+// If the database has no table, the schema is created at the version of
+// the dump, and migrated to the latest version after the rows are restored.
+// Otherwise the schema version must be the same as the dump's.
+func Restore(ctx context.Context, db *sql.DB, dump *Dump) (err error) {
+	if err := dump.Verify(); err != nil {
+		return err
+	}
+
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if dump.SchemaVersion > latest {
+		return fmt.Errorf("schema version %d of the dump is newer than %d of the server, upgrade the server first",
+			dump.SchemaVersion, latest)
+	}
+
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && version != dump.SchemaVersion {
+		return fmt.Errorf("schema version %d of the database is different from %d of the dump, "+
+			"restore into an empty database instead", version, dump.SchemaVersion)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // nolint
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}()
+
+	// the rows are restored as they are, the triggers of the changes
+	// are skipped, see 12_sync_version.up.sql
+	if _, err := tx.ExecContext(ctx, `SET LOCAL guard.restoring = 'on'`); err != nil {
+		return fmt.Errorf("failed to set restoring: %w", err)
+	}
+
+	if version == 0 {
+		if err := migrate(ctx, tx, 0, dump.SchemaVersion); err != nil {
+			return err
+		}
+	}
+
+	tables, err := listTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tables {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+pq.QuoteIdentifier(name)+`)`).
+			Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check table %s: %w", name, err)
+		}
+
+		if exists {
+			return fmt.Errorf("table %s is not empty, restore into an empty database", name)
+		}
+	}
+
+	for _, table := range dump.Tables {
+		if !slices.Contains(tables, table.Name) {
+			return fmt.Errorf("table %s of the dump does not exist", table.Name)
+		}
+
+		if err := restoreTable(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+
+	if version == 0 && dump.SchemaVersion < latest {
+		if err := migrate(ctx, tx, dump.SchemaVersion, latest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreTable(ctx context.Context, tx *sql.Tx, table *Table) error {
+	ident := pq.QuoteIdentifier(table.Name)
+	for i := 0; i < len(table.Rows); i += restoreBatch {
+		batch := table.Rows[i:min(i+restoreBatch, len(table.Rows))]
+		if _, err := tx.ExecContext(ctx, `INSERT INTO `+ident+
+			` SELECT * FROM json_populate_recordset(NULL::`+ident+`, $1::JSON)`,
+			"["+strings.Join(batch, ",")+"]"); err != nil {
+			return fmt.Errorf("failed to restore table %s: %w", table.Name, err)
+		}
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+ident).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count table %s: %w", table.Name, err)
+	}
+
+	if count != len(table.Rows) {
+		return fmt.Errorf("table %s has %d rows after restore, expected %d", table.Name, count, len(table.Rows))
+	}
+
+	// the next ids continue after the restored rows
+	if _, err := tx.ExecContext(ctx, `SELECT setval(pg_get_serial_sequence($1, 'id'),
+		COALESCE((SELECT MAX(id) FROM `+ident+`), 0) + 1, false)`, ident); err != nil {
+		return fmt.Errorf("failed to reset the id sequence of table %s: %w", table.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+type event struct {
+	*baseRepo
+}
+
+func NewEvent(br *baseRepo) repo.EventRepo {
+	return &event{
+		baseRepo: br,
+	}
+}
+
+// Create creates a new event
+func (e *event) Create(ctx context.Context, event *model.Event) error {
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().Unix()
+	}
+
+	err := e.queryRowContext(ctx, `
+		INSERT INTO event (type, space_id, role_id, user_id, node_id, message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`, event.Type, event.SpaceID, event.RoleID, event.UserID, event.NodeID, event.Message, event.CreatedAt).
+		Scan(&event.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	return nil
+}
+
+var eventColumns = map[string]string{
+	"id":         "id",
+	"type":       "type",
+	"created_at": "created_at",
+}
+
+// List lists the events
+func (e *event) List(ctx context.Context, filter *repo.EventFilter, opt *repo.ListOption) ([]*model.Event, int64, error) {
+	c := &conditions{}
+	if filter.Type != "" {
+		c.add("type = ?", filter.Type)
+	}
+	if filter.SpaceID > 0 {
+		c.add("space_id = ?", filter.SpaceID)
+	}
+	if filter.RoleID > 0 {
+		c.add("role_id = ?", filter.RoleID)
+	}
+	if filter.UserID > 0 {
+		c.add("user_id = ?", filter.UserID)
+	}
+	if filter.NodeID > 0 {
+		c.add("node_id = ?", filter.NodeID)
+	}
+	if filter.From > 0 {
+		c.add("created_at >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		c.add("created_at <= ?", filter.To)
+	}
+
+	var total int64
+	if err := e.queryRowContext(ctx, `SELECT COUNT(id) FROM event`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	page, args := c.page(opt, eventColumns, "id DESC")
+	rows, err := e.queryContext(ctx, `
+		SELECT id, type, space_id, role_id, user_id, node_id, message, created_at
+		FROM event`+c.where()+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*model.Event, 0)
+	for rows.Next() {
+		event := &model.Event{}
+		if err := rows.Scan(&event.ID, &event.Type, &event.SpaceID, &event.RoleID, &event.UserID,
+			&event.NodeID, &event.Message, &event.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,317 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+type group struct {
+	*baseRepo
+}
+
+// NewGroup returns a new GroupRepo
+func NewGroup(br *baseRepo) repo.GroupRepo {
+	return &group{
+		baseRepo: br,
+	}
+}
+
+// groupFields is the column list scanned by scan
+const groupFields = `id, name, description, created_at`
+
+func (g *group) scan(row scanner) (*model.Group, error) {
+	group := &model.Group{}
+
+	var description sql.NullString
+	err := row.Scan(&group.ID, &group.Name, &description, &group.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan group: %w", err)
+	}
+
+	group.Description = description.String
+
+	return group, nil
+}
+
+var groupColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+}
+
+// List lists groups
+func (g *group) List(ctx context.Context, filter *repo.GroupFilter, opt *repo.ListOption) ([]*model.Group, int64, error) {
+	c := &conditions{}
+	c.contains("name", filter.Name)
+
+	var total int64
+	if err := g.queryRowContext(ctx, `SELECT COUNT(id) FROM "group"`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count groups: %w", err)
+	}
+
+	page, args := c.page(opt, groupColumns, "id")
+	rows, err := g.queryContext(ctx, `SELECT `+groupFields+` FROM "group"`+c.where()+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]*model.Group, 0)
+	for rows.Next() {
+		group, err := g.scan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, total, nil
+}
+
+// GetByID gets a group by id
+func (g *group) GetByID(ctx context.Context, id int64) (*model.Group, error) {
+	group, err := g.scan(g.queryRowContext(ctx, `SELECT `+groupFields+` FROM "group" WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group by id: %w", err)
+	}
+
+	return group, nil
+}
+
+// GetByName gets a group by name
+func (g *group) GetByName(ctx context.Context, name string) (*model.Group, error) {
+	group, err := g.scan(g.queryRowContext(ctx, `SELECT `+groupFields+` FROM "group" WHERE name = $1`, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group by name: %w", err)
+	}
+
+	return group, nil
+}
+
+// Create creates a new group
+func (g *group) Create(ctx context.Context, group *model.Group) error {
+	group.CreatedAt = time.Now().Unix()
+
+	err := g.queryRowContext(ctx,
+		`INSERT INTO "group" (name, description, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		group.Name, group.Description, group.CreatedAt).
+		Scan(&group.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates the name and description of the group
+func (g *group) Update(ctx context.Context, group *model.Group) error {
+	_, err := g.execContext(ctx, `UPDATE "group" SET name = $1, description = $2 WHERE id = $3`,
+		group.Name, group.Description, group.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a group
+func (g *group) Delete(ctx context.Context, id int64) error {
+	if _, err := g.execContext(ctx, `DELETE FROM "group" WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	return nil
+}
+
+var groupUserColumns = map[string]string{
+	"id":         "u.id",
+	"username":   "u.username",
+	"email":      "u.email",
+	"created_at": "gu.created_at",
+}
+
+// ListUser lists the users of the group
+func (g *group) ListUser(ctx context.Context, filter *repo.GroupUserFilter, opt *repo.ListOption) ([]*model.User, int64, error) {
+	c := &conditions{}
+	c.add("gu.group_id = ?", filter.GroupID)
+	c.contains("u.username", filter.Name)
+	c.contains("u.email", filter.Email)
+
+	var total int64
+	if err := g.queryRowContext(ctx, `SELECT COUNT(gu.id) FROM group_user gu
+		JOIN "user" u ON u.id = gu.user_id`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count group users: %w", err)
+	}
+
+	page, args := c.page(opt, groupUserColumns, "gu.id")
+	rows, err := g.queryContext(ctx,
+		`SELECT u.id, u.username, u.email, u.state, u.expires_at, gu.created_at
+		FROM group_user gu
+		JOIN "user" u ON u.id = gu.user_id`+c.where()+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list group users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		user := &model.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.State, &user.ExpiresAt,
+			&user.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan group user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
+// AddUser adds a user to the group, it's ignored if the user is already a member.
+func (g *group) AddUser(ctx context.Context, groupID, userID int64) error {
+	_, err := g.execContext(ctx, `INSERT INTO group_user (group_id, user_id, created_at)
+		VALUES ($1, $2, $3) ON CONFLICT (group_id, user_id) DO NOTHING`,
+		groupID, userID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to add user to group: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveUser removes users from the group
+func (g *group) RemoveUser(ctx context.Context, groupID int64, userIDs ...int64) error {
+	sql := "DELETE FROM group_user WHERE group_id = $1"
+	values := []interface{}{groupID}
+
+	if len(userIDs) != 0 {
+		sql += " AND user_id = ANY($2)"
+		values = append(values, pq.Array(userIDs))
+	}
+
+	if _, err := g.execContext(ctx, sql, values...); err != nil {
+		return fmt.Errorf("failed to remove user from group: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveUserByUserID removes the user from all groups
+func (g *group) RemoveUserByUserID(ctx context.Context, userID int64) error {
+	if _, err := g.execContext(ctx, `DELETE FROM group_user WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to remove user from group by user id: %w", err)
+	}
+
+	return nil
+}
+
+// ListByRoleID lists the groups bound to the role
+func (g *group) ListByRoleID(ctx context.Context, roleID int64) ([]*model.Group, error) {
+	rows, err := g.queryContext(ctx, `SELECT g.id, g.name, g.description, g.created_at
+		FROM role_group rg
+		JOIN "group" g ON g.id = rg.group_id
+		WHERE rg.role_id = $1 ORDER BY rg.id`, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups of role: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]*model.Group, 0)
+	for rows.Next() {
+		group, err := g.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// AddRole binds the group to the role, it's ignored if the group is already bound.
+func (g *group) AddRole(ctx context.Context, roleID, groupID int64) error {
+	_, err := g.execContext(ctx, `INSERT INTO role_group (role_id, group_id, created_at)
+		VALUES ($1, $2, $3) ON CONFLICT (role_id, group_id) DO NOTHING`,
+		roleID, groupID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to add group to role: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveRole unbinds groups from the role
+func (g *group) RemoveRole(ctx context.Context, roleID int64, groupIDs ...int64) error {
+	sql := "DELETE FROM role_group WHERE role_id = $1"
+	values := []interface{}{roleID}
+
+	if len(groupIDs) != 0 {
+		sql += " AND group_id = ANY($2)"
+		values = append(values, pq.Array(groupIDs))
+	}
+
+	if _, err := g.execContext(ctx, sql, values...); err != nil {
+		return fmt.Errorf("failed to remove group from role: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveRoleByGroupID unbinds the group from all roles
+func (g *group) RemoveRoleByGroupID(ctx context.Context, groupID int64) error {
+	if _, err := g.execContext(ctx, `DELETE FROM role_group WHERE group_id = $1`, groupID); err != nil {
+		return fmt.Errorf("failed to remove group from role by group id: %w", err)
+	}
+
+	return nil
+}
+
+// HasGlobalRole reports whether the group is bound to a global role
+func (g *group) HasGlobalRole(ctx context.Context, groupID int64) (bool, error) {
+	var exists bool
+	err := g.queryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM role_group rg
+		JOIN role r ON r.id = rg.role_id
+		WHERE rg.group_id = $1 AND r.global)`, groupID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check global role of group: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListRoleByUserID lists the ids of the roles which the user inherits from groups
+func (g *group) ListRoleByUserID(ctx context.Context, userID int64) ([]int64, error) {
+	rows, err := g.queryContext(ctx, `SELECT DISTINCT rg.role_id FROM role_group rg
+		JOIN group_user gu ON gu.group_id = rg.group_id
+		WHERE gu.user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles of user by group: %w", err)
+	}
+	defer rows.Close()
+
+	roleIDs := make([]int64, 0)
+	for rows.Next() {
+		var roleID int64
+		if err := rows.Scan(&roleID); err != nil {
+			return nil, fmt.Errorf("failed to scan role id: %w", err)
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	return roleIDs, nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// changeChannel is notified by the triggers when the data served to
+// the nodes changes, see 11_change_notify.up.sql
+const changeChannel = "guard_change"
+
+// Listen calls fn when the data served to the nodes is changed by any
+// server or command, and after the connection is re-established since
+// the notifications may be lost meanwhile. It blocks until ctx is done.
+func (br *baseRepo) Listen(ctx context.Context, fn func()) error {
+	if br.dsn == "" {
+		return fmt.Errorf("listen is not supported in a transaction")
+	}
+
+	listener := pq.NewListener(br.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.WarnContext(ctx, "change listener", "event", event, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	// Listen waits for the connection, it's closed to stop waiting
+	stop := context.AfterFunc(ctx, func() { listener.Close() }) // nolint
+	defer stop()
+
+	if err := listener.Listen(changeChannel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to listen %s: %w", changeChannel, err)
+	}
+
+	// the pings find the broken connections, they are re-established
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+			// nil is received after the connection is re-established
+			fn()
+		case <-ticker.C:
+			go listener.Ping() // nolint
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sysarmor/guard/server/pkg/metrics"
+)
+
+// queryDuration is the latency of the queries by the repo method
+// running them, e.g. user.ListCerts
+var queryDuration = metrics.NewHistogramVec("guard_db_query_duration_seconds",
+	"Latency of the database queries in seconds, by the repo method", metrics.DefaultBuckets, "method")
+
+func init() {
+	metrics.Register(queryDuration)
+}
+
+// observeQuery records the latency of a query since start, it's
+// deferred by the query helpers of baseRepo
+func observeQuery(start time.Time) {
+	queryDuration.With(caller(3)).Observe(time.Since(start).Seconds())
+}
+
+// caller returns the repo method of the stack frame, e.g. user.ListCerts
+// for github.com/sysarmor/guard/server/internal/repo/postgres.(*user).ListCerts
+func caller(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "postgres.")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
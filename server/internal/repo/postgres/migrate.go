@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaFS is the migrations of the database, named as
+// <version>_<name>.up.sql in the golang-migrate layout
+//
+//go:embed schema/*.up.sql
+var schemaFS embed.FS
+
+// migrationTable records the schema version, it's the table of golang-migrate
+const migrationTable = "schema_migrations"
+
+type migration struct {
+	version int
+	file    string
+}
+
+// migrations returns the embedded migrations ordered by version
+func migrations() ([]migration, error) {
+	entries, err := schemaFS.ReadDir("schema")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	list := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", entry.Name(), err)
+		}
+		list = append(list, migration{version: version, file: path.Join("schema", entry.Name())})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list, nil
+}
+
+// LatestSchemaVersion returns the version of the last embedded migration
+func LatestSchemaVersion() (int, error) {
+	list, err := migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(list) == 0 {
+		return 0, nil
+	}
+
+	return list[len(list)-1].version, nil
+}
+
+// SchemaVersion returns the schema version of the database, it's 0 if the
+// database has no table. The database with tables must record its version
+// in the migration table, the version of a database migrated by hand is
+// unknown, it's recorded by "migrate force", see README.
+func SchemaVersion(ctx context.Context, q queryer) (int, error) {
+	tables, err := listTables(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(tables) == 0 {
+		return 0, nil
+	}
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = $1)`, migrationTable).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check migration table: %w", err)
+	}
+
+	if !exists {
+		return 0, fmt.Errorf("schema version is unknown, the database has tables but no %s, "+
+			"record the version of the applied migrations first", migrationTable)
+	}
+
+	var version int
+	var dirty bool
+	err = q.QueryRowContext(ctx, `SELECT version, dirty FROM `+migrationTable+` LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("schema version is unknown, %s is empty, "+
+			"record the version of the applied migrations first", migrationTable)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("schema version %d is dirty, fix the failed migration first", version)
+	}
+
+	return version, nil
+}
+
+// migrate applies the migrations after the from version up to the to
+// version in the transaction, and records the version like golang-migrate
+func migrate(ctx context.Context, tx *sql.Tx, from, to int) error {
+	list, err := migrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if m.version <= from || m.version > to {
+			continue
+		}
+
+		body, err := schemaFS.ReadFile(m.file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", m.file, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.file, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationTable+
+		` (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create migration table: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+migrationTable); err != nil {
+		return fmt.Errorf("failed to clear schema version: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+migrationTable+` (version, dirty) VALUES ($1, FALSE)`, to); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	return nil
+}
+
+// listTables returns the tables of guard in the current schema, the
+// migration table is excluded
+func listTables(ctx context.Context, q queryer) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND table_name <> $1
+		ORDER BY table_name`, migrationTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
@@ -22,13 +22,15 @@ func NewNode(br *baseRepo) repo.NodeRepo {
 	}
 }
 
+// nodeFields is the column list scanned by scan
+const nodeFields = `id, space_id, name, description, unique_id, secret, ip, 
+	last_heartbeat, accounts, labels, health, created_at, updated_at`
+
 func (n *node) GetByUniqueID(ctx context.Context, uniqueID string) (*model.Node, error) {
-	return n.scan(n.queryRowContext(ctx, `SELECT id, space_id, name, description, unique_id, secret, ip, 
-	last_heartbeat, accounts, created_at, updated_at 
-	FROM node WHERE unique_id = $1`, uniqueID))
+	return n.scan(n.queryRowContext(ctx, `SELECT `+nodeFields+` FROM node WHERE unique_id = $1`, uniqueID))
 }
 
-func (n *node) scan(row *sql.Row) (*model.Node, error) {
+func (n *node) scan(row scanner) (*model.Node, error) {
 	node := &model.Node{}
 
 	var description sql.NullString
@@ -36,7 +38,7 @@ func (n *node) scan(row *sql.Row) (*model.Node, error) {
 	var updatedAt sql.NullInt64
 
 	err := row.Scan(&node.ID, &node.SpaceID, &node.Name, &description, &node.UniqueID, &node.Secret,
-		&node.IP, &lastHeartbeat, pq.Array(&node.Accounts), &node.CreatedAt, &updatedAt)
+		&node.IP, &lastHeartbeat, pq.Array(&node.Accounts), &node.Labels, &node.Health, &node.CreatedAt, &updatedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -63,8 +65,7 @@ func (n *node) scan(row *sql.Row) (*model.Node, error) {
 
 // GetByID returns a node by id.
 func (n *node) GetByID(ctx context.Context, id int64) (*model.Node, error) {
-	return n.scan(n.queryRowContext(ctx, `SELECT id, space_id, name, description, unique_id, secret, ip,
-	last_heartbeat, accounts, created_at, updated_at FROM node WHERE id = $1`, id))
+	return n.scan(n.queryRowContext(ctx, `SELECT `+nodeFields+` FROM node WHERE id = $1`, id))
 }
 
 // Create creates a new node.
@@ -72,9 +73,10 @@ func (n *node) Create(ctx context.Context, node *model.Node) error {
 	node.CreatedAt = time.Now().Unix()
 
 	err := n.queryRowContext(ctx,
-		`INSERT INTO node (space_id, name, description, unique_id, secret, ip, accounts, created_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
-		node.SpaceID, node.Name, node.Description, node.UniqueID, node.Secret, node.IP, pq.Array(node.Accounts), node.CreatedAt).
+		`INSERT INTO node (space_id, name, description, unique_id, secret, ip, accounts, labels, created_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		node.SpaceID, node.Name, node.Description, node.UniqueID, node.Secret, node.IP, pq.Array(node.Accounts),
+		node.Labels, node.CreatedAt).
 		Scan(&node.ID)
 
 	if err != nil {
@@ -84,65 +86,260 @@ func (n *node) Create(ctx context.Context, node *model.Node) error {
 	return nil
 }
 
-// Delete deletes a node.
+// Update updates the name, description, ip, accounts and labels of the node.
+func (n *node) Update(ctx context.Context, node *model.Node) error {
+	node.UpdatedAt = time.Now().Unix()
+
+	_, err := n.execContext(ctx,
+		`UPDATE node SET name = $1, description = $2, ip = $3, accounts = $4, labels = $5, updated_at = $6 
+		WHERE id = $7`,
+		node.Name, node.Description, node.IP, pq.Array(node.Accounts), node.Labels, node.UpdatedAt, node.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update node: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a node, its heartbeat history and reported files.
 func (n *node) Delete(ctx context.Context, id int64) error {
 	_, err := n.execContext(ctx, `DELETE FROM node WHERE id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete node: %w", err)
 	}
 
+	_, err = n.execContext(ctx, `DELETE FROM node_heartbeat WHERE node_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete node heartbeats: %w", err)
+	}
+
+	_, err = n.execContext(ctx, `DELETE FROM node_artifact WHERE node_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete node artifacts: %w", err)
+	}
+
 	return nil
 }
 
-// List returns all nodes.
-func (n *node) List(ctx context.Context, spaceID int64, offset, limit int64) ([]*model.Node, int64, error) {
-	rows, err := n.queryContext(ctx, `SELECT id, space_id, name, description, unique_id, secret, ip, 
-	last_heartbeat, accounts, created_at, updated_at FROM node WHERE space_id = $1 LIMIT $2 OFFSET $3`, spaceID, limit, offset)
+var nodeColumns = map[string]string{
+	"id":             "id",
+	"name":           "name",
+	"ip":             "ip",
+	"last_heartbeat": "last_heartbeat",
+	"health":         "health",
+	"created_at":     "created_at",
+}
+
+// List returns the nodes of the space.
+func (n *node) List(ctx context.Context, filter *repo.NodeFilter, opt *repo.ListOption) ([]*model.Node, int64, error) {
+	c := &conditions{}
+	c.add("space_id = ?", filter.SpaceID)
+	c.contains("name", filter.Name)
+	if filter.Account != "" {
+		c.add("? = ANY(accounts)", filter.Account)
+	}
+	if filter.HeartbeatFrom > 0 {
+		c.add("last_heartbeat >= ?", filter.HeartbeatFrom)
+	}
+	if filter.HeartbeatTo > 0 {
+		c.add("COALESCE(last_heartbeat, 0) <= ?", filter.HeartbeatTo)
+	}
+	if len(filter.Labels) > 0 {
+		c.add("labels @> ?", filter.Labels)
+	}
+	if filter.Health != "" {
+		c.add("health = ?", filter.Health)
+	}
+
+	var total int64
+	if err := n.queryRowContext(ctx, `SELECT COUNT(id) FROM node`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count nodes: %w", err)
+	}
+
+	page, args := c.page(opt, nodeColumns, "id")
+	rows, err := n.queryContext(ctx, `SELECT `+nodeFields+` FROM node`+c.where()+page, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list nodes: %w", err)
 	}
 	defer rows.Close()
 
-	var nodes []*model.Node
+	nodes := make([]*model.Node, 0)
 	for rows.Next() {
-		node := &model.Node{}
-		var description sql.NullString
-		var lastHeartbeat sql.NullInt64
-		var updatedAt sql.NullInt64
-
-		if err := rows.Scan(&node.ID, &node.SpaceID, &node.Name, &description, &node.UniqueID, &node.Secret,
-			&node.IP, &lastHeartbeat, pq.Array(&node.Accounts), &node.CreatedAt, &updatedAt); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan node: %w", err)
+		node, err := n.scan(rows)
+		if err != nil {
+			return nil, 0, err
 		}
 
-		if description.Valid {
-			node.Description = description.String
+		nodes = append(nodes, node)
+	}
+
+	return nodes, total, nil
+}
+
+// heartbeatGap is the min seconds between two recorded heartbeats of a
+// node, a sync calls several apis and each of them is a heartbeat
+const heartbeatGap = 60
+
+// UpdateLastHeartbeat updates the last heartbeat of the node, and records
+// it in the history if the last recorded one is older than heartbeatGap
+func (n *node) UpdateLastHeartbeat(ctx context.Context, uniqueID string) error {
+	_, err := n.execContext(ctx, `
+		WITH updated AS (
+			UPDATE node SET last_heartbeat = $1 WHERE unique_id = $2 RETURNING id
+		)
+		INSERT INTO node_heartbeat (node_id, created_at)
+		SELECT u.id, $1 FROM updated u
+		WHERE NOT EXISTS (
+			SELECT 1 FROM node_heartbeat h WHERE h.node_id = u.id AND h.created_at > $1 - $3
+		)
+	`, time.Now().Unix(), uniqueID, heartbeatGap)
+	if err != nil {
+		return fmt.Errorf("failed to update last heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// ListHeartbeat lists the recorded heartbeats of the node, newest first
+func (n *node) ListHeartbeat(ctx context.Context, nodeID int64, limit int) ([]int64, error) {
+	rows, err := n.queryContext(ctx,
+		`SELECT created_at FROM node_heartbeat WHERE node_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		nodeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list heartbeats: %w", err)
+	}
+	defer rows.Close()
+
+	heartbeats := make([]int64, 0)
+	for rows.Next() {
+		var at int64
+		if err := rows.Scan(&at); err != nil {
+			return nil, fmt.Errorf("failed to scan heartbeat: %w", err)
 		}
+		heartbeats = append(heartbeats, at)
+	}
+
+	return heartbeats, rows.Err()
+}
 
-		if lastHeartbeat.Valid {
-			node.LastHeartbeat = lastHeartbeat.Int64
+// PruneHeartbeat keeps the newest keep heartbeats of every node
+func (n *node) PruneHeartbeat(ctx context.Context, keep int) (int64, error) {
+	result, err := n.execContext(ctx, `
+		DELETE FROM node_heartbeat WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY node_id ORDER BY created_at DESC, id DESC) AS rn
+				FROM node_heartbeat
+			) h WHERE h.rn > $1
+		)
+	`, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune heartbeats: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdateHealth computes the health of all nodes by the thresholds of their
+// spaces and returns the changed nodes with the previous health
+func (n *node) UpdateHealth(ctx context.Context, now, staleAfter, offlineAfter int64) ([]*repo.HealthChange, error) {
+	rows, err := n.queryContext(ctx, `
+		WITH computed AS (
+			SELECT n.id, n.health AS previous,
+				CASE
+					WHEN COALESCE(n.last_heartbeat, 0) >= $1 - COALESCE(NULLIF(s.stale_after, 0), $2) THEN 'healthy'
+					WHEN COALESCE(n.last_heartbeat, 0) >= $1 - COALESCE(NULLIF(s.offline_after, 0), $3) THEN 'stale'
+					ELSE 'offline'
+				END AS health
+			FROM node n
+			JOIN space s ON s.id = n.space_id
+		)
+		UPDATE node SET health = c.health
+		FROM computed c
+		WHERE node.id = c.id AND node.health <> c.health
+		RETURNING node.id, node.space_id, node.name, COALESCE(node.last_heartbeat, 0), c.previous, c.health
+	`, now, staleAfter, offlineAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update node health: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]*repo.HealthChange, 0)
+	for rows.Next() {
+		c := &repo.HealthChange{}
+		if err := rows.Scan(&c.NodeID, &c.SpaceID, &c.Name, &c.LastHeartbeat, &c.From, &c.To); err != nil {
+			return nil, fmt.Errorf("failed to scan health change: %w", err)
 		}
+		changes = append(changes, c)
+	}
 
-		if updatedAt.Valid {
-			node.UpdatedAt = updatedAt.Int64
+	return changes, rows.Err()
+}
+
+// CountByHealth counts the nodes of every space by the health
+func (n *node) CountByHealth(ctx context.Context) ([]*repo.HealthCount, error) {
+	rows, err := n.queryContext(ctx, `
+		SELECT s.id, s.name, s.stale_after, s.offline_after,
+			COUNT(n.id) FILTER (WHERE n.health = 'healthy'),
+			COUNT(n.id) FILTER (WHERE n.health = 'stale'),
+			COUNT(n.id) FILTER (WHERE n.health = 'offline')
+		FROM space s
+		LEFT JOIN node n ON n.space_id = s.id
+		GROUP BY s.id, s.name, s.stale_after, s.offline_after
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count nodes by health: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]*repo.HealthCount, 0)
+	for rows.Next() {
+		c := &repo.HealthCount{}
+		if err := rows.Scan(&c.SpaceID, &c.SpaceName, &c.StaleAfter, &c.OfflineAfter,
+			&c.Healthy, &c.Stale, &c.Offline); err != nil {
+			return nil, fmt.Errorf("failed to scan health count: %w", err)
 		}
+		counts = append(counts, c)
+	}
 
-		nodes = append(nodes, node)
+	return counts, rows.Err()
+}
+
+// ListArtifact lists the reported managed files of the nodes
+func (n *node) ListArtifact(ctx context.Context, nodeIDs ...int64) ([]*model.NodeArtifact, error) {
+	rows, err := n.queryContext(ctx, `SELECT id, node_id, kind, received, applied, error, reported_at
+		FROM node_artifact WHERE node_id = ANY($1) ORDER BY node_id, kind`, pq.Array(nodeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node artifacts: %w", err)
 	}
+	defer rows.Close()
 
-	var total int64
-	if err := n.queryRowContext(ctx, `SELECT COUNT(id) FROM node WHERE space_id = $1`, spaceID).
-		Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("failed to count nodes: %w", err)
+	artifacts := make([]*model.NodeArtifact, 0)
+	for rows.Next() {
+		a := &model.NodeArtifact{}
+		if err := rows.Scan(&a.ID, &a.NodeID, &a.Kind, &a.Received, &a.Applied, &a.Error, &a.ReportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan node artifact: %w", err)
+		}
+		artifacts = append(artifacts, a)
 	}
 
-	return nodes, total, nil
+	return artifacts, rows.Err()
 }
 
-func (n *node) UpdateLastHeartbeat(ctx context.Context, uniqueID string) error {
-	_, err := n.execContext(ctx, `UPDATE node SET last_heartbeat = $1 WHERE unique_id = $2`, time.Now().Unix(), uniqueID)
+// SaveArtifact creates or replaces the report of the managed file
+func (n *node) SaveArtifact(ctx context.Context, a *model.NodeArtifact) error {
+	a.ReportedAt = time.Now().Unix()
+
+	err := n.queryRowContext(ctx, `
+		INSERT INTO node_artifact (node_id, kind, received, applied, error, reported_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (node_id, kind) DO UPDATE SET received = EXCLUDED.received, applied = EXCLUDED.applied,
+			error = EXCLUDED.error, reported_at = EXCLUDED.reported_at
+		RETURNING id`,
+		a.NodeID, a.Kind, a.Received, a.Applied, a.Error, a.ReportedAt).Scan(&a.ID)
 	if err != nil {
-		return fmt.Errorf("failed to update last heartbeat: %w", err)
+		return fmt.Errorf("failed to save node artifact: %w", err)
 	}
 
 	return nil
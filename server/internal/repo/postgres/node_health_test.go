@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// healthRepo returns the repo with the space a of the default thresholds
+// and the nodes web (synced at t0) and new (never synced), the space b of
+// 60s/120s and the node db (synced at t0), and the space empty
+func healthRepo(t *testing.T, t0 int64) (*sql.DB, *baseRepo) {
+	db := testDB(t, testDSN)
+	br := newBaseRepo(db, nil, "")
+	ctx := context.Background()
+
+	spaces := []*model.Space{{Name: "a"}, {Name: "b", StaleAfter: 60, OfflineAfter: 120}, {Name: "empty"}}
+	for _, space := range spaces {
+		if err := br.Space().Create(ctx, space); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes := []*model.Node{{SpaceID: 1, Name: "web"}, {SpaceID: 2, Name: "db"}, {SpaceID: 1, Name: "new"}}
+	for _, node := range nodes {
+		node.UniqueID, node.Secret, node.IP, node.Accounts = node.Name, "secret", "127.0.0.1", []string{"root"}
+		if err := br.Node().Create(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE node SET last_heartbeat = $1 WHERE name IN ('web', 'db')`, t0); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, br
+}
+
+// TestUpdateHealth checks the transitions of the nodes by the thresholds of
+// their spaces or the defaults as the time passes, only the changed nodes
+// are returned
+func TestUpdateHealth(t *testing.T) {
+	const t0 = 1700000000
+	db, br := healthRepo(t, t0)
+	ctx := context.Background()
+
+	steps := []struct {
+		name string
+		now  int64
+		// heartbeat is the node which syncs at now before the sweep
+		heartbeat string
+		// expected is the change as "node from->to"
+		expected []string
+	}{
+		{name: "first sweep", now: t0, expected: []string{"db offline->healthy", "web offline->healthy"}},
+		{name: "unchanged", now: t0 + 60, expected: []string{}},
+		{name: "space stale", now: t0 + 61, expected: []string{"db healthy->stale"}},
+		{name: "space offline", now: t0 + 121, expected: []string{"db stale->offline"}},
+		{name: "default healthy", now: t0 + 600, expected: []string{}},
+		{name: "default stale", now: t0 + 601, expected: []string{"web healthy->stale"}},
+		{name: "default offline", now: t0 + 3601, expected: []string{"web stale->offline"}},
+		{name: "heartbeat", now: t0 + 3700, heartbeat: "db", expected: []string{"db offline->healthy"}},
+	}
+
+	for _, step := range steps {
+		if step.heartbeat != "" {
+			if _, err := db.ExecContext(ctx, `UPDATE node SET last_heartbeat = $1 WHERE name = $2`, step.now, step.heartbeat); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		changes, err := br.Node().UpdateHealth(ctx, step.now, 600, 3600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual := make([]string, 0, len(changes))
+		for _, c := range changes {
+			actual = append(actual, fmt.Sprintf("%s %s->%s", c.Name, c.From, c.To))
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, step.expected) {
+			t.Fatalf("%s: expected %q, got %q", step.name, step.expected, actual)
+		}
+	}
+
+	counts, err := br.Node().CountByHealth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expected is the count as "space stale_after/offline_after healthy stale offline"
+	expected := []string{"a 0/0 0 0 2", "b 60/120 1 0 0", "empty 0/0 0 0 0"}
+	actual := make([]string, 0, len(counts))
+	for _, c := range counts {
+		actual = append(actual, fmt.Sprintf("%s %d/%d %d %d %d", c.SpaceName, c.StaleAfter, c.OfflineAfter, c.Healthy, c.Stale, c.Offline))
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/sysarmor/guard/server/internal/repo"
@@ -43,12 +44,26 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (c *Config) dsn() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Database, c.SSLMode)
+}
+
 // New creates a new postgres database connection
 func New(ctx context.Context, cfg *Config) (repo.Repo, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode)
+	db, err := Open(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	br := newBaseRepo(db, nil, "")
+	br.dsn = cfg.dsn()
+	return br, nil
+}
 
-	db, err := sql.Open("postgres", connStr)
+// Open opens the database and checks the connection
+func Open(ctx context.Context, cfg *Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.dsn())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -57,27 +72,50 @@ func New(ctx context.Context, cfg *Config) (repo.Repo, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	var br baseRepo
-	br = baseRepo{
-		db: db,
+	return db, nil
+}
 
-		node:  NewNode(&br),
-		role:  NewRole(&br),
-		space: NewSpace(&br),
-		user:  NewUser(&br),
+// newBaseRepo returns a baseRepo whose repos run on the transaction
+// if tx is set, otherwise on the db
+func newBaseRepo(db *sql.DB, tx *sql.Tx, savepoint string) *baseRepo {
+	br := &baseRepo{
+		db:        db,
+		tx:        tx,
+		savepoint: savepoint,
 	}
 
-	return &br, nil
+	br.node = NewNode(br)
+	br.role = NewRole(br)
+	br.space = NewSpace(br)
+	br.user = NewUser(br)
+	br.event = NewEvent(br)
+
+	br.accessRequest = NewAccessRequest(br)
+	br.breakGlass = NewBreakGlass(br)
+	br.group = NewGroup(br)
+
+	return br
 }
 
 type baseRepo struct {
 	db *sql.DB
 	tx *sql.Tx
+	// savepoint is set if the transaction is nested in another one
+	savepoint string
+	// savepoints counts the savepoints begun in the transaction
+	savepoints int
+	// dsn is set on the repo out of the transactions for the listener
+	dsn string
 
 	node  repo.NodeRepo
 	role  repo.RoleRepo
 	space repo.SpaceRepo
 	user  repo.UserRepo
+	event repo.EventRepo
+
+	accessRequest repo.AccessRequestRepo
+	breakGlass    repo.BreakGlassRepo
+	group         repo.GroupRepo
 }
 
 func (br *baseRepo) Node() repo.NodeRepo {
@@ -96,7 +134,25 @@ func (br *baseRepo) User() repo.UserRepo {
 	return br.user
 }
 
+func (br *baseRepo) Event() repo.EventRepo {
+	return br.event
+}
+
+func (br *baseRepo) AccessRequest() repo.AccessRequestRepo {
+	return br.accessRequest
+}
+
+func (br *baseRepo) BreakGlass() repo.BreakGlassRepo {
+	return br.breakGlass
+}
+
+func (br *baseRepo) Group() repo.GroupRepo {
+	return br.group
+}
+
 func (br *baseRepo) execContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(time.Now())
+
 	if br.tx != nil {
 		return br.tx.ExecContext(ctx, query, args...)
 	}
@@ -105,6 +161,8 @@ func (br *baseRepo) execContext(ctx context.Context, query string, args ...inter
 }
 
 func (br *baseRepo) queryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(time.Now())
+
 	if br.tx != nil {
 		return br.tx.QueryRowContext(ctx, query, args...)
 	}
@@ -113,6 +171,8 @@ func (br *baseRepo) queryRowContext(ctx context.Context, query string, args ...i
 }
 
 func (br *baseRepo) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(time.Now())
+
 	if br.tx != nil {
 		return br.tx.QueryContext(ctx, query, args...)
 	}
@@ -120,20 +180,68 @@ func (br *baseRepo) queryContext(ctx context.Context, query string, args ...inte
 	return br.db.QueryContext(ctx, query, args...)
 }
 
+// Ping checks the database is reachable
+func (br *baseRepo) Ping(ctx context.Context) error {
+	return br.db.PingContext(ctx)
+}
+
+// BeginTx begins a transaction, it begins a savepoint instead if the
+// repo is already in a transaction, so that the nested transaction is
+// committed or rolled back with the outer one
 func (br *baseRepo) BeginTx(ctx context.Context) (repo.Repo, error) {
+	if br.tx != nil {
+		br.savepoints++
+		savepoint := fmt.Sprintf("%s_%d", br.savepointPrefix(), br.savepoints)
+		if _, err := br.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+		}
+
+		return newBaseRepo(br.db, br.tx, savepoint), nil
+	}
+
 	tx, err := br.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	return &baseRepo{
-		db:    br.db,
-		tx:    tx,
-		node:  br.node,
-		role:  br.role,
-		space: br.space,
-		user:  br.user,
-	}, nil
+	return newBaseRepo(br.db, tx, ""), nil
+}
+
+// BeginSnapshot begins a repeatable read transaction, the queries see the
+// snapshot taken by the first one. It's a savepoint of the transaction if
+// the repo is already in one.
+func (br *baseRepo) BeginSnapshot(ctx context.Context) (repo.Repo, error) {
+	if br.tx != nil {
+		return br.BeginTx(ctx)
+	}
+
+	tx, err := br.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin snapshot: %w", err)
+	}
+
+	return newBaseRepo(br.db, tx, ""), nil
+}
+
+// Version returns the version of the data served to the nodes, it's
+// increased by the triggers of the changes, 0 if nothing is changed yet
+func (br *baseRepo) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := br.queryRowContext(ctx, `SELECT COALESCE((SELECT version FROM sync_version WHERE id = 1), 0)`).
+		Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	return version, nil
+}
+
+func (br *baseRepo) savepointPrefix() string {
+	if br.savepoint == "" {
+		return "sp"
+	}
+
+	return br.savepoint
 }
 
 func (br *baseRepo) CommitTx(ctx context.Context) error {
@@ -141,7 +249,11 @@ func (br *baseRepo) CommitTx(ctx context.Context) error {
 		return fmt.Errorf("transaction not started")
 	}
 
-	if err := br.tx.Commit(); err != nil {
+	if br.savepoint != "" {
+		if _, err := br.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+br.savepoint); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	} else if err := br.tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction")
 	}
 
@@ -154,7 +266,11 @@ func (br *baseRepo) RollbackTx(ctx context.Context) error {
 		return fmt.Errorf("transaction not started")
 	}
 
-	if err := br.tx.Rollback(); err != nil {
+	if br.savepoint != "" {
+		if _, err := br.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+br.savepoint); err != nil {
+			return fmt.Errorf("failed to rollback savepoint: %w", err)
+		}
+	} else if err := br.tx.Rollback(); err != nil {
 		return fmt.Errorf("failed to rollback transaction")
 	}
 
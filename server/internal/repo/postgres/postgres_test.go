@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDSN is the key=value dsn of the database of the tests which run the
+// queries, they are skipped if it's not set
+const testDSN = "GUARD_TEST_DSN"
+
+// testDB returns the database with the tables of the latest version in a
+// temporary schema, the dsn is read from the env. The schema is dropped
+// after the test.
+func testDB(tb testing.TB, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		tb.Skipf("%s is not set", env)
+	}
+
+	ctx := context.Background()
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("guard_test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE") // nolint
+		admin.Close()
+	})
+
+	db, err := sql.Open("postgres", dsn+" search_path="+schema)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback() // nolint
+
+	if err := migrate(ctx, tx, 0, latest); err != nil {
+		tb.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return db
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// conditions builds the WHERE clause with positional arguments
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add adds a clause, the "?" in the clause are replaced
+// by the positional placeholder of the argument
+func (c *conditions) add(clause string, arg interface{}) {
+	c.args = append(c.args, arg)
+	c.clauses = append(c.clauses, strings.ReplaceAll(clause, "?", fmt.Sprintf("$%d", len(c.args))))
+}
+
+// contains adds a case-insensitive substring match of the column
+func (c *conditions) contains(column, value string) {
+	if value == "" {
+		return
+	}
+
+	c.add(column+` ILIKE '%' || ? || '%'`, escapeLike(value))
+}
+
+// where returns the WHERE clause, it's empty if there is no condition
+func (c *conditions) where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// page returns the ORDER BY, LIMIT and OFFSET clause, columns maps
+// the sort field to the column, def is the default order
+func (c *conditions) page(opt *repo.ListOption, columns map[string]string, def string) (string, []interface{}) {
+	order := def
+	field, desc := opt.SortField()
+	if column, ok := columns[field]; ok {
+		order = column
+		if desc {
+			order += " DESC"
+		}
+	}
+
+	var limit, offset interface{}
+	if opt != nil {
+		if opt.Limit > 0 {
+			limit = opt.Limit
+		}
+		offset = opt.Offset
+	}
+
+	args := append(append([]interface{}{}, c.args...), limit, offset)
+	return fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)-1, len(args)), args
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes the wildcard characters of LIKE
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
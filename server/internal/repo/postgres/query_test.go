@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+func TestConditionsWhere(t *testing.T) {
+	c := &conditions{}
+	if where := c.where(); where != "" {
+		t.Fatalf("expected no WHERE clause, got %q", where)
+	}
+
+	c.add("state = ?", "active")
+	c.contains("username", "")
+	c.contains("email", `50%_a\b`)
+	// every "?" of a clause is the same argument
+	c.add("(created_at > ? OR updated_at > ?)", 100)
+
+	expected := ` WHERE state = $1 AND email ILIKE '%' || $2 || '%' AND (created_at > $3 OR updated_at > $3)`
+	if where := c.where(); where != expected {
+		t.Fatalf("expected %q, got %q", expected, where)
+	}
+
+	args := []interface{}{"active", `50\%\_a\\b`, 100}
+	if !reflect.DeepEqual(c.args, args) {
+		t.Fatalf("expected the args %v, got %v", args, c.args)
+	}
+}
+
+func TestConditionsPage(t *testing.T) {
+	columns := map[string]string{"name": "name", "created": "created_at"}
+
+	tests := []struct {
+		name     string
+		opt      *repo.ListOption
+		expected string
+		limit    interface{}
+		offset   interface{}
+	}{
+		{name: "nil", expected: " ORDER BY id DESC LIMIT $2 OFFSET $3"},
+		{name: "asc", opt: &repo.ListOption{Sort: "name", Limit: 10, Offset: 20},
+			expected: " ORDER BY name LIMIT $2 OFFSET $3", limit: int64(10), offset: int64(20)},
+		{name: "desc", opt: &repo.ListOption{Sort: "-created", Limit: 10},
+			expected: " ORDER BY created_at DESC LIMIT $2 OFFSET $3", limit: int64(10), offset: int64(0)},
+		{name: "unknown field", opt: &repo.ListOption{Sort: "-pub_key; DROP TABLE user"},
+			expected: " ORDER BY id DESC LIMIT $2 OFFSET $3", offset: int64(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &conditions{}
+			c.add("space_id = ?", 1)
+
+			page, args := c.page(tt.opt, columns, "id DESC")
+			if page != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, page)
+			}
+
+			expected := []interface{}{1, tt.limit, tt.offset}
+			if !reflect.DeepEqual(args, expected) {
+				t.Fatalf("expected the args %v, got %v", expected, args)
+			}
+
+			// the args of the count query are not changed
+			if len(c.args) != 1 {
+				t.Fatalf("expected the conditions args unchanged, got %v", c.args)
+			}
+		})
+	}
+}
@@ -23,13 +23,37 @@ func NewRole(br *baseRepo) repo.RoleRepo {
 	}
 }
 
+// roleFields is the column list scanned by scan
+const roleFields = `id, space_id, name, description, break_glass, global, created_at`
+
+func (r *role) scan(row scanner) (*model.Role, error) {
+	role := &model.Role{}
+
+	var description sql.NullString
+	err := row.Scan(&role.ID, &role.SpaceID, &role.Name, &description, &role.BreakGlass, &role.Global,
+		&role.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+
+	role.Description = description.String
+
+	return role, nil
+}
+
 // Create creates a new role
 func (r *role) Create(ctx context.Context, role *model.Role) error {
 	role.CreatedAt = time.Now().Unix()
 
 	err := r.queryRowContext(ctx,
-		`INSERT INTO role (space_id, name, description, created_at) VALUES ($1, $2, $3, $4) RETURNING id `,
-		role.SpaceID, role.Name, role.Description, role.CreatedAt).
+		`INSERT INTO role (space_id, name, description, break_glass, global, created_at) 
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id `,
+		role.SpaceID, role.Name, role.Description, role.BreakGlass, role.Global, role.CreatedAt).
 		Scan(&role.ID)
 
 	if err != nil {
@@ -41,23 +65,32 @@ func (r *role) Create(ctx context.Context, role *model.Role) error {
 
 // GetByID gets a role by id
 func (r *role) GetByID(ctx context.Context, id int64) (*model.Role, error) {
-	role := &model.Role{}
+	role, err := r.scan(r.queryRowContext(ctx, `SELECT `+roleFields+` FROM role WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role by id: %w", err)
+	}
 
-	var description sql.NullString
-	err := r.queryRowContext(ctx, `SELECT id, space_id, name, description, created_at FROM role WHERE id = $1`, id).
-		Scan(&role.ID, &role.SpaceID, &role.Name, &description, &role.CreatedAt)
+	return role, nil
+}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+func (r *role) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	role, err := r.scan(r.queryRowContext(ctx, `SELECT `+roleFields+` FROM role WHERE name = $1`, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role by name: %w", err)
 	}
 
+	return role, nil
+}
+
+// Update updates the name, description and break_glass of the role
+func (r *role) Update(ctx context.Context, role *model.Role) error {
+	_, err := r.execContext(ctx, `UPDATE role SET name = $1, description = $2, break_glass = $3 WHERE id = $4`,
+		role.Name, role.Description, role.BreakGlass, role.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get role by id: %w", err)
+		return fmt.Errorf("failed to update role: %w", err)
 	}
 
-	role.Description = description.String
-
-	return role, nil
+	return nil
 }
 
 // Delete deletes a role
@@ -70,38 +103,82 @@ func (r *role) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+var roleColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+}
+
 // List lists roles
-func (r *role) List(ctx context.Context, spaceID int64) ([]*model.Role, error) {
-	rows, err := r.queryContext(ctx, `SELECT id, space_id, name, description, created_at FROM role 
-	WHERE space_id = $1`,
-		spaceID)
+func (r *role) List(ctx context.Context, filter *repo.RoleFilter, opt *repo.ListOption) ([]*model.Role, int64, error) {
+	c := &conditions{}
+	switch {
+	case filter.Global:
+		c.add("global = ?", true)
+	case filter.IncludeGlobal:
+		c.add(`(space_id = ? OR global AND id IN (SELECT rn.role_id FROM `+roleNodeBinding+` 
+			JOIN node n ON n.id = rn.node_id WHERE n.space_id = ?))`, filter.SpaceID)
+	default:
+		c.add("space_id = ?", filter.SpaceID)
+	}
+	c.contains("name", filter.Name)
+	if filter.BreakGlass != nil {
+		c.add("break_glass = ?", *filter.BreakGlass)
+	}
+
+	var total int64
+	if err := r.queryRowContext(ctx, `SELECT COUNT(id) FROM role`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count roles: %w", err)
+	}
+
+	page, args := c.page(opt, roleColumns, "id")
+	rows, err := r.queryContext(ctx, `SELECT `+roleFields+` FROM role`+c.where()+page, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list roles: %w", err)
+		return nil, 0, fmt.Errorf("failed to list roles: %w", err)
 	}
 	defer rows.Close()
 
-	var description sql.NullString
-
 	roles := make([]*model.Role, 0)
 	for rows.Next() {
-		role := &model.Role{}
-		err = rows.Scan(&role.ID, &role.SpaceID, &role.Name, &description, &role.CreatedAt)
+		role, err := r.scan(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan role: %w", err)
+			return nil, 0, err
 		}
 
-		role.Description = description.String
 		roles = append(roles, role)
 	}
 
-	return roles, nil
+	return roles, total, nil
 }
 
-// ListRoleNodeByNodeID lists role node by node id
+// roleNodeBinding is the explicit and the label selector bindings of the roles
+// and the nodes. A selector binds the nodes of the role's space, or of all the
+// spaces for a global role, which have all the labels of the selector with the
+// account of the selector, or the default account of the node. The explicit
+// binding of the same node takes precedence.
+const roleNodeBinding = `(
+	SELECT rn.id, rn.role_id, rn.node_id, rn.account, rn.created_at, FALSE AS dynamic
+	FROM role_node rn
+	UNION ALL
+	SELECT MIN(s.id), s.role_id, s.node_id, s.account, MIN(s.created_at), TRUE
+	FROM (
+		SELECT rs.id, rs.role_id, n.id AS node_id, n.accounts, rs.created_at,
+		COALESCE(NULLIF(rs.account, ''), n.accounts[1]) AS account
+		FROM role_selector rs
+		JOIN role r ON r.id = rs.role_id
+		JOIN node n ON (r.global OR n.space_id = r.space_id) AND n.labels @> rs.selector
+	) s
+	WHERE s.account = ANY(s.accounts)
+	AND NOT EXISTS (SELECT 1 FROM role_node x WHERE x.role_id = s.role_id AND x.node_id = s.node_id)
+	GROUP BY s.role_id, s.node_id, s.account
+) rn`
+
+// ListRoleNodeByNodeID lists role node by node id, the ID of the role node is
+// the role id, the bindings by label selectors are included
 func (r *role) ListRoleNodeByNodeID(ctx context.Context, nodeID int64) ([]*model.RoleNode, error) {
 	rows, err := r.queryContext(ctx,
-		`SELECT r.id, rn.account, rn.node_id, rn.role_id FROM role r 
-		RIGHT JOIN role_node rn ON r.id = rn.role_id 
+		`SELECT rn.role_id, rn.account, rn.node_id, rn.role_id, rn.dynamic FROM `+roleNodeBinding+` 
 		WHERE rn.node_id = $1`, nodeID)
 	if err != nil {
 		return nil, err
@@ -111,7 +188,7 @@ func (r *role) ListRoleNodeByNodeID(ctx context.Context, nodeID int64) ([]*model
 	roles := make([]*model.RoleNode, 0)
 	for rows.Next() {
 		role := &model.RoleNode{}
-		err = rows.Scan(&role.ID, &role.Account, &role.NodeID, &role.RoleID)
+		err = rows.Scan(&role.ID, &role.Account, &role.NodeID, &role.RoleID, &role.Dynamic)
 		if err != nil {
 			return nil, err
 		}
@@ -121,16 +198,48 @@ func (r *role) ListRoleNodeByNodeID(ctx context.Context, nodeID int64) ([]*model
 	return roles, nil
 }
 
-// ListNodeByRoleID lists role node by role id
-func (r *role) ListNodeByRoleID(ctx context.Context, roleID int64) ([]*model.RoleNodeView, error) {
+var roleNodeColumns = map[string]string{
+	"id":             "n.id",
+	"name":           "n.name",
+	"account":        "rn.account",
+	"last_heartbeat": "n.last_heartbeat",
+	"created_at":     "rn.created_at",
+}
+
+// ListNode lists the nodes of the role, including the nodes matched by the
+// label selectors of the role
+func (r *role) ListNode(ctx context.Context, filter *repo.RoleNodeFilter, opt *repo.ListOption) ([]*model.RoleNodeView, int64, error) {
+	c := &conditions{}
+	c.add("rn.role_id = ?", filter.RoleID)
+	c.contains("n.name", filter.Name)
+	if filter.Account != "" {
+		c.add("rn.account = ?", filter.Account)
+	}
+	if filter.HeartbeatFrom > 0 {
+		c.add("n.last_heartbeat >= ?", filter.HeartbeatFrom)
+	}
+	if filter.HeartbeatTo > 0 {
+		c.add("COALESCE(n.last_heartbeat, 0) <= ?", filter.HeartbeatTo)
+	}
+	if filter.Dynamic != nil {
+		c.add("rn.dynamic = ?", *filter.Dynamic)
+	}
+
+	var total int64
+	if err := r.queryRowContext(ctx, `SELECT COUNT(rn.id) FROM `+roleNodeBinding+` 
+		JOIN node n ON n.id = rn.node_id`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count role nodes: %w", err)
+	}
+
+	page, args := c.page(opt, roleNodeColumns, "rn.dynamic, rn.id")
 	rows, err := r.queryContext(ctx,
-		`SELECT n.id, n.name, n.description, n.unique_id, n.secret, n.ip, n.last_heartbeat, n.accounts, n.created_at, n.updated_at, 
-		rn.account, rn.created_at
-		FROM node n 
-		RIGHT JOIN role_node rn ON n.id = rn.node_id 
-		WHERE rn.role_id = $1`, roleID)
+		`SELECT n.id, n.space_id, n.name, n.description, n.unique_id, n.secret, n.ip, n.last_heartbeat, n.accounts, n.labels, n.health, n.created_at, n.updated_at, 
+		rn.account, rn.created_at, rn.dynamic
+		FROM `+roleNodeBinding+` 
+		JOIN node n ON n.id = rn.node_id`+c.where()+page, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -141,11 +250,11 @@ func (r *role) ListNodeByRoleID(ctx context.Context, roleID int64) ([]*model.Rol
 		var lastHeartbeat sql.NullInt64
 		var updatedAt sql.NullInt64
 
-		if err := rows.Scan(&node.ID, &node.Name, &description, &node.UniqueID, &node.Secret,
-			&node.IP, &lastHeartbeat, pq.Array(&node.Accounts), &node.Node.CreatedAt, &updatedAt,
-			&node.Account, &node.CreatedAt,
+		if err := rows.Scan(&node.ID, &node.SpaceID, &node.Name, &description, &node.UniqueID, &node.Secret,
+			&node.IP, &lastHeartbeat, pq.Array(&node.Accounts), &node.Labels, &node.Health, &node.Node.CreatedAt,
+			&updatedAt, &node.Account, &node.CreatedAt, &node.Dynamic,
 		); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		if description.Valid {
@@ -163,7 +272,7 @@ func (r *role) ListNodeByRoleID(ctx context.Context, roleID int64) ([]*model.Rol
 		nodes = append(nodes, node)
 	}
 
-	return nodes, nil
+	return nodes, total, nil
 }
 
 // GetRoleNodeByRoleIDAndNodeID gets role node by role id and node id
@@ -215,12 +324,85 @@ func (r *role) RemoveNode(ctx context.Context, roleID int64, nodeIDs ...int64) e
 	return nil
 }
 
-// ListUserByRoleID lists users by role id
+// ListSelector lists the label selectors of the role
+func (r *role) ListSelector(ctx context.Context, roleID int64) ([]*model.RoleSelector, error) {
+	rows, err := r.queryContext(ctx, `SELECT id, role_id, selector, account, created_at 
+		FROM role_selector WHERE role_id = $1 ORDER BY id`, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role selectors: %w", err)
+	}
+	defer rows.Close()
+
+	selectors := make([]*model.RoleSelector, 0)
+	for rows.Next() {
+		selector := &model.RoleSelector{}
+		if err := rows.Scan(&selector.ID, &selector.RoleID, &selector.Selector, &selector.Account,
+			&selector.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role selector: %w", err)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+// AddSelector adds a label selector to the role
+func (r *role) AddSelector(ctx context.Context, selector *model.RoleSelector) error {
+	selector.CreatedAt = time.Now().Unix()
+
+	err := r.queryRowContext(ctx, `INSERT INTO role_selector (role_id, selector, account, created_at) 
+		VALUES ($1, $2, $3, $4) RETURNING id`,
+		selector.RoleID, selector.Selector, selector.Account, selector.CreatedAt).
+		Scan(&selector.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add selector to role: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveSelector removes the label selectors from the role, if selectorIDs
+// is empty, all selectors of the role are removed
+func (r *role) RemoveSelector(ctx context.Context, roleID int64, selectorIDs ...int64) error {
+	sql := "DELETE FROM role_selector WHERE role_id = $1"
+	values := []interface{}{roleID}
+
+	if len(selectorIDs) != 0 {
+		sql += " AND id = ANY($2)"
+		values = append(values, pq.Array(selectorIDs))
+	}
+
+	if _, err := r.execContext(ctx, sql, values...); err != nil {
+		return fmt.Errorf("failed to remove selector from role: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveNodeByNodeID removes the node from all roles
+func (r *role) RemoveNodeByNodeID(ctx context.Context, nodeID int64) error {
+	_, err := r.execContext(ctx, `DELETE FROM role_node WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to remove node from role by node id: %w", err)
+	}
+
+	return nil
+}
+
+// roleMember matches the users who are valid members of the role $1 at the
+// time $3, either directly or inherited from a group bound to the role
+const roleMember = `(EXISTS (SELECT 1 FROM role_user ru WHERE ru.user_id = u.id AND ru.role_id = $1
+	AND ru.valid_from <= $3 AND (ru.valid_until = 0 OR ru.valid_until > $3))
+	OR EXISTS (SELECT 1 FROM role_group rg JOIN group_user gu ON gu.group_id = rg.group_id
+	WHERE gu.user_id = u.id AND rg.role_id = $1))`
+
+// ListUserByRoleID lists the active users whose membership is valid by role id,
+// the members of the groups bound to the role are included
 func (r *role) ListUserByRoleID(ctx context.Context, roleID int64) ([]*model.User, error) {
 	rows, err := r.queryContext(ctx,
 		`SELECT u.id, u.username, u.email FROM "user" u 
-	RIGHT JOIN role_user ur ON u.id = ur.user_id 
-	WHERE ur.role_id = $1`, roleID)
+		WHERE u.state = $2 AND (u.expires_at = 0 OR u.expires_at > $3) AND `+roleMember,
+		roleID, model.UserStateActive, time.Now().Unix())
 	if err != nil {
 		return nil, err
 	}
@@ -239,11 +421,80 @@ func (r *role) ListUserByRoleID(ctx context.Context, roleID int64) ([]*model.Use
 	return users, nil
 }
 
+var roleUserColumns = map[string]string{
+	"id":          "u.id",
+	"username":    "u.username",
+	"email":       "u.email",
+	"valid_until": "MAX(m.valid_until)",
+	"created_at":  "MIN(m.created_at)",
+}
+
+// roleMembership is the direct and group inherited memberships of the roles,
+// a user has one row per group besides the direct membership
+const roleMembership = `(
+	SELECT ru.role_id, ru.user_id, TRUE AS direct, NULL::VARCHAR AS group_name,
+	ru.valid_from, ru.valid_until, ru.created_at
+	FROM role_user ru
+	UNION ALL
+	SELECT rg.role_id, gu.user_id, FALSE, g.name, 0, 0, GREATEST(rg.created_at, gu.created_at)
+	FROM role_group rg
+	JOIN group_user gu ON gu.group_id = rg.group_id
+	JOIN "group" g ON g.id = rg.group_id
+) m`
+
+// ListUser lists the users of the role, including the members of the groups
+// bound to the role, Direct and Groups of the view tell where the access is from
+func (r *role) ListUser(ctx context.Context, filter *repo.RoleUserFilter, opt *repo.ListOption) ([]*model.RoleUserView, int64, error) {
+	c := &conditions{}
+	c.add("m.role_id = ?", filter.RoleID)
+	c.contains("u.username", filter.Name)
+	c.contains("u.email", filter.Email)
+	if filter.State != "" {
+		c.add("u.state = ?", filter.State)
+	}
+
+	var total int64
+	if err := r.queryRowContext(ctx, `SELECT COUNT(DISTINCT u.id) FROM `+roleMembership+` 
+		JOIN "user" u ON u.id = m.user_id`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count role users: %w", err)
+	}
+
+	page, args := c.page(opt, roleUserColumns, "MIN(m.created_at), u.id")
+	rows, err := r.queryContext(ctx,
+		`SELECT u.id, u.username, u.email, u.state, u.expires_at,
+		COALESCE(MAX(m.valid_from) FILTER (WHERE m.direct), 0),
+		COALESCE(MAX(m.valid_until) FILTER (WHERE m.direct), 0),
+		MIN(m.created_at), bool_or(m.direct),
+		array_remove(array_agg(m.group_name ORDER BY m.group_name), NULL)
+		FROM `+roleMembership+` 
+		JOIN "user" u ON u.id = m.user_id`+c.where()+` GROUP BY u.id`+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*model.RoleUserView, 0)
+	for rows.Next() {
+		user := &model.RoleUserView{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.State, &user.ExpiresAt,
+			&user.ValidFrom, &user.ValidUntil, &user.JoinedAt, &user.Direct, pq.Array(&user.Groups)); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
 // AddUser adds user to role
-func (r *role) AddUser(ctx context.Context, roleID, userID int64) error {
-	_, err := r.execContext(ctx, `INSERT INTO role_user (role_id, user_id, created_at) 
-	VALUES ($1, $2, $3)`,
-		roleID, userID, time.Now().Unix())
+func (r *role) AddUser(ctx context.Context, roleUser *model.RoleUser) error {
+	roleUser.CreatedAt = time.Now().Unix()
+
+	err := r.queryRowContext(ctx, `INSERT INTO role_user (role_id, user_id, valid_from, valid_until, created_at) 
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		roleUser.RoleID, roleUser.UserID, roleUser.ValidFrom, roleUser.ValidUntil, roleUser.CreatedAt).
+		Scan(&roleUser.ID)
 	if err != nil {
 		return fmt.Errorf("failed to add user to role: %w", err)
 	}
@@ -251,6 +502,65 @@ func (r *role) AddUser(ctx context.Context, roleID, userID int64) error {
 	return nil
 }
 
+// UpdateUser updates the valid window of the membership
+func (r *role) UpdateUser(ctx context.Context, roleUser *model.RoleUser) error {
+	_, err := r.execContext(ctx, `UPDATE role_user SET valid_from = $1, valid_until = $2 WHERE id = $3`,
+		roleUser.ValidFrom, roleUser.ValidUntil, roleUser.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update role user: %w", err)
+	}
+
+	return nil
+}
+
+// ListRoleUserByUserID lists the memberships of the user
+func (r *role) ListRoleUserByUserID(ctx context.Context, userID int64) ([]*model.RoleUser, error) {
+	return r.listRoleUser(ctx, `WHERE user_id = $1`, userID)
+}
+
+// ListExpiredUser lists the temporary memberships which are ended at the time
+func (r *role) ListExpiredUser(ctx context.Context, now int64) ([]*model.RoleUser, error) {
+	return r.listRoleUser(ctx, `WHERE valid_until > 0 AND valid_until <= $1`, now)
+}
+
+// RemoveExpiredUser removes the membership if it's still ended, the one
+// extended after it was listed is kept
+func (r *role) RemoveExpiredUser(ctx context.Context, id, now int64) (bool, error) {
+	result, err := r.execContext(ctx,
+		`DELETE FROM role_user WHERE id = $1 AND valid_until > 0 AND valid_until <= $2`, id, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove expired role user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (r *role) listRoleUser(ctx context.Context, where string, args ...interface{}) ([]*model.RoleUser, error) {
+	rows, err := r.queryContext(ctx,
+		`SELECT id, role_id, user_id, valid_from, valid_until, created_at FROM role_user `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role users: %w", err)
+	}
+	defer rows.Close()
+
+	roleUsers := make([]*model.RoleUser, 0)
+	for rows.Next() {
+		roleUser := &model.RoleUser{}
+		if err := rows.Scan(&roleUser.ID, &roleUser.RoleID, &roleUser.UserID,
+			&roleUser.ValidFrom, &roleUser.ValidUntil, &roleUser.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role user: %w", err)
+		}
+		roleUsers = append(roleUsers, roleUser)
+	}
+
+	return roleUsers, nil
+}
+
 // RemoveUser removes user from role
 func (r *role) RemoveUser(ctx context.Context, roleID int64, userIDs ...int64) error {
 	sql := "DELETE FROM role_user WHERE role_id = $1"
@@ -284,9 +594,11 @@ func (r *role) GetRoleUserByRoleIDAndUserID(ctx context.Context, roleID, userID
 	roleUser := &model.RoleUser{}
 
 	err := r.queryRowContext(ctx,
-		`SELECT id, role_id, user_id, created_at FROM role_user WHERE role_id = $1 AND user_id = $2`,
+		`SELECT id, role_id, user_id, valid_from, valid_until, created_at FROM role_user 
+		WHERE role_id = $1 AND user_id = $2`,
 		roleID, userID).
-		Scan(&roleUser.ID, &roleUser.RoleID, &roleUser.UserID, &roleUser.CreatedAt)
+		Scan(&roleUser.ID, &roleUser.RoleID, &roleUser.UserID, &roleUser.ValidFrom, &roleUser.ValidUntil,
+			&roleUser.CreatedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -299,15 +611,23 @@ func (r *role) GetRoleUserByRoleIDAndUserID(ctx context.Context, roleID, userID
 	return roleUser, nil
 }
 
-// ListRevokedKeys lists revoked keys
+// ListRevokedKeys lists revoked keys of the users who are direct or group
+// inherited members of the roles bound to the node explicitly or by labels
 func (r *role) ListRevokedKeys(ctx context.Context, nodeID int64) ([]int64, error) {
 	rows, err := r.queryContext(ctx,
 		`SELECT uc.id
 		FROM user_cert uc
-		JOIN role_user ru ON uc.user_id = ru.user_id
-		JOIN role_node rn ON ru.role_id = rn.role_id
-		WHERE uc.is_revoked = TRUE
-		AND rn.node_id = $1 and uc.expires_at < $2`, nodeID, time.Now().Unix())
+		WHERE uc.is_revoked = TRUE AND uc.expires_at < $2
+		AND uc.user_id IN (
+			SELECT ru.user_id FROM role_user ru
+			JOIN `+roleNodeBinding+` ON ru.role_id = rn.role_id
+			WHERE rn.node_id = $1
+			UNION
+			SELECT gu.user_id FROM group_user gu
+			JOIN role_group rg ON rg.group_id = gu.group_id
+			JOIN `+roleNodeBinding+` ON rg.role_id = rn.role_id
+			WHERE rn.node_id = $1
+		)`, nodeID, time.Now().Unix())
 
 	if err != nil {
 		return nil, err
@@ -327,13 +647,13 @@ func (r *role) ListRevokedKeys(ctx context.Context, nodeID int64) ([]int64, erro
 	return revokedKeys, nil
 }
 
-// ListUserPublicKeyByRoleID lists user public key by role id
+// ListUserPublicKeyByRoleID lists the public key of the active users whose
+// membership is valid by role id, the members of the bound groups are included
 func (r *role) ListUserPublicKeyByRoleID(ctx context.Context, roleID int64) ([]string, error) {
 	rows, err := r.queryContext(ctx,
 		`SELECT pub_key FROM "user" as u 
-		LEFT JOIN role_user ru ON ru.user_id = u.id 
-		LEFT JOIN role ON ru.role_id = role.id 
-		WHERE role.id = $1 AND (u.ban != true OR ban is null)`, roleID)
+		WHERE u.state = $2 AND (u.expires_at = 0 OR u.expires_at > $3) AND `+roleMember,
+		roleID, model.UserStateActive, time.Now().Unix())
 
 	if err != nil {
 		return nil, err
@@ -352,3 +672,106 @@ func (r *role) ListUserPublicKeyByRoleID(ctx context.Context, roleID int64) ([]s
 
 	return keys, nil
 }
+
+// ListNodeMember lists the accounts bound to the node by the roles and the
+// active users whose membership is valid, the members of the bound groups
+// are included. An account without member has one row with empty email.
+func (r *role) ListNodeMember(ctx context.Context, nodeID int64) ([]*model.NodeMember, error) {
+	rows, err := r.queryContext(ctx,
+		`SELECT DISTINCT rn.account, COALESCE(u.email, ''), COALESCE(u.pub_key, '')
+		FROM `+roleNodeBinding+`
+		LEFT JOIN (`+roleMembership+`
+			JOIN "user" u ON u.id = m.user_id AND u.state = $2 AND (u.expires_at = 0 OR u.expires_at > $3)
+			AND m.valid_from <= $3 AND (m.valid_until = 0 OR m.valid_until > $3)
+		) ON m.role_id = rn.role_id
+		WHERE rn.node_id = $1
+		ORDER BY 1, 2, 3`, nodeID, model.UserStateActive, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]*model.NodeMember, 0)
+	for rows.Next() {
+		member := &model.NodeMember{}
+		if err := rows.Scan(&member.Account, &member.Email, &member.PublicKey); err != nil {
+			return nil, fmt.Errorf("failed to scan node member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// accessColumns are the sort fields of the access, until is the end of the
+// membership or the user, the access without the end is sorted last
+var accessColumns = map[string]string{
+	"email":     "u.email",
+	"node_name": "n.name",
+	"account":   "rn.account",
+	"role_name": "ro.name",
+	"until":     "LEAST(NULLIF(m.valid_until, 0), NULLIF(u.expires_at, 0))",
+}
+
+// ListAccess resolves the bindings of the roles and the valid memberships into
+// the effective access of the active users, one row per role and membership
+func (r *role) ListAccess(ctx context.Context, filter *repo.AccessFilter, opt *repo.ListOption) ([]*model.Access, int64, error) {
+	now := time.Now().Unix()
+
+	c := &conditions{}
+	c.add("u.state = ?", model.UserStateActive)
+	c.add("(u.expires_at = 0 OR u.expires_at > ?)", now)
+	c.add("m.valid_from <= ? AND (m.valid_until = 0 OR m.valid_until > ?)", now)
+	if filter.NodeID > 0 {
+		c.add("n.id = ?", filter.NodeID)
+	}
+	if filter.UserID > 0 {
+		c.add("u.id = ?", filter.UserID)
+	}
+	if filter.SpaceID > 0 {
+		c.add("n.space_id = ?", filter.SpaceID)
+	}
+	if filter.Account != "" {
+		c.add("rn.account = ?", filter.Account)
+	}
+	if len(filter.NodeIDs) > 0 {
+		c.add("n.id = ANY(?)", pq.Array(filter.NodeIDs))
+	}
+	if len(filter.UserIDs) > 0 {
+		c.add("u.id = ANY(?)", pq.Array(filter.UserIDs))
+	}
+
+	from := ` FROM ` + roleNodeBinding + `
+		JOIN role ro ON ro.id = rn.role_id
+		JOIN node n ON n.id = rn.node_id
+		JOIN ` + roleMembership + ` ON m.role_id = rn.role_id
+		JOIN "user" u ON u.id = m.user_id` + c.where()
+
+	var total int64
+	if err := r.queryRowContext(ctx, `SELECT COUNT(*)`+from, c.args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count access: %w", err)
+	}
+
+	page, args := c.page(opt, accessColumns, "u.email, n.space_id, n.name, rn.account, ro.name")
+	rows, err := r.queryContext(ctx,
+		`SELECT u.id, u.username, u.email, n.id, n.name, n.space_id, rn.account,
+		ro.id, ro.name, ro.global, COALESCE(m.group_name, ''), rn.dynamic, m.valid_until, u.expires_at`+from+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list access: %w", err)
+	}
+	defer rows.Close()
+
+	accesses := make([]*model.Access, 0)
+	for rows.Next() {
+		access := &model.Access{}
+		if err := rows.Scan(&access.UserID, &access.Username, &access.Email,
+			&access.NodeID, &access.NodeName, &access.SpaceID, &access.Account,
+			&access.RoleID, &access.RoleName, &access.Global, &access.Group, &access.Dynamic,
+			&access.ValidUntil, &access.ExpiresAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan access: %w", err)
+		}
+		accesses = append(accesses, access)
+	}
+
+	return accesses, total, rows.Err()
+}
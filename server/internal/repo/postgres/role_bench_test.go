@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+// benchDSN is the key=value dsn of the database of the benchmarks, they are
+// skipped if it's not set
+const benchDSN = "GUARD_BENCH_DSN"
+
+const (
+	benchUsers      = 5000
+	benchRoles      = 2000
+	benchRoleMember = 20
+)
+
+// benchRepo migrates a temporary schema and binds all the roles to one node
+// with three accounts, every role has benchRoleMember users
+func benchRepo(b *testing.B) (*baseRepo, int64) {
+	db := testDB(b, benchDSN)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback() // nolint
+
+	var nodeID int64
+	seeds := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO space (name, created_at) VALUES ('bench', 0)`, nil},
+		{`INSERT INTO "user" (username, email, pub_key, created_at)
+			SELECT 'user' || i, 'user' || i || '@example.com', 'ssh-ed25519 AAAA' || i, 0
+			FROM generate_series(1, $1) i`, []any{benchUsers}},
+		{`INSERT INTO role (space_id, name, created_at)
+			SELECT s.id, 'role' || i, 0 FROM space s, generate_series(1, $1) i`, []any{benchRoles}},
+		{`INSERT INTO node (unique_id, secret, name, space_id, ip, accounts, created_at)
+			SELECT 'bench', 'secret', 'bench', s.id, '127.0.0.1', ARRAY['root', 'deploy', 'app'], 0 FROM space s`, nil},
+		{`INSERT INTO role_node (role_id, node_id, account, created_at)
+			SELECT r.id, n.id, (ARRAY['root', 'deploy', 'app'])[r.id % 3 + 1], 0 FROM role r, node n`, nil},
+		{`INSERT INTO role_user (role_id, user_id, created_at)
+			SELECT r.id, (r.id * 7 + j) % $1 + 1, 0 FROM role r, generate_series(1, $2) j`, []any{benchUsers, benchRoleMember}},
+	}
+	for _, seed := range seeds {
+		if _, err := tx.ExecContext(ctx, seed.query, seed.args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM node`).Scan(&nodeID); err != nil {
+		b.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	return newBaseRepo(db, nil, ""), nodeID
+}
+
+// BenchmarkNodeMember compares the queries of the principals and the
+// authorized keys of a node: PerRole is the two queries per role used
+// before, Join is the one query of ListNodeMember
+func BenchmarkNodeMember(b *testing.B) {
+	br, nodeID := benchRepo(b)
+	ctx := context.Background()
+
+	b.Run("PerRole", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			roleNodes, err := br.Role().ListRoleNodeByNodeID(ctx, nodeID)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for _, roleNode := range roleNodes {
+				if _, err := br.Role().ListUserByRoleID(ctx, roleNode.ID); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := br.Role().ListUserPublicKeyByRoleID(ctx, roleNode.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("Join", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := br.Role().ListNodeMember(ctx, nodeID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
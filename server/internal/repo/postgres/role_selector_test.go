@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// binding is the account of a node bound to a role and how it's bound
+type binding struct {
+	account string
+	dynamic bool
+}
+
+// selectorRepo returns the repo with the nodes web (env=prod,team=web;
+// accounts root and deploy), db (env=prod,team=db), dev (env=dev) and bare
+// without labels of the space 1, and other (env=prod) of the space 2
+func selectorRepo(t *testing.T) (*baseRepo, map[string]int64) {
+	br := newBaseRepo(testDB(t, testDSN), nil, "")
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b"} {
+		if err := br.Space().Create(ctx, &model.Space{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes := []*model.Node{
+		{SpaceID: 1, Name: "web", Accounts: []string{"root", "deploy"}, Labels: model.Labels{"env": "prod", "team": "web"}},
+		{SpaceID: 1, Name: "db", Accounts: []string{"root"}, Labels: model.Labels{"env": "prod", "team": "db"}},
+		{SpaceID: 1, Name: "dev", Accounts: []string{"root"}, Labels: model.Labels{"env": "dev"}},
+		{SpaceID: 1, Name: "bare", Accounts: []string{"root"}},
+		{SpaceID: 2, Name: "other", Accounts: []string{"root"}, Labels: model.Labels{"env": "prod"}},
+	}
+	ids := make(map[string]int64)
+	for _, node := range nodes {
+		node.UniqueID, node.Secret, node.IP = node.Name, "secret", "127.0.0.1"
+		if err := br.Node().Create(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+		ids[node.Name] = node.ID
+	}
+
+	return br, ids
+}
+
+// roleBindings returns the nodes bound to the role by name
+func roleBindings(t *testing.T, br *baseRepo, roleID int64) map[string]binding {
+	nodes, total, err := br.Role().ListNode(context.Background(), &repo.RoleNodeFilter{RoleID: roleID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(total) != len(nodes) {
+		t.Fatalf("expected the total %d, got %d", len(nodes), total)
+	}
+
+	bindings := make(map[string]binding)
+	for _, node := range nodes {
+		if _, ok := bindings[node.Name]; ok {
+			t.Fatalf("node %s is bound twice", node.Name)
+		}
+		bindings[node.Name] = binding{account: node.Account, dynamic: node.Dynamic}
+	}
+	return bindings
+}
+
+// TestRoleSelectorMatch checks a node is bound by a selector if it has all
+// the labels of the selector and the account, in the space of the role or
+// in every space for a global role
+func TestRoleSelectorMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    bool
+		selectors []*model.RoleSelector
+		expected  map[string]binding
+	}{
+		{name: "one label", selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "prod"}}},
+			expected: map[string]binding{"web": {"root", true}, "db": {"root", true}}},
+		{name: "all labels", selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "prod", "team": "db"}}},
+			expected: map[string]binding{"db": {"root", true}}},
+		{name: "value mismatch", selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "staging"}}},
+			expected: map[string]binding{}},
+		{name: "missing key", selectors: []*model.RoleSelector{{Selector: model.Labels{"zone": "prod"}}},
+			expected: map[string]binding{}},
+		{name: "account", selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "prod"}, Account: "deploy"}},
+			expected: map[string]binding{"web": {"deploy", true}}},
+		{name: "overlapping selectors",
+			selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "prod"}}, {Selector: model.Labels{"team": "db"}}},
+			expected:  map[string]binding{"web": {"root", true}, "db": {"root", true}}},
+		{name: "global", global: true, selectors: []*model.RoleSelector{{Selector: model.Labels{"env": "prod"}}},
+			expected: map[string]binding{"web": {"root", true}, "db": {"root", true}, "other": {"root", true}}},
+	}
+
+	br, _ := selectorRepo(t)
+	ctx := context.Background()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := &model.Role{SpaceID: 1, Name: fmt.Sprintf("role-%d", i), Global: tt.global}
+			if tt.global {
+				role.SpaceID = 0
+			}
+			if err := br.Role().Create(ctx, role); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, selector := range tt.selectors {
+				selector.RoleID = role.ID
+				if err := br.Role().AddSelector(ctx, selector); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if bindings := roleBindings(t, br, role.ID); !reflect.DeepEqual(bindings, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, bindings)
+			}
+		})
+	}
+}
+
+// TestRoleSelectorPrecedence checks the explicit binding of a node takes
+// precedence over the selectors, the node is bound by the selectors again
+// after the explicit binding is removed
+func TestRoleSelectorPrecedence(t *testing.T) {
+	br, ids := selectorRepo(t)
+	ctx := context.Background()
+
+	role := &model.Role{SpaceID: 1, Name: "prod"}
+	if err := br.Role().Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Role().AddSelector(ctx, &model.RoleSelector{RoleID: role.ID, Selector: model.Labels{"env": "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	for name, account := range map[string]string{"web": "deploy", "dev": "root"} {
+		if err := br.Role().AddNode(ctx, role.ID, ids[name], account); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]binding{"web": {"deploy", false}, "db": {"root", true}, "dev": {"root", false}}
+	if bindings := roleBindings(t, br, role.ID); !reflect.DeepEqual(bindings, expected) {
+		t.Fatalf("expected %v, got %v", expected, bindings)
+	}
+
+	roleNodes, err := br.Role().ListRoleNodeByNodeID(ctx, ids["web"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roleNodes) != 1 || roleNodes[0].Account != "deploy" || roleNodes[0].Dynamic {
+		t.Fatalf("expected the explicit binding of web, got %+v", roleNodes)
+	}
+
+	if err := br.Role().RemoveNode(ctx, role.ID, ids["web"], ids["dev"]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = map[string]binding{"web": {"root", true}, "db": {"root", true}}
+	if bindings := roleBindings(t, br, role.ID); !reflect.DeepEqual(bindings, expected) {
+		t.Fatalf("expected %v, got %v", expected, bindings)
+	}
+}
+
+// TestNodeListLabels checks the nodes are filtered by all the labels, the
+// empty labels match every node
+func TestNodeListLabels(t *testing.T) {
+	br, _ := selectorRepo(t)
+
+	tests := []struct {
+		name     string
+		labels   model.Labels
+		expected []string
+	}{
+		{name: "empty", labels: model.Labels{}, expected: []string{"bare", "db", "dev", "web"}},
+		{name: "one label", labels: model.Labels{"env": "prod"}, expected: []string{"db", "web"}},
+		{name: "all labels", labels: model.Labels{"env": "prod", "team": "web"}, expected: []string{"web"}},
+		{name: "no match", labels: model.Labels{"env": "prod", "team": "cache"}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, _, err := br.Node().List(context.Background(),
+				&repo.NodeFilter{SpaceID: 1, Labels: tt.labels}, &repo.ListOption{Sort: "name"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := make([]string, 0, len(nodes))
+			for _, node := range nodes {
+				names = append(names, node.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+CREATE TABLE node_artifact (
+    id SERIAL PRIMARY KEY,
+    node_id BIGINT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    received VARCHAR(64) NOT NULL DEFAULT '',
+    applied VARCHAR(64) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    reported_at BIGINT NOT NULL,
+    UNIQUE (node_id, kind)
+);
+
+COMMENT ON COLUMN node_artifact.node_id IS 'Node ID';
+COMMENT ON COLUMN node_artifact.kind IS 'Kind of the managed file: ca, principals, krl or authorized_keys';
+COMMENT ON COLUMN node_artifact.received IS 'Digest of the content served to the node';
+COMMENT ON COLUMN node_artifact.applied IS 'Digest of the file read back by the node after the run';
+COMMENT ON COLUMN node_artifact.error IS 'Error of the run reported by the node, empty if succeeded';
+COMMENT ON COLUMN node_artifact.reported_at IS 'Time of the last report';
@@ -0,0 +1,28 @@
+-- notify the servers when the data served to the nodes changes, the
+-- notifications are delivered on commit and deduplicated per transaction
+CREATE FUNCTION notify_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('guard_change', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_change AFTER INSERT OR UPDATE OR DELETE ON "user"
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER user_cert_change AFTER INSERT OR UPDATE OR DELETE ON user_cert
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+-- the heartbeats and the health of the nodes do not change the data
+CREATE TRIGGER node_change AFTER INSERT OR DELETE OR UPDATE OF space_id, accounts, labels ON node
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER role_change AFTER INSERT OR UPDATE OR DELETE ON role
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER role_node_change AFTER INSERT OR UPDATE OR DELETE ON role_node
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER role_user_change AFTER INSERT OR UPDATE OR DELETE ON role_user
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER role_selector_change AFTER INSERT OR UPDATE OR DELETE ON role_selector
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER role_group_change AFTER INSERT OR UPDATE OR DELETE ON role_group
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
+CREATE TRIGGER group_user_change AFTER INSERT OR UPDATE OR DELETE ON group_user
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_change();
@@ -0,0 +1,22 @@
+CREATE TABLE sync_version (
+    id INT PRIMARY KEY,
+    version BIGINT NOT NULL
+);
+
+COMMENT ON COLUMN sync_version.version IS 'Version of the data served to the nodes, it increases on every change';
+
+-- the version increases in the transaction of the change, so that the
+-- snapshots see the version of their data. The triggers are skipped when
+-- a backup is restored, the version is restored from the backup.
+CREATE OR REPLACE FUNCTION notify_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('guard.restoring', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO sync_version (id, version) VALUES (1, 1)
+        ON CONFLICT (id) DO UPDATE SET version = sync_version.version + 1;
+    PERFORM pg_notify('guard_change', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
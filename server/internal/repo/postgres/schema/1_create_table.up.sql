@@ -82,7 +82,7 @@ COMMENT ON COLUMN node.description IS 'Description of the node';
 COMMENT ON COLUMN node.space_id IS 'Space ID';
 COMMENT ON COLUMN node.ip IS 'IP address of the node';
 COMMENT ON COLUMN node.last_heartbeat IS 'Last heartbeat of the node';
-COMMENT ON COLUMN node.account IS 'Account of the node, use "," to separate multiple accounts';
+COMMENT ON COLUMN node.accounts IS 'Account of the node, use "," to separate multiple accounts';
 COMMENT ON COLUMN node.created_at IS 'Creation time';
 COMMENT ON COLUMN node.updated_at IS 'Last update time';
 
@@ -100,8 +100,8 @@ COMMENT ON COLUMN role_user.role_id IS 'Role ID';
 COMMENT ON COLUMN role_user.user_id IS 'User ID';
 COMMENT ON COLUMN role_user.created_at IS 'Creation time';
 
-COMMENT ON COLUMN `user`.username IS 'Username';
-COMMENT ON COLUMN `user`.email IS 'Email';
-COMMENT ON COLUMN `user`.pub_key IS 'Public key';
-COMMENT ON COLUMN `user`.created_at IS 'Creation time';
-COMMENT ON COLUMN `user`.updated_at IS 'Last update time';
\ No newline at end of file
+COMMENT ON COLUMN "user".username IS 'Username';
+COMMENT ON COLUMN "user".email IS 'Email';
+COMMENT ON COLUMN "user".pub_key IS 'Public key';
+COMMENT ON COLUMN "user".created_at IS 'Creation time';
+COMMENT ON COLUMN "user".updated_at IS 'Last update time';
\ No newline at end of file
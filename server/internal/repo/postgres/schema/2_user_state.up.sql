@@ -0,0 +1,11 @@
+ALTER TABLE "user" ADD COLUMN state VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE "user" ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0;
+
+UPDATE "user" SET state = 'banned' WHERE ban = true;
+
+ALTER TABLE "user" DROP COLUMN ban;
+
+CREATE INDEX idx_user_state_expires_at ON "user"(state, expires_at);
+
+COMMENT ON COLUMN "user".state IS 'State of the user: active, suspended, expired or banned';
+COMMENT ON COLUMN "user".expires_at IS 'Expiry time of the user, 0 means never';
@@ -0,0 +1,29 @@
+ALTER TABLE role_user ADD COLUMN valid_from BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE role_user ADD COLUMN valid_until BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_role_user_valid_until ON role_user(valid_until);
+
+CREATE TABLE event (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    space_id BIGINT NOT NULL DEFAULT 0,
+    role_id BIGINT NOT NULL DEFAULT 0,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    node_id BIGINT NOT NULL DEFAULT 0,
+    message TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_event_type ON event(type);
+CREATE INDEX idx_event_created_at ON event(created_at);
+
+COMMENT ON COLUMN role_user.valid_from IS 'Start time of the membership, 0 means no limit';
+COMMENT ON COLUMN role_user.valid_until IS 'End time of the membership, 0 means never expires';
+
+COMMENT ON COLUMN event.type IS 'Type of the event';
+COMMENT ON COLUMN event.space_id IS 'Space ID, 0 if not related';
+COMMENT ON COLUMN event.role_id IS 'Role ID, 0 if not related';
+COMMENT ON COLUMN event.user_id IS 'User ID, 0 if not related';
+COMMENT ON COLUMN event.node_id IS 'Node ID, 0 if not related';
+COMMENT ON COLUMN event.message IS 'Human readable message';
+COMMENT ON COLUMN event.created_at IS 'Creation time';
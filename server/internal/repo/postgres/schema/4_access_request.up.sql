@@ -0,0 +1,56 @@
+CREATE TABLE role_approver (
+    id SERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (role_id, user_id)
+);
+
+CREATE TABLE access_request (
+    id SERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    duration BIGINT NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    reviewer VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT
+);
+
+CREATE INDEX idx_access_request_role_id ON access_request(role_id);
+CREATE INDEX idx_access_request_user_id ON access_request(user_id);
+
+CREATE TABLE access_request_log (
+    id SERIAL PRIMARY KEY,
+    request_id BIGINT NOT NULL,
+    from_state VARCHAR(16) NOT NULL,
+    to_state VARCHAR(16) NOT NULL,
+    operator VARCHAR(255) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_access_request_log_request_id ON access_request_log(request_id);
+
+COMMENT ON COLUMN role_approver.role_id IS 'Role ID';
+COMMENT ON COLUMN role_approver.user_id IS 'User ID of the approver';
+COMMENT ON COLUMN role_approver.created_at IS 'Creation time';
+
+COMMENT ON COLUMN access_request.role_id IS 'Role ID';
+COMMENT ON COLUMN access_request.user_id IS 'User ID of the requester';
+COMMENT ON COLUMN access_request.reason IS 'Justification of the request';
+COMMENT ON COLUMN access_request.duration IS 'Requested duration of the membership in seconds';
+COMMENT ON COLUMN access_request.state IS 'State of the request: pending, approved, denied or cancelled';
+COMMENT ON COLUMN access_request.reviewer IS 'Email of the approver who approved or denied the request';
+COMMENT ON COLUMN access_request.comment IS 'Comment of the reviewer';
+COMMENT ON COLUMN access_request.created_at IS 'Creation time';
+COMMENT ON COLUMN access_request.updated_at IS 'Last update time';
+
+COMMENT ON COLUMN access_request_log.request_id IS 'Access request ID';
+COMMENT ON COLUMN access_request_log.from_state IS 'State before the transition';
+COMMENT ON COLUMN access_request_log.to_state IS 'State after the transition';
+COMMENT ON COLUMN access_request_log.operator IS 'Operator of the transition';
+COMMENT ON COLUMN access_request_log.comment IS 'Comment of the transition';
+COMMENT ON COLUMN access_request_log.created_at IS 'Creation time';
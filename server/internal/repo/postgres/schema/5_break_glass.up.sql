@@ -0,0 +1,43 @@
+ALTER TABLE role ADD COLUMN break_glass BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE space_admin (
+    id SERIAL PRIMARY KEY,
+    space_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (space_id, user_id)
+);
+
+CREATE TABLE break_glass (
+    id SERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    space_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    operator VARCHAR(255) NOT NULL,
+    expires_at BIGINT NOT NULL,
+    acked_by VARCHAR(255) NOT NULL DEFAULT '',
+    acked_at BIGINT NOT NULL DEFAULT 0,
+    ack_comment TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_break_glass_user_id_created_at ON break_glass(user_id, created_at);
+CREATE INDEX idx_break_glass_acked_at ON break_glass(acked_at);
+
+COMMENT ON COLUMN role.break_glass IS 'Whether the role is used for the break-glass access';
+
+COMMENT ON COLUMN space_admin.space_id IS 'Space ID';
+COMMENT ON COLUMN space_admin.user_id IS 'User ID of the admin';
+COMMENT ON COLUMN space_admin.created_at IS 'Creation time';
+
+COMMENT ON COLUMN break_glass.role_id IS 'Role ID';
+COMMENT ON COLUMN break_glass.space_id IS 'Space ID of the role';
+COMMENT ON COLUMN break_glass.user_id IS 'User ID of the grantee';
+COMMENT ON COLUMN break_glass.reason IS 'Reason of the emergency access';
+COMMENT ON COLUMN break_glass.operator IS 'Operator who triggered the break-glass';
+COMMENT ON COLUMN break_glass.expires_at IS 'End time of the membership and the cert';
+COMMENT ON COLUMN break_glass.acked_by IS 'Operator who acknowledged the review, empty if not acknowledged';
+COMMENT ON COLUMN break_glass.acked_at IS 'Acknowledge time, 0 if not acknowledged';
+COMMENT ON COLUMN break_glass.ack_comment IS 'Comment of the review';
+COMMENT ON COLUMN break_glass.created_at IS 'Creation time';
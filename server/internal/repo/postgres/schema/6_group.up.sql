@@ -0,0 +1,39 @@
+CREATE TABLE "group" (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at BIGINT NOT NULL,
+    UNIQUE (name)
+);
+
+CREATE TABLE group_user (
+    id SERIAL PRIMARY KEY,
+    group_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (group_id, user_id)
+);
+
+CREATE INDEX idx_group_user_user_id ON group_user(user_id);
+
+CREATE TABLE role_group (
+    id SERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    group_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (role_id, group_id)
+);
+
+CREATE INDEX idx_role_group_group_id ON role_group(group_id);
+
+COMMENT ON COLUMN "group".name IS 'Name of the group';
+COMMENT ON COLUMN "group".description IS 'Description of the group';
+COMMENT ON COLUMN "group".created_at IS 'Creation time';
+
+COMMENT ON COLUMN group_user.group_id IS 'Group ID';
+COMMENT ON COLUMN group_user.user_id IS 'User ID';
+COMMENT ON COLUMN group_user.created_at IS 'Creation time';
+
+COMMENT ON COLUMN role_group.role_id IS 'Role ID';
+COMMENT ON COLUMN role_group.group_id IS 'Group ID';
+COMMENT ON COLUMN role_group.created_at IS 'Creation time';
@@ -0,0 +1,20 @@
+ALTER TABLE node ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_node_labels ON node USING GIN (labels);
+
+CREATE TABLE role_selector (
+    id SERIAL PRIMARY KEY,
+    role_id BIGINT NOT NULL,
+    selector JSONB NOT NULL,
+    account VARCHAR(120) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_role_selector_role_id ON role_selector(role_id);
+
+COMMENT ON COLUMN node.labels IS 'Key/value labels of the node';
+
+COMMENT ON COLUMN role_selector.role_id IS 'Role ID';
+COMMENT ON COLUMN role_selector.selector IS 'Labels the nodes must have to be bound to the role';
+COMMENT ON COLUMN role_selector.account IS 'Account of the matched nodes, empty means the default account of the node';
+COMMENT ON COLUMN role_selector.created_at IS 'Creation time';
@@ -0,0 +1,5 @@
+ALTER TABLE role ADD COLUMN global BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_role_global ON role(global) WHERE global;
+
+COMMENT ON COLUMN role.global IS 'Global role binds nodes of all spaces, its space_id is 0';
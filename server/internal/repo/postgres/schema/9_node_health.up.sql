@@ -0,0 +1,22 @@
+ALTER TABLE space ADD COLUMN stale_after BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE space ADD COLUMN offline_after BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE node ADD COLUMN health VARCHAR(16) NOT NULL DEFAULT 'offline';
+
+CREATE INDEX idx_node_health ON node(health);
+
+CREATE TABLE node_heartbeat (
+    id SERIAL PRIMARY KEY,
+    node_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_node_heartbeat_node_id_created_at ON node_heartbeat(node_id, created_at);
+
+COMMENT ON COLUMN space.stale_after IS 'Seconds without heartbeat before the nodes are stale, 0 means the server default';
+COMMENT ON COLUMN space.offline_after IS 'Seconds without heartbeat before the nodes are offline, 0 means the server default';
+
+COMMENT ON COLUMN node.health IS 'Health of the node computed by the sweep: healthy, stale or offline';
+
+COMMENT ON COLUMN node_heartbeat.node_id IS 'Node ID';
+COMMENT ON COLUMN node_heartbeat.created_at IS 'Time of the successful sync';
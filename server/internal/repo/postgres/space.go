@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/sysarmor/guard/server/internal/model"
 	"github.com/sysarmor/guard/server/internal/repo"
 )
@@ -21,10 +22,14 @@ func NewSpace(br *baseRepo) repo.SpaceRepo {
 	}
 }
 
+// spaceFields is the column list of the space queries
+const spaceFields = `id, name, description, stale_after, offline_after, created_at`
+
 func (s *space) GetByName(ctx context.Context, name string) (*model.Space, error) {
 	var space model.Space
-	if err := s.queryRowContext(ctx, `SELECT id, name, description, created_at FROM space WHERE name = $1`,
-		name).Scan(&space.ID, &space.Name, &space.Description, &space.CreatedAt); err != nil {
+	if err := s.queryRowContext(ctx, `SELECT `+spaceFields+` FROM space WHERE name = $1`,
+		name).Scan(&space.ID, &space.Name, &space.Description, &space.StaleAfter, &space.OfflineAfter,
+		&space.CreatedAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -36,8 +41,9 @@ func (s *space) GetByName(ctx context.Context, name string) (*model.Space, error
 
 func (s *space) GetByID(ctx context.Context, spaceID int64) (*model.Space, error) {
 	var space model.Space
-	if err := s.queryRowContext(ctx, `SELECT id, name, description, created_at FROM space WHERE id = $1`,
-		spaceID).Scan(&space.ID, &space.Name, &space.Description, &space.CreatedAt); err != nil {
+	if err := s.queryRowContext(ctx, `SELECT `+spaceFields+` FROM space WHERE id = $1`,
+		spaceID).Scan(&space.ID, &space.Name, &space.Description, &space.StaleAfter, &space.OfflineAfter,
+		&space.CreatedAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -52,8 +58,9 @@ func (s *space) Create(ctx context.Context, space *model.Space) error {
 	space.CreatedAt = time.Now().Unix()
 
 	err := s.queryRowContext(ctx,
-		`INSERT INTO space (name, description, created_at) VALUES ($1, $2, $3) RETURNING id `,
-		space.Name, space.Description, space.CreatedAt).
+		`INSERT INTO space (name, description, stale_after, offline_after, created_at) 
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		space.Name, space.Description, space.StaleAfter, space.OfflineAfter, space.CreatedAt).
 		Scan(&space.ID)
 
 	if err != nil {
@@ -63,24 +70,65 @@ func (s *space) Create(ctx context.Context, space *model.Space) error {
 	return nil
 }
 
-// List returns all spaces.
-func (s *space) List(ctx context.Context) ([]*model.Space, error) {
-	rows, err := s.queryContext(ctx, `SELECT id, name, description, created_at FROM space`)
+// Update updates the name, description and heartbeat thresholds of the space.
+func (s *space) Update(ctx context.Context, space *model.Space) error {
+	_, err := s.execContext(ctx,
+		`UPDATE space SET name = $1, description = $2, stale_after = $3, offline_after = $4 WHERE id = $5`,
+		space.Name, space.Description, space.StaleAfter, space.OfflineAfter, space.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update space: %v", err)
+	}
+
+	return nil
+}
+
+// Delete deletes the space.
+func (s *space) Delete(ctx context.Context, spaceID int64) error {
+	_, err := s.execContext(ctx, `DELETE FROM space WHERE id = $1`, spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete space: %v", err)
+	}
+
+	return nil
+}
+
+var spaceColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+}
+
+// List returns the spaces.
+func (s *space) List(ctx context.Context, filter *repo.SpaceFilter, opt *repo.ListOption) ([]*model.Space, int64, error) {
+	c := &conditions{}
+	c.contains("name", filter.Name)
+
+	var total int64
+	if err := s.queryRowContext(ctx, `SELECT COUNT(id) FROM space`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count spaces: %v", err)
+	}
+
+	page, args := c.page(opt, spaceColumns, "id")
+	rows, err := s.queryContext(ctx, `SELECT `+spaceFields+` FROM space`+c.where()+page, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list spaces: %v", err)
+		return nil, 0, fmt.Errorf("failed to list spaces: %v", err)
 	}
 	defer rows.Close()
 
-	var spaces []*model.Space
+	spaces := make([]*model.Space, 0)
 	for rows.Next() {
 		var space model.Space
-		if err := rows.Scan(&space.ID, &space.Name, &space.Description, &space.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan space: %v", err)
+		var description sql.NullString
+		if err := rows.Scan(&space.ID, &space.Name, &description, &space.StaleAfter, &space.OfflineAfter,
+			&space.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan space: %v", err)
 		}
+		space.Description = description.String
 		spaces = append(spaces, &space)
 	}
 
-	return spaces, nil
+	return spaces, total, nil
 }
 
 // AddUser adds a user to the space.
@@ -153,3 +201,77 @@ func (s *space) ExistUser(ctx context.Context, spaceID, userID int64) (bool, err
 
 	return true, nil
 }
+
+// ListAdmin returns the admins of the space.
+func (s *space) ListAdmin(ctx context.Context, spaceID int64) ([]*model.User, error) {
+	rows, err := s.queryContext(ctx, `
+		SELECT u.id, u.username, u.email, u.state FROM space_admin sa
+		JOIN "user" u ON u.id = sa.user_id
+		WHERE sa.space_id = $1 ORDER BY sa.id`, spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admins of space: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		user := &model.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.State); err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// AddAdmin adds an admin to the space, it's ignored if the user is already an admin.
+func (s *space) AddAdmin(ctx context.Context, spaceID, userID int64) error {
+	_, err := s.execContext(ctx, `INSERT INTO space_admin (space_id, user_id, created_at) 
+		VALUES ($1, $2, $3) ON CONFLICT (space_id, user_id) DO NOTHING`,
+		spaceID, userID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to add admin to space: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAdmin removes admins from the space.
+func (s *space) RemoveAdmin(ctx context.Context, spaceID int64, userIDs ...int64) error {
+	sql := "DELETE FROM space_admin WHERE space_id = $1"
+	values := []interface{}{spaceID}
+
+	if len(userIDs) != 0 {
+		sql += " AND user_id = ANY($2)"
+		values = append(values, pq.Array(userIDs))
+	}
+
+	if _, err := s.execContext(ctx, sql, values...); err != nil {
+		return fmt.Errorf("failed to remove admin from space: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAdminByUserID removes the user from the admins of all spaces.
+func (s *space) RemoveAdminByUserID(ctx context.Context, userID int64) error {
+	if _, err := s.execContext(ctx, `DELETE FROM space_admin WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to remove admin by user id: %w", err)
+	}
+
+	return nil
+}
+
+// IsAdmin checks if the user is an admin of the space.
+func (s *space) IsAdmin(ctx context.Context, spaceID, userID int64) (bool, error) {
+	var exists bool
+	err := s.queryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM space_admin WHERE space_id = $1 AND user_id = $2)`,
+		spaceID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin of space: %w", err)
+	}
+
+	return exists, nil
+}
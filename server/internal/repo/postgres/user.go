@@ -19,44 +19,87 @@ func NewUser(br *baseRepo) repo.UserRepo {
 	return &user{baseRepo: br}
 }
 
+var userColumns = map[string]string{
+	"id":         "id",
+	"username":   "username",
+	"email":      "email",
+	"expires_at": "expires_at",
+	"created_at": "created_at",
+}
+
 // List list users
-func (u *user) List(ctx context.Context, offset, limit int64) ([]*model.User, error) {
+func (u *user) List(ctx context.Context, filter *repo.UserFilter, opt *repo.ListOption) ([]*model.User, int64, error) {
+	c := &conditions{}
+	c.contains("username", filter.Name)
+	c.contains("email", filter.Email)
+	if filter.State != "" {
+		c.add("state = ?", filter.State)
+	}
+
+	var total int64
+	if err := u.queryRowContext(ctx, `SELECT COUNT(id) FROM "user"`+c.where(), c.args...).
+		Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	page, args := c.page(opt, userColumns, "id DESC")
 	rows, err := u.queryContext(ctx, `
-		SELECT id, username, email, pub_key, ban, created_at, updated_at
-		FROM "user"
-		ORDER BY id DESC
-		OFFSET $1 LIMIT $2
-	`, offset, limit)
+		SELECT `+userFields+`
+		FROM "user"`+c.where()+page, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
+	defer rows.Close()
 
 	users := []*model.User{}
 	for rows.Next() {
-		user := &model.User{}
-
-		var ban sql.NullBool
-
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PubKey, &ban, &user.CreatedAt, &user.UpdatedAt)
+		user, err := u.scan(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, 0, err
 		}
 
-		user.Ban = ban.Bool
 		users = append(users, user)
 	}
 
-	return users, nil
+	return users, total, nil
+}
+
+// userFields is the column list scanned by scan
+const userFields = `id, username, email, pub_key, state, expires_at, created_at, updated_at`
+
+func (u *user) scan(row scanner) (*model.User, error) {
+	user := &model.User{}
+
+	var updated sql.NullInt64
+
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PubKey, &user.State, &user.ExpiresAt,
+		&user.CreatedAt, &updated)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	user.UpdatedAt = updated.Int64
+
+	return user, nil
 }
 
 // Create a new user
 func (u *user) Create(ctx context.Context, user *model.User) error {
 	user.CreatedAt = time.Now().Unix()
 
+	if user.State == "" {
+		user.State = model.UserStateActive
+	}
+
 	err := u.queryRowContext(ctx, `
-		INSERT INTO "user" (username, email, pub_key, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id
-	`, user.Username, user.Email, user.PubKey, user.CreatedAt, user.UpdatedAt).
+		INSERT INTO "user" (username, email, pub_key, state, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`, user.Username, user.Email, user.PubKey, user.State, user.ExpiresAt, user.CreatedAt, user.UpdatedAt).
 		Scan(&user.ID)
 
 	if err != nil {
@@ -67,72 +110,101 @@ func (u *user) Create(ctx context.Context, user *model.User) error {
 }
 
 func (u *user) GetByID(ctx context.Context, id int64) (*model.User, error) {
-	user := &model.User{}
-
-	var ban sql.NullBool
-
-	err := u.queryRowContext(ctx, `
-		SELECT id, username, email, pub_key, ban, created_at, updated_at
-		FROM "user"
-		WHERE id = $1
-	`, id).
-		Scan(&user.ID, &user.Username, &user.Email, &user.PubKey, &ban, &user.CreatedAt, &user.UpdatedAt)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-
+	user, err := u.scan(u.queryRowContext(ctx, `SELECT `+userFields+` FROM "user" WHERE id = $1`, id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
 
-	user.Ban = ban.Bool
+	return user, nil
+}
+
+// GetByIDForUpdate gets the user and locks the row until the end of the transaction
+func (u *user) GetByIDForUpdate(ctx context.Context, id int64) (*model.User, error) {
+	user, err := u.scan(u.queryRowContext(ctx, `SELECT `+userFields+` FROM "user" WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id for update: %w", err)
+	}
 
 	return user, nil
 }
 
 func (u *user) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	user := &model.User{}
+	user, err := u.scan(u.queryRowContext(ctx, `SELECT `+userFields+` FROM "user" WHERE email = $1`, email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
 
-	var ban sql.NullBool
-	var updated sql.NullInt64
+	return user, nil
+}
 
-	err := u.queryRowContext(ctx, `
-		SELECT id, username, email, pub_key, ban, created_at, updated_at
-		FROM "user"
-		WHERE email = $1
-	`, email).
-		Scan(&user.ID, &user.Username, &user.Email, &user.PubKey, &ban, &user.CreatedAt, &updated)
+// Update updates the username, email and expires_at of the user
+func (u *user) Update(ctx context.Context, user *model.User) error {
+	user.UpdatedAt = time.Now().Unix()
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
+	_, err := u.execContext(ctx, `
+		UPDATE "user"
+		SET username = $1,
+			email = $2,
+			expires_at = $3,
+			updated_at = $4
+		WHERE id = $5
+	`, user.Username, user.Email, user.ExpiresAt, user.UpdatedAt, user.ID)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by email: %w", err)
+		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	user.Ban = ban.Bool
-	user.UpdatedAt = updated.Int64
+	return nil
+}
 
-	return user, nil
+// Delete deletes the user
+func (u *user) Delete(ctx context.Context, id int64) error {
+	_, err := u.execContext(ctx, `DELETE FROM "user" WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
 }
 
-// Get user by username
-func (u *user) Ban(ctx context.Context, id int64) error {
+// UpdateState updates the state of the user
+func (u *user) UpdateState(ctx context.Context, id int64, state model.UserState) error {
 	_, err := u.execContext(ctx, `
 		UPDATE "user"
-		SET ban = true
-		WHERE id = $1
-	`, id)
+		SET state = $1,
+			updated_at = $2
+		WHERE id = $3
+	`, state, time.Now().Unix(), id)
 
 	if err != nil {
-		return fmt.Errorf("failed to ban user: %w", err)
+		return fmt.Errorf("failed to update user state: %w", err)
 	}
 
 	return nil
 }
 
+// ListExpired lists the active users which are expired at the time
+func (u *user) ListExpired(ctx context.Context, now int64) ([]*model.User, error) {
+	rows, err := u.queryContext(ctx, `SELECT `+userFields+` FROM "user" 
+		WHERE state = $1 AND expires_at > 0 AND expires_at <= $2`, model.UserStateActive, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		user, err := u.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 // Update PubKey of the user
 func (u *user) UpdatePubKey(ctx context.Context, id int64, pubKey string) error {
 	_, err := u.execContext(ctx, `
@@ -196,20 +268,26 @@ func (u *user) RevokeCert(ctx context.Context, id int64) error {
 	return nil
 }
 
-// RevokeAllCerts revokes all certs of the user
-func (u *user) RevokeAllCerts(ctx context.Context, userID int64) error {
-	_, err := u.execContext(ctx, `
+// RevokeAllCerts revokes all certs of the user, it returns the number
+// of the certs revoked, the already revoked ones are not counted
+func (u *user) RevokeAllCerts(ctx context.Context, userID int64) (int64, error) {
+	result, err := u.execContext(ctx, `
 		UPDATE user_cert
 		SET is_revoked = true,
 			updated_at = $1
-		WHERE user_id = $2
+		WHERE user_id = $2 AND is_revoked = false
 	`, time.Now().Unix(), userID)
 
 	if err != nil {
-		return fmt.Errorf("failed to revoke all certs: %w", err)
+		return 0, fmt.Errorf("failed to revoke all certs: %w", err)
 	}
 
-	return nil
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get revoked certs: %w", err)
+	}
+
+	return revoked, nil
 }
 
 // ListCerts lists all certs of the user
@@ -218,6 +296,7 @@ func (u *user) ListCerts(ctx context.Context, userID int64) ([]*model.UserCert,
 		SELECT id, user_id, cert, expires_at, is_revoked, created_at, updated_at
 		FROM user_cert
 		WHERE user_id = $1
+		ORDER BY id
 	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list certs: %w", err)
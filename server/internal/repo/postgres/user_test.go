@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// TestUserColumns checks the sort fields accepted by the user list are
+// sorted by, not by the default order
+func TestUserColumns(t *testing.T) {
+	for _, field := range []string{"id", "username", "email", "expires_at", "created_at"} {
+		c := &conditions{}
+		page, _ := c.page(&repo.ListOption{Sort: "-" + field}, userColumns, "id DESC")
+		if !strings.HasPrefix(page, " ORDER BY "+field+" DESC ") {
+			t.Errorf("%s is not sorted by: %q", field, page)
+		}
+	}
+}
@@ -6,9 +6,24 @@ type Repo interface {
 	BeginTx(ctx context.Context) (Repo, error)
 	CommitTx(ctx context.Context) error
 	RollbackTx(ctx context.Context) error
+	// Ping checks the database is reachable
+	Ping(ctx context.Context) error
+	// Listen calls fn when the data served to the nodes changes,
+	// it blocks until ctx is done
+	Listen(ctx context.Context, fn func()) error
+	// BeginSnapshot begins a read-only transaction, all its queries see
+	// the same snapshot of the database, it must be rolled back
+	BeginSnapshot(ctx context.Context) (Repo, error)
+	// Version returns the version of the data served to the nodes, it
+	// increases on every change
+	Version(ctx context.Context) (int64, error)
 
 	Node() NodeRepo
 	Role() RoleRepo
 	User() UserRepo
 	Space() SpaceRepo
+	Event() EventRepo
+	AccessRequest() AccessRequestRepo
+	BreakGlass() BreakGlassRepo
+	Group() GroupRepo
 }
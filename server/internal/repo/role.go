@@ -7,20 +7,42 @@ import (
 )
 
 type RoleRepo interface {
-	List(ctx context.Context, spaceID int64) ([]*model.Role, error)
+	List(ctx context.Context, filter *RoleFilter, opt *ListOption) ([]*model.Role, int64, error)
 	GetByID(ctx context.Context, id int64) (*model.Role, error)
+	GetByName(ctx context.Context, name string) (*model.Role, error)
 	Create(ctx context.Context, role *model.Role) error
+	Update(ctx context.Context, role *model.Role) error
 	Delete(ctx context.Context, id int64) error
 
 	ListRoleNodeByNodeID(ctx context.Context, nodeID int64) ([]*model.RoleNode, error)
-	ListNodeByRoleID(ctx context.Context, roleID int64) ([]*model.RoleNodeView, error)
+	ListNode(ctx context.Context, filter *RoleNodeFilter, opt *ListOption) ([]*model.RoleNodeView, int64, error)
 	AddNode(ctx context.Context, roleID, nodeID int64, account string) error
 	// RemoveNode remove node from role, if nodeID is empty, remove all nodes from role
 	RemoveNode(ctx context.Context, roleID int64, nodeIDs ...int64) error
+	RemoveNodeByNodeID(ctx context.Context, nodeID int64) error
 	GetRoleNodeByRoleIDAndNodeID(ctx context.Context, roleID, nodeID int64) (*model.RoleNode, error)
 
+	ListSelector(ctx context.Context, roleID int64) ([]*model.RoleSelector, error)
+	AddSelector(ctx context.Context, selector *model.RoleSelector) error
+	// RemoveSelector removes the selectors from the role, if selectorIDs
+	// is empty, remove all selectors from the role
+	RemoveSelector(ctx context.Context, roleID int64, selectorIDs ...int64) error
+
 	ListUserByRoleID(ctx context.Context, roleID int64) ([]*model.User, error)
-	AddUser(ctx context.Context, roleID, userID int64) error
+	// ListAccess lists the effective access of the active users to the nodes,
+	// a nil opt lists all of them
+	ListAccess(ctx context.Context, filter *AccessFilter, opt *ListOption) ([]*model.Access, int64, error)
+	ListUser(ctx context.Context, filter *RoleUserFilter, opt *ListOption) ([]*model.RoleUserView, int64, error)
+	AddUser(ctx context.Context, roleUser *model.RoleUser) error
+	// UpdateUser updates the valid window of the membership
+	UpdateUser(ctx context.Context, roleUser *model.RoleUser) error
+	// ListRoleUserByUserID lists the memberships of the user
+	ListRoleUserByUserID(ctx context.Context, userID int64) ([]*model.RoleUser, error)
+	// ListExpiredUser lists the temporary memberships which are ended at the time
+	ListExpiredUser(ctx context.Context, now int64) ([]*model.RoleUser, error)
+	// RemoveExpiredUser removes the membership by id only if it's still
+	// ended at the time, it reports whether the membership is removed
+	RemoveExpiredUser(ctx context.Context, id, now int64) (bool, error)
 	// RemoveUser remove user from role, if userID is empty, remove all users from role
 	RemoveUser(ctx context.Context, roleID int64, userIDs ...int64) error
 	RemoveUserByUserID(ctx context.Context, userID int64) error
@@ -28,4 +50,7 @@ type RoleRepo interface {
 
 	ListRevokedKeys(ctx context.Context, nodeID int64) ([]int64, error)
 	ListUserPublicKeyByRoleID(ctx context.Context, roleID int64) ([]string, error)
+	// ListNodeMember lists the accounts of the node and their members in
+	// one query, ordered by the account and the email
+	ListNodeMember(ctx context.Context, nodeID int64) ([]*model.NodeMember, error)
 }
@@ -11,5 +11,15 @@ type SpaceRepo interface {
 	GetByName(ctx context.Context, name string) (*model.Space, error)
 	GetByID(ctx context.Context, spaceID int64) (*model.Space, error)
 	Create(ctx context.Context, space *model.Space) error
-	List(ctx context.Context) ([]*model.Space, error)
+	Update(ctx context.Context, space *model.Space) error
+	Delete(ctx context.Context, spaceID int64) error
+	List(ctx context.Context, filter *SpaceFilter, opt *ListOption) ([]*model.Space, int64, error)
+
+	ListAdmin(ctx context.Context, spaceID int64) ([]*model.User, error)
+	AddAdmin(ctx context.Context, spaceID, userID int64) error
+	// RemoveAdmin removes admins from the space, if userIDs is empty,
+	// remove all admins from the space
+	RemoveAdmin(ctx context.Context, spaceID int64, userIDs ...int64) error
+	RemoveAdminByUserID(ctx context.Context, userID int64) error
+	IsAdmin(ctx context.Context, spaceID, userID int64) (bool, error)
 }
@@ -7,15 +7,23 @@ import (
 )
 
 type UserRepo interface {
-	List(ctx context.Context, offset, limit int64) ([]*model.User, error)
+	List(ctx context.Context, filter *UserFilter, opt *ListOption) ([]*model.User, int64, error)
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id int64) (*model.User, error)
+	// GetByIDForUpdate gets the user and locks the row until the end of the transaction
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
-	Ban(ctx context.Context, id int64) error
+	// Update updates the username, email and expires_at of the user
+	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, id int64) error
+	UpdateState(ctx context.Context, id int64, state model.UserState) error
+	// ListExpired lists the active users which are expired at the time
+	ListExpired(ctx context.Context, now int64) ([]*model.User, error)
 	UpdatePubKey(ctx context.Context, id int64, pubKey string) error
 	GrantCert(ctx context.Context, cert *model.UserCert) error
 	UpdateCert(ctx context.Context, id int64, cert string) error
 	RevokeCert(ctx context.Context, id int64) error
-	RevokeAllCerts(ctx context.Context, userID int64) error
+	// RevokeAllCerts returns the number of the certs revoked
+	RevokeAllCerts(ctx context.Context, userID int64) (int64, error)
 	ListCerts(ctx context.Context, userID int64) ([]*model.UserCert, error)
 }
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+const (
+	accessViaDirect = "direct"
+	accessViaGroup  = "group:"
+
+	accessBindingExplicit = "explicit"
+	accessBindingSelector = "selector"
+)
+
+// ListEffectiveAccess resolves the roles, groups and label selectors into the effective
+// (user, node, account) access of the node or the user, one item per role and
+// membership which grants the access
+func (g *guard) ListEffectiveAccess(ctx context.Context, in *ListEffectiveAccessRequest) (*ListEffectiveAccessResponse, error) {
+	if in.NodeID > 0 {
+		node, err := g.repo.Node().GetByID(ctx, in.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node by id: %w", err)
+		}
+
+		if node == nil {
+			return nil, errors.ErrNodeNotFound
+		}
+	}
+
+	if in.UserID > 0 {
+		user, err := g.repo.User().GetByID(ctx, in.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return nil, errors.ErrUserNotFound
+		}
+	}
+
+	// the csv export is not paged, it's the full list for the reviews
+	var opt *repo.ListOption
+	if in.Format != AccessFormatCSV {
+		opt = in.ListOption()
+	}
+
+	accesses, total, err := g.repo.Role().ListAccess(ctx, &repo.AccessFilter{
+		NodeID:  in.NodeID,
+		UserID:  in.UserID,
+		SpaceID: in.SpaceID,
+		Account: in.Account,
+	}, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access: %w", err)
+	}
+
+	var resp = &ListEffectiveAccessResponse{
+		Total:    total,
+		Accesses: make([]*AccessVO, 0, len(accesses)),
+	}
+	for _, access := range accesses {
+		via := accessViaDirect
+		if access.Group != "" {
+			via = accessViaGroup + access.Group
+		}
+
+		binding := accessBindingExplicit
+		if access.Dynamic {
+			binding = accessBindingSelector
+		}
+
+		resp.Accesses = append(resp.Accesses, &AccessVO{
+			UserID:     access.UserID,
+			Username:   access.Username,
+			Email:      access.Email,
+			NodeID:     access.NodeID,
+			NodeName:   access.NodeName,
+			SpaceID:    access.SpaceID,
+			Account:    access.Account,
+			RoleID:     access.RoleID,
+			RoleName:   access.RoleName,
+			Global:     access.Global,
+			Via:        via,
+			Binding:    binding,
+			ValidUntil: access.ValidUntil,
+			ExpiresAt:  access.ExpiresAt,
+			Until:      access.Until(),
+		})
+	}
+
+	return resp, nil
+}
+
+var accessCSVHeader = []string{
+	"user_id", "username", "email", "node_id", "node_name", "space_id", "account",
+	"role_id", "role_name", "global", "via", "binding", "valid_until", "expires_at", "until",
+}
+
+// WriteCSV writes the access list as csv with a header line, it's used
+// to export the access for the reviews
+func (r *ListEffectiveAccessResponse) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(accessCSVHeader); err != nil {
+		return err
+	}
+
+	for _, a := range r.Accesses {
+		if err := cw.Write([]string{
+			strconv.FormatInt(a.UserID, 10), a.Username, a.Email,
+			strconv.FormatInt(a.NodeID, 10), a.NodeName, strconv.FormatInt(a.SpaceID, 10), a.Account,
+			strconv.FormatInt(a.RoleID, 10), a.RoleName, strconv.FormatBool(a.Global), a.Via, a.Binding,
+			strconv.FormatInt(a.ValidUntil, 10), strconv.FormatInt(a.ExpiresAt, 10), strconv.FormatInt(a.Until, 10),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
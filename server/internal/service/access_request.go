@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// CreateAccessRequest files an access request for a role. If the operator
+// is bound to a user, the request is filed for the user itself.
+func (g *guard) CreateAccessRequest(ctx context.Context, in *CreateAccessRequestRequest) (int64, error) {
+	var request *model.AccessRequest
+	err := g.transaction(ctx, func(tx repo.Repo) error {
+		requester, err := operatorUser(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		userID := in.UserID
+		if requester != nil {
+			if userID != 0 && userID != requester.ID {
+				return errors.ErrForbidden
+			}
+			userID = requester.ID
+		}
+
+		if userID <= 0 {
+			return errors.ErrUserNotFound
+		}
+
+		role, err := tx.Role().GetByID(ctx, in.RoleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role by id: %w", err)
+		}
+
+		if role == nil {
+			return errors.ErrRoleNotFound
+		}
+
+		user, err := tx.User().GetByID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if user.IsBanned() {
+			return errors.ErrUserBanned
+		}
+
+		if !user.IsActive(time.Now().Unix()) {
+			return errors.ErrUserInactive
+		}
+
+		_, pending, err := tx.AccessRequest().List(ctx, &repo.AccessRequestFilter{
+			RoleID: role.ID,
+			UserID: user.ID,
+			State:  string(model.AccessRequestPending),
+		}, &repo.ListOption{Limit: 1})
+		if err != nil {
+			return fmt.Errorf("failed to list access requests: %w", err)
+		}
+
+		if pending > 0 {
+			return errors.ErrAccessRequestExists
+		}
+
+		request = &model.AccessRequest{
+			RoleID:   role.ID,
+			UserID:   user.ID,
+			Reason:   in.Reason,
+			Duration: in.Duration,
+			State:    model.AccessRequestPending,
+		}
+		if err := tx.AccessRequest().Create(ctx, request); err != nil {
+			return fmt.Errorf("failed to create access request: %w", err)
+		}
+
+		return logAccessRequest(ctx, tx, request, "", in.Reason)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return request.ID, nil
+}
+
+// ListAccessRequest lists the access requests
+func (g *guard) ListAccessRequest(ctx context.Context, in *ListAccessRequestRequest) (*ListAccessRequestResponse, error) {
+	filter := &repo.AccessRequestFilter{
+		RoleID: in.RoleID,
+		UserID: in.UserID,
+		State:  in.State,
+	}
+
+	if in.Reviewable {
+		approver, err := operatorUser(ctx, g.repo)
+		if err != nil {
+			return nil, err
+		}
+
+		if approver == nil {
+			return nil, errors.ErrNotApprover
+		}
+		filter.ApproverID = approver.ID
+	}
+
+	requests, total, err := g.repo.AccessRequest().List(ctx, filter, in.ListOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access requests: %w", err)
+	}
+
+	var resp = &ListAccessRequestResponse{
+		Total:    total,
+		Requests: make([]*AccessRequestVO, 0, len(requests)),
+	}
+	for _, request := range requests {
+		resp.Requests = append(resp.Requests, newAccessRequestVO(request))
+	}
+
+	return resp, nil
+}
+
+// GetAccessRequest returns the access request with its state transitions
+func (g *guard) GetAccessRequest(ctx context.Context, id int64) (*GetAccessRequestResponse, error) {
+	request, err := g.repo.AccessRequest().GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request by id: %w", err)
+	}
+
+	if request == nil {
+		return nil, errors.ErrAccessRequestNotFound
+	}
+
+	logs, err := g.repo.AccessRequest().ListLog(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access request logs: %w", err)
+	}
+
+	var resp = &GetAccessRequestResponse{
+		AccessRequestVO: *newAccessRequestVO(request),
+		Logs:            make([]*AccessRequestLogVO, 0, len(logs)),
+	}
+	for _, log := range logs {
+		resp.Logs = append(resp.Logs, &AccessRequestLogVO{
+			FromState: log.FromState,
+			ToState:   log.ToState,
+			Operator:  log.Operator,
+			Comment:   log.Comment,
+			CreatedAt: log.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// ApproveAccessRequest approves the access request, the user is added to
+// the role until the requested duration, or the shorter duration given by
+// the approver, passes
+func (g *guard) ApproveAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		request, err := reviewableAccessRequest(ctx, tx, in.RequestID)
+		if err != nil {
+			return err
+		}
+
+		role, err := tx.Role().GetByID(ctx, request.RoleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role by id: %w", err)
+		}
+
+		if role == nil {
+			return errors.ErrRoleNotFound
+		}
+
+		// the request is closed first, the concurrent reviews wait for it
+		// and fail, so the membership is granted only once
+		request.State = model.AccessRequestApproved
+		request.Reviewer = OperatorFrom(ctx).String()
+		request.Comment = in.Comment
+		if err := closeAccessRequest(ctx, tx, request); err != nil {
+			return err
+		}
+
+		duration := request.Duration
+		if in.Duration > 0 && in.Duration < duration {
+			duration = in.Duration
+		}
+
+		now := time.Now().Unix()
+		return addUserToRole(ctx, tx, role.ID, request.UserID, now, now+duration)
+	})
+}
+
+// DenyAccessRequest denies the access request
+func (g *guard) DenyAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		request, err := reviewableAccessRequest(ctx, tx, in.RequestID)
+		if err != nil {
+			return err
+		}
+
+		request.State = model.AccessRequestDenied
+		request.Reviewer = OperatorFrom(ctx).String()
+		request.Comment = in.Comment
+		return closeAccessRequest(ctx, tx, request)
+	})
+}
+
+// CancelAccessRequest cancels the pending access request, only the requester
+// or an operator not bound to a user can cancel it
+func (g *guard) CancelAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		request, err := pendingAccessRequest(ctx, tx, in.RequestID)
+		if err != nil {
+			return err
+		}
+
+		operator, err := operatorUser(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if operator != nil && operator.ID != request.UserID {
+			return errors.ErrForbidden
+		}
+
+		request.State = model.AccessRequestCancelled
+		request.Comment = in.Comment
+		return closeAccessRequest(ctx, tx, request)
+	})
+}
+
+// pendingAccessRequest returns the access request if it's pending
+func pendingAccessRequest(ctx context.Context, tx repo.Repo, id int64) (*model.AccessRequest, error) {
+	request, err := tx.AccessRequest().GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request by id: %w", err)
+	}
+
+	if request == nil {
+		return nil, errors.ErrAccessRequestNotFound
+	}
+
+	if request.State != model.AccessRequestPending {
+		return nil, errors.ErrAccessRequestClosed
+	}
+
+	return request, nil
+}
+
+// reviewableAccessRequest returns the pending access request if the operator
+// is an active approver of the role, the requester can not review its own
+// request
+func reviewableAccessRequest(ctx context.Context, tx repo.Repo, id int64) (*model.AccessRequest, error) {
+	request, err := pendingAccessRequest(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	approver, err := operatorUser(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if approver == nil || approver.ID == request.UserID {
+		return nil, errors.ErrNotApprover
+	}
+
+	if !approver.IsActive(time.Now().Unix()) {
+		return nil, errors.ErrUserInactive
+	}
+
+	ok, err := tx.AccessRequest().IsApprover(ctx, request.RoleID, approver.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check approver: %w", err)
+	}
+
+	if !ok {
+		return nil, errors.ErrNotApprover
+	}
+
+	return request, nil
+}
+
+// closeAccessRequest moves the pending request to its new state and logs
+// the transition, it fails if another review has closed the request since
+// it was read
+func closeAccessRequest(ctx context.Context, tx repo.Repo, request *model.AccessRequest) error {
+	ok, err := tx.AccessRequest().UpdateState(ctx, request, model.AccessRequestPending)
+	if err != nil {
+		return fmt.Errorf("failed to update access request to %s: %w", request.State, err)
+	}
+
+	if !ok {
+		return errors.ErrAccessRequestClosed
+	}
+
+	return logAccessRequest(ctx, tx, request, model.AccessRequestPending, request.Comment)
+}
+
+// logAccessRequest records the transition of the request to its current state
+func logAccessRequest(ctx context.Context, tx repo.Repo, request *model.AccessRequest, from model.AccessRequestState, comment string) error {
+	operator := OperatorFrom(ctx).String()
+	if err := tx.AccessRequest().CreateLog(ctx, &model.AccessRequestLog{
+		RequestID: request.ID,
+		FromState: from,
+		ToState:   request.State,
+		Operator:  operator,
+		Comment:   comment,
+	}); err != nil {
+		return fmt.Errorf("failed to log access request: %w", err)
+	}
+
+	slog.InfoContext(ctx, "access request", "id", request.ID, "role_id", request.RoleID, "user_id", request.UserID,
+		"from", from, "to", request.State, "operator", operator)
+	return nil
+}
+
+func newAccessRequestVO(request *model.AccessRequest) *AccessRequestVO {
+	return &AccessRequestVO{
+		ID:        request.ID,
+		RoleID:    request.RoleID,
+		UserID:    request.UserID,
+		Reason:    request.Reason,
+		Duration:  request.Duration,
+		State:     request.State,
+		Reviewer:  request.Reviewer,
+		Comment:   request.Comment,
+		CreatedAt: request.CreatedAt,
+		UpdatedAt: request.UpdatedAt,
+	}
+}
+
+// ListRoleApprover lists the approvers of the role
+func (g *guard) ListRoleApprover(ctx context.Context, roleID int64) (*ListRoleApproverResponse, error) {
+	users, err := g.repo.AccessRequest().ListApprover(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvers: %w", err)
+	}
+
+	var resp = &ListRoleApproverResponse{
+		Approvers: make([]*RoleUserListVO, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.Approvers = append(resp.Approvers, &RoleUserListVO{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			State:    user.State,
+		})
+	}
+
+	return resp, nil
+}
+
+// AddApproverToRole adds the approvers to the role
+func (g *guard) AddApproverToRole(ctx context.Context, in *AddApproverToRoleRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		role, err := tx.Role().GetByID(ctx, in.RoleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role by id: %w", err)
+		}
+
+		if role == nil {
+			return errors.ErrRoleNotFound
+		}
+
+		for _, userID := range in.UserIDs {
+			user, err := tx.User().GetByID(ctx, userID)
+			if err != nil {
+				return fmt.Errorf("failed to get user by id: %w", err)
+			}
+
+			if user == nil {
+				return errors.ErrUserNotFound
+			}
+
+			if err := tx.AccessRequest().AddApprover(ctx, role.ID, user.ID); err != nil {
+				return fmt.Errorf("failed to add approver: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "add approver to role", "role", role.Name, "user_ids", in.UserIDs)
+		return nil
+	})
+}
+
+// RemoveApproverFromRole removes the approvers from the role
+func (g *guard) RemoveApproverFromRole(ctx context.Context, in *RemoveApproverFromRoleRequest) error {
+	if err := g.repo.AccessRequest().RemoveApprover(ctx, in.RoleID, in.UserIDs...); err != nil {
+		return fmt.Errorf("failed to remove approver: %w", err)
+	}
+
+	return nil
+}
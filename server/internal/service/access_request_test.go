@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// newAccessRequestDB returns the repo with the pending request 1 of the
+// user 1 to the role 1, which the user 2 approves
+func newAccessRequestDB() *memDB {
+	db := newMemDB(1, 2, 3)
+	db.data.addRole(model.Role{ID: 1, SpaceID: 1})
+	db.data.approvers = []model.RoleApprover{{ID: 1, RoleID: 1, UserID: 2}}
+	db.data.accessRequests = []model.AccessRequest{
+		{ID: 1, RoleID: 1, UserID: 1, Duration: 3600, State: model.AccessRequestPending},
+	}
+	return db
+}
+
+// accessRequestState returns the state of the request
+func accessRequestState(db *memDB, id int64) model.AccessRequestState {
+	return find(db.data.accessRequests, func(ar *model.AccessRequest) bool { return ar.ID == id }).State
+}
+
+func TestAccessRequestTransition(t *testing.T) {
+	requester := WithOperator(context.Background(), &Operator{Email: "user-1@example.com"})
+	approver := WithOperator(context.Background(), &Operator{Email: "user-2@example.com"})
+	other := WithOperator(context.Background(), &Operator{Email: "user-3@example.com"})
+
+	review := &ReviewAccessRequestRequest{RequestID: 1}
+
+	tests := []struct {
+		name string
+		// setup changes the data before the steps
+		setup func(d *memData)
+		steps []func(g *guard) error
+		errs  []error
+		state model.AccessRequestState
+		// members is the number of the memberships granted
+		members int
+	}{
+		{
+			name:    "approve",
+			steps:   []func(g *guard) error{func(g *guard) error { return g.ApproveAccessRequest(approver, review) }},
+			errs:    []error{nil},
+			state:   model.AccessRequestApproved,
+			members: 1,
+		},
+		{
+			name: "deny after approve",
+			steps: []func(g *guard) error{
+				func(g *guard) error { return g.ApproveAccessRequest(approver, review) },
+				func(g *guard) error { return g.DenyAccessRequest(approver, review) },
+			},
+			errs:    []error{nil, errors.ErrAccessRequestClosed},
+			state:   model.AccessRequestApproved,
+			members: 1,
+		},
+		{
+			name: "approve after cancel",
+			steps: []func(g *guard) error{
+				func(g *guard) error { return g.CancelAccessRequest(requester, review) },
+				func(g *guard) error { return g.ApproveAccessRequest(approver, review) },
+			},
+			errs:  []error{nil, errors.ErrAccessRequestClosed},
+			state: model.AccessRequestCancelled,
+		},
+		{
+			name:  "approve own request",
+			steps: []func(g *guard) error{func(g *guard) error { return g.ApproveAccessRequest(requester, review) }},
+			errs:  []error{errors.ErrNotApprover},
+			state: model.AccessRequestPending,
+		},
+		{
+			name:  "deny by not approver",
+			steps: []func(g *guard) error{func(g *guard) error { return g.DenyAccessRequest(other, review) }},
+			errs:  []error{errors.ErrNotApprover},
+			state: model.AccessRequestPending,
+		},
+		{
+			name:  "approve by suspended approver",
+			setup: func(d *memData) { d.users[1].State = model.UserStateSuspended },
+			steps: []func(g *guard) error{func(g *guard) error { return g.ApproveAccessRequest(approver, review) }},
+			errs:  []error{errors.ErrUserInactive},
+			state: model.AccessRequestPending,
+		},
+		{
+			name:  "deny by expired approver",
+			setup: func(d *memData) { d.users[1].ExpiresAt = time.Now().Unix() - 1 },
+			steps: []func(g *guard) error{func(g *guard) error { return g.DenyAccessRequest(approver, review) }},
+			errs:  []error{errors.ErrUserInactive},
+			state: model.AccessRequestPending,
+		},
+		{
+			name:  "cancel by other user",
+			steps: []func(g *guard) error{func(g *guard) error { return g.CancelAccessRequest(other, review) }},
+			errs:  []error{errors.ErrForbidden},
+			state: model.AccessRequestPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newAccessRequestDB()
+			if tt.setup != nil {
+				tt.setup(db.data)
+			}
+			g := &guard{repo: db}
+
+			for i, step := range tt.steps {
+				if err := step(g); !stderrors.Is(err, tt.errs[i]) {
+					t.Fatalf("step %d: expected error %v, got %v", i, tt.errs[i], err)
+				}
+			}
+
+			if state := accessRequestState(db, 1); state != tt.state {
+				t.Fatalf("expected state %s, got %s", tt.state, state)
+			}
+
+			if len(db.data.roleUsers) != tt.members {
+				t.Fatalf("expected %d memberships, got %d", tt.members, len(db.data.roleUsers))
+			}
+		})
+	}
+}
+
+// TestAccessRequestConcurrentReview reviews a request read before another
+// review closed it, the stale review fails and is not logged
+func TestAccessRequestConcurrentReview(t *testing.T) {
+	db := newAccessRequestDB()
+	approver := WithOperator(context.Background(), &Operator{Email: "user-2@example.com"})
+
+	stale, err := pendingAccessRequest(approver, db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &guard{repo: db}
+	if err := g.ApproveAccessRequest(approver, &ReviewAccessRequestRequest{RequestID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale.State = model.AccessRequestDenied
+	if err := closeAccessRequest(approver, db, stale); !stderrors.Is(err, errors.ErrAccessRequestClosed) {
+		t.Fatalf("expected error %v, got %v", errors.ErrAccessRequestClosed, err)
+	}
+
+	if state := accessRequestState(db, 1); state != model.AccessRequestApproved {
+		t.Fatalf("expected state %s, got %s", model.AccessRequestApproved, state)
+	}
+
+	if len(db.data.accessRequestLogs) != 1 {
+		t.Fatalf("expected 1 transition logged, got %d", len(db.data.accessRequestLogs))
+	}
+}
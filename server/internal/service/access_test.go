@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// newAccessDB returns the repo with the users 1-3, the user 3 is expired.
+// The nodes web and db of the space 1 are bound to the role ops as root, web
+// is bound to the role deploy as deploy, and the node other of the space 2
+// is bound to the global role audit. The users 1 and 3 are in ops until an
+// hour later, the user 2 is in deploy and in ops by the group dba, the user
+// 1 is in audit.
+func newAccessDB(now int64) *memDB {
+	db := newMemDB(1, 2, 3)
+	db.data.users[2].ExpiresAt = now - 1
+	db.data.nodes = []model.Node{
+		{ID: 1, SpaceID: 1, Name: "web"},
+		{ID: 2, SpaceID: 1, Name: "db"},
+		{ID: 3, SpaceID: 2, Name: "other"},
+	}
+	db.data.addRole(model.Role{ID: 1, SpaceID: 1, Name: "ops"})
+	db.data.addRole(model.Role{ID: 2, SpaceID: 1, Name: "deploy"})
+	db.data.addRole(model.Role{ID: 3, Global: true, Name: "audit"})
+	db.data.roleNodes = []model.RoleNode{
+		{ID: 1, RoleID: 1, NodeID: 1, Account: "root"},
+		{ID: 2, RoleID: 1, NodeID: 2, Account: "root"},
+		{ID: 3, RoleID: 2, NodeID: 1, Account: "deploy"},
+		{ID: 4, RoleID: 3, NodeID: 3, Account: "root"},
+	}
+	db.data.roleUsers = []model.RoleUser{
+		{ID: 1, RoleID: 1, UserID: 1, ValidUntil: now + 3600},
+		{ID: 2, RoleID: 1, UserID: 3, ValidUntil: now + 3600},
+		{ID: 3, RoleID: 2, UserID: 2},
+		{ID: 4, RoleID: 3, UserID: 1},
+	}
+	db.data.groups = []model.Group{{ID: 4, Name: "dba"}}
+	db.data.groupUsers = []model.GroupUser{{ID: 1, GroupID: 4, UserID: 2}}
+	db.data.roleGroups = []model.RoleGroup{{ID: 1, RoleID: 1, GroupID: 4}}
+	db.data.reserve(4)
+	return db
+}
+
+func TestListEffectiveAccess(t *testing.T) {
+	now := time.Now().Unix()
+	hour := fmt.Sprint(now + 3600)
+
+	tests := []struct {
+		name string
+		in   *ListEffectiveAccessRequest
+		// expected is the access as "user node account role via until"
+		expected []string
+		total    int64
+		err      error
+	}{
+		{name: "node", in: &ListEffectiveAccessRequest{NodeID: 1}, total: 3, expected: []string{
+			"user-1 web root ops direct " + hour,
+			"user-2 web deploy deploy direct 0",
+			"user-2 web root ops group:dba 0",
+		}},
+		{name: "node account", in: &ListEffectiveAccessRequest{NodeID: 1, Account: "root"}, total: 2, expected: []string{
+			"user-1 web root ops direct " + hour,
+			"user-2 web root ops group:dba 0",
+		}},
+		{name: "user", in: &ListEffectiveAccessRequest{UserID: 1}, total: 3, expected: []string{
+			"user-1 db root ops direct " + hour,
+			"user-1 web root ops direct " + hour,
+			"user-1 other root audit direct 0",
+		}},
+		{name: "user space", in: &ListEffectiveAccessRequest{UserID: 1, SpaceID: 2}, total: 1, expected: []string{
+			"user-1 other root audit direct 0",
+		}},
+		{name: "expired user", in: &ListEffectiveAccessRequest{UserID: 3}, expected: []string{}},
+		{name: "first page", in: &ListEffectiveAccessRequest{UserID: 2, PageRequest: PageRequest{Limit: 2}}, total: 3, expected: []string{
+			"user-2 db root ops group:dba 0",
+			"user-2 web deploy deploy direct 0",
+		}},
+		{name: "second page", in: &ListEffectiveAccessRequest{UserID: 2, PageRequest: PageRequest{Page: 2, Limit: 2}}, total: 3, expected: []string{
+			"user-2 web root ops group:dba 0",
+		}},
+		{name: "out of pages", in: &ListEffectiveAccessRequest{UserID: 2, PageRequest: PageRequest{Page: 3, Limit: 2}}, total: 3, expected: []string{}},
+		{name: "csv not paged", in: &ListEffectiveAccessRequest{UserID: 2, Format: AccessFormatCSV, PageRequest: PageRequest{Page: 2, Limit: 1}}, total: 3, expected: []string{
+			"user-2 db root ops group:dba 0",
+			"user-2 web deploy deploy direct 0",
+			"user-2 web root ops group:dba 0",
+		}},
+		{name: "unknown node", in: &ListEffectiveAccessRequest{NodeID: 9}, err: errors.ErrNodeNotFound},
+		{name: "unknown user", in: &ListEffectiveAccessRequest{UserID: 9}, err: errors.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.in.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			g := &guard{repo: newAccessDB(now)}
+			resp, err := g.ListEffectiveAccess(context.Background(), tt.in)
+			if !stderrors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			accesses := make([]string, 0, len(resp.Accesses))
+			for _, a := range resp.Accesses {
+				accesses = append(accesses, fmt.Sprintf("%s %s %s %s %s %d", a.Username, a.NodeName, a.Account, a.RoleName, a.Via, a.Until))
+			}
+			if !reflect.DeepEqual(accesses, tt.expected) {
+				t.Fatalf("expected %q, got %q", tt.expected, accesses)
+			}
+			if resp.Total != tt.total {
+				t.Fatalf("expected the total %d, got %d", tt.total, resp.Total)
+			}
+		})
+	}
+}
+
+func TestListEffectiveAccessValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      ListEffectiveAccessRequest
+		wantErr bool
+	}{
+		{name: "node", in: ListEffectiveAccessRequest{NodeID: 1}},
+		{name: "csv", in: ListEffectiveAccessRequest{UserID: 1, Format: AccessFormatCSV}},
+		{name: "sort", in: ListEffectiveAccessRequest{UserID: 1, PageRequest: PageRequest{Sort: "-until"}}},
+		{name: "no subject", in: ListEffectiveAccessRequest{SpaceID: 1}, wantErr: true},
+		{name: "unknown format", in: ListEffectiveAccessRequest{NodeID: 1, Format: "xml"}, wantErr: true},
+		{name: "unknown sort", in: ListEffectiveAccessRequest{NodeID: 1, PageRequest: PageRequest{Sort: "pub_key"}}, wantErr: true},
+		{name: "limit", in: ListEffectiveAccessRequest{NodeID: 1, PageRequest: PageRequest{Limit: maxPageLimit + 1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.in.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestWriteCSV checks the header order and the fields with the separators,
+// the quotes and the line breaks are quoted
+func TestWriteCSV(t *testing.T) {
+	resp := &ListEffectiveAccessResponse{
+		Total: 2,
+		Accesses: []*AccessVO{
+			{UserID: 1, Username: "alice", Email: "alice@example.com", NodeID: 2, NodeName: "web", SpaceID: 3,
+				Account: "root", RoleID: 4, RoleName: "ops", Via: "direct", Binding: "explicit", ValidUntil: 100, Until: 100},
+			{UserID: 5, Username: `bob "b", jr`, Email: "bob@example.com", NodeID: 6, NodeName: "db\nprimary", SpaceID: 0,
+				Account: "deploy", RoleID: 7, RoleName: "audit", Global: true, Via: "group:a,b", Binding: "selector", ExpiresAt: 200, Until: 200},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := resp.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "user_id,username,email,node_id,node_name,space_id,account,role_id,role_name,global,via,binding,valid_until,expires_at,until\n" +
+		"1,alice,alice@example.com,2,web,3,root,4,ops,false,direct,explicit,100,0,100\n" +
+		"5,\"bob \"\"b\"\", jr\",bob@example.com,6,\"db\nprimary\",0,deploy,7,audit,true,\"group:a,b\",selector,0,200,200\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
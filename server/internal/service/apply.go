@@ -0,0 +1,1012 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	err "github.com/sysarmor/guard/server/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Apply applies the state in one transaction, the objects missing in the
+// server are created and the changed ones are updated. The objects and the
+// bindings not in the state are removed only if prune is set. With plan,
+// the changes are rolled back and only reported.
+func (g *guard) Apply(ctx context.Context, in *ApplyRequest) (*ApplyResponse, error) {
+	a := &applier{prune: in.Prune}
+	diffs, err := g.diffTx(ctx, nil, !in.Plan, func(tx *guard) error {
+		a.g = tx
+		return a.apply(ctx, in.State)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ApplyResponse{
+		Plan:    in.Plan,
+		Changes: a.changes,
+		Nodes:   a.nodes,
+		Diffs:   diffs,
+	}, nil
+}
+
+// applier applies the state with the service bound to the transaction,
+// the changes are made through the service methods one by one
+type applier struct {
+	g     *guard
+	prune bool
+
+	changes []string
+	nodes   []*AppliedNode
+
+	users  map[string]*model.User
+	groups map[string]*model.Group
+	spaces map[string]*model.Space
+	roles  map[string]*model.Role
+	// spaceNodes are the nodes of the spaces by space id
+	spaceNodes map[int64][]*model.Node
+}
+
+// paramError returns the param error of the invalid state
+func paramError(format string, args ...interface{}) error {
+	return err.New(errors.ParamError, fmt.Sprintf(format, args...))
+}
+
+func (a *applier) change(op, format string, args ...interface{}) {
+	a.changes = append(a.changes, op+" "+fmt.Sprintf(format, args...))
+}
+
+func (a *applier) apply(ctx context.Context, state *State) error {
+	a.changes = make([]string, 0)
+	if err := a.load(ctx); err != nil {
+		return err
+	}
+
+	if err := a.applyUsers(ctx, state.Users); err != nil {
+		return err
+	}
+
+	if err := a.applyGroups(ctx, state.Groups); err != nil {
+		return err
+	}
+
+	if err := a.applySpaces(ctx, state.Spaces); err != nil {
+		return err
+	}
+
+	// the roles are applied after all nodes, the global roles
+	// bind the nodes of any space
+	managed := make(map[int64]bool)
+	for _, s := range state.Spaces {
+		space := a.spaces[s.Name]
+		for _, r := range s.Roles {
+			role, err := a.applyRole(ctx, space, r)
+			if err != nil {
+				return err
+			}
+			managed[role.ID] = true
+		}
+	}
+
+	for _, r := range state.GlobalRoles {
+		role, err := a.applyRole(ctx, nil, r)
+		if err != nil {
+			return err
+		}
+		managed[role.ID] = true
+	}
+
+	if !a.prune {
+		return nil
+	}
+
+	for _, name := range sortedKeys(a.roles) {
+		role := a.roles[name]
+		if managed[role.ID] {
+			continue
+		}
+
+		if err := a.g.DeleteRole(ctx, role.ID); err != nil {
+			return fmt.Errorf("failed to delete role %s: %w", name, err)
+		}
+		a.change("-", "role %s", name)
+	}
+
+	return nil
+}
+
+// load loads the current objects of the server
+func (a *applier) load(ctx context.Context) error {
+	tx := a.g.repo
+
+	users, _, err := tx.User().List(ctx, &repo.UserFilter{}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+	a.users = make(map[string]*model.User, len(users))
+	for _, user := range users {
+		a.users[user.Email] = user
+	}
+
+	groups, _, err := tx.Group().List(ctx, &repo.GroupFilter{}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+	a.groups = make(map[string]*model.Group, len(groups))
+	for _, group := range groups {
+		a.groups[group.Name] = group
+	}
+
+	spaces, _, err := tx.Space().List(ctx, &repo.SpaceFilter{}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list spaces: %w", err)
+	}
+	a.spaces = make(map[string]*model.Space, len(spaces))
+	a.spaceNodes = make(map[int64][]*model.Node, len(spaces))
+	a.roles = make(map[string]*model.Role)
+	for _, space := range spaces {
+		a.spaces[space.Name] = space
+
+		nodes, _, err := tx.Node().List(ctx, &repo.NodeFilter{SpaceID: space.ID}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list nodes: %w", err)
+		}
+		a.spaceNodes[space.ID] = nodes
+
+		if err := a.loadRoles(ctx, &repo.RoleFilter{SpaceID: space.ID}); err != nil {
+			return err
+		}
+	}
+
+	return a.loadRoles(ctx, &repo.RoleFilter{Global: true})
+}
+
+func (a *applier) loadRoles(ctx context.Context, filter *repo.RoleFilter) error {
+	roles, _, err := a.g.repo.Role().List(ctx, filter, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	for _, role := range roles {
+		a.roles[role.Name] = role
+	}
+
+	return nil
+}
+
+func (a *applier) applyUsers(ctx context.Context, users []*StateUser) error {
+	managed := make(map[string]bool, len(users))
+	for _, u := range users {
+		managed[u.Email] = true
+
+		user, ok := a.users[u.Email]
+		if !ok {
+			req := &CreateUserRequest{
+				Username:  u.Username,
+				Email:     u.Email,
+				PublicKey: u.PublicKey,
+				ExpiresAt: u.ExpiresAt,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("user %s: %w", u.Email, err)
+			}
+
+			id, err := a.g.CreateUser(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to create user %s: %w", u.Email, err)
+			}
+
+			a.users[u.Email] = &model.User{ID: id, Username: u.Username, Email: u.Email}
+			a.change("+", "user %s", u.Email)
+			continue
+		}
+
+		var fields []string
+		req := &UpdateUserRequest{UserID: user.ID}
+		if u.Username != user.Username {
+			req.Username = &u.Username
+			fields = append(fields, "username")
+		}
+		if u.ExpiresAt != user.ExpiresAt {
+			req.ExpiresAt = &u.ExpiresAt
+			fields = append(fields, "expires_at")
+		}
+		if req.Username != nil || req.ExpiresAt != nil {
+			if err := a.g.UpdateUser(ctx, req); err != nil {
+				return fmt.Errorf("failed to update user %s: %w", u.Email, err)
+			}
+		}
+
+		if u.PublicKey != "" && strings.TrimSpace(u.PublicKey) != strings.TrimSpace(user.PubKey) {
+			if err := a.g.UpdateUserPublicKey(ctx, &UpdateUserPublicKeyRequest{
+				UserID:    user.ID,
+				PublicKey: u.PublicKey,
+			}); err != nil {
+				return fmt.Errorf("failed to update public key of user %s: %w", u.Email, err)
+			}
+			fields = append(fields, "public_key")
+		}
+
+		if len(fields) > 0 {
+			a.change("~", "user %s (%s)", u.Email, strings.Join(fields, ", "))
+		}
+	}
+
+	if !a.prune {
+		return nil
+	}
+
+	for _, email := range sortedKeys(a.users) {
+		user := a.users[email]
+		if managed[email] {
+			continue
+		}
+
+		if err := a.g.DeleteUser(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete user %s: %w", email, err)
+		}
+		delete(a.users, email)
+		a.change("-", "user %s", email)
+	}
+
+	return nil
+}
+
+// userIDs returns the ids of the users by emails, the users must exist
+func (a *applier) userIDs(owner string, emails []string) ([]int64, error) {
+	ids := make([]int64, 0, len(emails))
+	for _, email := range emails {
+		user, ok := a.users[email]
+		if !ok {
+			return nil, paramError("%s: unknown user %s", owner, email)
+		}
+		ids = append(ids, user.ID)
+	}
+
+	return ids, nil
+}
+
+func (a *applier) applyGroups(ctx context.Context, groups []*StateGroup) error {
+	managed := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		managed[g.Name] = true
+
+		group, ok := a.groups[g.Name]
+		if !ok {
+			id, err := a.g.CreateGroup(ctx, &CreateGroupRequest{Name: g.Name, Description: g.Description})
+			if err != nil {
+				return fmt.Errorf("failed to create group %s: %w", g.Name, err)
+			}
+
+			group = &model.Group{ID: id, Name: g.Name, Description: g.Description}
+			a.groups[g.Name] = group
+			a.change("+", "group %s", g.Name)
+		} else if group.Description != g.Description {
+			if err := a.g.UpdateGroup(ctx, &UpdateGroupRequest{
+				GroupID:     group.ID,
+				Description: &g.Description,
+			}); err != nil {
+				return fmt.Errorf("failed to update group %s: %w", g.Name, err)
+			}
+			a.change("~", "group %s (description)", g.Name)
+		}
+
+		want, err := a.userIDs("group "+g.Name, g.Users)
+		if err != nil {
+			return err
+		}
+
+		members, _, err := a.g.repo.Group().ListUser(ctx, &repo.GroupUserFilter{GroupID: group.ID}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list users of group %s: %w", g.Name, err)
+		}
+
+		have := make([]int64, 0, len(members))
+		for _, member := range members {
+			have = append(have, member.ID)
+		}
+
+		if add := subtract(want, have); len(add) > 0 {
+			if err := a.g.AddUserToGroup(ctx, &AddUserToGroupRequest{GroupID: group.ID, UserIDs: add}); err != nil {
+				return fmt.Errorf("failed to add users to group %s: %w", g.Name, err)
+			}
+			a.change("+", "group %s users %s", g.Name, a.emails(add))
+		}
+
+		if remove := subtract(have, want); a.prune && len(remove) > 0 {
+			if err := a.g.RemoveUserFromGroup(ctx, &RemoveUserFromGroupRequest{GroupID: group.ID, UserIDs: remove}); err != nil {
+				return fmt.Errorf("failed to remove users from group %s: %w", g.Name, err)
+			}
+			a.change("-", "group %s users %s", g.Name, a.emails(remove))
+		}
+	}
+
+	if !a.prune {
+		return nil
+	}
+
+	for _, name := range sortedKeys(a.groups) {
+		group := a.groups[name]
+		if managed[name] {
+			continue
+		}
+
+		if err := a.g.DeleteGroup(ctx, group.ID); err != nil {
+			return fmt.Errorf("failed to delete group %s: %w", name, err)
+		}
+		delete(a.groups, name)
+		a.change("-", "group %s", name)
+	}
+
+	return nil
+}
+
+func (a *applier) applySpaces(ctx context.Context, spaces []*StateSpace) error {
+	managed := make(map[string]bool, len(spaces))
+	for _, s := range spaces {
+		managed[s.Name] = true
+
+		space, ok := a.spaces[s.Name]
+		if !ok {
+			id, err := a.g.CreateSpace(ctx, &CreateSpaceRequest{Name: s.Name, Description: s.Description})
+			if err != nil {
+				return fmt.Errorf("failed to create space %s: %w", s.Name, err)
+			}
+
+			space = &model.Space{ID: id, Name: s.Name, Description: s.Description}
+			a.spaces[s.Name] = space
+			a.change("+", "space %s", s.Name)
+		} else if space.Description != s.Description {
+			if err := a.g.UpdateSpace(ctx, &UpdateSpaceRequest{
+				SpaceID:     space.ID,
+				Description: &s.Description,
+			}); err != nil {
+				return fmt.Errorf("failed to update space %s: %w", s.Name, err)
+			}
+			a.change("~", "space %s (description)", s.Name)
+		}
+
+		if err := a.applyNodes(ctx, space, s.Nodes); err != nil {
+			return err
+		}
+	}
+
+	if !a.prune {
+		return nil
+	}
+
+	for _, name := range sortedKeys(a.spaces) {
+		space := a.spaces[name]
+		if managed[name] {
+			continue
+		}
+
+		if err := a.g.DeleteSpace(ctx, &DeleteSpaceRequest{SpaceID: space.ID, Force: true}); err != nil {
+			return fmt.Errorf("failed to delete space %s: %w", name, err)
+		}
+
+		// the roles of the space are deleted with it
+		for roleName, role := range a.roles {
+			if !role.Global && role.SpaceID == space.ID {
+				delete(a.roles, roleName)
+			}
+		}
+		delete(a.spaces, name)
+		a.change("-", "space %s", name)
+	}
+
+	return nil
+}
+
+func (a *applier) applyNodes(ctx context.Context, space *model.Space, nodes []*StateNode) error {
+	managed := make(map[int64]bool, len(nodes))
+	for _, n := range nodes {
+		accounts := n.Accounts
+		if len(accounts) == 0 {
+			accounts = []string{defaultAccount}
+		}
+
+		key := n.UniqueID
+		if key == "" {
+			key = n.Name
+		}
+
+		node, err := a.findNode(space, key)
+		if err != nil {
+			return err
+		}
+
+		if node == nil {
+			if n.UniqueID != "" {
+				return paramError("node %s/%s: unique id %s not found", space.Name, n.Name, n.UniqueID)
+			}
+
+			req := &CreateNodeRequest{
+				SpaceID:     space.ID,
+				Name:        n.Name,
+				Description: n.Description,
+				IP:          n.IP,
+				Accounts:    accounts,
+				Labels:      n.Labels,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("node %s/%s: %w", space.Name, n.Name, err)
+			}
+
+			resp, err := a.g.CreateNode(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to create node %s/%s: %w", space.Name, n.Name, err)
+			}
+
+			node = &model.Node{
+				ID:       resp.ID,
+				SpaceID:  space.ID,
+				Name:     n.Name,
+				UniqueID: resp.UniqueID,
+				Accounts: accounts,
+				Labels:   n.Labels,
+			}
+			a.spaceNodes[space.ID] = append(a.spaceNodes[space.ID], node)
+			a.nodes = append(a.nodes, &AppliedNode{
+				Space:    space.Name,
+				Name:     n.Name,
+				UniqueID: resp.UniqueID,
+				Secret:   resp.Secret,
+			})
+			a.change("+", "node %s/%s", space.Name, n.Name)
+			managed[node.ID] = true
+			continue
+		}
+		managed[node.ID] = true
+
+		var fields []string
+		req := &UpdateNodeRequest{SpaceID: space.ID, NodeID: node.ID, Force: a.prune}
+		if n.Name != node.Name {
+			req.Name = &n.Name
+			fields = append(fields, "name")
+		}
+		if n.Description != node.Description {
+			req.Description = &n.Description
+			fields = append(fields, "description")
+		}
+		if n.IP != node.IP {
+			req.IP = &n.IP
+			fields = append(fields, "ip")
+		}
+		if !slices.Equal(accounts, node.Accounts) {
+			req.Accounts = accounts
+			fields = append(fields, "accounts")
+		}
+		if n.Labels.String() != node.Labels.String() {
+			req.Labels = n.Labels
+			if req.Labels == nil {
+				req.Labels = model.Labels{}
+			}
+			fields = append(fields, "labels")
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := a.g.UpdateNode(ctx, req); err != nil {
+			return fmt.Errorf("failed to update node %s/%s: %w", space.Name, n.Name, err)
+		}
+
+		node.Name, node.Accounts, node.Labels = n.Name, accounts, n.Labels
+		a.change("~", "node %s/%s (%s)", space.Name, n.Name, strings.Join(fields, ", "))
+	}
+
+	if !a.prune {
+		return nil
+	}
+
+	kept := make([]*model.Node, 0, len(managed))
+	for _, node := range a.spaceNodes[space.ID] {
+		if managed[node.ID] {
+			kept = append(kept, node)
+			continue
+		}
+
+		if err := a.g.DeleteNode(ctx, &DeleteNodeRequest{SpaceID: space.ID, NodeID: node.ID}); err != nil {
+			return fmt.Errorf("failed to delete node %s/%s: %w", space.Name, node.Name, err)
+		}
+		a.change("-", "node %s/%s", space.Name, node.Name)
+	}
+	a.spaceNodes[space.ID] = kept
+
+	return nil
+}
+
+// findNode finds the node of the space by unique id or by name,
+// the name must be unique in the space
+func (a *applier) findNode(space *model.Space, key string) (*model.Node, error) {
+	var found *model.Node
+	for _, node := range a.spaceNodes[space.ID] {
+		if node.UniqueID == key {
+			return node, nil
+		}
+
+		if node.Name == key {
+			if found != nil {
+				return nil, paramError("node %s/%s: name is ambiguous, use the unique id", space.Name, key)
+			}
+			found = node
+		}
+	}
+
+	return found, nil
+}
+
+// applyRole applies the role of the space, or the global role if space is nil
+func (a *applier) applyRole(ctx context.Context, space *model.Space, r *StateRole) (*model.Role, error) {
+	role, ok := a.roles[r.Name]
+	switch {
+	case !ok:
+		req := &CreateRoleRequest{Name: r.Name, Description: r.Description, BreakGlass: r.BreakGlass, Global: space == nil}
+		if space != nil {
+			req.SpaceID = space.ID
+		}
+
+		id, err := a.g.CreateRole(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create role %s: %w", r.Name, err)
+		}
+
+		role = &model.Role{ID: id, SpaceID: req.SpaceID, Name: r.Name, Global: req.Global}
+		a.roles[r.Name] = role
+		a.change("+", "role %s", r.Name)
+	case space == nil && !role.Global, space != nil && (role.Global || role.SpaceID != space.ID):
+		return nil, paramError("role %s already exists in another space", r.Name)
+	case role.Description != r.Description || role.BreakGlass != r.BreakGlass:
+		if err := a.g.UpdateRole(ctx, &UpdateRoleRequest{
+			SpaceID:     role.SpaceID,
+			RoleID:      role.ID,
+			Description: &r.Description,
+			BreakGlass:  &r.BreakGlass,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to update role %s: %w", r.Name, err)
+		}
+		a.change("~", "role %s (description, break_glass)", r.Name)
+	}
+
+	if err := a.applyRoleNodes(ctx, space, role, r.Nodes); err != nil {
+		return nil, err
+	}
+
+	if err := a.applyRoleSelectors(ctx, role, r.Selectors); err != nil {
+		return nil, err
+	}
+
+	if err := a.applyRoleUsers(ctx, role, r.Users); err != nil {
+		return nil, err
+	}
+
+	if err := a.applyRoleGroups(ctx, role, r.Groups); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (a *applier) applyRoleNodes(ctx context.Context, space *model.Space, role *model.Role, nodes []*StateRoleNode) error {
+	want := make(map[int64]string, len(nodes))
+	names := make(map[int64]string, len(nodes))
+	for _, n := range nodes {
+		s := space
+		if n.Space != "" {
+			s = a.spaces[n.Space]
+		}
+		if s == nil {
+			return paramError("role %s: unknown space of node %s", role.Name, n.Node)
+		}
+
+		node, err := a.findNode(s, n.Node)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			return paramError("role %s: unknown node %s/%s", role.Name, s.Name, n.Node)
+		}
+
+		account := n.Account
+		if account == "" {
+			account = node.Accounts[0]
+		}
+		want[node.ID] = account
+		names[node.ID] = s.Name + "/" + node.Name
+	}
+
+	dynamic := false
+	bound, _, err := a.g.repo.Role().ListNode(ctx, &repo.RoleNodeFilter{RoleID: role.ID, Dynamic: &dynamic}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes of role %s: %w", role.Name, err)
+	}
+
+	have := make(map[int64]string, len(bound))
+	for _, b := range bound {
+		have[b.ID] = b.Account
+		if _, ok := want[b.ID]; !ok && a.prune {
+			if err := a.g.RemoveNodeFromRole(ctx, &RemoveNodeFromRoleRequest{RoleID: role.ID, NodeIDs: []int64{b.ID}}); err != nil {
+				return fmt.Errorf("failed to remove node from role %s: %w", role.Name, err)
+			}
+			a.change("-", "role %s node %s (%s)", role.Name, b.Name, b.Account)
+		}
+	}
+
+	ids := make([]int64, 0, len(want))
+	for id := range want {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		account, ok := have[id]
+		if ok && account == want[id] {
+			continue
+		}
+
+		op := "+"
+		if ok {
+			// the account is changed, rebind the node
+			if err := a.g.RemoveNodeFromRole(ctx, &RemoveNodeFromRoleRequest{RoleID: role.ID, NodeIDs: []int64{id}}); err != nil {
+				return fmt.Errorf("failed to remove node from role %s: %w", role.Name, err)
+			}
+			op = "~"
+		}
+
+		if err := a.g.AddNodeToRole(ctx, &AddNodeToRoleRequest{
+			RoleID: role.ID,
+			Nodes:  RoleNodeListRequest{{NodeID: id, Account: want[id]}},
+		}); err != nil {
+			return fmt.Errorf("failed to add node to role %s: %w", role.Name, err)
+		}
+		a.change(op, "role %s node %s (%s)", role.Name, names[id], want[id])
+	}
+
+	return nil
+}
+
+func (a *applier) applyRoleSelectors(ctx context.Context, role *model.Role, selectors []*StateRoleSelector) error {
+	current, err := a.g.repo.Role().ListSelector(ctx, role.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list selectors of role %s: %w", role.Name, err)
+	}
+
+	key := func(selector model.Labels, account string) string {
+		return selector.String() + " (" + account + ")"
+	}
+
+	have := make(map[string]bool, len(current))
+	want := make(map[string]bool, len(selectors))
+	for _, s := range selectors {
+		want[key(s.Selector, s.Account)] = true
+	}
+
+	for _, s := range current {
+		k := key(s.Selector, s.Account)
+		have[k] = true
+		if want[k] || !a.prune {
+			continue
+		}
+
+		if err := a.g.RemoveRoleSelector(ctx, &RemoveRoleSelectorRequest{RoleID: role.ID, SelectorIDs: []int64{s.ID}}); err != nil {
+			return fmt.Errorf("failed to remove selector from role %s: %w", role.Name, err)
+		}
+		a.change("-", "role %s selector %s", role.Name, k)
+	}
+
+	for _, s := range selectors {
+		k := key(s.Selector, s.Account)
+		if have[k] {
+			continue
+		}
+
+		if _, err := a.g.AddRoleSelector(ctx, &AddRoleSelectorRequest{
+			RoleID:   role.ID,
+			Selector: s.Selector,
+			Account:  s.Account,
+		}); err != nil {
+			return fmt.Errorf("failed to add selector to role %s: %w", role.Name, err)
+		}
+		have[k] = true
+		a.change("+", "role %s selector %s", role.Name, k)
+	}
+
+	return nil
+}
+
+func (a *applier) applyRoleUsers(ctx context.Context, role *model.Role, emails []string) error {
+	want, err := a.userIDs("role "+role.Name, emails)
+	if err != nil {
+		return err
+	}
+
+	members, _, err := a.g.repo.Role().ListUser(ctx, &repo.RoleUserFilter{RoleID: role.ID}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list users of role %s: %w", role.Name, err)
+	}
+
+	// only the permanent direct memberships are managed by the state
+	have := make([]int64, 0, len(members))
+	for _, member := range members {
+		if member.Direct && member.ValidFrom == 0 && member.ValidUntil == 0 {
+			have = append(have, member.ID)
+		}
+	}
+
+	if add := subtract(want, have); len(add) > 0 {
+		if err := a.g.AddUserToRole(ctx, &AddUserToRoleRequest{RoleID: role.ID, UserIDs: add}); err != nil {
+			return fmt.Errorf("failed to add users to role %s: %w", role.Name, err)
+		}
+		a.change("+", "role %s users %s", role.Name, a.emails(add))
+	}
+
+	if remove := subtract(have, want); a.prune && len(remove) > 0 {
+		if err := a.g.RemoveUserFromRole(ctx, &RemoveUserFromRoleRequest{RoleID: role.ID, UserIDs: remove}); err != nil {
+			return fmt.Errorf("failed to remove users from role %s: %w", role.Name, err)
+		}
+		a.change("-", "role %s users %s", role.Name, a.emails(remove))
+	}
+
+	return nil
+}
+
+func (a *applier) applyRoleGroups(ctx context.Context, role *model.Role, names []string) error {
+	want := make([]int64, 0, len(names))
+	for _, name := range names {
+		group, ok := a.groups[name]
+		if !ok {
+			return paramError("role %s: unknown group %s", role.Name, name)
+		}
+		want = append(want, group.ID)
+	}
+
+	groups, err := a.g.repo.Group().ListByRoleID(ctx, role.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list groups of role %s: %w", role.Name, err)
+	}
+
+	have := make([]int64, 0, len(groups))
+	for _, group := range groups {
+		have = append(have, group.ID)
+	}
+
+	if add := subtract(want, have); len(add) > 0 {
+		if err := a.g.AddGroupToRole(ctx, &AddGroupToRoleRequest{RoleID: role.ID, GroupIDs: add}); err != nil {
+			return fmt.Errorf("failed to add groups to role %s: %w", role.Name, err)
+		}
+		a.change("+", "role %s groups %s", role.Name, a.groupNames(add))
+	}
+
+	if remove := subtract(have, want); a.prune && len(remove) > 0 {
+		if err := a.g.RemoveGroupFromRole(ctx, &RemoveGroupFromRoleRequest{RoleID: role.ID, GroupIDs: remove}); err != nil {
+			return fmt.Errorf("failed to remove groups from role %s: %w", role.Name, err)
+		}
+		a.change("-", "role %s groups %s", role.Name, a.groupNames(remove))
+	}
+
+	return nil
+}
+
+func (a *applier) emails(ids []int64) string {
+	emails := make([]string, 0, len(ids))
+	for _, user := range a.users {
+		if slices.Contains(ids, user.ID) {
+			emails = append(emails, user.Email)
+		}
+	}
+	slices.Sort(emails)
+
+	return strings.Join(emails, ", ")
+}
+
+func (a *applier) groupNames(ids []int64) string {
+	names := make([]string, 0, len(ids))
+	for _, group := range a.groups {
+		if slices.Contains(ids, group.ID) {
+			names = append(names, group.Name)
+		}
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+// sortedKeys returns the keys of the map in order, the objects
+// are pruned in order so that the changes are stable
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// subtract returns the ids in a but not in b
+func subtract(a, b []int64) []int64 {
+	ids := make([]int64, 0)
+	for _, id := range a {
+		if !slices.Contains(b, id) && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Export returns the current state of the server, it can be applied
+// to bootstrap the state file. The temporary memberships are not included.
+func (g *guard) Export(ctx context.Context) (*State, error) {
+	a := &applier{g: g}
+	if err := a.load(ctx); err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	for _, user := range a.users {
+		state.Users = append(state.Users, &StateUser{
+			Email:     user.Email,
+			Username:  user.Username,
+			PublicKey: user.PubKey,
+			ExpiresAt: user.ExpiresAt,
+		})
+	}
+	slices.SortFunc(state.Users, func(x, y *StateUser) int { return strings.Compare(x.Email, y.Email) })
+
+	for _, group := range a.groups {
+		members, _, err := g.repo.Group().ListUser(ctx, &repo.GroupUserFilter{GroupID: group.ID}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list users of group %s: %w", group.Name, err)
+		}
+
+		sg := &StateGroup{Name: group.Name, Description: group.Description}
+		for _, member := range members {
+			sg.Users = append(sg.Users, member.Email)
+		}
+		slices.Sort(sg.Users)
+		state.Groups = append(state.Groups, sg)
+	}
+	slices.SortFunc(state.Groups, func(x, y *StateGroup) int { return strings.Compare(x.Name, y.Name) })
+
+	spaces := make(map[int64]*StateSpace, len(a.spaces))
+	for _, space := range a.spaces {
+		ss := &StateSpace{Name: space.Name, Description: space.Description}
+		for _, node := range a.spaceNodes[space.ID] {
+			ss.Nodes = append(ss.Nodes, &StateNode{
+				Name:        node.Name,
+				UniqueID:    node.UniqueID,
+				Description: node.Description,
+				IP:          node.IP,
+				Accounts:    node.Accounts,
+				Labels:      node.Labels,
+			})
+		}
+		slices.SortFunc(ss.Nodes, func(x, y *StateNode) int {
+			return strings.Compare(x.Name+"/"+x.UniqueID, y.Name+"/"+y.UniqueID)
+		})
+
+		spaces[space.ID] = ss
+		state.Spaces = append(state.Spaces, ss)
+	}
+	slices.SortFunc(state.Spaces, func(x, y *StateSpace) int { return strings.Compare(x.Name, y.Name) })
+
+	for _, role := range a.roles {
+		sr, err := a.exportRole(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+
+		if role.Global {
+			state.GlobalRoles = append(state.GlobalRoles, sr)
+		} else if ss, ok := spaces[role.SpaceID]; ok {
+			ss.Roles = append(ss.Roles, sr)
+		}
+	}
+
+	byName := func(x, y *StateRole) int { return strings.Compare(x.Name, y.Name) }
+	slices.SortFunc(state.GlobalRoles, byName)
+	for _, ss := range state.Spaces {
+		slices.SortFunc(ss.Roles, byName)
+	}
+
+	return state, nil
+}
+
+func (a *applier) exportRole(ctx context.Context, role *model.Role) (*StateRole, error) {
+	sr := &StateRole{Name: role.Name, Description: role.Description, BreakGlass: role.BreakGlass}
+
+	spaceNames := make(map[int64]string, len(a.spaces))
+	for _, space := range a.spaces {
+		spaceNames[space.ID] = space.Name
+	}
+
+	dynamic := false
+	bound, _, err := a.g.repo.Role().ListNode(ctx, &repo.RoleNodeFilter{RoleID: role.ID, Dynamic: &dynamic}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes of role %s: %w", role.Name, err)
+	}
+
+	for _, b := range bound {
+		rn := &StateRoleNode{Node: b.Name, Account: b.Account}
+		if role.Global {
+			rn.Space = spaceNames[b.SpaceID]
+		}
+
+		// the unique id is used if the name is ambiguous in the space
+		space := &model.Space{ID: b.SpaceID, Name: spaceNames[b.SpaceID]}
+		if node, err := a.findNode(space, b.Name); err != nil || node == nil || node.ID != b.ID {
+			rn.Node = b.UniqueID
+		}
+		sr.Nodes = append(sr.Nodes, rn)
+	}
+
+	selectors, err := a.g.repo.Role().ListSelector(ctx, role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list selectors of role %s: %w", role.Name, err)
+	}
+
+	for _, s := range selectors {
+		sr.Selectors = append(sr.Selectors, &StateRoleSelector{Selector: s.Selector, Account: s.Account})
+	}
+
+	members, _, err := a.g.repo.Role().ListUser(ctx, &repo.RoleUserFilter{RoleID: role.ID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users of role %s: %w", role.Name, err)
+	}
+
+	for _, member := range members {
+		if member.Direct && member.ValidFrom == 0 && member.ValidUntil == 0 {
+			sr.Users = append(sr.Users, member.Email)
+		}
+	}
+	slices.Sort(sr.Users)
+
+	groups, err := a.g.repo.Group().ListByRoleID(ctx, role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups of role %s: %w", role.Name, err)
+	}
+
+	for _, group := range groups {
+		sr.Groups = append(sr.Groups, group.Name)
+	}
+	slices.Sort(sr.Groups)
+
+	return sr, nil
+}
+
+// DecodeState decodes the state in yaml or json, the unknown fields
+// are rejected to catch the typos
+func DecodeState(r io.Reader) (*State, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var state State
+	if e := decoder.Decode(&state); e != nil {
+		if e == io.EOF {
+			return nil, err.New(errors.ParamError, "state is empty")
+		}
+		return nil, err.New(errors.ParamError, fmt.Sprintf("invalid state: %s", e))
+	}
+
+	return &state, nil
+}
+
+// Encode writes the state as yaml
+func (s *State) Encode(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// testState is a state with two users, a group and a space with the roles
+// binding the nodes to a user and the group
+func testState() *State {
+	return &State{
+		Users: []*StateUser{
+			{Email: "alice@example.com", Username: "alice", PublicKey: "ssh-ed25519 alice"},
+			{Email: "bob@example.com", Username: "bob", PublicKey: "ssh-ed25519 bob"},
+		},
+		Groups: []*StateGroup{
+			{Name: "ops", Users: []string{"bob@example.com"}},
+		},
+		Spaces: []*StateSpace{
+			{
+				Name: "prod",
+				Nodes: []*StateNode{
+					{Name: "web", IP: "10.0.0.1"},
+					{Name: "db", IP: "10.0.0.2", Accounts: []string{"postgres"}},
+				},
+				Roles: []*StateRole{
+					{Name: "web-admin", Nodes: []*StateRoleNode{{Node: "web"}}, Users: []string{"alice@example.com"}},
+					{Name: "db-ops", Nodes: []*StateRoleNode{{Node: "db"}}, Groups: []string{"ops"}},
+				},
+			},
+		},
+	}
+}
+
+func newApplyGuard() (*guard, *memDB) {
+	db := newMemDB()
+	g := &guard{repo: db, notifier: nopNotifier{}}
+	if err := g.nodeHealth.Validate(); err != nil {
+		panic(err)
+	}
+	return g, db
+}
+
+func TestApplyPlan(t *testing.T) {
+	ctx := context.Background()
+	g, db := newApplyGuard()
+
+	resp, err := g.Apply(ctx, &ApplyRequest{State: testState(), Plan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.Plan || len(resp.Changes) == 0 {
+		t.Fatalf("expected the planned changes, got %v", resp.Changes)
+	}
+
+	if len(resp.Diffs) != 2 {
+		t.Fatalf("expected 2 principals diffs, got %d", len(resp.Diffs))
+	}
+
+	if len(db.data.users) != 0 || len(db.data.spaces) != 0 || len(db.data.roles) != 0 {
+		t.Fatalf("expected nothing applied by the plan, got %d users, %d spaces, %d roles",
+			len(db.data.users), len(db.data.spaces), len(db.data.roles))
+	}
+
+	applied, err := g.Apply(ctx, &ApplyRequest{State: testState()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(applied.Changes, resp.Changes) {
+		t.Fatalf("expected the changes of the plan %v, got %v", resp.Changes, applied.Changes)
+	}
+
+	if len(db.data.users) != 2 || len(db.data.nodes) != 2 || len(db.data.roles) != 2 {
+		t.Fatalf("expected the state applied, got %d users, %d nodes, %d roles",
+			len(db.data.users), len(db.data.nodes), len(db.data.roles))
+	}
+
+	if len(applied.Nodes) != 2 || applied.Nodes[0].UniqueID == "" || applied.Nodes[0].Secret == "" {
+		t.Fatalf("expected the unique ids and the secrets of the created nodes, got %v", applied.Nodes)
+	}
+
+	expected := map[string][]string{
+		"web": {"+alice@example.com"},
+		"db":  {"+bob@example.com"},
+	}
+	for _, diff := range applied.Diffs {
+		if !slices.Equal(diff.Diff, expected[diff.NodeName]) {
+			t.Fatalf("expected the diff %v of node %s, got %v", expected[diff.NodeName], diff.NodeName, diff.Diff)
+		}
+	}
+}
+
+func TestApplyIdempotent(t *testing.T) {
+	ctx := context.Background()
+	g, db := newApplyGuard()
+
+	if _, err := g.Apply(ctx, &ApplyRequest{State: testState(), Prune: true}); err != nil {
+		t.Fatal(err)
+	}
+	lastID := db.data.lastID
+
+	for _, prune := range []bool{false, true} {
+		resp, err := g.Apply(ctx, &ApplyRequest{State: testState(), Prune: prune})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resp.Changes) != 0 || len(resp.Diffs) != 0 || len(resp.Nodes) != 0 {
+			t.Fatalf("prune %v: expected no changes, got %v, diffs %d", prune, resp.Changes, len(resp.Diffs))
+		}
+	}
+
+	if db.data.lastID != lastID {
+		t.Fatalf("expected nothing created by the re-apply, last id %d, got %d", lastID, db.data.lastID)
+	}
+}
+
+func TestApplyPrune(t *testing.T) {
+	ctx := context.Background()
+	g, db := newApplyGuard()
+
+	state := testState()
+	state.Users = append(state.Users, &StateUser{Email: "carol@example.com", Username: "carol", PublicKey: "ssh-ed25519 carol"})
+	state.Groups = append(state.Groups, &StateGroup{Name: "dev"})
+	state.Spaces = append(state.Spaces, &StateSpace{
+		Name:  "staging",
+		Nodes: []*StateNode{{Name: "app", IP: "10.0.1.1"}},
+		Roles: []*StateRole{{Name: "app-admin", Nodes: []*StateRoleNode{{Node: "app"}}, Users: []string{"carol@example.com"}}},
+	})
+	state.GlobalRoles = []*StateRole{{Name: "auditor", Nodes: []*StateRoleNode{{Space: "prod", Node: "web"}}}}
+	if _, err := g.Apply(ctx, &ApplyRequest{State: state}); err != nil {
+		t.Fatal(err)
+	}
+
+	// without prune the objects missing in the state are kept
+	resp, err := g.Apply(ctx, &ApplyRequest{State: testState()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Changes) != 0 {
+		t.Fatalf("expected no changes without prune, got %v", resp.Changes)
+	}
+
+	state = testState()
+	state.Spaces[0].Nodes = state.Spaces[0].Nodes[:1]
+	state.Spaces[0].Roles = state.Spaces[0].Roles[:1]
+	resp, err = g.Apply(ctx, &ApplyRequest{State: state, Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the users go first and the roles last, the roles of the deleted
+	// space are deleted with it
+	expected := []string{
+		"- user carol@example.com",
+		"- group dev",
+		"- node prod/db",
+		"- space staging",
+		"- role auditor",
+		"- role db-ops",
+	}
+	if !slices.Equal(resp.Changes, expected) {
+		t.Fatalf("expected the changes %v, got %v", expected, resp.Changes)
+	}
+
+	if len(db.data.spaces) != 1 || len(db.data.nodes) != 1 || len(db.data.roles) != 1 || len(db.data.groups) != 1 {
+		t.Fatalf("expected the objects pruned, got %d spaces, %d nodes, %d roles, %d groups",
+			len(db.data.spaces), len(db.data.nodes), len(db.data.roles), len(db.data.groups))
+	}
+
+	// the principals of the nodes deleted or unbound are removed
+	expectedDiffs := map[string][]string{
+		"db":  {"-bob@example.com"},
+		"app": {"-carol@example.com"},
+	}
+	if len(resp.Diffs) != len(expectedDiffs) {
+		t.Fatalf("expected %d principals diffs, got %d", len(expectedDiffs), len(resp.Diffs))
+	}
+	for _, diff := range resp.Diffs {
+		if !slices.Equal(diff.Diff, expectedDiffs[diff.NodeName]) {
+			t.Fatalf("expected the diff %v of node %s, got %v", expectedDiffs[diff.NodeName], diff.NodeName, diff.Diff)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// BreakGlass grants the emergency access to a break-glass role without
+// approval. The user joins the role and gets a cert for a short time,
+// the admins of the space are notified and a review item is opened,
+// which must be acknowledged afterwards.
+func (g *guard) BreakGlass(ctx context.Context, in *BreakGlassRequest) (*BreakGlassResponse, error) {
+	var role *model.Role
+	var user *model.User
+	var bg *model.BreakGlass
+	var cert *GrantCertResponse
+
+	err := g.transaction(ctx, func(tx repo.Repo) error {
+		operator, err := operatorUser(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		userID := in.UserID
+		if operator != nil {
+			if userID != 0 && userID != operator.ID {
+				return errors.ErrForbidden
+			}
+			userID = operator.ID
+		}
+
+		role, err = tx.Role().GetByID(ctx, in.RoleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role by id: %w", err)
+		}
+
+		if role == nil {
+			return errors.ErrRoleNotFound
+		}
+
+		if !role.BreakGlass {
+			return errors.ErrRoleNotBreakGlass
+		}
+
+		// no space admin reviews the break-glass of a global role
+		if role.Global {
+			return errors.ErrGlobalRoleBreakGlass
+		}
+
+		// the row of the user is locked, so the concurrent break-glass
+		// of the user are counted one by one
+		user, err = tx.User().GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if user.IsBanned() {
+			return errors.ErrUserBanned
+		}
+
+		now := time.Now()
+		if !user.IsActive(now.Unix()) {
+			return errors.ErrUserInactive
+		}
+
+		count, err := tx.BreakGlass().CountByUserID(ctx, user.ID, now.Add(-g.breakGlass.Window).Unix())
+		if err != nil {
+			return fmt.Errorf("failed to count break glass: %w", err)
+		}
+
+		if count >= int64(g.breakGlass.Limit) {
+			return errors.ErrBreakGlassLimited
+		}
+
+		expiresAt := now.Add(g.breakGlass.Duration).Unix()
+		if err := addUserToRole(ctx, tx, role.ID, user.ID, now.Unix(), expiresAt); err != nil {
+			return err
+		}
+
+		bg = &model.BreakGlass{
+			RoleID:    role.ID,
+			SpaceID:   role.SpaceID,
+			UserID:    user.ID,
+			Reason:    in.Reason,
+			Operator:  OperatorFrom(ctx).String(),
+			ExpiresAt: expiresAt,
+		}
+		if err := tx.BreakGlass().Create(ctx, bg); err != nil {
+			return fmt.Errorf("failed to create break glass: %w", err)
+		}
+
+		// the cert is granted in the transaction, the membership is
+		// rolled back if it fails
+		txg := *g
+		txg.repo = tx
+		cert, err = txg.GrantCert(ctx, &GrantCertRequest{
+			UserID: user.ID,
+			Effect: int64(g.breakGlass.Duration.Seconds()),
+			RoleID: role.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return emit(ctx, tx, &model.Event{
+			Type:    model.EventBreakGlassGranted,
+			SpaceID: role.SpaceID,
+			RoleID:  role.ID,
+			UserID:  user.ID,
+			Message: fmt.Sprintf("break-glass %d by %s: %s", bg.ID, bg.Operator, in.Reason),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g.notifyBreakGlass(ctx, role, user, bg)
+
+	return &BreakGlassResponse{
+		ID:        bg.ID,
+		Cert:      cert.Cert,
+		ExpiresAt: bg.ExpiresAt,
+	}, nil
+}
+
+// notifyBreakGlass notifies the admins of the space, the failure is only
+// logged, the access is already granted
+func (g *guard) notifyBreakGlass(ctx context.Context, role *model.Role, user *model.User, bg *model.BreakGlass) {
+	admins, err := g.repo.Space().ListAdmin(ctx, role.SpaceID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list space admins", "space_id", role.SpaceID, "error", err)
+	}
+
+	recipients := make([]string, 0, len(admins))
+	for _, admin := range admins {
+		recipients = append(recipients, admin.Email)
+	}
+
+	err = g.notifier.Notify(ctx, &Notification{
+		Type:    string(model.EventBreakGlassGranted),
+		Subject: fmt.Sprintf("[guard] break-glass access to role %s by %s", role.Name, user.Email),
+		Message: fmt.Sprintf("%s got the emergency access to role %s until %s, operator %s, reason: %s. "+
+			"Please review and acknowledge break-glass %d.",
+			user.Email, role.Name, time.Unix(bg.ExpiresAt, 0).UTC().Format(time.RFC3339), bg.Operator, bg.Reason, bg.ID),
+		Recipients: recipients,
+		CreatedAt:  bg.CreatedAt,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to notify break glass", "id", bg.ID, "error", err)
+	}
+}
+
+// ListBreakGlass lists the break-glass review items
+func (g *guard) ListBreakGlass(ctx context.Context, in *ListBreakGlassRequest) (*ListBreakGlassResponse, error) {
+	list, total, err := g.repo.BreakGlass().List(ctx, &repo.BreakGlassFilter{
+		SpaceID: in.SpaceID,
+		RoleID:  in.RoleID,
+		UserID:  in.UserID,
+		Acked:   in.Acked,
+	}, in.ListOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list break glass: %w", err)
+	}
+
+	var resp = &ListBreakGlassResponse{
+		Total: total,
+		Items: make([]*BreakGlassVO, 0, len(list)),
+	}
+	for _, bg := range list {
+		resp.Items = append(resp.Items, &BreakGlassVO{
+			ID:         bg.ID,
+			RoleID:     bg.RoleID,
+			SpaceID:    bg.SpaceID,
+			UserID:     bg.UserID,
+			Reason:     bg.Reason,
+			Operator:   bg.Operator,
+			ExpiresAt:  bg.ExpiresAt,
+			AckedBy:    bg.AckedBy,
+			AckedAt:    bg.AckedAt,
+			AckComment: bg.AckComment,
+			CreatedAt:  bg.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// AckBreakGlass acknowledges the review of the break-glass. If the operator
+// is bound to a user, it must not be the grantee and must be an admin of the
+// space or a super admin.
+func (g *guard) AckBreakGlass(ctx context.Context, in *AckBreakGlassRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		bg, err := tx.BreakGlass().GetByID(ctx, in.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get break glass by id: %w", err)
+		}
+
+		if bg == nil {
+			return errors.ErrBreakGlassNotFound
+		}
+
+		if bg.IsAcked() {
+			return errors.ErrBreakGlassAcked
+		}
+
+		operator, err := operatorUser(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if operator != nil {
+			if operator.ID == bg.UserID {
+				return errors.ErrForbidden
+			}
+
+			// the super admins acknowledge the break-glass of any space,
+			// including the ones of the global roles granted before
+			if !superAdmin(ctx) {
+				ok, err := tx.Space().IsAdmin(ctx, bg.SpaceID, operator.ID)
+				if err != nil {
+					return fmt.Errorf("failed to check space admin: %w", err)
+				}
+
+				if !ok {
+					return errors.ErrForbidden
+				}
+			}
+		}
+
+		bg.AckedBy = OperatorFrom(ctx).String()
+		bg.AckComment = in.Comment
+		if err := tx.BreakGlass().Ack(ctx, bg); err != nil {
+			return fmt.Errorf("failed to ack break glass: %w", err)
+		}
+
+		return emit(ctx, tx, &model.Event{
+			Type:    model.EventBreakGlassAcked,
+			SpaceID: bg.SpaceID,
+			RoleID:  bg.RoleID,
+			UserID:  bg.UserID,
+			Message: fmt.Sprintf("break-glass %d acknowledged by %s: %s", bg.ID, bg.AckedBy, in.Comment),
+		})
+	})
+}
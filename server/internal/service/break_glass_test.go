@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/certificate"
+)
+
+func TestBreakGlass(t *testing.T) {
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name string
+		// used is the number of the break-glass used by the user
+		used int
+		// err is the expected error, the invalid public key of the user
+		// fails the cert if it's nil
+		err error
+	}{
+		{name: "limited", used: 3, err: errors.ErrBreakGlassLimited},
+		{name: "cert failed", used: 0, err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemDB(1)
+			db.data.users[0].PubKey = "invalid"
+			db.data.addRole(model.Role{ID: 1, SpaceID: 1, BreakGlass: true})
+			for i := 0; i < tt.used; i++ {
+				db.data.breakGlass = append(db.data.breakGlass, model.BreakGlass{ID: db.data.nextID(), RoleID: 1, UserID: 1, CreatedAt: now})
+			}
+
+			g := &guard{
+				repo:              db,
+				breakGlass:        BreakGlassConfig{Duration: time.Hour, Limit: 3, Window: 24 * time.Hour},
+				certificateSigner: certificate.New(nil, nil),
+				getPassphrase:     func(context.Context) string { return "" },
+			}
+
+			_, err := g.BreakGlass(context.Background(), &BreakGlassRequest{RoleID: 1, UserID: 1, Reason: "incident"})
+			if tt.err != nil {
+				if !stderrors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+			} else if err == nil {
+				t.Fatal("expected the cert to fail")
+			}
+
+			// the membership is granted in the transaction, it's rolled
+			// back with the failed cert
+			if len(db.data.roleUsers) != 0 || len(db.data.breakGlass) != tt.used || len(db.data.userCerts) != 0 {
+				t.Fatalf("expected the transaction rolled back, got %d memberships, %d break-glass and %d certs",
+					len(db.data.roleUsers), len(db.data.breakGlass), len(db.data.userCerts))
+			}
+		})
+	}
+}
+
+func TestGlobalRoleBreakGlass(t *testing.T) {
+	ctx := context.Background()
+	db := newMemDB(1)
+	db.data.addRole(model.Role{ID: 1, Global: true, BreakGlass: true})
+	g := &guard{repo: db}
+
+	if _, err := g.CreateRole(ctx, &CreateRoleRequest{Name: "global", Global: true, BreakGlass: true}); !stderrors.Is(err, errors.ErrGlobalRoleBreakGlass) {
+		t.Fatalf("expected error %v, got %v", errors.ErrGlobalRoleBreakGlass, err)
+	}
+
+	// the global role marked as break-glass before
+	if _, err := g.BreakGlass(ctx, &BreakGlassRequest{RoleID: 1, UserID: 1, Reason: "incident"}); !stderrors.Is(err, errors.ErrGlobalRoleBreakGlass) {
+		t.Fatalf("expected error %v, got %v", errors.ErrGlobalRoleBreakGlass, err)
+	}
+
+	if len(db.data.roleUsers) != 0 || len(db.data.roles) != 1 {
+		t.Fatalf("expected no membership and no role created, got %d memberships and %d roles",
+			len(db.data.roleUsers), len(db.data.roles))
+	}
+}
+
+// TestAckGlobalBreakGlass acknowledges the break-glass of a global role,
+// it has no space admin, only the super admins acknowledge it
+func TestAckGlobalBreakGlass(t *testing.T) {
+	admin := WithOperator(context.Background(), &Operator{Name: "admin", Email: "user-2@example.com"})
+	super := WithOperator(context.Background(), &Operator{Name: "super", Email: "user-3@example.com", SuperAdmin: true})
+	grantee := WithOperator(context.Background(), &Operator{Name: "grantee", Email: "user-1@example.com", SuperAdmin: true})
+
+	// no admin in any space
+	db := newMemDB(1, 2, 3)
+	db.data.breakGlass = []model.BreakGlass{{ID: 1, RoleID: 1, UserID: 1}}
+	g := &guard{repo: db}
+
+	for _, ctx := range []context.Context{admin, grantee} {
+		if err := g.AckBreakGlass(ctx, &AckBreakGlassRequest{ID: 1}); !stderrors.Is(err, errors.ErrForbidden) {
+			t.Fatalf("expected error %v, got %v", errors.ErrForbidden, err)
+		}
+	}
+
+	if err := g.AckBreakGlass(super, &AckBreakGlassRequest{ID: 1, Comment: "reviewed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !db.data.breakGlass[0].IsAcked() {
+		t.Fatal("expected the break-glass acknowledged")
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// GetBundle reads the files of the node in a read-only snapshot, the
+// changes committed meanwhile are not seen, so the files are consistent
+// with each other and with the version of the bundle.
+func (g *guard) GetBundle(ctx context.Context, uniqueID string, modified func(digest string) bool) (*Bundle, error) {
+	start := time.Now()
+
+	tx, err := g.repo.BeginSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.RollbackTx(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to rollback snapshot", "error", err)
+		}
+	}()
+
+	txg := *g
+	txg.repo = tx
+	return txg.bundle(ctx, uniqueID, modified, start)
+}
+
+func (g *guard) bundle(ctx context.Context, uniqueID string, modified func(digest string) bool, start time.Time) (*Bundle, error) {
+	version, err := g.repo.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := g.nodeByUniqueID(ctx, uniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	principals, keys, err := g.nodeMembers(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	krlVersion, err := g.krlVersion(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := g.digestNode(principals, keys, krlVersion)
+	if !modified(digest) {
+		return nil, nil
+	}
+
+	krl, err := g.krl(ctx, node.ID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bundle{
+		Version:        version,
+		Digest:         digest,
+		CA:             string(g.publicKey),
+		Principals:     principals,
+		KRL:            krl,
+		AuthorizedKeys: keys,
+	}, nil
+}
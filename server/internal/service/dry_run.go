@@ -0,0 +1,248 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// DryRun runs fn in a transaction which is always rolled back, and returns
+// the principals diff of every node and account of the scope affected by fn.
+// The service passed to fn runs on the transaction and sends no notification.
+func (g *guard) DryRun(ctx context.Context, scope *DryRunScope, fn func(svc Guard) error) (*DryRunResponse, error) {
+	diffs, err := g.diffTx(ctx, scope, false, func(tx *guard) error {
+		return fn(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DryRunResponse{
+		DryRun: true,
+		Diffs:  diffs,
+	}, nil
+}
+
+// diffTx runs fn with a copy of the service bound to a transaction, and
+// returns the principals diff made by fn in the scope, a nil scope diffs
+// every node. The transaction is committed if commit is set and fn succeeds,
+// otherwise it's rolled back.
+func (g *guard) diffTx(ctx context.Context, scope *DryRunScope, commit bool, fn func(tx *guard) error) (diffs []*PrincipalsDiff, err error) {
+	tx, err := g.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if commit && err == nil {
+			if txErr := tx.CommitTx(ctx); txErr != nil {
+				diffs, err = nil, fmt.Errorf("failed to commit transaction: %w", txErr)
+			}
+			return
+		}
+
+		if txErr := tx.RollbackTx(ctx); txErr != nil {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", txErr)
+		}
+	}()
+
+	filter, err := scope.accessFilter(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := principalsSnapshot(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	txg := *g
+	txg.repo = tx
+	if !commit {
+		txg.notifier = nopNotifier{}
+		txg.dryRun = true
+	}
+	if err := fn(&txg); err != nil {
+		return nil, err
+	}
+
+	after, err := principalsSnapshot(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffPrincipals(before, after), nil
+}
+
+// principalsKey is a node and one of its accounts, the account is the
+// role of the principals synced by guard-client
+type principalsKey struct {
+	nodeID  int64
+	account string
+}
+
+type principalsState struct {
+	nodeName   string
+	spaceID    int64
+	principals []string
+}
+
+// accessFilter resolves the scope into the filter of the principals
+// snapshots, it's resolved before the mutation, so the nodes unbound by the
+// mutation are kept. A nil filter means the mutation can not touch any node
+// or user of the scope.
+func (s *DryRunScope) accessFilter(ctx context.Context, tx repo.Repo) (*repo.AccessFilter, error) {
+	filter := &repo.AccessFilter{}
+	if s == nil {
+		return filter, nil
+	}
+
+	nodeIDs := slices.Clone(s.NodeIDs)
+	for _, uniqueID := range s.NodeUniqueIDs {
+		node, err := tx.Node().GetByUniqueID(ctx, uniqueID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node: %w", err)
+		}
+		// the unknown node is rejected by the mutation
+		if node != nil {
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+	}
+
+	limitNodes := len(s.NodeIDs) > 0 || len(s.NodeUniqueIDs) > 0
+	if s.RoleID > 0 && s.Selector {
+		role, err := tx.Role().GetByID(ctx, s.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role: %w", err)
+		}
+		if role != nil && !role.Global {
+			filter.SpaceID = role.SpaceID
+		}
+		limitNodes = false
+	} else if s.RoleID > 0 {
+		nodes, _, err := tx.Role().ListNode(ctx, &repo.RoleNodeFilter{RoleID: s.RoleID}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list role nodes: %w", err)
+		}
+		for _, node := range nodes {
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+		limitNodes = true
+	}
+
+	if limitNodes {
+		if len(nodeIDs) == 0 {
+			return nil, nil
+		}
+		filter.NodeIDs = nodeIDs
+	}
+
+	userIDs := slices.Clone(s.UserIDs)
+	if s.GroupID > 0 {
+		users, _, err := tx.Group().ListUser(ctx, &repo.GroupUserFilter{GroupID: s.GroupID}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list group users: %w", err)
+		}
+		for _, user := range users {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	if len(s.UserIDs) > 0 || s.GroupID > 0 {
+		if len(userIDs) == 0 {
+			return nil, nil
+		}
+		filter.UserIDs = userIDs
+	}
+
+	return filter, nil
+}
+
+// principalsSnapshot returns the principals of the nodes and accounts of
+// the filter, they are the same as the ones returned by GetPrincipals
+func principalsSnapshot(ctx context.Context, tx repo.Repo, filter *repo.AccessFilter) (map[principalsKey]*principalsState, error) {
+	snapshot := make(map[principalsKey]*principalsState)
+	if filter == nil {
+		return snapshot, nil
+	}
+
+	accesses, _, err := tx.Role().ListAccess(ctx, filter, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access: %w", err)
+	}
+
+	for _, access := range accesses {
+		key := principalsKey{nodeID: access.NodeID, account: access.Account}
+		state, ok := snapshot[key]
+		if !ok {
+			state = &principalsState{nodeName: access.NodeName, spaceID: access.SpaceID}
+			snapshot[key] = state
+		}
+
+		if !slices.Contains(state.principals, access.Email) {
+			state.principals = append(state.principals, access.Email)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// diffPrincipals compares the principals like guard-client principals does,
+// "+" for the added principals and "-" for the removed ones
+func diffPrincipals(before, after map[principalsKey]*principalsState) []*PrincipalsDiff {
+	keys := make([]principalsKey, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	diffs := make([]*PrincipalsDiff, 0)
+	for _, key := range keys {
+		var local, remote []string
+		state, ok := before[key]
+		if ok {
+			local = state.principals
+		}
+		if s, ok := after[key]; ok {
+			remote = s.principals
+			state = s
+		}
+
+		diff := make([]string, 0)
+		for _, val := range remote {
+			if !slices.Contains(local, val) {
+				diff = append(diff, "+"+val)
+			}
+		}
+		for _, val := range local {
+			if !slices.Contains(remote, val) {
+				diff = append(diff, "-"+val)
+			}
+		}
+
+		if len(diff) == 0 {
+			continue
+		}
+
+		diffs = append(diffs, &PrincipalsDiff{
+			NodeID:   key.nodeID,
+			NodeName: state.nodeName,
+			SpaceID:  state.spaceID,
+			Role:     key.account,
+			Diff:     diff,
+		})
+	}
+
+	slices.SortFunc(diffs, func(a, b *PrincipalsDiff) int {
+		return cmp.Or(cmp.Compare(a.NodeID, b.NodeID), cmp.Compare(a.Role, b.Role))
+	})
+
+	return diffs
+}
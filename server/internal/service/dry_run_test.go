@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+func TestDiffPrincipals(t *testing.T) {
+	web := principalsKey{nodeID: 1, account: "root"}
+	webDeploy := principalsKey{nodeID: 1, account: "deploy"}
+	db := principalsKey{nodeID: 2, account: "root"}
+	cache := principalsKey{nodeID: 3, account: "root"}
+
+	before := map[principalsKey]*principalsState{
+		web:   {nodeName: "web", spaceID: 1, principals: []string{"alice", "bob"}},
+		db:    {nodeName: "db", spaceID: 1, principals: []string{"carol"}},
+		cache: {nodeName: "cache", spaceID: 2, principals: []string{"dave"}},
+	}
+	after := map[principalsKey]*principalsState{
+		web:       {nodeName: "web", spaceID: 1, principals: []string{"bob", "erin"}},
+		webDeploy: {nodeName: "web", spaceID: 1, principals: []string{"alice"}},
+		cache:     {nodeName: "cache", spaceID: 2, principals: []string{"dave"}},
+	}
+
+	// sorted by node and account, the unchanged cache is skipped and
+	// the removed db keeps its name
+	expected := []*PrincipalsDiff{
+		{NodeID: 1, NodeName: "web", SpaceID: 1, Role: "deploy", Diff: []string{"+alice"}},
+		{NodeID: 1, NodeName: "web", SpaceID: 1, Role: "root", Diff: []string{"+erin", "-alice"}},
+		{NodeID: 2, NodeName: "db", SpaceID: 1, Role: "root", Diff: []string{"-carol"}},
+	}
+
+	diffs := diffPrincipals(before, after)
+	if !reflect.DeepEqual(diffs, expected) {
+		for _, diff := range diffs {
+			t.Logf("%+v", diff)
+		}
+		t.Fatal("unexpected diffs")
+	}
+
+	if diffs := diffPrincipals(after, after); len(diffs) != 0 {
+		t.Fatalf("expected no diff of the same principals, got %d", len(diffs))
+	}
+}
+
+// newDryRunDB returns the repo with the users 1-3, the nodes 1 and 2 of the
+// space 1 bound to the role 1, the node 3 of the space 2, the unbound global
+// role 2, the group 4 of the users 2 and 3 and the empty group 5
+func newDryRunDB() *memDB {
+	db := newMemDB(1, 2, 3)
+	for _, node := range []model.Node{{ID: 1, SpaceID: 1}, {ID: 2, SpaceID: 1}, {ID: 3, SpaceID: 2}} {
+		node.Name = fmt.Sprintf("node-%d", node.ID)
+		node.UniqueID = node.Name
+		db.data.nodes = append(db.data.nodes, node)
+	}
+	db.data.addRole(model.Role{ID: 1, SpaceID: 1})
+	db.data.addRole(model.Role{ID: 2, Global: true})
+	db.data.roleNodes = []model.RoleNode{
+		{ID: 1, RoleID: 1, NodeID: 1, Account: "root"},
+		{ID: 2, RoleID: 1, NodeID: 2, Account: "root"},
+	}
+	db.data.roleUsers = []model.RoleUser{
+		{ID: 1, RoleID: 1, UserID: 1},
+		{ID: 2, RoleID: 1, UserID: 2},
+	}
+	db.data.groups = []model.Group{{ID: 4}, {ID: 5}}
+	db.data.groupUsers = []model.GroupUser{{ID: 1, GroupID: 4, UserID: 2}, {ID: 2, GroupID: 4, UserID: 3}}
+	db.data.reserve(5)
+	return db
+}
+
+func TestDryRunScopeAccessFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    *DryRunScope
+		expected *repo.AccessFilter
+	}{
+		{name: "nil", expected: &repo.AccessFilter{}},
+		{name: "nodes", scope: &DryRunScope{NodeIDs: []int64{3}, NodeUniqueIDs: []string{"node-1", "unknown"}},
+			expected: &repo.AccessFilter{NodeIDs: []int64{3, 1}}},
+		{name: "unknown nodes", scope: &DryRunScope{NodeUniqueIDs: []string{"unknown"}}},
+		{name: "role", scope: &DryRunScope{RoleID: 1}, expected: &repo.AccessFilter{NodeIDs: []int64{1, 2}}},
+		{name: "role users", scope: &DryRunScope{RoleID: 1, UserIDs: []int64{1}},
+			expected: &repo.AccessFilter{NodeIDs: []int64{1, 2}, UserIDs: []int64{1}}},
+		{name: "unbound role", scope: &DryRunScope{RoleID: 2}},
+		{name: "role selector", scope: &DryRunScope{RoleID: 1, Selector: true}, expected: &repo.AccessFilter{SpaceID: 1}},
+		{name: "global role selector", scope: &DryRunScope{RoleID: 2, Selector: true}, expected: &repo.AccessFilter{}},
+		{name: "group", scope: &DryRunScope{GroupID: 4}, expected: &repo.AccessFilter{UserIDs: []int64{2, 3}}},
+		{name: "empty group", scope: &DryRunScope{GroupID: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.scope.accessFilter(context.Background(), newDryRunDB())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(filter, tt.expected) {
+				t.Fatalf("expected the filter %+v, got %+v", tt.expected, filter)
+			}
+		})
+	}
+}
+
+// TestDryRunScope removes the users 1 and 2 from the role 1, only the
+// principals of the user 1 in the scope are compared
+func TestDryRunScope(t *testing.T) {
+	ctx := context.Background()
+	db := newDryRunDB()
+	g := &guard{repo: db, notifier: nopNotifier{}}
+
+	resp, err := g.DryRun(ctx, &DryRunScope{RoleID: 1, UserIDs: []int64{1}}, func(svc Guard) error {
+		return svc.(*guard).repo.Role().RemoveUser(ctx, 1, 1, 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*PrincipalsDiff{
+		{NodeID: 1, NodeName: "node-1", SpaceID: 1, Role: "root", Diff: []string{"-user-1@example.com"}},
+		{NodeID: 2, NodeName: "node-2", SpaceID: 1, Role: "root", Diff: []string{"-user-1@example.com"}},
+	}
+	if !reflect.DeepEqual(resp.Diffs, expected) {
+		for _, diff := range resp.Diffs {
+			t.Logf("%+v", diff)
+		}
+		t.Fatal("unexpected diffs")
+	}
+
+	if len(db.data.roleUsers) != 2 {
+		t.Fatalf("expected the dry run rolled back, got %v", db.data.roleUsers)
+	}
+}
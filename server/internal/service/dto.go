@@ -2,7 +2,12 @@ package service
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
 	"github.com/sysarmor/guard/server/internal/service/errors"
 	"github.com/sysarmor/guard/server/pkg/apis/dto"
 	err "github.com/sysarmor/guard/server/pkg/errors"
@@ -10,64 +15,176 @@ import (
 
 type PrincipalList = dto.PrincipalList
 type Principals = dto.Principals
+type Bundle = dto.Bundle
 
 type Node struct {
-	ID            int64    `json:"id"`
-	UniqueID      string   `json:"unique_id"`
-	Secret        string   `json:"secret"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	SpaceID       int64    `json:"space_id"`
-	IP            string   `json:"ip"`
-	LastHeartbeat int64    `json:"last_heartbeat"`
-	Accounts      []string `json:"accounts"`
-	CreatedAt     int64    `json:"created_at"`
-	UpdatedAt     int64    `json:"updated_at"`
+	ID            int64        `json:"id"`
+	UniqueID      string       `json:"unique_id"`
+	Secret        string       `json:"secret"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	SpaceID       int64        `json:"space_id"`
+	IP            string       `json:"ip"`
+	LastHeartbeat int64        `json:"last_heartbeat"`
+	Accounts      []string     `json:"accounts"`
+	Labels        model.Labels `json:"labels"`
+	CreatedAt     int64        `json:"created_at"`
+	UpdatedAt     int64        `json:"updated_at"`
 }
 
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// ReadyResponse is the result of the readiness checks
+type ReadyResponse struct {
+	Ready bool `json:"ready"`
+	// Checks are the results of the checks by the name,
+	// "ok" or "failed"
+	Checks map[string]string `json:"checks"`
+}
+
+// PageRequest is the common request of the list endpoints
 type PageRequest struct {
 	// Page is the page number, start from 1
 	Page int64 `form:"page"`
-	// Limit is the number of items per page,
+	// Limit is the number of items per page, default is 100,
 	// must be less than or equal to 1000
 	Limit int64 `form:"limit"`
+	// Sort is the field to sort by, prefix "-" means descending,
+	// e.g. "-created_at"
+	Sort string `form:"sort"`
 }
 
-func (pr *PageRequest) Validate() error {
-	if pr.Page < 0 {
-		pr.Page = 0
+// Validate validates the page request, sortable is the
+// field list which can be sorted by
+func (pr *PageRequest) Validate(sortable ...string) error {
+	if pr.Page <= 0 {
+		pr.Page = 1
 	}
 	if pr.Limit <= 0 {
-		return err.New(errors.ParamError, "limit must be greater than 0")
+		pr.Limit = defaultPageLimit
+	}
+	if pr.Limit > maxPageLimit {
+		return err.New(errors.ParamError, fmt.Sprintf("limit must be less than or equal to %d", maxPageLimit))
+	}
+
+	if pr.Sort != "" && !slices.Contains(sortable, strings.TrimPrefix(pr.Sort, "-")) {
+		return err.New(errors.ParamError, fmt.Sprintf("sort must be one of %s", strings.Join(sortable, ", ")))
 	}
 	return nil
 }
 
 func (pr *PageRequest) Offset() int64 {
+	if pr.Page <= 0 {
+		return 0
+	}
 	return (pr.Page - 1) * pr.Limit
 }
 
+// ListOption returns the list option of the repo
+func (pr *PageRequest) ListOption() *repo.ListOption {
+	return &repo.ListOption{
+		Offset: pr.Offset(),
+		Limit:  pr.Limit,
+		Sort:   pr.Sort,
+	}
+}
+
 // ==== Space ====
 type CreateSpaceRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// StaleAfter and OfflineAfter are the seconds without heartbeat
+	// before the nodes are stale or offline, 0 means the server default
+	StaleAfter   int64 `json:"stale_after"`
+	OfflineAfter int64 `json:"offline_after"`
 }
 
 func (cs *CreateSpaceRequest) Validate() error {
 	if cs.Name == "" {
 		return err.New(errors.ParamError, "name is required")
 	}
+	return validateThresholds(cs.StaleAfter, cs.OfflineAfter)
+}
+
+// validateThresholds checks the heartbeat thresholds of a space, the
+// thresholds falling back to the defaults are checked by the service
+func validateThresholds(staleAfter, offlineAfter int64) error {
+	if staleAfter < 0 || offlineAfter < 0 {
+		return err.New(errors.ParamError, "stale_after and offline_after can not be negative")
+	}
+	if staleAfter > 0 && offlineAfter > 0 && offlineAfter <= staleAfter {
+		return err.New(errors.ParamError, "offline_after must be greater than stale_after")
+	}
 	return nil
 }
 
 type ListSpaceVO struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	CreatedAt   int64  `json:"created_at"`
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	StaleAfter   int64  `json:"stale_after"`
+	OfflineAfter int64  `json:"offline_after"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+type ListSpaceRequest struct {
+	PageRequest
+
+	// Name matches the space name by substring
+	Name string `form:"name"`
+}
+
+func (lsr *ListSpaceRequest) Validate() error {
+	return lsr.PageRequest.Validate("id", "name", "created_at")
+}
+
+type ListSpaceResponse struct {
+	Total  int64          `json:"total"`
+	Spaces []*ListSpaceVO `json:"spaces"`
 }
 
-type ListSpaceResponse []*ListSpaceVO
+// UpdateSpaceRequest updates the space, the nil fields are not changed
+type UpdateSpaceRequest struct {
+	SpaceID      int64   `json:"-"`
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	StaleAfter   *int64  `json:"stale_after"`
+	OfflineAfter *int64  `json:"offline_after"`
+}
+
+func (usr *UpdateSpaceRequest) Validate() error {
+	if usr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if usr.Name != nil && *usr.Name == "" {
+		return err.New(errors.ParamError, "name can not be empty")
+	}
+	var staleAfter, offlineAfter int64
+	if usr.StaleAfter != nil {
+		staleAfter = *usr.StaleAfter
+	}
+	if usr.OfflineAfter != nil {
+		offlineAfter = *usr.OfflineAfter
+	}
+	return validateThresholds(staleAfter, offlineAfter)
+}
+
+type DeleteSpaceRequest struct {
+	SpaceID int64 `json:"-" form:"-"`
+	// Force deletes the nodes and roles of the space, otherwise
+	// a space with nodes or roles can not be deleted
+	Force bool `form:"force"`
+}
+
+func (dsr *DeleteSpaceRequest) Validate() error {
+	if dsr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	return nil
+}
 
 type AddUserToSpaceRequest struct {
 	SpaceID int64 `json:"-"`
@@ -92,6 +209,9 @@ type CreateUserRequest struct {
 	Email    string `json:"email"`
 	// PublicKey is the public key of the ssh key
 	PublicKey string `json:"public_key"`
+	// ExpiresAt is the unix time when the user will be expired,
+	// if it is 0, the user never expires
+	ExpiresAt int64 `json:"expires_at"`
 }
 
 func (cur *CreateUserRequest) Validate() error {
@@ -104,6 +224,36 @@ func (cur *CreateUserRequest) Validate() error {
 	if cur.PublicKey == "" {
 		return err.New(errors.ParamError, "public key is required")
 	}
+	if cur.ExpiresAt < 0 {
+		return err.New(errors.ParamError, "expires at can not be negative")
+	}
+	return nil
+}
+
+// UpdateUserRequest updates the user, the nil fields are not changed.
+// Changing the email revokes all certs, because the email is the principal
+type UpdateUserRequest struct {
+	UserID   int64   `json:"-"`
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	// ExpiresAt changes the expiry of the user, 0 means never expires.
+	// Extending it reactivates the expired user.
+	ExpiresAt *int64 `json:"expires_at"`
+}
+
+func (uur *UpdateUserRequest) Validate() error {
+	if uur.UserID <= 0 {
+		return err.New(errors.ParamError, "user id is required")
+	}
+	if uur.Username != nil && *uur.Username == "" {
+		return err.New(errors.ParamError, "username can not be empty")
+	}
+	if uur.Email != nil && *uur.Email == "" {
+		return err.New(errors.ParamError, "email can not be empty")
+	}
+	if uur.ExpiresAt != nil && *uur.ExpiresAt < 0 {
+		return err.New(errors.ParamError, "expires at can not be negative")
+	}
 	return nil
 }
 
@@ -111,18 +261,38 @@ type UserListVO struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
-	// Ban is the status of the user
-	// If it is true, the user is banned
-	Ban       bool  `json:"ban"`
+	// State is the lifecycle state of the user,
+	// one of active, suspended, expired and banned
+	State model.UserState `json:"state"`
+	// ExpiresAt is the time when the user will be expired,
+	// if it is 0, the user never expires
+	ExpiresAt int64 `json:"expires_at"`
 	CreatedAt int64 `json:"created_at"`
 	UpdatedAt int64 `json:"updated_at"`
 }
 
 type ListUserRequest struct {
 	PageRequest
+
+	// Name matches the username by substring
+	Name string `form:"name"`
+	// Email matches the email by substring
+	Email string `form:"email"`
+	// State filters the users by state
+	State string `form:"state"`
 }
 
-type ListUserResponse []*UserListVO
+func (lur *ListUserRequest) Validate() error {
+	if lur.State != "" && !model.UserState(lur.State).Valid() {
+		return err.New(errors.ParamError, "invalid state")
+	}
+	return lur.PageRequest.Validate("id", "username", "email", "expires_at", "created_at")
+}
+
+type ListUserResponse struct {
+	Total int64         `json:"total"`
+	Users []*UserListVO `json:"users"`
+}
 
 type UpdateUserPublicKeyRequest struct {
 	UserID    int64  `json:"user_id"`
@@ -140,13 +310,14 @@ func (uupr *UpdateUserPublicKeyRequest) Validate() error {
 }
 
 type UserVO struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	PubKey    string `json:"public_key"`
-	Ban       bool   `json:"ban"`
-	CreatedAt int64  `json:"created_at"`
-	UpdateAt  int64  `json:"updated_at"`
+	ID        int64           `json:"id"`
+	Username  string          `json:"username"`
+	Email     string          `json:"email"`
+	PubKey    string          `json:"public_key"`
+	State     model.UserState `json:"state"`
+	ExpiresAt int64           `json:"expires_at"`
+	CreatedAt int64           `json:"created_at"`
+	UpdateAt  int64           `json:"updated_at"`
 }
 
 type GetUserResponse UserVO
@@ -164,6 +335,9 @@ type CreateNodeRequest struct {
 	// is account from the machine. If it is empty, the default
 	// account is root.
 	Accounts []string `json:"accounts"`
+	// Labels is the key/value labels of the node, e.g. {"env": "prod"},
+	// the node is bound to the roles whose selectors match the labels
+	Labels model.Labels `json:"labels"`
 }
 
 func (cnr *CreateNodeRequest) Validate() error {
@@ -176,6 +350,9 @@ func (cnr *CreateNodeRequest) Validate() error {
 	if cnr.IP == "" {
 		return err.New(errors.ParamError, "ip is required")
 	}
+	if e := cnr.Labels.Validate(); e != nil {
+		return err.New(errors.ParamError, e.Error())
+	}
 
 	return nil
 }
@@ -190,32 +367,114 @@ type CreateNodeResponse struct {
 	Secret string `json:"secret"`
 }
 
-type ListNodeRequest struct {
-	PageRequest
+// UpdateNodeRequest updates the node, the nil fields are not changed
+type UpdateNodeRequest struct {
+	SpaceID int64 `json:"-"`
+	NodeID  int64 `json:"-"`
 
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IP          *string `json:"ip"`
+	// Accounts replaces the account list of the node
+	Accounts []string `json:"accounts"`
+	// Labels replaces the labels of the node, an empty object
+	// removes all labels
+	Labels model.Labels `json:"labels"`
+
+	// Force removes the role bindings of the removed accounts,
+	// otherwise an account bound to roles can not be removed
+	Force bool `json:"-"`
+}
+
+func (unr *UpdateNodeRequest) Validate() error {
+	if unr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if unr.NodeID <= 0 {
+		return err.New(errors.ParamError, "node id is required")
+	}
+	if unr.Name != nil && *unr.Name == "" {
+		return err.New(errors.ParamError, "name can not be empty")
+	}
+	if unr.IP != nil && *unr.IP == "" {
+		return err.New(errors.ParamError, "ip can not be empty")
+	}
+	if unr.Accounts != nil && len(unr.Accounts) == 0 {
+		return err.New(errors.ParamError, "at least one account is required")
+	}
+	if e := unr.Labels.Validate(); e != nil {
+		return err.New(errors.ParamError, e.Error())
+	}
+	return nil
+}
+
+type DeleteNodeRequest struct {
 	SpaceID int64 `json:"-"`
+	NodeID  int64 `json:"-"`
 }
 
-func (lnr *ListNodeRequest) Validate() error {
-	if err := lnr.PageRequest.Validate(); err != nil {
-		return err
+func (dnr *DeleteNodeRequest) Validate() error {
+	if dnr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
 	}
+	if dnr.NodeID <= 0 {
+		return err.New(errors.ParamError, "node id is required")
+	}
+	return nil
+}
+
+type ListNodeRequest struct {
+	PageRequest
+
+	SpaceID int64 `json:"-" form:"-"`
+
+	// Name matches the node name by substring
+	Name string `form:"name"`
+	// Account filters the nodes which have the account
+	Account string `form:"account"`
+	// HeartbeatFrom and HeartbeatTo filter the nodes by
+	// the last heartbeat, unix seconds
+	HeartbeatFrom int64 `form:"heartbeat_from"`
+	HeartbeatTo   int64 `form:"heartbeat_to"`
+	// Labels filters the nodes which have all the labels,
+	// in the form of "k1=v1,k2=v2"
+	Labels string `form:"labels"`
+	// Health filters the nodes by the health,
+	// one of healthy, stale and offline
+	Health string `form:"health"`
+}
 
+func (lnr *ListNodeRequest) Validate() error {
 	if lnr.SpaceID <= 0 {
 		return err.New(errors.ParamError, "space id is required")
 	}
-	return lnr.PageRequest.Validate()
+	if _, e := model.ParseLabels(lnr.Labels); e != nil {
+		return err.New(errors.ParamError, e.Error())
+	}
+	if lnr.Health != "" && !model.NodeHealth(lnr.Health).Valid() {
+		return err.New(errors.ParamError, "invalid health")
+	}
+	return lnr.PageRequest.Validate("id", "name", "ip", "last_heartbeat", "health", "created_at")
+}
+
+// LabelSelector returns the parsed labels, it's valid after Validate
+func (lnr *ListNodeRequest) LabelSelector() model.Labels {
+	labels, _ := model.ParseLabels(lnr.Labels)
+	return labels
 }
 
 type ListNodeVO struct {
-	ID            int64    `json:"id"`
-	UniqueID      string   `json:"unique_id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	IP            string   `json:"ip"`
-	Accounts      []string `json:"accounts"`
-	LastHeartbeat int64    `json:"last_heartbeat"`
-	CreatedAt     int64    `json:"created_at"`
+	ID            int64        `json:"id"`
+	UniqueID      string       `json:"unique_id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	IP            string       `json:"ip"`
+	Accounts      []string     `json:"accounts"`
+	Labels        model.Labels `json:"labels"`
+	LastHeartbeat int64        `json:"last_heartbeat"`
+	// Health is computed from the last heartbeat by the sweep
+	Health    model.NodeHealth `json:"health"`
+	CreatedAt int64            `json:"created_at"`
 }
 
 type ListNodeResponse struct {
@@ -223,15 +482,64 @@ type ListNodeResponse struct {
 	Nodes []*ListNodeVO `json:"nodes"`
 }
 
+type ListNodeHeartbeatRequest struct {
+	SpaceID int64 `json:"-"`
+	NodeID  int64 `json:"-"`
+}
+
+func (lnhr *ListNodeHeartbeatRequest) Validate() error {
+	if lnhr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if lnhr.NodeID <= 0 {
+		return err.New(errors.ParamError, "node id is required")
+	}
+	return nil
+}
+
+// ListNodeHeartbeatResponse is the health and the recent successful
+// syncs of a node, the thresholds are the effective ones in seconds
+type ListNodeHeartbeatResponse struct {
+	NodeID        int64            `json:"node_id"`
+	Health        model.NodeHealth `json:"health"`
+	LastHeartbeat int64            `json:"last_heartbeat"`
+	StaleAfter    int64            `json:"stale_after"`
+	OfflineAfter  int64            `json:"offline_after"`
+	// Heartbeats are the recorded heartbeats, newest first
+	Heartbeats []int64 `json:"heartbeats"`
+}
+
+// SpaceHealthVO is the number of the nodes of a space by the health,
+// the thresholds are the effective ones in seconds
+type SpaceHealthVO struct {
+	SpaceID      int64  `json:"space_id"`
+	SpaceName    string `json:"space_name"`
+	StaleAfter   int64  `json:"stale_after"`
+	OfflineAfter int64  `json:"offline_after"`
+	Total        int64  `json:"total"`
+	Healthy      int64  `json:"healthy"`
+	Stale        int64  `json:"stale"`
+	Offline      int64  `json:"offline"`
+}
+
+type FleetHealthResponse struct {
+	Spaces []*SpaceHealthVO `json:"spaces"`
+}
+
 // ==== Role ====
 type CreateRoleRequest struct {
 	SpaceID     int64  `json:"-"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// BreakGlass marks the role for the emergency access
+	BreakGlass bool `json:"break_glass"`
+	// Global creates a role binding the nodes of all spaces,
+	// it's set by the global role api
+	Global bool `json:"-"`
 }
 
 func (crr *CreateRoleRequest) Validate() error {
-	if crr.SpaceID <= 0 {
+	if !crr.Global && crr.SpaceID <= 0 {
 		return err.New(errors.ParamError, "space id is required")
 	}
 	if crr.Name == "" {
@@ -240,25 +548,62 @@ func (crr *CreateRoleRequest) Validate() error {
 	return nil
 }
 
+// UpdateRoleRequest updates the role, the nil fields are not changed
+type UpdateRoleRequest struct {
+	SpaceID     int64   `json:"-"`
+	RoleID      int64   `json:"-"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	BreakGlass  *bool   `json:"break_glass"`
+}
+
+func (urr *UpdateRoleRequest) Validate() error {
+	if urr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if urr.Name != nil && *urr.Name == "" {
+		return err.New(errors.ParamError, "name can not be empty")
+	}
+	return nil
+}
+
 type ListRoleRequest struct {
-	SpaceID int64 `json:"space_id"`
+	PageRequest
+
+	SpaceID int64 `json:"space_id" form:"-"`
+
+	// Name matches the role name by substring
+	Name string `form:"name"`
+	// BreakGlass filters the break-glass roles or the normal roles
+	BreakGlass *bool `form:"break_glass"`
+	// Global lists the global roles instead of the roles of the
+	// space, it's set by the global role api
+	Global bool `form:"-"`
 }
 
 func (lrr *ListRoleRequest) Validate() error {
-	if lrr.SpaceID <= 0 {
-		return fmt.Errorf("space id is required")
+	if !lrr.Global && lrr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
 	}
-	return nil
+	return lrr.PageRequest.Validate("id", "name", "created_at")
 }
 
 type ListRoleVO struct {
 	ID          int64  `json:"id"`
+	SpaceID     int64  `json:"space_id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	CreatedAt   int64  `json:"created_at"`
+	BreakGlass  bool   `json:"break_glass"`
+	// Global is true for the global roles, they are listed in the
+	// space if they bind the nodes of the space
+	Global    bool  `json:"global"`
+	CreatedAt int64 `json:"created_at"`
 }
 
-type ListRoleResponse []*ListRoleVO
+type ListRoleResponse struct {
+	Total int64         `json:"total"`
+	Roles []*ListRoleVO `json:"roles"`
+}
 
 type AddNodeToRoleRequest struct {
 	RoleID int64 `json:"role_id"`
@@ -278,6 +623,20 @@ type RoleNodeRequest struct {
 	Account string `json:"account"`
 }
 
+// DryRunScope returns the scope of the nodes, the node id is used if
+// both are provided
+func (rnl RoleNodeListRequest) DryRunScope() *DryRunScope {
+	scope := &DryRunScope{}
+	for _, node := range rnl {
+		if node.NodeID > 0 {
+			scope.NodeIDs = append(scope.NodeIDs, node.NodeID)
+		} else {
+			scope.NodeUniqueIDs = append(scope.NodeUniqueIDs, node.UniqueID)
+		}
+	}
+	return scope
+}
+
 func (ar *AddNodeToRoleRequest) Validate() error {
 	if ar.RoleID <= 0 {
 		return err.New(errors.ParamError, "role id is required")
@@ -298,27 +657,48 @@ func (ar *AddNodeToRoleRequest) Validate() error {
 
 // ListRoleNodeRequest list role node request
 type ListRoleNodeRequest struct {
-	RoleID int64 `json:"role_id"`
+	PageRequest
+
+	RoleID int64 `json:"role_id" form:"-"`
+
+	// Name matches the node name by substring
+	Name string `form:"name"`
+	// Account filters the nodes bound with the account
+	Account       string `form:"account"`
+	HeartbeatFrom int64  `form:"heartbeat_from"`
+	HeartbeatTo   int64  `form:"heartbeat_to"`
+	// Dynamic filters the nodes bound by the label selectors,
+	// or the nodes added to the role explicitly
+	Dynamic *bool `form:"dynamic"`
 }
 
 func (lrnr *ListRoleNodeRequest) Validate() error {
 	if lrnr.RoleID <= 0 {
 		return err.New(errors.ParamError, "role id is required")
 	}
-	return nil
+	return lrnr.PageRequest.Validate("id", "name", "account", "last_heartbeat", "created_at")
 }
 
 type RoleNodeListVO struct {
-	ID            int64  `json:"id"`
-	UniqueID      string `json:"unique_id"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	IP            string `json:"ip"`
-	Account       string `json:"account"`
-	LastHeartbeat int64  `json:"last_heartbeat"`
+	ID            int64        `json:"id"`
+	SpaceID       int64        `json:"space_id"`
+	UniqueID      string       `json:"unique_id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	IP            string       `json:"ip"`
+	Account       string       `json:"account"`
+	Labels        model.Labels `json:"labels"`
+	LastHeartbeat int64        `json:"last_heartbeat"`
+	// Health is computed from the last heartbeat by the sweep
+	Health model.NodeHealth `json:"health"`
+	// Dynamic is true if the node is bound by a label selector
+	Dynamic bool `json:"dynamic"`
 }
 
-type ListRoleNodeResponse []*RoleNodeListVO
+type ListRoleNodeResponse struct {
+	Total int64             `json:"total"`
+	Nodes []*RoleNodeListVO `json:"nodes"`
+}
 
 type RemoveNodeFromRoleRequest struct {
 	RoleID  int64   `json:"-"`
@@ -335,10 +715,63 @@ func (rnfr *RemoveNodeFromRoleRequest) Validate() error {
 	return nil
 }
 
+type AddRoleSelectorRequest struct {
+	RoleID int64 `json:"-"`
+	// Selector is the labels the nodes must have, e.g. {"env": "prod"}
+	Selector model.Labels `json:"selector"`
+	// Account is the account of the matched nodes, if it is empty,
+	// the default account of each node is used
+	Account string `json:"account"`
+}
+
+func (arsr *AddRoleSelectorRequest) Validate() error {
+	if arsr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(arsr.Selector) == 0 {
+		return err.New(errors.ParamError, "at least one label is required")
+	}
+	if e := arsr.Selector.Validate(); e != nil {
+		return err.New(errors.ParamError, e.Error())
+	}
+	return nil
+}
+
+type RoleSelectorVO struct {
+	ID        int64        `json:"id"`
+	Selector  model.Labels `json:"selector"`
+	Account   string       `json:"account"`
+	CreatedAt int64        `json:"created_at"`
+}
+
+type ListRoleSelectorResponse struct {
+	Selectors []*RoleSelectorVO `json:"selectors"`
+}
+
+type RemoveRoleSelectorRequest struct {
+	RoleID      int64   `json:"-"`
+	SelectorIDs []int64 `json:"selector_ids"`
+}
+
+func (rrsr *RemoveRoleSelectorRequest) Validate() error {
+	if rrsr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(rrsr.SelectorIDs) == 0 {
+		return err.New(errors.ParamError, "at least one selector id is required")
+	}
+	return nil
+}
+
 type AddUserToRoleRequest struct {
 	// UserIDs is the user id list, at least one user id is required
-	UserIDs []int64 `json:"user_ids"`
-	RoleID  int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids" form:"-"`
+	RoleID  int64   `json:"-" form:"-"`
+	// ValidFrom and ValidUntil limit the membership to a time window
+	// in unix seconds, 0 means no limit. The certs granted to the users
+	// are capped at the ValidUntil.
+	ValidFrom  int64 `json:"valid_from" form:"valid_from"`
+	ValidUntil int64 `json:"valid_until" form:"valid_until"`
 }
 
 func (aur *AddUserToRoleRequest) Validate() error {
@@ -350,27 +783,59 @@ func (aur *AddUserToRoleRequest) Validate() error {
 		return err.New(errors.ParamError, "at least one user id is required")
 	}
 
+	if aur.ValidFrom < 0 || aur.ValidUntil < 0 {
+		return err.New(errors.ParamError, "valid window can not be negative")
+	}
+
+	if aur.ValidUntil > 0 && aur.ValidUntil <= max(aur.ValidFrom, time.Now().Unix()) {
+		return err.New(errors.ParamError, "valid until must be later than valid from and now")
+	}
+
 	return nil
 }
 
 type ListRoleUserRequest struct {
-	RoleID int64 `json:"role_id"`
+	PageRequest
+
+	RoleID int64 `json:"role_id" form:"-"`
+
+	Name  string `form:"name"`
+	Email string `form:"email"`
+	State string `form:"state"`
 }
 
 func (lrur *ListRoleUserRequest) Validate() error {
 	if lrur.RoleID <= 0 {
 		return err.New(errors.ParamError, "role id is required")
 	}
-	return nil
+	if lrur.State != "" && !model.UserState(lrur.State).Valid() {
+		return err.New(errors.ParamError, "invalid state")
+	}
+	return lrur.PageRequest.Validate("id", "username", "email", "valid_until", "created_at")
 }
 
 type RoleUserListVO struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	// State is the state of the user, the users not active
+	// are kept in the role but excluded from the principals
+	State     model.UserState `json:"state"`
+	ExpiresAt int64           `json:"expires_at"`
+	// ValidFrom and ValidUntil is the valid window of the membership
+	ValidFrom  int64 `json:"valid_from"`
+	ValidUntil int64 `json:"valid_until"`
+	JoinedAt   int64 `json:"joined_at"`
+	// Direct is true if the user is added to the role directly,
+	// Groups are the groups the user inherits the role from
+	Direct bool     `json:"direct"`
+	Groups []string `json:"groups,omitempty"`
 }
 
-type ListRoleUserResponse []*RoleUserListVO
+type ListRoleUserResponse struct {
+	Total int64             `json:"total"`
+	Users []*RoleUserListVO `json:"users"`
+}
 
 type RemoveUserFromRoleRequest struct {
 	RoleID  int64   `json:"-"`
@@ -395,6 +860,11 @@ type GrantCertRequest struct {
 	// StartDate is the start time of the certificate
 	// If it is 0, it means the current time
 	StartDate int64 `json:"start_date"`
+
+	// RoleID is the role which the certificate is granted for, the
+	// certificate does not outlive the membership of the user in it.
+	// If it is 0, all the memberships of the user are taken
+	RoleID int64 `json:"role_id"`
 }
 
 func (scr *GrantCertRequest) Validate() error {
@@ -411,3 +881,863 @@ type GrantCertResponse struct {
 	// Cert is the certificate content
 	Cert string `json:"cert"`
 }
+
+type UserCertVO struct {
+	ID        int64 `json:"id"`
+	IsRevoked bool  `json:"is_revoked"`
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+type ListUserCertResponse struct {
+	Certs []*UserCertVO `json:"certs"`
+}
+
+type RevokeCertRequest struct {
+	UserID int64 `json:"-"`
+	// CertIDs are the certs to revoke, all certs of the user
+	// are revoked if it's empty
+	CertIDs []int64 `json:"cert_ids"`
+}
+
+func (rcr *RevokeCertRequest) Validate() error {
+	if rcr.UserID <= 0 {
+		return err.New(errors.ParamError, "user id is required")
+	}
+	return nil
+}
+
+// ==== Event ====
+
+type ListEventRequest struct {
+	PageRequest
+
+	Type    string `form:"type"`
+	SpaceID int64  `form:"space_id"`
+	RoleID  int64  `form:"role_id"`
+	UserID  int64  `form:"user_id"`
+	NodeID  int64  `form:"node_id"`
+	// From and To filter the events by the creation time, unix seconds
+	From int64 `form:"from"`
+	To   int64 `form:"to"`
+}
+
+func (ler *ListEventRequest) Validate() error {
+	return ler.PageRequest.Validate("id", "type", "created_at")
+}
+
+type EventVO struct {
+	ID        int64           `json:"id"`
+	Type      model.EventType `json:"type"`
+	SpaceID   int64           `json:"space_id"`
+	RoleID    int64           `json:"role_id"`
+	UserID    int64           `json:"user_id"`
+	NodeID    int64           `json:"node_id"`
+	Message   string          `json:"message"`
+	CreatedAt int64           `json:"created_at"`
+}
+
+type ListEventResponse struct {
+	Total  int64      `json:"total"`
+	Events []*EventVO `json:"events"`
+}
+
+// ==== Access Request ====
+
+// maxAccessRequestDuration is the max duration of the requested membership
+const maxAccessRequestDuration = 30 * 24 * 60 * 60
+
+type CreateAccessRequestRequest struct {
+	RoleID int64 `json:"role_id"`
+	// UserID is the requester, it's ignored if the api token is
+	// bound to a user, the request is filed for the user itself
+	UserID int64 `json:"user_id"`
+	// Reason is the justification of the request
+	Reason string `json:"reason"`
+	// Duration is the requested duration of the membership in seconds
+	Duration int64 `json:"duration"`
+}
+
+func (car *CreateAccessRequestRequest) Validate() error {
+	if car.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if car.Reason == "" {
+		return err.New(errors.ParamError, "reason is required")
+	}
+	if car.Duration <= 0 || car.Duration > maxAccessRequestDuration {
+		return err.New(errors.ParamError, fmt.Sprintf("duration must be between 1 and %d seconds", maxAccessRequestDuration))
+	}
+	return nil
+}
+
+type ListAccessRequestRequest struct {
+	PageRequest
+
+	RoleID int64  `form:"role_id"`
+	UserID int64  `form:"user_id"`
+	State  string `form:"state"`
+	// Reviewable lists the requests which the operator can approve
+	Reviewable bool `form:"reviewable"`
+}
+
+func (lar *ListAccessRequestRequest) Validate() error {
+	if lar.State != "" && !model.AccessRequestState(lar.State).Valid() {
+		return err.New(errors.ParamError, "invalid state")
+	}
+	return lar.PageRequest.Validate("id", "state", "created_at", "updated_at")
+}
+
+type AccessRequestVO struct {
+	ID       int64  `json:"id"`
+	RoleID   int64  `json:"role_id"`
+	UserID   int64  `json:"user_id"`
+	Reason   string `json:"reason"`
+	Duration int64  `json:"duration"`
+	// State is one of pending, approved, denied and cancelled
+	State     model.AccessRequestState `json:"state"`
+	Reviewer  string                   `json:"reviewer"`
+	Comment   string                   `json:"comment"`
+	CreatedAt int64                    `json:"created_at"`
+	UpdatedAt int64                    `json:"updated_at"`
+}
+
+type ListAccessRequestResponse struct {
+	Total    int64              `json:"total"`
+	Requests []*AccessRequestVO `json:"requests"`
+}
+
+type AccessRequestLogVO struct {
+	FromState model.AccessRequestState `json:"from_state"`
+	ToState   model.AccessRequestState `json:"to_state"`
+	Operator  string                   `json:"operator"`
+	Comment   string                   `json:"comment"`
+	CreatedAt int64                    `json:"created_at"`
+}
+
+type GetAccessRequestResponse struct {
+	AccessRequestVO
+
+	// Logs is the state transitions of the request in order
+	Logs []*AccessRequestLogVO `json:"logs"`
+}
+
+// ReviewAccessRequestRequest approves, denies or cancels the access request
+type ReviewAccessRequestRequest struct {
+	RequestID int64  `json:"-"`
+	Comment   string `json:"comment"`
+	// Duration shortens the membership on approval, in seconds,
+	// 0 means the requested duration
+	Duration int64 `json:"duration"`
+}
+
+func (rar *ReviewAccessRequestRequest) Validate() error {
+	if rar.RequestID <= 0 {
+		return err.New(errors.ParamError, "request id is required")
+	}
+	if rar.Duration < 0 {
+		return err.New(errors.ParamError, "duration can not be negative")
+	}
+	return nil
+}
+
+type ListRoleApproverResponse struct {
+	Approvers []*RoleUserListVO `json:"approvers"`
+}
+
+type AddApproverToRoleRequest struct {
+	RoleID  int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (aar *AddApproverToRoleRequest) Validate() error {
+	if aar.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(aar.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+type RemoveApproverFromRoleRequest struct {
+	RoleID  int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (rar *RemoveApproverFromRoleRequest) Validate() error {
+	if rar.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(rar.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+// ==== Break Glass ====
+
+type ListSpaceAdminResponse struct {
+	Admins []*RoleUserListVO `json:"admins"`
+}
+
+type AddSpaceAdminRequest struct {
+	SpaceID int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (asr *AddSpaceAdminRequest) Validate() error {
+	if asr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if len(asr.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+type RemoveSpaceAdminRequest struct {
+	SpaceID int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (rsr *RemoveSpaceAdminRequest) Validate() error {
+	if rsr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if len(rsr.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+type BreakGlassRequest struct {
+	RoleID int64 `json:"role_id"`
+	// UserID is the grantee, it's ignored if the api token is
+	// bound to a user, the access is granted to the user itself
+	UserID int64 `json:"user_id"`
+	// Reason is required, it's sent to the admins of the space
+	Reason string `json:"reason"`
+}
+
+func (bgr *BreakGlassRequest) Validate() error {
+	if bgr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if strings.TrimSpace(bgr.Reason) == "" {
+		return err.New(errors.ParamError, "reason is required")
+	}
+	return nil
+}
+
+type BreakGlassResponse struct {
+	// ID is the id of the review item
+	ID   int64  `json:"id"`
+	Cert string `json:"cert"`
+	// ExpiresAt is the end of the membership and the cert
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type ListBreakGlassRequest struct {
+	PageRequest
+
+	SpaceID int64 `form:"space_id"`
+	RoleID  int64 `form:"role_id"`
+	UserID  int64 `form:"user_id"`
+	// Acked filters the acknowledged or pending reviews
+	Acked *bool `form:"acked"`
+}
+
+func (lbr *ListBreakGlassRequest) Validate() error {
+	return lbr.PageRequest.Validate("id", "expires_at", "acked_at", "created_at")
+}
+
+type BreakGlassVO struct {
+	ID         int64  `json:"id"`
+	RoleID     int64  `json:"role_id"`
+	SpaceID    int64  `json:"space_id"`
+	UserID     int64  `json:"user_id"`
+	Reason     string `json:"reason"`
+	Operator   string `json:"operator"`
+	ExpiresAt  int64  `json:"expires_at"`
+	AckedBy    string `json:"acked_by"`
+	AckedAt    int64  `json:"acked_at"`
+	AckComment string `json:"ack_comment"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type ListBreakGlassResponse struct {
+	Total int64           `json:"total"`
+	Items []*BreakGlassVO `json:"items"`
+}
+
+type AckBreakGlassRequest struct {
+	ID      int64  `json:"-"`
+	Comment string `json:"comment"`
+}
+
+func (abr *AckBreakGlassRequest) Validate() error {
+	if abr.ID <= 0 {
+		return err.New(errors.ParamError, "break-glass id is required")
+	}
+	if strings.TrimSpace(abr.Comment) == "" {
+		return err.New(errors.ParamError, "comment is required")
+	}
+	return nil
+}
+
+// ==== Group ====
+
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (cgr *CreateGroupRequest) Validate() error {
+	if cgr.Name == "" {
+		return err.New(errors.ParamError, "name is required")
+	}
+	return nil
+}
+
+type ListGroupRequest struct {
+	PageRequest
+
+	// Name matches the group name by substring
+	Name string `form:"name"`
+}
+
+func (lgr *ListGroupRequest) Validate() error {
+	return lgr.PageRequest.Validate("id", "name", "created_at")
+}
+
+type GroupVO struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+type ListGroupResponse struct {
+	Total  int64      `json:"total"`
+	Groups []*GroupVO `json:"groups"`
+}
+
+// UpdateGroupRequest updates the group, the nil fields are not changed
+type UpdateGroupRequest struct {
+	GroupID     int64   `json:"-"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (ugr *UpdateGroupRequest) Validate() error {
+	if ugr.GroupID <= 0 {
+		return err.New(errors.ParamError, "group id is required")
+	}
+	if ugr.Name != nil && *ugr.Name == "" {
+		return err.New(errors.ParamError, "name can not be empty")
+	}
+	return nil
+}
+
+type ListGroupUserRequest struct {
+	PageRequest
+
+	GroupID int64 `json:"-" form:"-"`
+
+	Name  string `form:"name"`
+	Email string `form:"email"`
+}
+
+func (lgur *ListGroupUserRequest) Validate() error {
+	if lgur.GroupID <= 0 {
+		return err.New(errors.ParamError, "group id is required")
+	}
+	return lgur.PageRequest.Validate("id", "username", "email", "created_at")
+}
+
+type GroupUserVO struct {
+	ID       int64           `json:"id"`
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+	State    model.UserState `json:"state"`
+	// JoinedAt is the time when the user is added to the group
+	JoinedAt int64 `json:"joined_at"`
+}
+
+type ListGroupUserResponse struct {
+	Total int64          `json:"total"`
+	Users []*GroupUserVO `json:"users"`
+}
+
+type AddUserToGroupRequest struct {
+	GroupID int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (aug *AddUserToGroupRequest) Validate() error {
+	if aug.GroupID <= 0 {
+		return err.New(errors.ParamError, "group id is required")
+	}
+	if len(aug.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+type RemoveUserFromGroupRequest struct {
+	GroupID int64   `json:"-"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+func (rug *RemoveUserFromGroupRequest) Validate() error {
+	if rug.GroupID <= 0 {
+		return err.New(errors.ParamError, "group id is required")
+	}
+	if len(rug.UserIDs) == 0 {
+		return err.New(errors.ParamError, "at least one user id is required")
+	}
+	return nil
+}
+
+type ListRoleGroupResponse struct {
+	Groups []*GroupVO `json:"groups"`
+}
+
+type AddGroupToRoleRequest struct {
+	RoleID   int64   `json:"-"`
+	GroupIDs []int64 `json:"group_ids"`
+}
+
+func (agr *AddGroupToRoleRequest) Validate() error {
+	if agr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(agr.GroupIDs) == 0 {
+		return err.New(errors.ParamError, "at least one group id is required")
+	}
+	return nil
+}
+
+type RemoveGroupFromRoleRequest struct {
+	RoleID   int64   `json:"-"`
+	GroupIDs []int64 `json:"group_ids"`
+}
+
+func (rgr *RemoveGroupFromRoleRequest) Validate() error {
+	if rgr.RoleID <= 0 {
+		return err.New(errors.ParamError, "role id is required")
+	}
+	if len(rgr.GroupIDs) == 0 {
+		return err.New(errors.ParamError, "at least one group id is required")
+	}
+	return nil
+}
+
+// ==== Access ====
+
+const (
+	AccessFormatJSON = "json"
+	AccessFormatCSV  = "csv"
+)
+
+// ListEffectiveAccessRequest queries the effective access of a node or a user,
+// the json response is paged and the csv one has all the access
+type ListEffectiveAccessRequest struct {
+	PageRequest
+
+	NodeID int64 `json:"-" form:"-"`
+	UserID int64 `json:"-" form:"-"`
+
+	// SpaceID limits the nodes to the space
+	SpaceID int64 `form:"space_id"`
+	// Account limits the access to the account of the nodes
+	Account string `form:"account"`
+	// Format is the format of the response, json or csv, default json
+	Format string `form:"format"`
+}
+
+func (lar *ListEffectiveAccessRequest) Validate() error {
+	if lar.NodeID <= 0 && lar.UserID <= 0 {
+		return err.New(errors.ParamError, "node id or user id is required")
+	}
+	switch lar.Format {
+	case "", AccessFormatJSON, AccessFormatCSV:
+	default:
+		return err.New(errors.ParamError, "format must be json or csv")
+	}
+	return lar.PageRequest.Validate("email", "node_name", "account", "role_name", "until")
+}
+
+// AccessVO is an effective access of a user to a node with an account
+type AccessVO struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	NodeID   int64  `json:"node_id"`
+	NodeName string `json:"node_name"`
+	SpaceID  int64  `json:"space_id"`
+	Account  string `json:"account"`
+	RoleID   int64  `json:"role_id"`
+	RoleName string `json:"role_name"`
+	Global   bool   `json:"global"`
+	// Via is "direct" if the user is added to the role directly,
+	// or "group:<name>" if the user inherits the role from a group
+	Via string `json:"via"`
+	// Binding is "explicit" if the node is added to the role, or
+	// "selector" if the node is bound by a label selector
+	Binding string `json:"binding"`
+	// ValidUntil is the end of the membership, ExpiresAt is the expiry
+	// of the user, Until is the earlier one, 0 means no limit
+	ValidUntil int64 `json:"valid_until"`
+	ExpiresAt  int64 `json:"expires_at"`
+	Until      int64 `json:"until"`
+}
+
+type ListEffectiveAccessResponse struct {
+	Total    int64       `json:"total"`
+	Accesses []*AccessVO `json:"accesses"`
+}
+
+// ==== Dry Run ====
+
+// PrincipalsDiff is the change of the principals of an account on a node,
+// Role is the account and Diff is in the format logged by guard-client
+// principals, e.g. ["+alice@example.com", "-bob@example.com"]
+type PrincipalsDiff struct {
+	NodeID   int64    `json:"node_id"`
+	NodeName string   `json:"node_name"`
+	SpaceID  int64    `json:"space_id"`
+	Role     string   `json:"role"`
+	Diff     []string `json:"diff"`
+}
+
+// DryRunScope is what the mutation of a dry run can touch, the principals
+// are compared only on the nodes and the users in it. The zero value of each
+// field means no limit, a nil scope compares the principals of every node.
+type DryRunScope struct {
+	// NodeIDs and NodeUniqueIDs are the nodes the mutation binds, unbinds
+	// or changes
+	NodeIDs       []int64
+	NodeUniqueIDs []string
+	// RoleID adds the nodes bound to the role before the mutation, with
+	// Selector the nodes the selectors of the role can bind instead, they
+	// are the nodes of the space of the role, or every node if it's global
+	RoleID   int64
+	Selector bool
+	// UserIDs are the users the mutation grants, revokes or changes
+	UserIDs []int64
+	// GroupID adds the users of the group
+	GroupID int64
+}
+
+// DryRunResponse is returned instead of the result of the mutation when
+// dry_run=true, nothing is changed
+type DryRunResponse struct {
+	DryRun bool              `json:"dry_run"`
+	Diffs  []*PrincipalsDiff `json:"diffs"`
+}
+
+// ==== Apply ====
+
+// State is the declarative description of the access model, it's kept in
+// a yaml file and applied to the server. Users are matched by email, groups,
+// spaces and roles by name, nodes by unique id or by name in the space.
+type State struct {
+	Users       []*StateUser  `json:"users,omitempty" yaml:"users,omitempty"`
+	Groups      []*StateGroup `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Spaces      []*StateSpace `json:"spaces,omitempty" yaml:"spaces,omitempty"`
+	GlobalRoles []*StateRole  `json:"global_roles,omitempty" yaml:"global_roles,omitempty"`
+}
+
+type StateUser struct {
+	Email    string `json:"email" yaml:"email"`
+	Username string `json:"username" yaml:"username"`
+	// PublicKey is required to create the user, the key of an existing
+	// user is not changed if it is empty
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+type StateGroup struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Users is the email list of the members
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+type StateSpace struct {
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description,omitempty" yaml:"description,omitempty"`
+	Nodes       []*StateNode `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Roles       []*StateRole `json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+type StateNode struct {
+	Name string `json:"name" yaml:"name"`
+	// UniqueID matches the existing node, it's generated when the node
+	// is created and returned by the apply
+	UniqueID    string `json:"unique_id,omitempty" yaml:"unique_id,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	IP          string `json:"ip" yaml:"ip"`
+	// Accounts is the account list of the node, default root
+	Accounts []string     `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+	Labels   model.Labels `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type StateRole struct {
+	Name        string               `json:"name" yaml:"name"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	BreakGlass  bool                 `json:"break_glass,omitempty" yaml:"break_glass,omitempty"`
+	Nodes       []*StateRoleNode     `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Selectors   []*StateRoleSelector `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+	// Users is the email list of the permanent members, the temporary
+	// memberships are managed by the access requests and the break-glass
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+	// Groups is the name list of the groups bound to the role
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+type StateRoleNode struct {
+	// Space is the space of the node, it's required for the global roles,
+	// the space of the role is used by default
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+	// Node is the name or the unique id of the node
+	Node string `json:"node" yaml:"node"`
+	// Account is the account of the node, default the first account
+	Account string `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+type StateRoleSelector struct {
+	Selector model.Labels `json:"selector" yaml:"selector"`
+	Account  string       `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+type ApplyRequest struct {
+	State *State `json:"state" form:"-"`
+	// Plan computes the changes and the principals diff without applying
+	Plan bool `json:"plan" form:"plan"`
+	// Prune removes the objects and the bindings which are not in the state
+	Prune bool `json:"prune" form:"prune"`
+}
+
+func (ar *ApplyRequest) Validate() error {
+	if ar.State == nil {
+		return err.New(errors.ParamError, "state is required")
+	}
+	return ar.State.Validate()
+}
+
+// Validate checks the required fields and the duplicates of the state,
+// the references are checked when the state is applied
+func (s *State) Validate() error {
+	seen := make(map[string]bool)
+	unique := func(kind, key string) error {
+		if key == "" {
+			return err.New(errors.ParamError, kind+" name is required")
+		}
+		if seen[kind+"/"+key] {
+			return err.New(errors.ParamError, fmt.Sprintf("duplicate %s %s", kind, key))
+		}
+		seen[kind+"/"+key] = true
+		return nil
+	}
+
+	for _, u := range s.Users {
+		if e := unique("user", u.Email); e != nil {
+			return e
+		}
+		if u.Username == "" {
+			return err.New(errors.ParamError, fmt.Sprintf("username of user %s is required", u.Email))
+		}
+	}
+
+	for _, g := range s.Groups {
+		if e := unique("group", g.Name); e != nil {
+			return e
+		}
+	}
+
+	roles := slices.Clone(s.GlobalRoles)
+	for _, space := range s.Spaces {
+		if e := unique("space", space.Name); e != nil {
+			return e
+		}
+
+		for _, node := range space.Nodes {
+			if e := unique("node", space.Name+"/"+node.Name); e != nil {
+				return e
+			}
+			if e := node.Labels.Validate(); e != nil {
+				return err.New(errors.ParamError, e.Error())
+			}
+		}
+		roles = append(roles, space.Roles...)
+	}
+
+	// the role names are unique across the spaces
+	for _, role := range roles {
+		if e := unique("role", role.Name); e != nil {
+			return e
+		}
+		for _, selector := range role.Selectors {
+			if len(selector.Selector) == 0 {
+				return err.New(errors.ParamError, fmt.Sprintf("selector of role %s is empty", role.Name))
+			}
+			if e := selector.Selector.Validate(); e != nil {
+				return err.New(errors.ParamError, e.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// AppliedNode is the node created by the apply, the secret is only
+// returned once and is required by guard-client
+type AppliedNode struct {
+	Space    string `json:"space"`
+	Name     string `json:"name"`
+	UniqueID string `json:"unique_id"`
+	Secret   string `json:"secret"`
+}
+
+type ApplyResponse struct {
+	Plan bool `json:"plan"`
+	// Changes are the changes in the order they are made,
+	// "+" creates, "~" updates and "-" removes
+	Changes []string       `json:"changes"`
+	Nodes   []*AppliedNode `json:"nodes,omitempty"`
+	// Diffs is the principals diff made by the changes
+	Diffs []*PrincipalsDiff `json:"diffs"`
+}
+
+// ==== Sync ====
+
+// ReportRequest is the state of the managed files reported by the node
+type ReportRequest struct {
+	UniqueID string `json:"-"`
+	dto.Report
+}
+
+func (rr *ReportRequest) Validate() error {
+	if rr.UniqueID == "" {
+		return err.New(errors.ParamError, "node id is required")
+	}
+	if len(rr.Artifacts) == 0 {
+		return err.New(errors.ParamError, "artifacts are required")
+	}
+
+	seen := make(map[dto.Artifact]bool, len(rr.Artifacts))
+	for _, a := range rr.Artifacts {
+		if !a.Kind.Valid() {
+			return err.New(errors.ParamError, fmt.Sprintf("invalid artifact kind %q", a.Kind))
+		}
+		if seen[a.Kind] {
+			return err.New(errors.ParamError, fmt.Sprintf("duplicate artifact kind %q", a.Kind))
+		}
+		seen[a.Kind] = true
+
+		if len(a.Received) > 64 || len(a.Applied) > 64 {
+			return err.New(errors.ParamError, "digest is too long")
+		}
+	}
+	return nil
+}
+
+// ListSpaceSyncRequest lists the sync status of the managed files
+// of the nodes in the space
+type ListSpaceSyncRequest struct {
+	SpaceID int64 `json:"-" form:"-"`
+
+	// NodeID filters the node, 0 means all nodes of the space
+	NodeID int64 `form:"node_id"`
+	// Kind filters the managed files, one of ca, principals,
+	// krl and authorized_keys
+	Kind string `form:"kind"`
+	// Status filters the nodes with a file in the status, one
+	// of synced, lagging, drift and unknown
+	Status string `form:"status"`
+}
+
+func (lssr *ListSpaceSyncRequest) Validate() error {
+	if lssr.SpaceID <= 0 {
+		return err.New(errors.ParamError, "space id is required")
+	}
+	if lssr.Kind != "" && !dto.Artifact(lssr.Kind).Valid() {
+		return err.New(errors.ParamError, "invalid kind")
+	}
+	if lssr.Status != "" && !model.SyncStatus(lssr.Status).Valid() {
+		return err.New(errors.ParamError, "invalid status")
+	}
+	return nil
+}
+
+// ArtifactSyncVO is a managed file of a node, Current is the digest of
+// what the server serves now, Received and Applied are reported by the node
+type ArtifactSyncVO struct {
+	Kind       dto.Artifact     `json:"kind"`
+	Status     model.SyncStatus `json:"status"`
+	Current    string           `json:"current"`
+	Received   string           `json:"received"`
+	Applied    string           `json:"applied"`
+	Error      string           `json:"error"`
+	ReportedAt int64            `json:"reported_at"`
+}
+
+type NodeSyncVO struct {
+	NodeID    int64             `json:"node_id"`
+	Name      string            `json:"name"`
+	Artifacts []*ArtifactSyncVO `json:"artifacts"`
+}
+
+// SyncSummaryVO is the number of the nodes of the space by the
+// sync status of a managed file
+type SyncSummaryVO struct {
+	Kind    dto.Artifact `json:"kind"`
+	Synced  int64        `json:"synced"`
+	Lagging int64        `json:"lagging"`
+	Drift   int64        `json:"drift"`
+	Unknown int64        `json:"unknown"`
+}
+
+func (s *SyncSummaryVO) add(status model.SyncStatus) {
+	switch status {
+	case model.SyncSynced:
+		s.Synced++
+	case model.SyncLagging:
+		s.Lagging++
+	case model.SyncDrift:
+		s.Drift++
+	default:
+		s.Unknown++
+	}
+}
+
+// ListSpaceSyncResponse is the summary of all nodes of the space and
+// the nodes matching the filters
+type ListSpaceSyncResponse struct {
+	Summary []*SyncSummaryVO `json:"summary"`
+	Nodes   []*NodeSyncVO    `json:"nodes"`
+}
+
+// ==== Watch ====
+
+type WatchResponse = dto.Watch
+
+// WatchRequest waits until the version of the files served to the node
+// is different from Version, an empty Version returns the current one
+type WatchRequest struct {
+	UniqueID string `form:"-"`
+	Version  string `form:"version"`
+}
+
+func (wr *WatchRequest) Validate() error {
+	if wr.UniqueID == "" {
+		return err.New(errors.ParamError, "node id is required")
+	}
+	if len(wr.Version) > 64 {
+		return err.New(errors.ParamError, "version is too long")
+	}
+	return nil
+}
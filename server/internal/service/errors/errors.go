@@ -18,4 +18,24 @@ var (
 	ErrSpaceNameAlreadyExists = errors.New(100005, "space name already exists")
 	ErrUserBanned             = errors.NewWithHTTPCode(http.StatusForbidden, 100006, "user is banned")
 	ErrUserAlreadyExists      = errors.New(100007, "user already exists")
+	ErrTooManyRequests        = errors.NewWithHTTPCode(http.StatusTooManyRequests, 100008, "too many requests")
+	ErrUnauthorized           = errors.NewWithHTTPCode(http.StatusUnauthorized, 100009, "unauthorized")
+	ErrSpaceNotEmpty          = errors.NewWithHTTPCode(http.StatusConflict, 100010, "space has nodes or roles, use force to delete")
+	ErrAccountInUse           = errors.NewWithHTTPCode(http.StatusConflict, 100011, "account is bound to roles, use force to remove")
+	ErrUserInactive           = errors.NewWithHTTPCode(http.StatusForbidden, 100012, "user is suspended or expired")
+	ErrAccessRequestNotFound  = errors.NewWithHTTPCode(http.StatusNotFound, 100013, "access request not found")
+	ErrAccessRequestClosed    = errors.NewWithHTTPCode(http.StatusConflict, 100014, "access request is not pending")
+	ErrAccessRequestExists    = errors.NewWithHTTPCode(http.StatusConflict, 100015, "a pending access request already exists")
+	ErrNotApprover            = errors.NewWithHTTPCode(http.StatusForbidden, 100016, "operator is not an approver of the role")
+	ErrForbidden              = errors.NewWithHTTPCode(http.StatusForbidden, 100017, "operation is not allowed for the operator")
+	ErrRoleNotBreakGlass      = errors.NewWithHTTPCode(http.StatusForbidden, 100018, "role is not a break-glass role")
+	ErrBreakGlassLimited      = errors.NewWithHTTPCode(http.StatusTooManyRequests, 100019, "too many break-glass requests of the user")
+	ErrBreakGlassNotFound     = errors.NewWithHTTPCode(http.StatusNotFound, 100020, "break-glass not found")
+	ErrBreakGlassAcked        = errors.NewWithHTTPCode(http.StatusConflict, 100021, "break-glass is already acknowledged")
+	ErrGroupNotFound          = errors.NewWithHTTPCode(http.StatusNotFound, 100022, "group not found")
+	ErrGroupNameAlreadyExists = errors.New(100023, "group name already exists")
+	ErrWatchUnavailable       = errors.NewWithHTTPCode(http.StatusServiceUnavailable, 100024, "server is shutting down, watch again later")
+	ErrMembershipConflict     = errors.NewWithHTTPCode(http.StatusConflict, 100025, "valid window conflicts with the membership")
+	ErrGlobalRoleBreakGlass   = errors.New(100026, "global role can not be break-glass")
+	ErrRoleNameAlreadyExists  = errors.New(100027, "role name already exists")
 )
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// emit records the event in the transaction
+func emit(ctx context.Context, tx repo.Repo, event *model.Event) error {
+	if err := tx.Event().Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	slog.InfoContext(ctx, "event", "type", event.Type, "space_id", event.SpaceID, "role_id", event.RoleID,
+		"user_id", event.UserID, "node_id", event.NodeID, "message", event.Message)
+	return nil
+}
+
+// ListEvent lists the events
+func (g *guard) ListEvent(ctx context.Context, in *ListEventRequest) (*ListEventResponse, error) {
+	events, total, err := g.repo.Event().List(ctx, &repo.EventFilter{
+		Type:    in.Type,
+		SpaceID: in.SpaceID,
+		RoleID:  in.RoleID,
+		UserID:  in.UserID,
+		NodeID:  in.NodeID,
+		From:    in.From,
+		To:      in.To,
+	}, in.ListOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var resp = &ListEventResponse{
+		Total:  total,
+		Events: make([]*EventVO, 0, len(events)),
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, &EventVO{
+			ID:        event.ID,
+			Type:      event.Type,
+			SpaceID:   event.SpaceID,
+			RoleID:    event.RoleID,
+			UserID:    event.UserID,
+			NodeID:    event.NodeID,
+			Message:   event.Message,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// CreateGroup creates a group of users
+func (g *guard) CreateGroup(ctx context.Context, in *CreateGroupRequest) (int64, error) {
+	group, err := g.repo.Group().GetByName(ctx, in.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get group by name: %w", err)
+	}
+
+	if group != nil {
+		return 0, errors.ErrGroupNameAlreadyExists
+	}
+
+	group = &model.Group{
+		Name:        in.Name,
+		Description: in.Description,
+	}
+
+	if err := g.repo.Group().Create(ctx, group); err != nil {
+		return 0, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return group.ID, nil
+}
+
+// ListGroup lists the groups
+func (g *guard) ListGroup(ctx context.Context, in *ListGroupRequest) (*ListGroupResponse, error) {
+	groups, total, err := g.repo.Group().List(ctx, &repo.GroupFilter{Name: in.Name}, in.ListOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	return &ListGroupResponse{
+		Total:  total,
+		Groups: groupVOs(groups),
+	}, nil
+}
+
+func groupVOs(groups []*model.Group) []*GroupVO {
+	vos := make([]*GroupVO, 0, len(groups))
+	for _, group := range groups {
+		vos = append(vos, &GroupVO{
+			ID:          group.ID,
+			Name:        group.Name,
+			Description: group.Description,
+			CreatedAt:   group.CreatedAt,
+		})
+	}
+
+	return vos
+}
+
+// UpdateGroup updates the name and description of a group
+func (g *guard) UpdateGroup(ctx context.Context, in *UpdateGroupRequest) error {
+	group, err := g.repo.Group().GetByID(ctx, in.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group by id: %w", err)
+	}
+
+	if group == nil {
+		return errors.ErrGroupNotFound
+	}
+
+	if in.Name != nil && *in.Name != group.Name {
+		exist, err := g.repo.Group().GetByName(ctx, *in.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get group by name: %w", err)
+		}
+
+		if exist != nil {
+			return errors.ErrGroupNameAlreadyExists
+		}
+
+		group.Name = *in.Name
+	}
+
+	if in.Description != nil {
+		group.Description = *in.Description
+	}
+
+	if err := g.repo.Group().Update(ctx, group); err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a group, the members lose the roles
+// inherited from the group
+func (g *guard) DeleteGroup(ctx context.Context, groupID int64) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		group, err := tx.Group().GetByID(ctx, groupID)
+		if err != nil {
+			return fmt.Errorf("failed to get group by id: %w", err)
+		}
+
+		if group == nil {
+			return errors.ErrGroupNotFound
+		}
+
+		if err := checkGlobalGroup(ctx, tx, group.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Group().RemoveRoleByGroupID(ctx, group.ID); err != nil {
+			return fmt.Errorf("failed to remove group from roles: %w", err)
+		}
+
+		if err := tx.Group().RemoveUser(ctx, group.ID); err != nil {
+			return fmt.Errorf("failed to remove users from group: %w", err)
+		}
+
+		if err := tx.Group().Delete(ctx, group.ID); err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+
+		slog.Info("group deleted", "group_id", group.ID, "name", group.Name)
+		return nil
+	})
+}
+
+// AddUserToGroup adds users to the group, the users inherit
+// the roles of the group at once
+func (g *guard) AddUserToGroup(ctx context.Context, in *AddUserToGroupRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		group, err := tx.Group().GetByID(ctx, in.GroupID)
+		if err != nil {
+			return fmt.Errorf("failed to get group by id: %w", err)
+		}
+
+		if group == nil {
+			return errors.ErrGroupNotFound
+		}
+
+		if err := checkGlobalGroup(ctx, tx, group.ID); err != nil {
+			return err
+		}
+
+		for _, userID := range in.UserIDs {
+			user, err := tx.User().GetByID(ctx, userID)
+			if err != nil {
+				return fmt.Errorf("failed to get user by id: %w", err)
+			}
+
+			if user == nil {
+				return errors.ErrUserNotFound
+			}
+
+			if user.IsBanned() {
+				return errors.ErrUserBanned
+			}
+
+			if err := tx.Group().AddUser(ctx, group.ID, user.ID); err != nil {
+				return fmt.Errorf("failed to add user to group: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "add user to group", "group", group.Name, "user_ids", in.UserIDs)
+		return nil
+	})
+}
+
+// ListGroupUser lists the members of the group
+func (g *guard) ListGroupUser(ctx context.Context, in *ListGroupUserRequest) (*ListGroupUserResponse, error) {
+	users, total, err := g.repo.Group().ListUser(ctx, &repo.GroupUserFilter{
+		GroupID: in.GroupID,
+		Name:    in.Name,
+		Email:   in.Email,
+	}, in.ListOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group users: %w", err)
+	}
+
+	var resp = &ListGroupUserResponse{
+		Total: total,
+		Users: make([]*GroupUserVO, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, &GroupUserVO{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			State:    user.State,
+			JoinedAt: user.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// RemoveUserFromGroup removes users from the group, the users
+// lose the roles inherited from the group
+func (g *guard) RemoveUserFromGroup(ctx context.Context, in *RemoveUserFromGroupRequest) error {
+	group, err := g.repo.Group().GetByID(ctx, in.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group by id: %w", err)
+	}
+
+	if group == nil {
+		return errors.ErrGroupNotFound
+	}
+
+	if err := checkGlobalGroup(ctx, g.repo, group.ID); err != nil {
+		return err
+	}
+
+	if err := g.repo.Group().RemoveUser(ctx, group.ID, in.UserIDs...); err != nil {
+		return fmt.Errorf("failed to remove user from group: %w", err)
+	}
+
+	slog.InfoContext(ctx, "remove user from group", "group", group.Name, "user_ids", in.UserIDs)
+	return nil
+}
+
+// AddGroupToRole binds groups to the role, the members of
+// the groups are principals of the role
+func (g *guard) AddGroupToRole(ctx context.Context, in *AddGroupToRoleRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		role, err := tx.Role().GetByID(ctx, in.RoleID)
+		if err != nil {
+			return fmt.Errorf("failed to get role by id: %w", err)
+		}
+
+		if role == nil {
+			return errors.ErrRoleNotFound
+		}
+
+		if role.Global && !superAdmin(ctx) {
+			return errors.ErrForbidden
+		}
+
+		for _, groupID := range in.GroupIDs {
+			group, err := tx.Group().GetByID(ctx, groupID)
+			if err != nil {
+				return fmt.Errorf("failed to get group by id: %w", err)
+			}
+
+			if group == nil {
+				return errors.ErrGroupNotFound
+			}
+
+			if err := tx.Group().AddRole(ctx, role.ID, group.ID); err != nil {
+				return fmt.Errorf("failed to add group to role: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "add group to role", "role", role.Name, "group_ids", in.GroupIDs)
+		return nil
+	})
+}
+
+// ListRoleGroup lists the groups bound to the role
+func (g *guard) ListRoleGroup(ctx context.Context, roleID int64) (*ListRoleGroupResponse, error) {
+	groups, err := g.repo.Group().ListByRoleID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role groups: %w", err)
+	}
+
+	return &ListRoleGroupResponse{
+		Groups: groupVOs(groups),
+	}, nil
+}
+
+// RemoveGroupFromRole unbinds groups from the role
+func (g *guard) RemoveGroupFromRole(ctx context.Context, in *RemoveGroupFromRoleRequest) error {
+	role, err := g.repo.Role().GetByID(ctx, in.RoleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role by id: %w", err)
+	}
+
+	if role == nil {
+		return errors.ErrRoleNotFound
+	}
+
+	if role.Global && !superAdmin(ctx) {
+		return errors.ErrForbidden
+	}
+
+	if err := g.repo.Group().RemoveRole(ctx, role.ID, in.GroupIDs...); err != nil {
+		return fmt.Errorf("failed to remove group from role: %w", err)
+	}
+
+	slog.InfoContext(ctx, "remove group from role", "role", role.Name, "group_ids", in.GroupIDs)
+	return nil
+}
+
+// checkGlobalGroup rejects the changes of the group bound to a global role
+// unless the operator is a super admin, the members of the group are the
+// principals of the nodes in all spaces
+func checkGlobalGroup(ctx context.Context, r repo.Repo, groupID int64) error {
+	if superAdmin(ctx) {
+		return nil
+	}
+
+	global, err := r.Group().HasGlobalRole(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	if global {
+		return errors.ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// newGlobalGroupDB returns the repo with the group 1 of the user 1 bound
+// to the global role 1 and the group 2 bound to the space role 2
+func newGlobalGroupDB() *memDB {
+	db := newMemDB(1, 2)
+	db.data.addRole(model.Role{ID: 1, Global: true})
+	db.data.addRole(model.Role{ID: 2, SpaceID: 1})
+	db.data.groups = []model.Group{{ID: 1, Name: "global"}, {ID: 2, Name: "space"}}
+	db.data.groupUsers = []model.GroupUser{{ID: 1, GroupID: 1, UserID: 1}}
+	db.data.roleGroups = []model.RoleGroup{{ID: 1, RoleID: 1, GroupID: 1}, {ID: 2, RoleID: 2, GroupID: 2}}
+	return db
+}
+
+func TestGlobalGroup(t *testing.T) {
+	admin := WithOperator(context.Background(), &Operator{Name: "admin"})
+	super := WithOperator(context.Background(), &Operator{Name: "root", SuperAdmin: true})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		fn   func(ctx context.Context, g *guard) error
+		err  error
+	}{
+		{"add user to global group", admin, func(ctx context.Context, g *guard) error {
+			return g.AddUserToGroup(ctx, &AddUserToGroupRequest{GroupID: 1, UserIDs: []int64{2}})
+		}, errors.ErrForbidden},
+		{"remove user from global group", admin, func(ctx context.Context, g *guard) error {
+			return g.RemoveUserFromGroup(ctx, &RemoveUserFromGroupRequest{GroupID: 1, UserIDs: []int64{1}})
+		}, errors.ErrForbidden},
+		{"delete global group", admin, func(ctx context.Context, g *guard) error {
+			return g.DeleteGroup(ctx, 1)
+		}, errors.ErrForbidden},
+		{"bind group to global role", admin, func(ctx context.Context, g *guard) error {
+			return g.AddGroupToRole(ctx, &AddGroupToRoleRequest{RoleID: 1, GroupIDs: []int64{2}})
+		}, errors.ErrForbidden},
+		{"unbind group from global role", admin, func(ctx context.Context, g *guard) error {
+			return g.RemoveGroupFromRole(ctx, &RemoveGroupFromRoleRequest{RoleID: 1, GroupIDs: []int64{2}})
+		}, errors.ErrForbidden},
+		{"add user to space group", admin, func(ctx context.Context, g *guard) error {
+			return g.AddUserToGroup(ctx, &AddUserToGroupRequest{GroupID: 2, UserIDs: []int64{2}})
+		}, nil},
+		{"bind group to space role", admin, func(ctx context.Context, g *guard) error {
+			return g.AddGroupToRole(ctx, &AddGroupToRoleRequest{RoleID: 2, GroupIDs: []int64{1}})
+		}, nil},
+		{"super admin adds user to global group", super, func(ctx context.Context, g *guard) error {
+			return g.AddUserToGroup(ctx, &AddUserToGroupRequest{GroupID: 1, UserIDs: []int64{2}})
+		}, nil},
+		{"unprotected api adds user to global group", context.Background(), func(ctx context.Context, g *guard) error {
+			return g.AddUserToGroup(ctx, &AddUserToGroupRequest{GroupID: 1, UserIDs: []int64{2}})
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newGlobalGroupDB()
+			before := db.data.clone()
+			g := &guard{repo: db}
+
+			err := tt.fn(tt.ctx, g)
+			if !stderrors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil && (len(db.data.groups) != len(before.groups) ||
+				!reflect.DeepEqual(db.data.groupUsers, before.groupUsers) ||
+				!reflect.DeepEqual(db.data.roleGroups, before.roleGroups)) {
+				t.Fatalf("the forbidden change is applied: members %v, bindings %v", db.data.groupUsers, db.data.roleGroups)
+			}
+		})
+	}
+}
@@ -4,50 +4,241 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"time"
 
+	"github.com/sysarmor/guard/server/internal/model"
 	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
 	"github.com/sysarmor/guard/server/pkg/certificate"
 )
 
 type Guard interface {
 	GetCA(ctx context.Context) []byte
+	Ready(ctx context.Context) *ReadyResponse
 	GetPrincipals(ctx context.Context, uniqueID string) (PrincipalList, error)
 	GetNodeByUniqueID(ctx context.Context, uniqueID string) (*Node, error)
 	GetKRL(ctx context.Context, uniqueID string) (string, error)
+	// GetKRLVersion returns the version of the KRL of the node without
+	// generating it, it changes only if the revoked keys change
+	GetKRLVersion(ctx context.Context, uniqueID string) (string, error)
 	GetAuthorizedKeys(ctx context.Context, uniqueID string) ([]string, error)
 
 	CreateUser(ctx context.Context, in *CreateUserRequest) (int64, error)
-	ListUser(ctx context.Context, in *ListUserRequest) (ListUserResponse, error)
+	ListUser(ctx context.Context, in *ListUserRequest) (*ListUserResponse, error)
 	GetUser(ctx context.Context, id int64) (*GetUserResponse, error)
 	GetUserByEmail(ctx context.Context, email string) (*GetUserResponse, error)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest) error
+	DeleteUser(ctx context.Context, id int64) error
 	BanUser(ctx context.Context, id int64) error
+	UnbanUser(ctx context.Context, id int64) error
+	SuspendUser(ctx context.Context, id int64) error
+	ResumeUser(ctx context.Context, id int64) error
 	UpdateUserPublicKey(ctx context.Context, in *UpdateUserPublicKeyRequest) error
 	GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCertResponse, error)
+	ListUserCert(ctx context.Context, userID int64) (*ListUserCertResponse, error)
+	RevokeCert(ctx context.Context, in *RevokeCertRequest) error
 
 	CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64, error)
-	ListSpace(ctx context.Context) (ListSpaceResponse, error)
+	ListSpace(ctx context.Context, in *ListSpaceRequest) (*ListSpaceResponse, error)
+	UpdateSpace(ctx context.Context, in *UpdateSpaceRequest) error
+	DeleteSpace(ctx context.Context, in *DeleteSpaceRequest) error
 
 	CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateNodeResponse, error)
 	ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeResponse, error)
-	DeleteNode(ctx context.Context, id int64) error
+	UpdateNode(ctx context.Context, in *UpdateNodeRequest) error
+	DeleteNode(ctx context.Context, in *DeleteNodeRequest) error
 	UpdateLastHeartbeat(ctx context.Context, uniqueID string) error
+	ListNodeHeartbeat(ctx context.Context, in *ListNodeHeartbeatRequest) (*ListNodeHeartbeatResponse, error)
+	// FleetHealth counts the nodes of every space by the health
+	FleetHealth(ctx context.Context) (*FleetHealthResponse, error)
+	// Report saves the state of the managed files reported by the node
+	Report(ctx context.Context, in *ReportRequest) error
+	// ListSpaceSync compares the files reported by the nodes with
+	// what the server serves now
+	ListSpaceSync(ctx context.Context, in *ListSpaceSyncRequest) (*ListSpaceSyncResponse, error)
+	// Watch waits until the files served to the node change
+	Watch(ctx context.Context, in *WatchRequest) (*WatchResponse, error)
+	// GetBundle returns all the files served to the node from one snapshot
+	// of the database. modified is called with the digest of the bundle
+	// before the KRL is generated, nil is returned if it returns false.
+	GetBundle(ctx context.Context, uniqueID string, modified func(digest string) bool) (*Bundle, error)
 
 	CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, error)
-	ListRole(ctx context.Context, in *ListRoleRequest) (ListRoleResponse, error)
+	ListRole(ctx context.Context, in *ListRoleRequest) (*ListRoleResponse, error)
+	GetRole(ctx context.Context, roleID int64) (*ListRoleVO, error)
+	UpdateRole(ctx context.Context, in *UpdateRoleRequest) error
 	DeleteRole(ctx context.Context, roleID int64) error
 	AddNodeToRole(ctx context.Context, in *AddNodeToRoleRequest) error
-	ListRoleNode(ctx context.Context, in *ListRoleNodeRequest) (ListRoleNodeResponse, error)
+	ListRoleNode(ctx context.Context, in *ListRoleNodeRequest) (*ListRoleNodeResponse, error)
 	RemoveNodeFromRole(ctx context.Context, in *RemoveNodeFromRoleRequest) error
+	AddRoleSelector(ctx context.Context, in *AddRoleSelectorRequest) (int64, error)
+	ListRoleSelector(ctx context.Context, roleID int64) (*ListRoleSelectorResponse, error)
+	RemoveRoleSelector(ctx context.Context, in *RemoveRoleSelectorRequest) error
 	AddUserToRole(ctx context.Context, in *AddUserToRoleRequest) error
-	ListRoleUser(ctx context.Context, in *ListRoleUserRequest) (ListRoleUserResponse, error)
+	ListRoleUser(ctx context.Context, in *ListRoleUserRequest) (*ListRoleUserResponse, error)
 	RemoveUserFromRole(ctx context.Context, in *RemoveUserFromRoleRequest) error
+
+	CreateGroup(ctx context.Context, in *CreateGroupRequest) (int64, error)
+	ListGroup(ctx context.Context, in *ListGroupRequest) (*ListGroupResponse, error)
+	UpdateGroup(ctx context.Context, in *UpdateGroupRequest) error
+	DeleteGroup(ctx context.Context, groupID int64) error
+	AddUserToGroup(ctx context.Context, in *AddUserToGroupRequest) error
+	ListGroupUser(ctx context.Context, in *ListGroupUserRequest) (*ListGroupUserResponse, error)
+	RemoveUserFromGroup(ctx context.Context, in *RemoveUserFromGroupRequest) error
+	AddGroupToRole(ctx context.Context, in *AddGroupToRoleRequest) error
+	ListRoleGroup(ctx context.Context, roleID int64) (*ListRoleGroupResponse, error)
+	RemoveGroupFromRole(ctx context.Context, in *RemoveGroupFromRoleRequest) error
+
+	ListRoleApprover(ctx context.Context, roleID int64) (*ListRoleApproverResponse, error)
+	AddApproverToRole(ctx context.Context, in *AddApproverToRoleRequest) error
+	RemoveApproverFromRole(ctx context.Context, in *RemoveApproverFromRoleRequest) error
+
+	CreateAccessRequest(ctx context.Context, in *CreateAccessRequestRequest) (int64, error)
+	ListAccessRequest(ctx context.Context, in *ListAccessRequestRequest) (*ListAccessRequestResponse, error)
+	GetAccessRequest(ctx context.Context, id int64) (*GetAccessRequestResponse, error)
+	ApproveAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error
+	DenyAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error
+	CancelAccessRequest(ctx context.Context, in *ReviewAccessRequestRequest) error
+
+	ListSpaceAdmin(ctx context.Context, spaceID int64) (*ListSpaceAdminResponse, error)
+	AddSpaceAdmin(ctx context.Context, in *AddSpaceAdminRequest) error
+	RemoveSpaceAdmin(ctx context.Context, in *RemoveSpaceAdminRequest) error
+
+	BreakGlass(ctx context.Context, in *BreakGlassRequest) (*BreakGlassResponse, error)
+	ListBreakGlass(ctx context.Context, in *ListBreakGlassRequest) (*ListBreakGlassResponse, error)
+	AckBreakGlass(ctx context.Context, in *AckBreakGlassRequest) error
+
+	ListEvent(ctx context.Context, in *ListEventRequest) (*ListEventResponse, error)
+
+	// ListEffectiveAccess lists the effective access of a node or a user
+	ListEffectiveAccess(ctx context.Context, in *ListEffectiveAccessRequest) (*ListEffectiveAccessResponse, error)
+
+	// DryRun previews the mutations in fn with the principals diff of the
+	// scope, the mutations are rolled back
+	DryRun(ctx context.Context, scope *DryRunScope, fn func(svc Guard) error) (*DryRunResponse, error)
+
+	// Apply applies the declarative state, Export returns the current one
+	Apply(ctx context.Context, in *ApplyRequest) (*ApplyResponse, error)
+	Export(ctx context.Context) (*State, error)
+
+	// Sweep runs the periodic jobs, e.g. expiring the users
+	// and the temporary memberships
+	Sweep(ctx context.Context) error
+	// Listen wakes the watching nodes on the changes of the database
+	// until ctx is done, the watches fail after that
+	Listen(ctx context.Context) error
 }
 
 type Config struct {
 	CaPassphrase string `yaml:"ca_passphrase"`
 	PubKeyPath   string `yaml:"public_key_path"`
 	PrivKeyPath  string `yaml:"private_key_path"`
+
+	// SweepInterval is the interval of the periodic jobs, default 1m
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+
+	// NotifyWebhook receives the notifications to the admins as json,
+	// if it is empty, the notifications are only logged
+	NotifyWebhook string `yaml:"notify_webhook"`
+
+	BreakGlass BreakGlassConfig `yaml:"break_glass"`
+	NodeHealth NodeHealthConfig `yaml:"node_health"`
+	Watch      WatchConfig      `yaml:"watch"`
+}
+
+// BreakGlassConfig is the configuration of the emergency access
+type BreakGlassConfig struct {
+	// Duration is the lifetime of the membership and the cert, default 1h
+	Duration time.Duration `yaml:"duration"`
+	// Limit is the max number of the break-glass per user in the window,
+	// default 3 in 24h
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+func (c *BreakGlassConfig) Validate() error {
+	if c.Duration < 0 || c.Limit < 0 || c.Window < 0 {
+		return fmt.Errorf("break glass duration, limit and window must not be negative")
+	}
+
+	if c.Duration == 0 {
+		c.Duration = time.Hour
+	}
+
+	if c.Limit == 0 {
+		c.Limit = 3
+	}
+
+	if c.Window == 0 {
+		c.Window = 24 * time.Hour
+	}
+
+	return nil
+}
+
+// NodeHealthConfig is the default heartbeat thresholds of the nodes,
+// the spaces can override them
+type NodeHealthConfig struct {
+	// StaleAfter and OfflineAfter are the durations without heartbeat
+	// before a node is stale or offline, default 10m and 1h
+	StaleAfter   time.Duration `yaml:"stale_after"`
+	OfflineAfter time.Duration `yaml:"offline_after"`
+	// History is the number of the heartbeats kept per node, default 20
+	History int `yaml:"history"`
+}
+
+func (c *NodeHealthConfig) Validate() error {
+	if c.StaleAfter < 0 || c.OfflineAfter < 0 || c.History < 0 {
+		return fmt.Errorf("node health stale_after, offline_after and history must not be negative")
+	}
+
+	if c.StaleAfter == 0 {
+		c.StaleAfter = 10 * time.Minute
+	}
+
+	if c.OfflineAfter == 0 {
+		c.OfflineAfter = time.Hour
+	}
+
+	if c.OfflineAfter <= c.StaleAfter {
+		return fmt.Errorf("node health offline_after must be greater than stale_after")
+	}
+
+	if c.History == 0 {
+		c.History = 20
+	}
+
+	return nil
+}
+
+// WatchConfig is the configuration of the watches of the nodes
+type WatchConfig struct {
+	// Timeout is the max time a watch is held, it must be shorter than
+	// the timeouts of the proxies in front of the server, default 55s
+	Timeout time.Duration `yaml:"timeout"`
+	// Coalesce is the interval the changes are coalesced in before the
+	// watches wake, default 1s
+	Coalesce time.Duration `yaml:"coalesce"`
+}
+
+func (c *WatchConfig) Validate() error {
+	if c.Timeout < 0 || c.Coalesce < 0 {
+		return fmt.Errorf("watch timeout and coalesce must not be negative")
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = 55 * time.Second
+	}
+
+	if c.Coalesce == 0 {
+		c.Coalesce = time.Second
+	}
+
+	return nil
 }
 
 func (c *Config) Validate() error {
@@ -63,7 +254,23 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ca passphrase is required")
 	}
 
-	return nil
+	if c.SweepInterval < 0 {
+		return fmt.Errorf("sweep interval must not be negative")
+	}
+
+	if c.SweepInterval == 0 {
+		c.SweepInterval = time.Minute
+	}
+
+	if err := c.BreakGlass.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Watch.Validate(); err != nil {
+		return err
+	}
+
+	return c.NodeHealth.Validate()
 }
 
 type guard struct {
@@ -72,11 +279,26 @@ type guard struct {
 
 	repo          repo.Repo
 	getPassphrase func(ctx context.Context) string
+
+	notifier   Notifier
+	breakGlass BreakGlassConfig
+	nodeHealth NodeHealthConfig
+	watch      WatchConfig
+	watcher    *watcher
+
+	// dryRun is set on the copies of the service running the dry runs,
+	// their changes are rolled back and not counted by the metrics
+	dryRun bool
 }
 
 func New(cfg Config, repo repo.Repo) (Guard, error) {
 	guard := &guard{
-		repo: repo,
+		repo:       repo,
+		notifier:   newNotifier(cfg.NotifyWebhook),
+		breakGlass: cfg.BreakGlass,
+		nodeHealth: cfg.NodeHealth,
+		watch:      cfg.Watch,
+		watcher:    newWatcher(),
 	}
 
 	if err := guard.init(&cfg); err != nil {
@@ -106,49 +328,128 @@ func (g *guard) init(cfg *Config) error {
 	return nil
 }
 
+// transaction runs fn in a transaction, the transaction is committed
+// if fn returns nil, otherwise it's rolled back
+func (g *guard) transaction(ctx context.Context, fn func(tx repo.Repo) error) (err error) {
+	tx, err := g.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.RollbackTx(ctx); txErr != nil {
+				slog.ErrorContext(ctx, "failed to rollback transaction", "error", txErr)
+			}
+			return
+		}
+
+		if txErr := tx.CommitTx(ctx); txErr != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", txErr)
+		}
+	}()
+
+	return fn(tx)
+}
+
+// Sweep runs the periodic jobs, it's called by the server
+// every sweep interval
+func (g *guard) Sweep(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	if err := g.expireUsers(ctx, now); err != nil {
+		return fmt.Errorf("failed to expire users: %w", err)
+	}
+
+	if err := g.expireRoleUsers(ctx, now); err != nil {
+		return fmt.Errorf("failed to expire role users: %w", err)
+	}
+
+	if err := g.updateNodeHealth(ctx, now); err != nil {
+		return fmt.Errorf("failed to update node health: %w", err)
+	}
+
+	return nil
+}
+
 // GetCA returns the CA certificate.
 func (g *guard) GetCA(ctx context.Context) []byte {
 	return g.publicKey
 }
 
+// readyTimeout is the timeout of the database ping of the readiness check
+const readyTimeout = 3 * time.Second
+
+// Ready checks the database is reachable and the CA can sign the certs.
+// The errors are logged instead of returned, because the readiness is
+// checked without the authentication.
+func (g *guard) Ready(ctx context.Context) *ReadyResponse {
+	resp := &ReadyResponse{Ready: true, Checks: make(map[string]string)}
+	check := func(name string, err error) {
+		if err != nil {
+			slog.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+			resp.Ready = false
+			resp.Checks[name] = "failed"
+			return
+		}
+		resp.Checks[name] = "ok"
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	check("database", g.repo.Ping(pingCtx))
+	check("ca", g.certificateSigner.Check([]byte(g.getPassphrase(ctx))))
+	return resp
+}
+
 // GetPrincipals returns the principals of the node with the given unique id.
 func (g *guard) GetPrincipals(ctx context.Context, uniqueID string) (PrincipalList, error) {
-	node, err := g.repo.Node().GetByUniqueID(ctx, uniqueID)
+	node, err := g.nodeByUniqueID(ctx, uniqueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get node by unique id: %w", err)
+		return nil, err
 	}
 
-	roleNodes, err := g.repo.Role().ListRoleNodeByNodeID(ctx, node.ID)
+	principals, _, err := g.nodeMembers(ctx, node.ID)
+	return principals, err
+}
+
+// nodeMembers returns the principals and the authorized keys of the node
+// from one query, the principals of every account are listed even if the
+// account has no member, so that the node empties its file
+func (g *guard) nodeMembers(ctx context.Context, nodeID int64) (PrincipalList, []string, error) {
+	members, err := g.repo.Role().ListNodeMember(ctx, nodeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list roles by node id: %w", err)
+		return nil, nil, fmt.Errorf("failed to list node members: %w", err)
 	}
 
-	index := make(map[string]*Principals, len(roleNodes))
-	principals := make(PrincipalList, 0, len(roleNodes))
-	for _, roleNode := range roleNodes {
-		users, err := g.repo.Role().ListUserByRoleID(ctx, roleNode.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list users by role id: %w", err)
-		}
+	principals := make(PrincipalList, 0)
+	keys := make([]string, 0)
+	keysIndex := make(map[string]struct{})
 
-		var p *Principals
-		var ok bool
-		if p, ok = index[roleNode.Account]; !ok {
+	// the members are ordered by the account
+	var p *Principals
+	for _, member := range members {
+		if p == nil || p.Role != member.Account {
 			p = &Principals{
-				Role:       roleNode.Account,
+				Role:       member.Account,
 				Principals: make([]string, 0),
 			}
-			index[roleNode.Account] = p
 			principals = append(principals, p)
 		}
 
-		for _, user := range users {
-			p.Principals = append(p.Principals, user.Email)
+		if member.Email == "" {
+			continue
 		}
+		p.Principals = append(p.Principals, member.Email)
 
+		if _, ok := keysIndex[member.PublicKey]; ok || member.PublicKey == "" {
+			continue
+		}
+		keys = append(keys, member.PublicKey)
+		keysIndex[member.PublicKey] = struct{}{}
 	}
 
-	return principals, nil
+	return principals, keys, nil
 }
 
 // GetNodeByUniqueID returns the node with the given unique id.
@@ -159,7 +460,7 @@ func (g *guard) GetNodeByUniqueID(ctx context.Context, uniqueID string) (*Node,
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("node not found")
+		return nil, nil
 	}
 
 	return &Node{
@@ -172,18 +473,65 @@ func (g *guard) GetNodeByUniqueID(ctx context.Context, uniqueID string) (*Node,
 		IP:            node.IP,
 		LastHeartbeat: node.LastHeartbeat,
 		Accounts:      node.Accounts,
+		Labels:        node.Labels,
 		CreatedAt:     node.CreatedAt,
 	}, nil
 }
 
+type nodeKey struct{}
+
+// WithNode returns the context carrying the node authenticated by the
+// request, the node apis use it instead of getting the node again
+func WithNode(ctx context.Context, node *Node) context.Context {
+	return context.WithValue(ctx, nodeKey{}, node)
+}
+
+// nodeByUniqueID returns the node carried by ctx if it's the one with the
+// unique id, otherwise it gets the node from the repo
+func (g *guard) nodeByUniqueID(ctx context.Context, uniqueID string) (*model.Node, error) {
+	if node, ok := ctx.Value(nodeKey{}).(*Node); ok && node.UniqueID == uniqueID {
+		return &model.Node{
+			ID:            node.ID,
+			UniqueID:      node.UniqueID,
+			Secret:        node.Secret,
+			Name:          node.Name,
+			Description:   node.Description,
+			SpaceID:       node.SpaceID,
+			IP:            node.IP,
+			LastHeartbeat: node.LastHeartbeat,
+			Accounts:      node.Accounts,
+			Labels:        node.Labels,
+			CreatedAt:     node.CreatedAt,
+		}, nil
+	}
+
+	node, err := g.repo.Node().GetByUniqueID(ctx, uniqueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node by unique id: %w", err)
+	}
+
+	if node == nil {
+		return nil, errors.ErrNodeNotFound
+	}
+
+	return node, nil
+}
+
 // GetKRL returns the key revocation list.
 func (g *guard) GetKRL(ctx context.Context, uniqueID string) (string, error) {
-	node, err := g.repo.Node().GetByUniqueID(ctx, uniqueID)
+	start := time.Now()
+
+	node, err := g.nodeByUniqueID(ctx, uniqueID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get node by unique id: %w", err)
+		return "", err
 	}
 
-	revokedKeys, err := g.repo.Role().ListRevokedKeys(ctx, node.ID)
+	return g.krl(ctx, node.ID, start)
+}
+
+// krl generates the KRL of the node, start is the beginning of the request
+func (g *guard) krl(ctx context.Context, nodeID int64, start time.Time) (string, error) {
+	revokedKeys, err := g.repo.Role().ListRevokedKeys(ctx, nodeID)
 	if err != nil {
 		return "", fmt.Errorf("failed to list revoked keys: %w", err)
 	}
@@ -197,40 +545,42 @@ func (g *guard) GetKRL(ctx context.Context, uniqueID string) (string, error) {
 		return "", fmt.Errorf("failed to revoke keys: %w", err)
 	}
 
+	// the empty KRLs are not generated, so they are not observed
+	krlDuration.With().Observe(time.Since(start).Seconds())
+	krlSize.With().Observe(float64(len(crl)))
+
 	return base64.StdEncoding.EncodeToString(crl), nil
 }
 
-// GetAuthorizedKeys returns the public keys of the user with the given unique id.
-// If ssh server not support certificate authentication, the public key is used for authentication.
-func (g *guard) GetAuthorizedKeys(ctx context.Context, uniqueID string) ([]string, error) {
-	node, err := g.repo.Node().GetByUniqueID(ctx, uniqueID)
+// GetKRLVersion returns the version of the KRL of the node, it's the digest
+// of the CA and the revoked keys, the KRL itself has the generated date.
+func (g *guard) GetKRLVersion(ctx context.Context, uniqueID string) (string, error) {
+	node, err := g.nodeByUniqueID(ctx, uniqueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get node by unique id: %w", err)
+		return "", err
 	}
 
-	roleIDs, err := g.repo.Role().ListRoleNodeByNodeID(ctx, node.ID)
+	return g.krlVersion(ctx, node.ID)
+}
+
+func (g *guard) krlVersion(ctx context.Context, nodeID int64) (string, error) {
+	serials, err := g.repo.Role().ListRevokedKeys(ctx, nodeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list role node by node id: %w", err)
+		return "", fmt.Errorf("failed to list revoked keys: %w", err)
 	}
+	slices.Sort(serials)
 
-	publicKeys := make([]string, 0)
-	publicKeysIndex := make(map[string]struct{})
-
-	for _, roleID := range roleIDs {
-		keys, err := g.repo.Role().ListUserPublicKeyByRoleID(ctx, roleID.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list user public key by role id: %w", err)
-		}
-
-		for _, key := range keys {
-			if _, ok := publicKeysIndex[key]; ok {
-				continue
-			}
+	return dto.Digest(fmt.Appendf(nil, "%s\n%v", g.publicKey, serials)), nil
+}
 
-			publicKeys = append(publicKeys, key)
-			publicKeysIndex[key] = struct{}{}
-		}
+// GetAuthorizedKeys returns the public keys of the user with the given unique id.
+// If ssh server not support certificate authentication, the public key is used for authentication.
+func (g *guard) GetAuthorizedKeys(ctx context.Context, uniqueID string) ([]string, error) {
+	node, err := g.nodeByUniqueID(ctx, uniqueID)
+	if err != nil {
+		return nil, err
 	}
 
-	return publicKeys, nil
+	_, keys, err := g.nodeMembers(ctx, node.ID)
+	return keys, err
 }
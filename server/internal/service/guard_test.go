@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"github.com/sysarmor/guard/server/pkg/certificate"
+	"golang.org/x/crypto/ssh"
+)
+
+// newCA returns the private key encrypted by the passphrase and the public key
+func newCA(t *testing.T, passphrase string) ([]byte, []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(block), ssh.MarshalAuthorizedKey(publicKey)
+}
+
+func TestReady(t *testing.T) {
+	privateKey, publicKey := newCA(t, "secret")
+
+	tests := []struct {
+		name       string
+		pingErr    error
+		passphrase string
+		expected   *ReadyResponse
+	}{
+		{name: "ready", passphrase: "secret",
+			expected: &ReadyResponse{Ready: true, Checks: map[string]string{"database": "ok", "ca": "ok"}}},
+		{name: "database down", pingErr: stderrors.New("connection refused"), passphrase: "secret",
+			expected: &ReadyResponse{Checks: map[string]string{"database": "failed", "ca": "ok"}}},
+		{name: "wrong passphrase", passphrase: "wrong",
+			expected: &ReadyResponse{Checks: map[string]string{"database": "ok", "ca": "failed"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemDB()
+			db.pingErr = tt.pingErr
+			g := &guard{
+				repo:              db,
+				certificateSigner: certificate.New(privateKey, publicKey),
+				getPassphrase:     func(context.Context) string { return tt.passphrase },
+			}
+
+			if resp := g.Ready(context.Background()); !reflect.DeepEqual(resp, tt.expected) {
+				t.Fatalf("expected %+v, got %+v", tt.expected, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"github.com/sysarmor/guard/server/pkg/metrics"
+)
+
+// the reasons of the revoked certs
+const (
+	revokeReasonAPI       = "api"
+	revokeReasonBan       = "ban"
+	revokeReasonSuspend   = "suspend"
+	revokeReasonDelete    = "delete"
+	revokeReasonExpired   = "expired"
+	revokeReasonEmail     = "email"
+	revokeReasonPublicKey = "public_key"
+)
+
+var (
+	certsIssued = metrics.NewCounterVec("guard_certs_issued_total",
+		"Number of the user certs issued")
+	certsRevoked = metrics.NewCounterVec("guard_certs_revoked_total",
+		"Number of the user certs revoked, by the reason", "reason")
+	krlDuration = metrics.NewHistogramVec("guard_krl_generation_seconds",
+		"Time to generate the KRL of a node in seconds", metrics.DefaultBuckets)
+	krlSize = metrics.NewHistogramVec("guard_krl_size_bytes",
+		"Size of the KRL of a node in bytes", []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576})
+)
+
+func init() {
+	// the counters start from 0 instead of missing
+	certsIssued.With()
+	for _, reason := range []string{revokeReasonAPI, revokeReasonBan, revokeReasonSuspend,
+		revokeReasonDelete, revokeReasonExpired, revokeReasonEmail, revokeReasonPublicKey} {
+		certsRevoked.With(reason)
+	}
+
+	metrics.Register(certsIssued, certsRevoked, krlDuration, krlSize)
+}
+
+func (g *guard) countIssued() {
+	if !g.dryRun {
+		certsIssued.With().Inc()
+	}
+}
+
+func (g *guard) countRevoked(reason string, n int64) {
+	if !g.dryRun && n > 0 {
+		certsRevoked.With(reason).Add(float64(n))
+	}
+}
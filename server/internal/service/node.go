@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
 	"github.com/sysarmor/guard/server/internal/service/errors"
 	"github.com/sysarmor/guard/server/pkg/helper"
 )
@@ -41,6 +44,7 @@ func (g *guard) CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateN
 		Secret:      helper.RandString(defaultSecretLength),
 		IP:          in.IP,
 		Accounts:    in.Accounts,
+		Labels:      in.Labels,
 	}
 
 	if err := g.repo.Node().Create(ctx, node); err != nil {
@@ -56,13 +60,20 @@ func (g *guard) CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateN
 
 // ListNode list nodes
 func (g *guard) ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeResponse, error) {
-	nodes, total, err := g.repo.Node().List(ctx, in.SpaceID,
-		in.PageRequest.Offset(), in.PageRequest.Limit)
+	nodes, total, err := g.repo.Node().List(ctx, &repo.NodeFilter{
+		SpaceID:       in.SpaceID,
+		Name:          in.Name,
+		Account:       in.Account,
+		HeartbeatFrom: in.HeartbeatFrom,
+		HeartbeatTo:   in.HeartbeatTo,
+		Labels:        in.LabelSelector(),
+		Health:        in.Health,
+	}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list nodes: %w", err)
 	}
 
-	var resp []*ListNodeVO
+	var resp = make([]*ListNodeVO, 0, len(nodes))
 	for _, node := range nodes {
 		resp = append(resp, &ListNodeVO{
 			ID:            node.ID,
@@ -72,6 +83,8 @@ func (g *guard) ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeRes
 			IP:            node.IP,
 			LastHeartbeat: node.LastHeartbeat,
 			Accounts:      node.Accounts,
+			Labels:        node.Labels,
+			Health:        node.Health,
 			CreatedAt:     node.CreatedAt,
 		})
 	}
@@ -82,21 +95,97 @@ func (g *guard) ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeRes
 	}, nil
 }
 
+// UpdateNode updates a node. Removing an account which is bound to roles
+// is refused, unless force is set, then the bindings are removed too.
+func (g *guard) UpdateNode(ctx context.Context, in *UpdateNodeRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		node, err := tx.Node().GetByID(ctx, in.NodeID)
+		if err != nil {
+			return fmt.Errorf("failed to get node by id: %w", err)
+		}
+
+		if node == nil || node.SpaceID != in.SpaceID {
+			return errors.ErrNodeNotFound
+		}
+
+		if in.Name != nil {
+			node.Name = *in.Name
+		}
+
+		if in.Description != nil {
+			node.Description = *in.Description
+		}
+
+		if in.IP != nil {
+			node.IP = *in.IP
+		}
+
+		if in.Accounts != nil {
+			roleNodes, err := tx.Role().ListRoleNodeByNodeID(ctx, node.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list role node by node id: %w", err)
+			}
+
+			for _, roleNode := range roleNodes {
+				// the selectors follow the accounts of the node
+				if roleNode.Dynamic || slices.Contains(in.Accounts, roleNode.Account) {
+					continue
+				}
+
+				if !in.Force {
+					return errors.ErrAccountInUse
+				}
+
+				if err := tx.Role().RemoveNode(ctx, roleNode.RoleID, node.ID); err != nil {
+					return fmt.Errorf("failed to remove node from role: %w", err)
+				}
+
+				slog.Info("node removed from role", "node_id", node.ID,
+					"role_id", roleNode.RoleID, "account", roleNode.Account)
+			}
+
+			node.Accounts = in.Accounts
+		}
+
+		if in.Labels != nil {
+			node.Labels = in.Labels
+		}
+
+		if err := tx.Node().Update(ctx, node); err != nil {
+			return fmt.Errorf("failed to update node: %w", err)
+		}
+
+		return nil
+	})
+}
+
 // DeleteNode delete a node
-func (g *guard) DeleteNode(ctx context.Context, id int64) error {
-	// remove the node from all roles
-	roles, err := g.repo.Role().ListRoleNodeByNodeID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to list role node by node id: %w", err)
-	}
+func (g *guard) DeleteNode(ctx context.Context, in *DeleteNodeRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		node, err := tx.Node().GetByID(ctx, in.NodeID)
+		if err != nil {
+			return fmt.Errorf("failed to get node by id: %w", err)
+		}
+
+		if node == nil {
+			return errors.ErrNodeNotFound
+		}
 
-	for _, role := range roles {
-		if err := g.repo.Role().RemoveNode(ctx, role.ID, id); err != nil {
-			return fmt.Errorf("failed to remove node from role: %w", err)
+		if node.SpaceID != in.SpaceID {
+			return paramError("node %d is not in space %d", node.ID, in.SpaceID)
 		}
+
+		return deleteNode(ctx, tx, node.ID)
+	})
+}
+
+// deleteNode removes the node from all roles and deletes it
+func deleteNode(ctx context.Context, tx repo.Repo, id int64) error {
+	if err := tx.Role().RemoveNodeByNodeID(ctx, id); err != nil {
+		return fmt.Errorf("failed to remove node from roles: %w", err)
 	}
 
-	if err := g.repo.Node().Delete(ctx, id); err != nil {
+	if err := tx.Node().Delete(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete node: %w", err)
 	}
 
@@ -110,3 +199,124 @@ func (g *guard) UpdateLastHeartbeat(ctx context.Context, uniqueID string) error
 
 	return nil
 }
+
+// thresholds returns the effective heartbeat thresholds of the space
+// in seconds, the zero values fall back to the server defaults
+func (g *guard) thresholds(space *model.Space) (staleAfter, offlineAfter int64) {
+	staleAfter, offlineAfter = space.StaleAfter, space.OfflineAfter
+	if staleAfter == 0 {
+		staleAfter = int64(g.nodeHealth.StaleAfter / time.Second)
+	}
+	if offlineAfter == 0 {
+		offlineAfter = int64(g.nodeHealth.OfflineAfter / time.Second)
+	}
+	return staleAfter, offlineAfter
+}
+
+// ListNodeHeartbeat returns the health and the recent heartbeats of a node
+func (g *guard) ListNodeHeartbeat(ctx context.Context, in *ListNodeHeartbeatRequest) (*ListNodeHeartbeatResponse, error) {
+	node, err := g.repo.Node().GetByID(ctx, in.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node by id: %w", err)
+	}
+
+	if node == nil || node.SpaceID != in.SpaceID {
+		return nil, errors.ErrNodeNotFound
+	}
+
+	space, err := g.repo.Space().GetByID(ctx, node.SpaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get space by id: %w", err)
+	}
+
+	if space == nil {
+		return nil, errors.ErrSpaceNotFound
+	}
+
+	heartbeats, err := g.repo.Node().ListHeartbeat(ctx, node.ID, g.nodeHealth.History)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list heartbeats: %w", err)
+	}
+
+	staleAfter, offlineAfter := g.thresholds(space)
+	return &ListNodeHeartbeatResponse{
+		NodeID:        node.ID,
+		Health:        node.Health,
+		LastHeartbeat: node.LastHeartbeat,
+		StaleAfter:    staleAfter,
+		OfflineAfter:  offlineAfter,
+		Heartbeats:    heartbeats,
+	}, nil
+}
+
+// FleetHealth counts the nodes of every space by the health
+func (g *guard) FleetHealth(ctx context.Context) (*FleetHealthResponse, error) {
+	counts, err := g.repo.Node().CountByHealth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count nodes by health: %w", err)
+	}
+
+	resp := &FleetHealthResponse{Spaces: make([]*SpaceHealthVO, 0, len(counts))}
+	for _, c := range counts {
+		staleAfter, offlineAfter := g.thresholds(&model.Space{StaleAfter: c.StaleAfter, OfflineAfter: c.OfflineAfter})
+		resp.Spaces = append(resp.Spaces, &SpaceHealthVO{
+			SpaceID:      c.SpaceID,
+			SpaceName:    c.SpaceName,
+			StaleAfter:   staleAfter,
+			OfflineAfter: offlineAfter,
+			Total:        c.Healthy + c.Stale + c.Offline,
+			Healthy:      c.Healthy,
+			Stale:        c.Stale,
+			Offline:      c.Offline,
+		})
+	}
+
+	return resp, nil
+}
+
+// healthEvents is the event of the health a node changes to
+var healthEvents = map[model.NodeHealth]model.EventType{
+	model.NodeHealthy: model.EventNodeHealthy,
+	model.NodeStale:   model.EventNodeStale,
+	model.NodeOffline: model.EventNodeOffline,
+}
+
+// updateNodeHealth computes the health of the nodes, emits an event for
+// every node crossing a threshold, and prunes the heartbeat history
+func (g *guard) updateNodeHealth(ctx context.Context, now int64) error {
+	err := g.transaction(ctx, func(tx repo.Repo) error {
+		changes, err := tx.Node().UpdateHealth(ctx, now,
+			int64(g.nodeHealth.StaleAfter/time.Second), int64(g.nodeHealth.OfflineAfter/time.Second))
+		if err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			message := "no heartbeat"
+			if c.LastHeartbeat > 0 {
+				message = fmt.Sprintf("last heartbeat at %s",
+					time.Unix(c.LastHeartbeat, 0).UTC().Format(time.RFC3339))
+			}
+
+			if err := emit(ctx, tx, &model.Event{
+				Type:    healthEvents[c.To],
+				SpaceID: c.SpaceID,
+				NodeID:  c.NodeID,
+				Message: fmt.Sprintf("node %s changed from %s to %s, %s", c.Name, c.From, c.To, message),
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.repo.Node().PruneHeartbeat(ctx, g.nodeHealth.History); err != nil {
+		return fmt.Errorf("failed to prune heartbeats: %w", err)
+	}
+
+	return nil
+}
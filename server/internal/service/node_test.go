@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+// defaultNodeHealth is the node health config with the default thresholds
+var defaultNodeHealth = NodeHealthConfig{StaleAfter: 10 * time.Minute, OfflineAfter: time.Hour, History: 20}
+
+// TestThresholds checks the thresholds of the space override the defaults
+// one by one, and the effective thresholds are refused if offline_after
+// is not greater than stale_after
+func TestThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		space   model.Space
+		stale   int64
+		offline int64
+		wantErr bool
+	}{
+		{name: "defaults", stale: 600, offline: 3600},
+		{name: "override", space: model.Space{StaleAfter: 60, OfflineAfter: 120}, stale: 60, offline: 120},
+		{name: "stale only", space: model.Space{StaleAfter: 1800}, stale: 1800, offline: 3600},
+		{name: "offline only", space: model.Space{OfflineAfter: 1200}, stale: 600, offline: 1200},
+		{name: "stale over default offline", space: model.Space{StaleAfter: 7200}, stale: 7200, offline: 3600, wantErr: true},
+		{name: "offline under default stale", space: model.Space{OfflineAfter: 300}, stale: 600, offline: 300, wantErr: true},
+		{name: "equal", space: model.Space{StaleAfter: 60, OfflineAfter: 60}, stale: 60, offline: 60, wantErr: true},
+	}
+
+	g := &guard{nodeHealth: defaultNodeHealth}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stale, offline := g.thresholds(&tt.space)
+			if stale != tt.stale || offline != tt.offline {
+				t.Fatalf("expected the thresholds %d/%d, got %d/%d", tt.stale, tt.offline, stale, offline)
+			}
+			if err := g.checkThresholds(&tt.space); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestUpdateNodeHealth checks the nodes go from healthy to stale to
+// offline by the thresholds of their spaces as the time passes, and back
+// to healthy on a heartbeat, with an event for every change only
+func TestUpdateNodeHealth(t *testing.T) {
+	const t0 = 1700000000
+	at := func(sec int64) string { return time.Unix(sec, 0).UTC().Format(time.RFC3339) }
+
+	// web is in the space a of the default thresholds, db is in the space
+	// b of 60s/120s, new never synced and legacy is migrated as healthy
+	db := newMemDB()
+	db.data.spaces = []model.Space{{ID: 1, Name: "a"}, {ID: 2, Name: "b", StaleAfter: 60, OfflineAfter: 120}}
+	db.data.nodes = []model.Node{
+		{ID: 3, SpaceID: 1, Name: "web", LastHeartbeat: t0, Health: model.NodeOffline},
+		{ID: 4, SpaceID: 2, Name: "db", LastHeartbeat: t0, Health: model.NodeOffline},
+		{ID: 5, SpaceID: 1, Name: "new", Health: model.NodeOffline},
+		{ID: 6, SpaceID: 1, Name: "legacy", Health: model.NodeHealthy},
+	}
+	db.data.reserve(6)
+
+	steps := []struct {
+		name string
+		now  int64
+		// heartbeat is the node which syncs at now before the sweep
+		heartbeat string
+		// expected is the event as "type node message"
+		expected []string
+	}{
+		{name: "first sweep", now: t0, expected: []string{
+			"node.healthy 3 node web changed from offline to healthy, last heartbeat at " + at(t0),
+			"node.healthy 4 node db changed from offline to healthy, last heartbeat at " + at(t0),
+			"node.offline 6 node legacy changed from healthy to offline, no heartbeat",
+		}},
+		{name: "unchanged", now: t0 + 60, expected: []string{}},
+		{name: "space stale", now: t0 + 61, expected: []string{
+			"node.stale 4 node db changed from healthy to stale, last heartbeat at " + at(t0),
+		}},
+		{name: "space offline", now: t0 + 121, expected: []string{
+			"node.offline 4 node db changed from stale to offline, last heartbeat at " + at(t0),
+		}},
+		{name: "default healthy", now: t0 + 600, expected: []string{}},
+		{name: "default stale", now: t0 + 601, expected: []string{
+			"node.stale 3 node web changed from healthy to stale, last heartbeat at " + at(t0),
+		}},
+		{name: "default offline", now: t0 + 3601, expected: []string{
+			"node.offline 3 node web changed from stale to offline, last heartbeat at " + at(t0),
+		}},
+		{name: "heartbeat", now: t0 + 3700, heartbeat: "web", expected: []string{
+			"node.healthy 3 node web changed from offline to healthy, last heartbeat at " + at(t0+3700),
+		}},
+	}
+
+	g := &guard{repo: db, nodeHealth: defaultNodeHealth}
+	for _, step := range steps {
+		for i := range db.data.nodes {
+			if db.data.nodes[i].Name == step.heartbeat {
+				db.data.nodes[i].LastHeartbeat = step.now
+			}
+		}
+
+		seen := len(db.data.events)
+		if err := g.updateNodeHealth(context.Background(), step.now); err != nil {
+			t.Fatal(err)
+		}
+
+		events := make([]string, 0)
+		for _, e := range db.data.events[seen:] {
+			events = append(events, fmt.Sprintf("%s %d %s", e.Type, e.NodeID, e.Message))
+		}
+		if !reflect.DeepEqual(events, step.expected) {
+			t.Fatalf("%s: expected %q, got %q", step.name, step.expected, events)
+		}
+	}
+
+	if db.data.heartbeatsKept != defaultNodeHealth.History {
+		t.Fatalf("expected %d heartbeats kept, got %d", defaultNodeHealth.History, db.data.heartbeatsKept)
+	}
+}
+
+// TestFleetHealth checks the nodes are counted by the space and the
+// health, the spaces without nodes are listed and the thresholds fall
+// back to the defaults
+func TestFleetHealth(t *testing.T) {
+	db := newMemDB()
+	db.data.spaces = []model.Space{
+		{ID: 1, Name: "a"},
+		{ID: 2, Name: "b", StaleAfter: 60, OfflineAfter: 120},
+		{ID: 3, Name: "empty", OfflineAfter: 7200},
+	}
+	db.data.nodes = []model.Node{
+		{ID: 4, SpaceID: 1, Health: model.NodeHealthy},
+		{ID: 5, SpaceID: 1, Health: model.NodeHealthy},
+		{ID: 6, SpaceID: 1, Health: model.NodeOffline},
+		{ID: 7, SpaceID: 2, Health: model.NodeStale},
+		{ID: 8, SpaceID: 2, Health: model.NodeOffline},
+	}
+
+	g := &guard{repo: db, nodeHealth: defaultNodeHealth}
+	resp, err := g.FleetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*SpaceHealthVO{
+		{SpaceID: 1, SpaceName: "a", StaleAfter: 600, OfflineAfter: 3600, Total: 3, Healthy: 2, Offline: 1},
+		{SpaceID: 2, SpaceName: "b", StaleAfter: 60, OfflineAfter: 120, Total: 2, Stale: 1, Offline: 1},
+		{SpaceID: 3, SpaceName: "empty", StaleAfter: 600, OfflineAfter: 7200},
+	}
+	if !reflect.DeepEqual(resp.Spaces, expected) {
+		for i, s := range resp.Spaces {
+			t.Logf("%d: %+v", i, s)
+		}
+		t.Fatal("unexpected fleet health")
+	}
+}
+
+// TestDeleteNode checks a node is only deleted with its role bindings by
+// the space it belongs to
+func TestDeleteNode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *DeleteNodeRequest
+		err  error
+	}{
+		{name: "delete", in: &DeleteNodeRequest{SpaceID: 1, NodeID: 3}},
+		{name: "other space", in: &DeleteNodeRequest{SpaceID: 2, NodeID: 3}, err: paramError("")},
+		{name: "unknown node", in: &DeleteNodeRequest{SpaceID: 1, NodeID: 9}, err: errors.ErrNodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemDB()
+			db.data.nodes = []model.Node{{ID: 3, SpaceID: 1, Name: "web"}, {ID: 4, SpaceID: 2, Name: "db"}}
+			db.data.addRole(model.Role{ID: 5, SpaceID: 1})
+			db.data.roleNodes = []model.RoleNode{{ID: 6, RoleID: 5, NodeID: 3, Account: "root"}}
+
+			g := &guard{repo: db}
+			err := g.DeleteNode(context.Background(), tt.in)
+			if !stderrors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			nodes, roleNodes := 1, 0
+			if err != nil {
+				nodes, roleNodes = 2, 1
+			}
+			if len(db.data.nodes) != nodes || len(db.data.roleNodes) != roleNodes {
+				t.Fatalf("expected %d nodes and %d role nodes, got %d and %d",
+					nodes, roleNodes, len(db.data.nodes), len(db.data.roleNodes))
+			}
+		})
+	}
+}
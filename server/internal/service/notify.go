@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Notification is the message sent to the admins
+type Notification struct {
+	Type    string `json:"type"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// Recipients is the email list of the receivers
+	Recipients []string `json:"recipients"`
+	CreatedAt  int64    `json:"created_at"`
+}
+
+// Notifier sends the notifications
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// newNotifier returns the webhook notifier if the url is set,
+// otherwise the notifications are only logged
+func newNotifier(webhook string) Notifier {
+	if webhook == "" {
+		return logNotifier{}
+	}
+
+	return &webhookNotifier{
+		url:    webhook,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type logNotifier struct{}
+
+func (logNotifier) Notify(ctx context.Context, n *Notification) error {
+	slog.WarnContext(ctx, "notification", "type", n.Type, "subject", n.Subject,
+		"message", n.Message, "recipients", n.Recipients)
+	return nil
+}
+
+// webhookNotifier posts the notification as json to the url,
+// the receiver delivers it to the recipients
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	logNotifier{}.Notify(ctx, n) // nolint
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to post notification: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// nopNotifier drops the notifications, it's used by the dry runs
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(ctx context.Context, n *Notification) error {
+	return nil
+}
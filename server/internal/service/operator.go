@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+type operatorKey struct{}
+
+// Operator is the caller of the admin api, it's set by the controller
+// after the api token is authenticated
+type Operator struct {
+	// Name is the name of the api token
+	Name string
+	// Email is the email of the guard user bound to the token, it's
+	// empty if the token is not bound to a user
+	Email string
+	// SuperAdmin manages the global roles
+	SuperAdmin bool
+}
+
+// WithOperator returns a context with the operator
+func WithOperator(ctx context.Context, operator *Operator) context.Context {
+	return context.WithValue(ctx, operatorKey{}, operator)
+}
+
+// OperatorFrom returns the operator in the context, it's an empty
+// operator if the admin api is not protected
+func OperatorFrom(ctx context.Context) *Operator {
+	if operator, ok := ctx.Value(operatorKey{}).(*Operator); ok && operator != nil {
+		return operator
+	}
+	return &Operator{}
+}
+
+// superAdmin reports whether the caller can change the global roles and
+// their members. The internal calls and the unprotected admin api have no
+// operator, they are allowed as the controller allows them.
+func superAdmin(ctx context.Context) bool {
+	operator, ok := ctx.Value(operatorKey{}).(*Operator)
+	return !ok || operator == nil || operator.SuperAdmin
+}
+
+// String returns the email of the operator, or the token name if the
+// token is not bound to a user
+func (o *Operator) String() string {
+	if o.Email != "" {
+		return o.Email
+	}
+
+	if o.Name != "" {
+		return o.Name
+	}
+
+	return "anonymous"
+}
+
+// operatorUser returns the user bound to the operator, it's nil if the
+// operator is not bound to a user
+func operatorUser(ctx context.Context, r repo.Repo) (*model.User, error) {
+	operator := OperatorFrom(ctx)
+	if operator.Email == "" {
+		return nil, nil
+	}
+
+	user, err := r.User().GetByEmail(ctx, operator.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operator by email: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	return user, nil
+}
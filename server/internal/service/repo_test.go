@@ -0,0 +1,864 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+)
+
+// memData is the tables of memDB, the rows are kept by value so that a
+// transaction works on its own copy
+type memData struct {
+	lastID int64
+
+	users             []model.User
+	userCerts         []model.UserCert
+	groups            []model.Group
+	groupUsers        []model.GroupUser
+	spaces            []model.Space
+	spaceAdmins       []model.SpaceUser
+	nodes             []model.Node
+	roles             []model.Role
+	roleNodes         []model.RoleNode
+	roleUsers         []model.RoleUser
+	roleGroups        []model.RoleGroup
+	approvers         []model.RoleApprover
+	accessRequests    []model.AccessRequest
+	accessRequestLogs []model.AccessRequestLog
+	breakGlass        []model.BreakGlass
+	events            []model.Event
+
+	// locked is the ids of the users locked for update
+	locked []int64
+	// afterListExpired is called after the expired memberships are listed,
+	// the test changes them before they are removed
+	afterListExpired func()
+	// heartbeatsKept is the keep of the last PruneHeartbeat
+	heartbeatsKept int
+}
+
+func (d *memData) clone() *memData {
+	return &memData{
+		lastID:            d.lastID,
+		users:             slices.Clone(d.users),
+		userCerts:         slices.Clone(d.userCerts),
+		groups:            slices.Clone(d.groups),
+		groupUsers:        slices.Clone(d.groupUsers),
+		spaces:            slices.Clone(d.spaces),
+		spaceAdmins:       slices.Clone(d.spaceAdmins),
+		nodes:             slices.Clone(d.nodes),
+		roles:             slices.Clone(d.roles),
+		roleNodes:         slices.Clone(d.roleNodes),
+		roleUsers:         slices.Clone(d.roleUsers),
+		roleGroups:        slices.Clone(d.roleGroups),
+		approvers:         slices.Clone(d.approvers),
+		accessRequests:    slices.Clone(d.accessRequests),
+		accessRequestLogs: slices.Clone(d.accessRequestLogs),
+		breakGlass:        slices.Clone(d.breakGlass),
+		events:            slices.Clone(d.events),
+		locked:            slices.Clone(d.locked),
+	}
+}
+
+func (d *memData) nextID() int64 {
+	d.lastID++
+	return d.lastID
+}
+
+// reserve returns the id given by the test, the ids created later are
+// greater than it
+func (d *memData) reserve(id int64) int64 {
+	d.lastID = max(d.lastID, id)
+	return id
+}
+
+// addUser adds the active user user-<id>@example.com
+func (d *memData) addUser(id int64) {
+	d.users = append(d.users, model.User{
+		ID:       d.reserve(id),
+		Username: fmt.Sprintf("user-%d", id),
+		Email:    fmt.Sprintf("user-%d@example.com", id),
+		State:    model.UserStateActive,
+	})
+}
+
+// addRole adds the role, it's named role-<id> if the name is empty
+func (d *memData) addRole(role model.Role) {
+	d.reserve(role.ID)
+	if role.Name == "" {
+		role.Name = fmt.Sprintf("role-%d", role.ID)
+	}
+	d.roles = append(d.roles, role)
+}
+
+// memDB is the in-memory repo of the tests. The transactions work on a
+// copy which replaces the data of the parent on commit, so a rolled back
+// change is not seen. The methods not used by the tests panic.
+type memDB struct {
+	repo.Repo
+
+	data   *memData
+	parent *memDB
+
+	// notify passes the notifications of the test to Listen, version
+	// is set by the test and versions counts the reads of it
+	notify   chan struct{}
+	version  atomic.Int64
+	versions atomic.Int64
+
+	// pingErr is returned by Ping
+	pingErr error
+}
+
+// newMemDB returns the repo with the active users of the ids
+func newMemDB(userIDs ...int64) *memDB {
+	db := &memDB{data: &memData{}, notify: make(chan struct{})}
+	for _, id := range userIDs {
+		db.data.addUser(id)
+	}
+	return db
+}
+
+func (db *memDB) BeginTx(ctx context.Context) (repo.Repo, error) {
+	return &memDB{data: db.data.clone(), parent: db}, nil
+}
+
+func (db *memDB) CommitTx(ctx context.Context) error {
+	db.parent.data = db.data
+	return nil
+}
+
+func (db *memDB) RollbackTx(ctx context.Context) error { return nil }
+
+func (db *memDB) Ping(ctx context.Context) error { return db.pingErr }
+
+func (db *memDB) Listen(ctx context.Context, fn func()) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-db.notify:
+			fn()
+		}
+	}
+}
+
+func (db *memDB) Version(ctx context.Context) (int64, error) {
+	db.versions.Add(1)
+	return db.version.Load(), nil
+}
+
+func (db *memDB) User() repo.UserRepo                   { return &memDBUser{memData: db.data} }
+func (db *memDB) Group() repo.GroupRepo                 { return &memDBGroup{memData: db.data} }
+func (db *memDB) Space() repo.SpaceRepo                 { return &memDBSpace{memData: db.data} }
+func (db *memDB) Node() repo.NodeRepo                   { return &memDBNode{memData: db.data} }
+func (db *memDB) Role() repo.RoleRepo                   { return &memDBRole{memData: db.data} }
+func (db *memDB) AccessRequest() repo.AccessRequestRepo { return &memDBAccessRequest{memData: db.data} }
+func (db *memDB) BreakGlass() repo.BreakGlassRepo       { return &memDBBreakGlass{memData: db.data} }
+func (db *memDB) Event() repo.EventRepo                 { return &memDBEvent{memData: db.data} }
+
+// find returns the first row matched, nil if none
+func find[T any](rows []T, match func(*T) bool) *T {
+	for i := range rows {
+		if match(&rows[i]) {
+			row := rows[i]
+			return &row
+		}
+	}
+	return nil
+}
+
+// selectRows returns the rows matched
+func selectRows[T any](rows []T, match func(*T) bool) []*T {
+	list := make([]*T, 0)
+	for i := range rows {
+		if match(&rows[i]) {
+			row := rows[i]
+			list = append(list, &row)
+		}
+	}
+	return list
+}
+
+// filterAll returns all rows
+func filterAll[T any](rows []T) []*T {
+	return selectRows(rows, func(*T) bool { return true })
+}
+
+// update replaces the rows matched
+func update[T any](rows []T, match func(*T) bool, row T) {
+	for i := range rows {
+		if match(&rows[i]) {
+			rows[i] = row
+		}
+	}
+}
+
+// in reports whether the id is in ids, all ids match if ids is empty
+func in(ids []int64, id int64) bool {
+	return len(ids) == 0 || slices.Contains(ids, id)
+}
+
+type memDBUser struct {
+	repo.UserRepo
+	*memData
+}
+
+func (r *memDBUser) List(ctx context.Context, filter *repo.UserFilter, opt *repo.ListOption) ([]*model.User, int64, error) {
+	users := filterAll(r.users)
+	return users, int64(len(users)), nil
+}
+
+func (r *memDBUser) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	return r.get(id), nil
+}
+
+func (r *memDBUser) GetByIDForUpdate(ctx context.Context, id int64) (*model.User, error) {
+	r.locked = append(r.locked, id)
+	return r.get(id), nil
+}
+
+func (r *memDBUser) get(id int64) *model.User {
+	return find(r.users, func(u *model.User) bool { return u.ID == id })
+}
+
+func (r *memDBUser) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return find(r.users, func(u *model.User) bool { return u.Email == email }), nil
+}
+
+func (r *memDBUser) Create(ctx context.Context, user *model.User) error {
+	user.ID = r.nextID()
+	if user.State == "" {
+		user.State = model.UserStateActive
+	}
+	r.users = append(r.users, *user)
+	return nil
+}
+
+func (r *memDBUser) Update(ctx context.Context, user *model.User) error {
+	update(r.users, func(u *model.User) bool { return u.ID == user.ID }, *user)
+	return nil
+}
+
+func (r *memDBUser) UpdatePubKey(ctx context.Context, id int64, pubKey string) error {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			r.users[i].PubKey = pubKey
+		}
+	}
+	return nil
+}
+
+func (r *memDBUser) GrantCert(ctx context.Context, cert *model.UserCert) error {
+	cert.ID = r.nextID()
+	r.userCerts = append(r.userCerts, *cert)
+	return nil
+}
+
+func (r *memDBUser) RevokeAllCerts(ctx context.Context, userID int64) (int64, error) {
+	var revoked int64
+	for i := range r.userCerts {
+		if r.userCerts[i].UserID == userID && !r.userCerts[i].IsRevoked {
+			r.userCerts[i].IsRevoked = true
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *memDBUser) Delete(ctx context.Context, id int64) error {
+	r.users = slices.DeleteFunc(r.users, func(u model.User) bool { return u.ID == id })
+	return nil
+}
+
+type memDBGroup struct {
+	repo.GroupRepo
+	*memData
+}
+
+func (r *memDBGroup) List(ctx context.Context, filter *repo.GroupFilter, opt *repo.ListOption) ([]*model.Group, int64, error) {
+	groups := filterAll(r.groups)
+	return groups, int64(len(groups)), nil
+}
+
+func (r *memDBGroup) GetByID(ctx context.Context, id int64) (*model.Group, error) {
+	return find(r.groups, func(g *model.Group) bool { return g.ID == id }), nil
+}
+
+func (r *memDBGroup) GetByName(ctx context.Context, name string) (*model.Group, error) {
+	return find(r.groups, func(g *model.Group) bool { return g.Name == name }), nil
+}
+
+func (r *memDBGroup) Create(ctx context.Context, group *model.Group) error {
+	group.ID = r.nextID()
+	r.groups = append(r.groups, *group)
+	return nil
+}
+
+func (r *memDBGroup) Update(ctx context.Context, group *model.Group) error {
+	update(r.groups, func(g *model.Group) bool { return g.ID == group.ID }, *group)
+	return nil
+}
+
+func (r *memDBGroup) Delete(ctx context.Context, id int64) error {
+	r.groups = slices.DeleteFunc(r.groups, func(g model.Group) bool { return g.ID == id })
+	return nil
+}
+
+func (r *memDBGroup) ListUser(ctx context.Context, filter *repo.GroupUserFilter, opt *repo.ListOption) ([]*model.User, int64, error) {
+	users := make([]*model.User, 0)
+	for _, gu := range r.groupUsers {
+		if gu.GroupID == filter.GroupID {
+			users = append(users, (&memDBUser{memData: r.memData}).get(gu.UserID))
+		}
+	}
+	return users, int64(len(users)), nil
+}
+
+func (r *memDBGroup) AddUser(ctx context.Context, groupID, userID int64) error {
+	r.groupUsers = append(r.groupUsers, model.GroupUser{ID: r.nextID(), GroupID: groupID, UserID: userID})
+	return nil
+}
+
+func (r *memDBGroup) RemoveUser(ctx context.Context, groupID int64, userIDs ...int64) error {
+	r.groupUsers = slices.DeleteFunc(r.groupUsers, func(gu model.GroupUser) bool {
+		return gu.GroupID == groupID && in(userIDs, gu.UserID)
+	})
+	return nil
+}
+
+func (r *memDBGroup) RemoveUserByUserID(ctx context.Context, userID int64) error {
+	r.groupUsers = slices.DeleteFunc(r.groupUsers, func(gu model.GroupUser) bool { return gu.UserID == userID })
+	return nil
+}
+
+func (r *memDBGroup) ListByRoleID(ctx context.Context, roleID int64) ([]*model.Group, error) {
+	groups := make([]*model.Group, 0)
+	for _, rg := range r.roleGroups {
+		if rg.RoleID == roleID {
+			groups = append(groups, find(r.groups, func(g *model.Group) bool { return g.ID == rg.GroupID }))
+		}
+	}
+	return groups, nil
+}
+
+func (r *memDBGroup) ListRoleByUserID(ctx context.Context, userID int64) ([]int64, error) {
+	roleIDs := make([]int64, 0)
+	for _, gu := range r.groupUsers {
+		if gu.UserID != userID {
+			continue
+		}
+		for _, rg := range r.roleGroups {
+			if rg.GroupID == gu.GroupID && !slices.Contains(roleIDs, rg.RoleID) {
+				roleIDs = append(roleIDs, rg.RoleID)
+			}
+		}
+	}
+	return roleIDs, nil
+}
+
+func (r *memDBGroup) AddRole(ctx context.Context, roleID, groupID int64) error {
+	r.roleGroups = append(r.roleGroups, model.RoleGroup{ID: r.nextID(), RoleID: roleID, GroupID: groupID})
+	return nil
+}
+
+func (r *memDBGroup) RemoveRole(ctx context.Context, roleID int64, groupIDs ...int64) error {
+	r.roleGroups = slices.DeleteFunc(r.roleGroups, func(rg model.RoleGroup) bool {
+		return rg.RoleID == roleID && in(groupIDs, rg.GroupID)
+	})
+	return nil
+}
+
+func (r *memDBGroup) RemoveRoleByGroupID(ctx context.Context, groupID int64) error {
+	r.roleGroups = slices.DeleteFunc(r.roleGroups, func(rg model.RoleGroup) bool { return rg.GroupID == groupID })
+	return nil
+}
+
+func (r *memDBGroup) HasGlobalRole(ctx context.Context, groupID int64) (bool, error) {
+	for _, rg := range r.roleGroups {
+		role := find(r.roles, func(role *model.Role) bool { return role.ID == rg.RoleID })
+		if rg.GroupID == groupID && role.Global {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memDBSpace struct {
+	repo.SpaceRepo
+	*memData
+}
+
+func (r *memDBSpace) List(ctx context.Context, filter *repo.SpaceFilter, opt *repo.ListOption) ([]*model.Space, int64, error) {
+	spaces := filterAll(r.spaces)
+	return spaces, int64(len(spaces)), nil
+}
+
+func (r *memDBSpace) GetByID(ctx context.Context, id int64) (*model.Space, error) {
+	return find(r.spaces, func(s *model.Space) bool { return s.ID == id }), nil
+}
+
+func (r *memDBSpace) GetByName(ctx context.Context, name string) (*model.Space, error) {
+	return find(r.spaces, func(s *model.Space) bool { return s.Name == name }), nil
+}
+
+func (r *memDBSpace) Create(ctx context.Context, space *model.Space) error {
+	space.ID = r.nextID()
+	r.spaces = append(r.spaces, *space)
+	return nil
+}
+
+func (r *memDBSpace) Update(ctx context.Context, space *model.Space) error {
+	update(r.spaces, func(s *model.Space) bool { return s.ID == space.ID }, *space)
+	return nil
+}
+
+func (r *memDBSpace) Delete(ctx context.Context, id int64) error {
+	r.spaces = slices.DeleteFunc(r.spaces, func(s model.Space) bool { return s.ID == id })
+	return nil
+}
+
+func (r *memDBSpace) IsAdmin(ctx context.Context, spaceID, userID int64) (bool, error) {
+	admin := find(r.spaceAdmins, func(su *model.SpaceUser) bool { return su.SpaceID == spaceID && su.UserID == userID })
+	return admin != nil, nil
+}
+
+func (r *memDBSpace) ListAdmin(ctx context.Context, spaceID int64) ([]*model.User, error) {
+	admins := make([]*model.User, 0)
+	for _, su := range r.spaceAdmins {
+		if su.SpaceID == spaceID {
+			admins = append(admins, (&memDBUser{memData: r.memData}).get(su.UserID))
+		}
+	}
+	return admins, nil
+}
+
+func (r *memDBSpace) RemoveAdmin(ctx context.Context, spaceID int64, userIDs ...int64) error {
+	r.spaceAdmins = slices.DeleteFunc(r.spaceAdmins, func(su model.SpaceUser) bool {
+		return su.SpaceID == spaceID && in(userIDs, su.UserID)
+	})
+	return nil
+}
+
+func (r *memDBSpace) RemoveAdminByUserID(ctx context.Context, userID int64) error {
+	r.spaceAdmins = slices.DeleteFunc(r.spaceAdmins, func(su model.SpaceUser) bool { return su.UserID == userID })
+	return nil
+}
+
+type memDBNode struct {
+	repo.NodeRepo
+	*memData
+}
+
+func (r *memDBNode) List(ctx context.Context, filter *repo.NodeFilter, opt *repo.ListOption) ([]*model.Node, int64, error) {
+	nodes := selectRows(r.nodes, func(n *model.Node) bool { return n.SpaceID == filter.SpaceID })
+	return nodes, int64(len(nodes)), nil
+}
+
+func (r *memDBNode) GetByID(ctx context.Context, id int64) (*model.Node, error) {
+	return find(r.nodes, func(n *model.Node) bool { return n.ID == id }), nil
+}
+
+func (r *memDBNode) GetByUniqueID(ctx context.Context, uniqueID string) (*model.Node, error) {
+	return find(r.nodes, func(n *model.Node) bool { return n.UniqueID == uniqueID }), nil
+}
+
+func (r *memDBNode) Create(ctx context.Context, node *model.Node) error {
+	node.ID = r.nextID()
+	r.nodes = append(r.nodes, *node)
+	return nil
+}
+
+func (r *memDBNode) Update(ctx context.Context, node *model.Node) error {
+	update(r.nodes, func(n *model.Node) bool { return n.ID == node.ID }, *node)
+	return nil
+}
+
+func (r *memDBNode) Delete(ctx context.Context, id int64) error {
+	r.nodes = slices.DeleteFunc(r.nodes, func(n model.Node) bool { return n.ID == id })
+	return nil
+}
+
+// UpdateHealth computes the health the same way as the query, the
+// thresholds of the space fall back to the defaults if they are zero
+func (r *memDBNode) UpdateHealth(ctx context.Context, now, staleAfter, offlineAfter int64) ([]*repo.HealthChange, error) {
+	changes := make([]*repo.HealthChange, 0)
+	for i := range r.nodes {
+		n := &r.nodes[i]
+		space := find(r.spaces, func(s *model.Space) bool { return s.ID == n.SpaceID })
+		if space == nil {
+			continue
+		}
+
+		stale, offline := cmp.Or(space.StaleAfter, staleAfter), cmp.Or(space.OfflineAfter, offlineAfter)
+		health := model.NodeOffline
+		switch {
+		case n.LastHeartbeat >= now-stale:
+			health = model.NodeHealthy
+		case n.LastHeartbeat >= now-offline:
+			health = model.NodeStale
+		}
+
+		if health != n.Health {
+			changes = append(changes, &repo.HealthChange{NodeID: n.ID, SpaceID: n.SpaceID, Name: n.Name,
+				LastHeartbeat: n.LastHeartbeat, From: n.Health, To: health})
+			n.Health = health
+		}
+	}
+	return changes, nil
+}
+
+func (r *memDBNode) CountByHealth(ctx context.Context) ([]*repo.HealthCount, error) {
+	counts := make([]*repo.HealthCount, 0, len(r.spaces))
+	for _, s := range r.spaces {
+		c := &repo.HealthCount{SpaceID: s.ID, SpaceName: s.Name, StaleAfter: s.StaleAfter, OfflineAfter: s.OfflineAfter}
+		for _, n := range r.nodes {
+			if n.SpaceID != s.ID {
+				continue
+			}
+			switch n.Health {
+			case model.NodeHealthy:
+				c.Healthy++
+			case model.NodeStale:
+				c.Stale++
+			default:
+				c.Offline++
+			}
+		}
+		counts = append(counts, c)
+	}
+	return counts, nil
+}
+
+func (r *memDBNode) PruneHeartbeat(ctx context.Context, keep int) (int64, error) {
+	r.heartbeatsKept = keep
+	return 0, nil
+}
+
+type memDBRole struct {
+	repo.RoleRepo
+	*memData
+}
+
+func (r *memDBRole) List(ctx context.Context, filter *repo.RoleFilter, opt *repo.ListOption) ([]*model.Role, int64, error) {
+	roles := selectRows(r.roles, func(role *model.Role) bool {
+		if filter.Global {
+			return role.Global
+		}
+		return !role.Global && role.SpaceID == filter.SpaceID
+	})
+	return roles, int64(len(roles)), nil
+}
+
+func (r *memDBRole) GetByID(ctx context.Context, id int64) (*model.Role, error) {
+	return find(r.roles, func(role *model.Role) bool { return role.ID == id }), nil
+}
+
+func (r *memDBRole) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	return find(r.roles, func(role *model.Role) bool { return role.Name == name }), nil
+}
+
+func (r *memDBRole) Create(ctx context.Context, role *model.Role) error {
+	role.ID = r.nextID()
+	r.roles = append(r.roles, *role)
+	return nil
+}
+
+func (r *memDBRole) Update(ctx context.Context, role *model.Role) error {
+	update(r.roles, func(old *model.Role) bool { return old.ID == role.ID }, *role)
+	return nil
+}
+
+func (r *memDBRole) Delete(ctx context.Context, id int64) error {
+	r.roles = slices.DeleteFunc(r.roles, func(role model.Role) bool { return role.ID == id })
+	return nil
+}
+
+func (r *memDBRole) ListNode(ctx context.Context, filter *repo.RoleNodeFilter, opt *repo.ListOption) ([]*model.RoleNodeView, int64, error) {
+	nodes := make([]*model.RoleNodeView, 0)
+	for _, rn := range r.roleNodes {
+		if rn.RoleID == filter.RoleID {
+			node := find(r.nodes, func(n *model.Node) bool { return n.ID == rn.NodeID })
+			nodes = append(nodes, &model.RoleNodeView{Node: *node, Account: rn.Account})
+		}
+	}
+	return nodes, int64(len(nodes)), nil
+}
+
+func (r *memDBRole) ListRoleNodeByNodeID(ctx context.Context, nodeID int64) ([]*model.RoleNode, error) {
+	return selectRows(r.roleNodes, func(rn *model.RoleNode) bool { return rn.NodeID == nodeID }), nil
+}
+
+func (r *memDBRole) GetRoleNodeByRoleIDAndNodeID(ctx context.Context, roleID, nodeID int64) (*model.RoleNode, error) {
+	return find(r.roleNodes, func(rn *model.RoleNode) bool { return rn.RoleID == roleID && rn.NodeID == nodeID }), nil
+}
+
+func (r *memDBRole) AddNode(ctx context.Context, roleID, nodeID int64, account string) error {
+	r.roleNodes = append(r.roleNodes, model.RoleNode{ID: r.nextID(), RoleID: roleID, NodeID: nodeID, Account: account})
+	return nil
+}
+
+func (r *memDBRole) RemoveNode(ctx context.Context, roleID int64, nodeIDs ...int64) error {
+	r.roleNodes = slices.DeleteFunc(r.roleNodes, func(rn model.RoleNode) bool {
+		return rn.RoleID == roleID && in(nodeIDs, rn.NodeID)
+	})
+	return nil
+}
+
+func (r *memDBRole) RemoveNodeByNodeID(ctx context.Context, nodeID int64) error {
+	r.roleNodes = slices.DeleteFunc(r.roleNodes, func(rn model.RoleNode) bool { return rn.NodeID == nodeID })
+	return nil
+}
+
+func (r *memDBRole) ListSelector(ctx context.Context, roleID int64) ([]*model.RoleSelector, error) {
+	return nil, nil
+}
+
+func (r *memDBRole) RemoveSelector(ctx context.Context, roleID int64, selectorIDs ...int64) error {
+	return nil
+}
+
+func (r *memDBRole) ListUser(ctx context.Context, filter *repo.RoleUserFilter, opt *repo.ListOption) ([]*model.RoleUserView, int64, error) {
+	users := make([]*model.RoleUserView, 0)
+	for _, ru := range r.roleUsers {
+		if ru.RoleID == filter.RoleID {
+			users = append(users, &model.RoleUserView{
+				User:       *(&memDBUser{memData: r.memData}).get(ru.UserID),
+				ValidFrom:  ru.ValidFrom,
+				ValidUntil: ru.ValidUntil,
+				Direct:     true,
+			})
+		}
+	}
+	return users, int64(len(users)), nil
+}
+
+func (r *memDBRole) GetRoleUserByRoleIDAndUserID(ctx context.Context, roleID, userID int64) (*model.RoleUser, error) {
+	return find(r.roleUsers, func(ru *model.RoleUser) bool { return ru.RoleID == roleID && ru.UserID == userID }), nil
+}
+
+func (r *memDBRole) ListRoleUserByUserID(ctx context.Context, userID int64) ([]*model.RoleUser, error) {
+	return selectRows(r.roleUsers, func(ru *model.RoleUser) bool { return ru.UserID == userID }), nil
+}
+
+func (r *memDBRole) AddUser(ctx context.Context, roleUser *model.RoleUser) error {
+	roleUser.ID = r.nextID()
+	r.roleUsers = append(r.roleUsers, *roleUser)
+	return nil
+}
+
+func (r *memDBRole) UpdateUser(ctx context.Context, roleUser *model.RoleUser) error {
+	update(r.roleUsers, func(ru *model.RoleUser) bool { return ru.ID == roleUser.ID }, *roleUser)
+	return nil
+}
+
+func (r *memDBRole) RemoveUser(ctx context.Context, roleID int64, userIDs ...int64) error {
+	r.roleUsers = slices.DeleteFunc(r.roleUsers, func(ru model.RoleUser) bool {
+		return ru.RoleID == roleID && in(userIDs, ru.UserID)
+	})
+	return nil
+}
+
+func (r *memDBRole) ListExpiredUser(ctx context.Context, now int64) ([]*model.RoleUser, error) {
+	expired := selectRows(r.roleUsers, func(ru *model.RoleUser) bool { return ru.ValidUntil > 0 && ru.ValidUntil <= now })
+	if r.afterListExpired != nil {
+		r.afterListExpired()
+	}
+	return expired, nil
+}
+
+func (r *memDBRole) RemoveExpiredUser(ctx context.Context, id, now int64) (bool, error) {
+	expired := func(ru model.RoleUser) bool { return ru.ID == id && ru.ValidUntil > 0 && ru.ValidUntil <= now }
+	n := len(r.roleUsers)
+	r.roleUsers = slices.DeleteFunc(r.roleUsers, expired)
+	return len(r.roleUsers) < n, nil
+}
+
+func (r *memDBRole) RemoveUserByUserID(ctx context.Context, userID int64) error {
+	r.roleUsers = slices.DeleteFunc(r.roleUsers, func(ru model.RoleUser) bool { return ru.UserID == userID })
+	return nil
+}
+
+// ListAccess lists the access of the active users by the nodes bound
+// explicitly, the direct memberships valid now and the groups, it's sorted
+// like the postgres repo by default
+func (r *memDBRole) ListAccess(ctx context.Context, filter *repo.AccessFilter, opt *repo.ListOption) ([]*model.Access, int64, error) {
+	now := time.Now().Unix()
+	users := &memDBUser{memData: r.memData}
+
+	// member is a user of the role, by the group if group is set
+	type member struct {
+		userID     int64
+		group      string
+		validUntil int64
+	}
+
+	accesses := make([]*model.Access, 0)
+	for _, role := range r.roles {
+		members := make([]member, 0)
+		for _, ru := range r.roleUsers {
+			if ru.RoleID == role.ID && ru.ValidAt(now) {
+				members = append(members, member{userID: ru.UserID, validUntil: ru.ValidUntil})
+			}
+		}
+		for _, rg := range r.roleGroups {
+			if rg.RoleID != role.ID {
+				continue
+			}
+			group := find(r.groups, func(g *model.Group) bool { return g.ID == rg.GroupID })
+			for _, gu := range r.groupUsers {
+				if gu.GroupID == rg.GroupID {
+					members = append(members, member{userID: gu.UserID, group: group.Name})
+				}
+			}
+		}
+
+		for _, rn := range r.roleNodes {
+			if rn.RoleID != role.ID {
+				continue
+			}
+			node := find(r.nodes, func(n *model.Node) bool { return n.ID == rn.NodeID })
+			for _, m := range members {
+				user := users.get(m.userID)
+				if user.State != model.UserStateActive || (user.ExpiresAt > 0 && user.ExpiresAt <= now) {
+					continue
+				}
+				access := &model.Access{
+					UserID:     user.ID,
+					Username:   user.Username,
+					Email:      user.Email,
+					NodeID:     node.ID,
+					NodeName:   node.Name,
+					SpaceID:    node.SpaceID,
+					Account:    rn.Account,
+					RoleID:     role.ID,
+					RoleName:   role.Name,
+					Global:     role.Global,
+					Group:      m.group,
+					ValidUntil: m.validUntil,
+					ExpiresAt:  user.ExpiresAt,
+				}
+				if matchAccess(filter, access) {
+					accesses = append(accesses, access)
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(accesses, func(a, b *model.Access) int {
+		return cmp.Or(strings.Compare(a.Email, b.Email), cmp.Compare(a.SpaceID, b.SpaceID),
+			strings.Compare(a.NodeName, b.NodeName), strings.Compare(a.Account, b.Account),
+			strings.Compare(a.RoleName, b.RoleName))
+	})
+
+	total := int64(len(accesses))
+	if opt != nil {
+		accesses = accesses[min(opt.Offset, total):]
+		if opt.Limit > 0 {
+			accesses = accesses[:min(opt.Limit, int64(len(accesses)))]
+		}
+	}
+	return accesses, total, nil
+}
+
+// matchAccess reports whether the access matches the filter
+func matchAccess(filter *repo.AccessFilter, access *model.Access) bool {
+	return (filter.NodeID == 0 || access.NodeID == filter.NodeID) &&
+		(filter.UserID == 0 || access.UserID == filter.UserID) &&
+		(filter.SpaceID == 0 || access.SpaceID == filter.SpaceID) &&
+		(filter.Account == "" || access.Account == filter.Account) &&
+		(len(filter.NodeIDs) == 0 || in(filter.NodeIDs, access.NodeID)) &&
+		(len(filter.UserIDs) == 0 || in(filter.UserIDs, access.UserID))
+}
+
+type memDBAccessRequest struct {
+	repo.AccessRequestRepo
+	*memData
+}
+
+func (r *memDBAccessRequest) GetByID(ctx context.Context, id int64) (*model.AccessRequest, error) {
+	return find(r.accessRequests, func(ar *model.AccessRequest) bool { return ar.ID == id }), nil
+}
+
+func (r *memDBAccessRequest) UpdateState(ctx context.Context, request *model.AccessRequest, from model.AccessRequestState) (bool, error) {
+	match := func(ar *model.AccessRequest) bool { return ar.ID == request.ID && ar.State == from }
+	if find(r.accessRequests, match) == nil {
+		return false, nil
+	}
+	update(r.accessRequests, match, *request)
+	return true, nil
+}
+
+func (r *memDBAccessRequest) CreateLog(ctx context.Context, log *model.AccessRequestLog) error {
+	log.ID = r.nextID()
+	r.accessRequestLogs = append(r.accessRequestLogs, *log)
+	return nil
+}
+
+func (r *memDBAccessRequest) IsApprover(ctx context.Context, roleID, userID int64) (bool, error) {
+	approver := find(r.approvers, func(ra *model.RoleApprover) bool { return ra.RoleID == roleID && ra.UserID == userID })
+	return approver != nil, nil
+}
+
+func (r *memDBAccessRequest) RemoveApprover(ctx context.Context, roleID int64, userIDs ...int64) error {
+	r.approvers = slices.DeleteFunc(r.approvers, func(ra model.RoleApprover) bool {
+		return ra.RoleID == roleID && in(userIDs, ra.UserID)
+	})
+	return nil
+}
+
+func (r *memDBAccessRequest) RemoveApproverByUserID(ctx context.Context, userID int64) error {
+	r.approvers = slices.DeleteFunc(r.approvers, func(ra model.RoleApprover) bool { return ra.UserID == userID })
+	return nil
+}
+
+// memDBBreakGlass fails the count if the user is not locked, the
+// concurrent break-glass of the user must be counted one by one
+type memDBBreakGlass struct {
+	repo.BreakGlassRepo
+	*memData
+}
+
+func (r *memDBBreakGlass) Create(ctx context.Context, bg *model.BreakGlass) error {
+	bg.ID = r.nextID()
+	bg.CreatedAt = time.Now().Unix()
+	r.breakGlass = append(r.breakGlass, *bg)
+	return nil
+}
+
+func (r *memDBBreakGlass) GetByID(ctx context.Context, id int64) (*model.BreakGlass, error) {
+	return find(r.breakGlass, func(bg *model.BreakGlass) bool { return bg.ID == id }), nil
+}
+
+func (r *memDBBreakGlass) Ack(ctx context.Context, bg *model.BreakGlass) error {
+	bg.AckedAt = time.Now().Unix()
+	update(r.breakGlass, func(old *model.BreakGlass) bool { return old.ID == bg.ID }, *bg)
+	return nil
+}
+
+func (r *memDBBreakGlass) CountByUserID(ctx context.Context, userID, since int64) (int64, error) {
+	if !slices.Contains(r.locked, userID) {
+		return 0, fmt.Errorf("user %d is not locked", userID)
+	}
+
+	count := selectRows(r.breakGlass, func(bg *model.BreakGlass) bool { return bg.UserID == userID && bg.CreatedAt >= since })
+	return int64(len(count)), nil
+}
+
+type memDBEvent struct {
+	repo.EventRepo
+	*memData
+}
+
+func (r *memDBEvent) Create(ctx context.Context, event *model.Event) error {
+	event.ID = r.nextID()
+	r.events = append(r.events, *event)
+	return nil
+}
@@ -4,27 +4,48 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
 	"github.com/sysarmor/guard/server/internal/service/errors"
 )
 
-// CreateRole create a role
+// CreateRole create a role, a global role does not belong to any space
 func (g *guard) CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, error) {
-	space, err := g.repo.Space().GetByID(ctx, in.SpaceID)
+	if in.Global {
+		// the break-glass is reviewed by the admins of the space
+		if in.BreakGlass {
+			return 0, errors.ErrGlobalRoleBreakGlass
+		}
+		in.SpaceID = 0
+	} else {
+		space, err := g.repo.Space().GetByID(ctx, in.SpaceID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get space by id: %w", err)
+		}
+
+		if space == nil {
+			slog.Error("space not found", "space_id", in.SpaceID)
+			return 0, errors.ErrSpaceNotFound
+		}
+	}
+
+	exist, err := g.repo.Role().GetByName(ctx, in.Name)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get space by id: %w", err)
+		return 0, fmt.Errorf("failed to get role by name: %w", err)
 	}
 
-	if space == nil {
-		slog.Error("space not found", "space_id", in.SpaceID)
-		return 0, errors.ErrSpaceNotFound
+	if exist != nil {
+		return 0, errors.ErrRoleNameAlreadyExists
 	}
 
 	role := &model.Role{
 		SpaceID:     in.SpaceID,
 		Name:        in.Name,
 		Description: in.Description,
+		BreakGlass:  in.BreakGlass,
+		Global:      in.Global,
 	}
 
 	if err := g.repo.Role().Create(ctx, role); err != nil {
@@ -34,40 +55,132 @@ func (g *guard) CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, e
 	return role.ID, nil
 }
 
-// ListRole list roles
-func (g *guard) ListRole(ctx context.Context, in *ListRoleRequest) (ListRoleResponse, error) {
-	roles, err := g.repo.Role().List(ctx, in.SpaceID)
+// ListRole list roles, the roles of a space include the global
+// roles which bind the nodes of the space
+func (g *guard) ListRole(ctx context.Context, in *ListRoleRequest) (*ListRoleResponse, error) {
+	roles, total, err := g.repo.Role().List(ctx, &repo.RoleFilter{
+		SpaceID:       in.SpaceID,
+		Global:        in.Global,
+		IncludeGlobal: !in.Global,
+		Name:          in.Name,
+		BreakGlass:    in.BreakGlass,
+	}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list roles: %w", err)
 	}
 
-	var resp = make(ListRoleResponse, 0, len(roles))
+	var resp = &ListRoleResponse{
+		Total: total,
+		Roles: make([]*ListRoleVO, 0, len(roles)),
+	}
 	for _, role := range roles {
-		resp = append(resp, &ListRoleVO{
-			ID:          role.ID,
-			Name:        role.Name,
-			Description: role.Description,
-			CreatedAt:   role.CreatedAt,
-		})
+		resp.Roles = append(resp.Roles, roleVO(role))
 	}
 
 	return resp, nil
 }
 
+// GetRole returns the role by id
+func (g *guard) GetRole(ctx context.Context, roleID int64) (*ListRoleVO, error) {
+	role, err := g.repo.Role().GetByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role by id: %w", err)
+	}
+
+	if role == nil {
+		return nil, errors.ErrRoleNotFound
+	}
+
+	return roleVO(role), nil
+}
+
+func roleVO(role *model.Role) *ListRoleVO {
+	return &ListRoleVO{
+		ID:          role.ID,
+		SpaceID:     role.SpaceID,
+		Name:        role.Name,
+		Description: role.Description,
+		BreakGlass:  role.BreakGlass,
+		Global:      role.Global,
+		CreatedAt:   role.CreatedAt,
+	}
+}
+
+// UpdateRole updates the name, description and break-glass mark of a role
+func (g *guard) UpdateRole(ctx context.Context, in *UpdateRoleRequest) error {
+	role, err := g.repo.Role().GetByID(ctx, in.RoleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role by id: %w", err)
+	}
+
+	if role == nil || role.SpaceID != in.SpaceID {
+		return errors.ErrRoleNotFound
+	}
+
+	if in.Name != nil && *in.Name != role.Name {
+		exist, err := g.repo.Role().GetByName(ctx, *in.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get role by name: %w", err)
+		}
+
+		if exist != nil {
+			return errors.ErrRoleNameAlreadyExists
+		}
+
+		role.Name = *in.Name
+	}
+
+	if in.Description != nil {
+		role.Description = *in.Description
+	}
+
+	if in.BreakGlass != nil {
+		if *in.BreakGlass && role.Global {
+			return errors.ErrGlobalRoleBreakGlass
+		}
+		role.BreakGlass = *in.BreakGlass
+	}
+
+	if err := g.repo.Role().Update(ctx, role); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteRole delete a role
 func (g *guard) DeleteRole(ctx context.Context, roleID int64) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		return deleteRole(ctx, tx, roleID)
+	})
+}
+
+// deleteRole removes all users and nodes from the role and deletes it
+func deleteRole(ctx context.Context, tx repo.Repo, roleID int64) error {
 	// delete users from role
-	if err := g.repo.Role().RemoveUser(ctx, roleID); err != nil {
+	if err := tx.Role().RemoveUser(ctx, roleID); err != nil {
 		return fmt.Errorf("failed to remove users from role: %w", err)
 	}
 
 	// delete nodes from role
-	if err := g.repo.Role().RemoveNode(ctx, roleID); err != nil {
+	if err := tx.Role().RemoveNode(ctx, roleID); err != nil {
 		return fmt.Errorf("failed to remove nodes from role: %w", err)
 	}
 
+	if err := tx.Role().RemoveSelector(ctx, roleID); err != nil {
+		return fmt.Errorf("failed to remove selectors from role: %w", err)
+	}
+
+	if err := tx.AccessRequest().RemoveApprover(ctx, roleID); err != nil {
+		return fmt.Errorf("failed to remove approvers from role: %w", err)
+	}
+
+	if err := tx.Group().RemoveRole(ctx, roleID); err != nil {
+		return fmt.Errorf("failed to remove groups from role: %w", err)
+	}
+
 	// delete role
-	if err := g.repo.Role().Delete(ctx, roleID); err != nil {
+	if err := tx.Role().Delete(ctx, roleID); err != nil {
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
 
@@ -118,7 +231,8 @@ func (g *guard) AddNodeToRole(ctx context.Context, in *AddNodeToRoleRequest) (er
 			}
 		}
 
-		if node == nil {
+		// a role only binds the nodes of its space, unless it's global
+		if node == nil || !role.Global && node.SpaceID != role.SpaceID {
 			return errors.ErrNodeNotFound
 		}
 
@@ -148,22 +262,36 @@ func (g *guard) AddNodeToRole(ctx context.Context, in *AddNodeToRoleRequest) (er
 }
 
 // ListRoleNode list role nodes
-func (g *guard) ListRoleNode(ctx context.Context, in *ListRoleNodeRequest) (ListRoleNodeResponse, error) {
-	nodes, err := g.repo.Role().ListNodeByRoleID(ctx, in.RoleID)
+func (g *guard) ListRoleNode(ctx context.Context, in *ListRoleNodeRequest) (*ListRoleNodeResponse, error) {
+	nodes, total, err := g.repo.Role().ListNode(ctx, &repo.RoleNodeFilter{
+		RoleID:        in.RoleID,
+		Name:          in.Name,
+		Account:       in.Account,
+		HeartbeatFrom: in.HeartbeatFrom,
+		HeartbeatTo:   in.HeartbeatTo,
+		Dynamic:       in.Dynamic,
+	}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list role nodes: %w", err)
 	}
 
-	var resp = make(ListRoleNodeResponse, 0, len(nodes))
+	var resp = &ListRoleNodeResponse{
+		Total: total,
+		Nodes: make([]*RoleNodeListVO, 0, len(nodes)),
+	}
 	for _, node := range nodes {
-		resp = append(resp, &RoleNodeListVO{
+		resp.Nodes = append(resp.Nodes, &RoleNodeListVO{
 			ID:            node.ID,
+			SpaceID:       node.SpaceID,
 			Name:          node.Name,
 			Description:   node.Description,
 			UniqueID:      node.UniqueID,
 			IP:            node.IP,
 			LastHeartbeat: node.LastHeartbeat,
+			Health:        node.Health,
 			Account:       node.Account,
+			Labels:        node.Labels,
+			Dynamic:       node.Dynamic,
 		})
 	}
 
@@ -179,58 +307,181 @@ func (g *guard) RemoveNodeFromRole(ctx context.Context, in *RemoveNodeFromRoleRe
 	return nil
 }
 
-// AddUserToRole add a user to a role
-func (g *guard) AddUserToRole(ctx context.Context, in *AddUserToRoleRequest) error {
-	tx, err := g.repo.BeginTx(ctx)
+// AddRoleSelector adds a label selector to the role, the nodes of the space
+// matching the selector are bound to the role, including the nodes enrolled later
+func (g *guard) AddRoleSelector(ctx context.Context, in *AddRoleSelectorRequest) (int64, error) {
+	role, err := g.repo.Role().GetByID(ctx, in.RoleID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to get role by id: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			txErr := tx.RollbackTx(ctx)
-			if txErr != nil {
-				err = fmt.Errorf("failed to rollback transaction: %w", txErr)
-			}
-		} else {
-			txErr := tx.CommitTx(ctx)
-			if txErr != nil {
-				err = fmt.Errorf("failed to commit transaction: %w", txErr)
-			}
-		}
-	}()
 
-	role, err := tx.Role().GetByID(ctx, in.RoleID)
+	if role == nil {
+		return 0, errors.ErrRoleNotFound
+	}
+
+	selector := &model.RoleSelector{
+		RoleID:   role.ID,
+		Selector: in.Selector,
+		Account:  in.Account,
+	}
+
+	if err := g.repo.Role().AddSelector(ctx, selector); err != nil {
+		return 0, fmt.Errorf("failed to add selector to role: %w", err)
+	}
+
+	slog.InfoContext(ctx, "add selector to role", "role", role.Name,
+		"selector", selector.Selector.String(), "account", selector.Account)
+	return selector.ID, nil
+}
+
+// ListRoleSelector lists the label selectors of the role
+func (g *guard) ListRoleSelector(ctx context.Context, roleID int64) (*ListRoleSelectorResponse, error) {
+	selectors, err := g.repo.Role().ListSelector(ctx, roleID)
 	if err != nil {
-		return fmt.Errorf("failed to get role by id: %w", err)
+		return nil, fmt.Errorf("failed to list role selectors: %w", err)
 	}
 
-	if role == nil {
-		return fmt.Errorf("role not found")
+	var resp = &ListRoleSelectorResponse{
+		Selectors: make([]*RoleSelectorVO, 0, len(selectors)),
+	}
+	for _, selector := range selectors {
+		resp.Selectors = append(resp.Selectors, &RoleSelectorVO{
+			ID:        selector.ID,
+			Selector:  selector.Selector,
+			Account:   selector.Account,
+			CreatedAt: selector.CreatedAt,
+		})
 	}
 
-	for _, userID := range in.UserIDs {
-		roleUser, err := tx.Role().GetRoleUserByRoleIDAndUserID(ctx, role.ID, userID)
+	return resp, nil
+}
+
+// RemoveRoleSelector removes the label selectors from the role, the nodes
+// matched by them are unbound unless they are added explicitly
+func (g *guard) RemoveRoleSelector(ctx context.Context, in *RemoveRoleSelectorRequest) error {
+	if err := g.repo.Role().RemoveSelector(ctx, in.RoleID, in.SelectorIDs...); err != nil {
+		return fmt.Errorf("failed to remove selector from role: %w", err)
+	}
+
+	return nil
+}
+
+// AddUserToRole add a user to a role. If the user is already in the role,
+// the valid window of the membership is extended, it's never shortened.
+func (g *guard) AddUserToRole(ctx context.Context, in *AddUserToRoleRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		role, err := tx.Role().GetByID(ctx, in.RoleID)
 		if err != nil {
-			return fmt.Errorf("failed to get role user by role id and user id: %w", err)
+			return fmt.Errorf("failed to get role by id: %w", err)
 		}
 
-		if roleUser != nil {
-			// already in the role, skip
-			continue
+		if role == nil {
+			return errors.ErrRoleNotFound
 		}
 
-		user, err := tx.User().GetByID(ctx, userID)
-		if err != nil {
-			return fmt.Errorf("failed to get user by id: %w", err)
+		for _, userID := range in.UserIDs {
+			if err := addUserToRole(ctx, tx, role.ID, userID, in.ValidFrom, in.ValidUntil); err != nil {
+				return err
+			}
 		}
 
-		if user == nil {
-			slog.Error("user not found", "user_id", userID)
-			return errors.ErrUserNotFound
+		return nil
+	})
+}
+
+// addUserToRole adds the user to the role in the valid window, 0 means no limit
+func addUserToRole(ctx context.Context, tx repo.Repo, roleID, userID, validFrom, validUntil int64) error {
+	roleUser, err := tx.Role().GetRoleUserByRoleIDAndUserID(ctx, roleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get role user by role id and user id: %w", err)
+	}
+
+	if roleUser != nil {
+		// already in the role, the windows are merged if they overlap or
+		// touch, an ended window is replaced
+		switch {
+		case overlapWindow(roleUser.ValidFrom, roleUser.ValidUntil, validFrom, validUntil):
+			validFrom = min(roleUser.ValidFrom, validFrom)
+			if roleUser.ValidUntil == 0 || validUntil == 0 {
+				validUntil = 0
+			} else {
+				validUntil = max(roleUser.ValidUntil, validUntil)
+			}
+		case roleUser.IsTemporary() && roleUser.ValidUntil <= time.Now().Unix():
+		default:
+			return errors.ErrMembershipConflict
+		}
+
+		if validFrom == roleUser.ValidFrom && validUntil == roleUser.ValidUntil {
+			return nil
 		}
 
-		if err := tx.Role().AddUser(ctx, role.ID, user.ID); err != nil {
-			return fmt.Errorf("failed to add user to role: %w", err)
+		roleUser.ValidFrom, roleUser.ValidUntil = validFrom, validUntil
+		if err := tx.Role().UpdateUser(ctx, roleUser); err != nil {
+			return fmt.Errorf("failed to update role user: %w", err)
+		}
+
+		slog.Info("extend role user", "role_id", roleID, "user_id", userID, "valid_from", validFrom, "valid_until", validUntil)
+		return nil
+	}
+
+	user, err := tx.User().GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	if user == nil {
+		slog.Error("user not found", "user_id", userID)
+		return errors.ErrUserNotFound
+	}
+
+	if err := tx.Role().AddUser(ctx, &model.RoleUser{
+		RoleID:     roleID,
+		UserID:     user.ID,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+	}); err != nil {
+		return fmt.Errorf("failed to add user to role: %w", err)
+	}
+
+	return nil
+}
+
+// overlapWindow reports whether the valid windows overlap or touch, 0 of the
+// end means no limit
+func overlapWindow(from1, until1, from2, until2 int64) bool {
+	return (until2 == 0 || from1 <= until2) && (until1 == 0 || from2 <= until1)
+}
+
+// expireRoleUsers removes the temporary memberships which are ended
+func (g *guard) expireRoleUsers(ctx context.Context, now int64) error {
+	roleUsers, err := g.repo.Role().ListExpiredUser(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list expired role users: %w", err)
+	}
+
+	for _, roleUser := range roleUsers {
+		err := g.transaction(ctx, func(tx repo.Repo) error {
+			// the membership may be extended after it was listed, e.g. by
+			// an approved access request or a break-glass, then it's kept
+			removed, err := tx.Role().RemoveExpiredUser(ctx, roleUser.ID, now)
+			if err != nil {
+				return err
+			}
+
+			if !removed {
+				return nil
+			}
+
+			return emit(ctx, tx, &model.Event{
+				Type:    model.EventRoleUserExpired,
+				RoleID:  roleUser.RoleID,
+				UserID:  roleUser.UserID,
+				Message: fmt.Sprintf("membership ended at %s", time.Unix(roleUser.ValidUntil, 0).UTC().Format(time.RFC3339)),
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire role user %d: %w", roleUser.ID, err)
 		}
 	}
 
@@ -238,18 +489,33 @@ func (g *guard) AddUserToRole(ctx context.Context, in *AddUserToRoleRequest) err
 }
 
 // ListRoleUser list role users
-func (g *guard) ListRoleUser(ctx context.Context, in *ListRoleUserRequest) (ListRoleUserResponse, error) {
-	users, err := g.repo.Role().ListUserByRoleID(ctx, in.RoleID)
+func (g *guard) ListRoleUser(ctx context.Context, in *ListRoleUserRequest) (*ListRoleUserResponse, error) {
+	users, total, err := g.repo.Role().ListUser(ctx, &repo.RoleUserFilter{
+		RoleID: in.RoleID,
+		Name:   in.Name,
+		Email:  in.Email,
+		State:  in.State,
+	}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list role users: %w", err)
 	}
 
-	var resp = make(ListRoleUserResponse, 0, len(users))
+	var resp = &ListRoleUserResponse{
+		Total: total,
+		Users: make([]*RoleUserListVO, 0, len(users)),
+	}
 	for _, user := range users {
-		resp = append(resp, &RoleUserListVO{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
+		resp.Users = append(resp.Users, &RoleUserListVO{
+			ID:         user.ID,
+			Username:   user.Username,
+			Email:      user.Email,
+			State:      user.State,
+			ExpiresAt:  user.ExpiresAt,
+			ValidFrom:  user.ValidFrom,
+			ValidUntil: user.ValidUntil,
+			JoinedAt:   user.JoinedAt,
+			Direct:     user.Direct,
+			Groups:     user.Groups,
 		})
 	}
 
@@ -264,7 +530,7 @@ func (g *guard) RemoveUserFromRole(ctx context.Context, in *RemoveUserFromRoleRe
 	}
 
 	if role == nil {
-		return fmt.Errorf("role not found")
+		return errors.ErrRoleNotFound
 	}
 
 	if err := g.repo.Role().RemoveUser(ctx, in.RoleID, in.UserIDs...); err != nil {
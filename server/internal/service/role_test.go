@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+)
+
+func TestAddUserToRoleWindow(t *testing.T) {
+	now := time.Now().Unix()
+	hour := int64(3600)
+
+	tests := []struct {
+		name string
+		// existing is the valid window of the membership, nil means none
+		existing *[2]int64
+		window   [2]int64
+		expected [2]int64
+		err      error
+	}{
+		{name: "new", window: [2]int64{now, now + hour}, expected: [2]int64{now, now + hour}},
+		{name: "extend", existing: &[2]int64{now, now + hour}, window: [2]int64{now, now + 2*hour}, expected: [2]int64{now, now + 2*hour}},
+		{name: "never shortened", existing: &[2]int64{now, now + 2*hour}, window: [2]int64{now, now + hour}, expected: [2]int64{now, now + 2*hour}},
+		{name: "touch", existing: &[2]int64{now, now + hour}, window: [2]int64{now + hour, now + 2*hour}, expected: [2]int64{now, now + 2*hour}},
+		{name: "gap", existing: &[2]int64{now + 2*hour, now + 3*hour}, window: [2]int64{now, now + hour}, expected: [2]int64{now + 2*hour, now + 3*hour}, err: errors.ErrMembershipConflict},
+		{name: "ended", existing: &[2]int64{now - 2*hour, now - hour}, window: [2]int64{now, now + hour}, expected: [2]int64{now, now + hour}},
+		{name: "permanent", existing: &[2]int64{now - hour, 0}, window: [2]int64{now, now + hour}, expected: [2]int64{now - hour, 0}},
+		{name: "permanent in future", existing: &[2]int64{now + hour, 0}, window: [2]int64{now, now + hour}, expected: [2]int64{now, 0}},
+		{name: "permanent after gap", existing: &[2]int64{now + 2*hour, 0}, window: [2]int64{now, now + hour}, expected: [2]int64{now + 2*hour, 0}, err: errors.ErrMembershipConflict},
+		{name: "to permanent", existing: &[2]int64{now, now + hour}, window: [2]int64{now, 0}, expected: [2]int64{now, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemDB(1)
+			db.data.addRole(model.Role{ID: 2, SpaceID: 1})
+			if tt.existing != nil {
+				db.data.roleUsers = []model.RoleUser{{ID: 1, RoleID: 2, UserID: 1, ValidFrom: tt.existing[0], ValidUntil: tt.existing[1]}}
+			}
+
+			err := addUserToRole(context.Background(), db, 2, 1, tt.window[0], tt.window[1])
+			if !stderrors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if len(db.data.roleUsers) != 1 {
+				t.Fatalf("expected 1 membership, got %d", len(db.data.roleUsers))
+			}
+
+			member := db.data.roleUsers[0]
+			if got := [2]int64{member.ValidFrom, member.ValidUntil}; got != tt.expected {
+				t.Fatalf("expected window %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// newRoleNameDB returns the repo with the role-1 of the space 1 and the
+// global role-2
+func newRoleNameDB() *memDB {
+	db := newMemDB()
+	db.data.addRole(model.Role{ID: 1, SpaceID: 1})
+	db.data.addRole(model.Role{ID: 2, Global: true})
+	return db
+}
+
+func TestUpdateRoleName(t *testing.T) {
+	tests := []struct {
+		name string
+		to   string
+		err  error
+	}{
+		{name: "unchanged", to: "role-1"},
+		{name: "renamed", to: "deploy"},
+		{name: "taken", to: "role-2", err: errors.ErrRoleNameAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newRoleNameDB()
+			g := &guard{repo: db}
+
+			err := g.UpdateRole(context.Background(), &UpdateRoleRequest{SpaceID: 1, RoleID: 1, Name: &tt.to})
+			if !stderrors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			expected := tt.to
+			if tt.err != nil {
+				expected = "role-1"
+			}
+			if name := db.data.roles[0].Name; name != expected {
+				t.Fatalf("expected name %s, got %s", expected, name)
+			}
+		})
+	}
+
+	if _, err := (&guard{repo: newRoleNameDB()}).CreateRole(context.Background(),
+		&CreateRoleRequest{Name: "role-2", Global: true}); !stderrors.Is(err, errors.ErrRoleNameAlreadyExists) {
+		t.Fatalf("expected error %v, got %v", errors.ErrRoleNameAlreadyExists, err)
+	}
+}
+
+// TestExpireRoleUsers extends a membership after the sweeper listed it,
+// the extended membership is kept and only the ended one expires
+func TestExpireRoleUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	hour := int64(3600)
+
+	db := newMemDB(1, 2)
+	db.data.addRole(model.Role{ID: 3, SpaceID: 1})
+	db.data.roleUsers = []model.RoleUser{
+		{ID: 1, RoleID: 3, UserID: 1, ValidFrom: now - 2*hour, ValidUntil: now - hour},
+		{ID: 2, RoleID: 3, UserID: 2, ValidFrom: now - 2*hour, ValidUntil: now - hour},
+	}
+	db.data.afterListExpired = func() {
+		// e.g. an access request of the user 1 approved meanwhile
+		if err := addUserToRole(ctx, db, 3, 1, now, now+hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &guard{repo: db}
+	if err := g.expireRoleUsers(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.data.roleUsers) != 1 || db.data.roleUsers[0].UserID != 1 || db.data.roleUsers[0].ValidUntil != now+hour {
+		t.Fatalf("expected the extended membership kept, got %v", db.data.roleUsers)
+	}
+
+	if len(db.data.events) != 1 || db.data.events[0].UserID != 2 {
+		t.Fatalf("expected the expiry of the user 2 only, got %v", db.data.events)
+	}
+}
+
+func TestSelectorValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector model.Labels
+		labels   string
+		wantErr  bool
+	}{
+		{name: "valid", selector: model.Labels{"env": "prod"}, labels: "env=prod"},
+		{name: "empty", selector: model.Labels{}, labels: "", wantErr: true},
+		{name: "empty key", selector: model.Labels{"": "prod"}, labels: "=prod", wantErr: true},
+		{name: "comma in value", selector: model.Labels{"env": "prod,dev"}, labels: "env=prod,dev", wantErr: true},
+		{name: "duplicate", labels: "env=prod,env=dev", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.selector != nil {
+				req := &AddRoleSelectorRequest{RoleID: 1, Selector: tt.selector}
+				if err := req.Validate(); (err != nil) != tt.wantErr {
+					t.Fatalf("expected the selector error %v, got %v", tt.wantErr, err)
+				}
+			}
+
+			// the empty labels of the node list filter nothing
+			req := &ListNodeRequest{SpaceID: 1, Labels: tt.labels}
+			if err := req.Validate(); (err != nil) != (tt.wantErr && tt.labels != "") {
+				t.Fatalf("expected the labels error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
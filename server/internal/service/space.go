@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
 	"github.com/sysarmor/guard/server/internal/service/errors"
 )
 
@@ -20,8 +22,14 @@ func (g *guard) CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64,
 	}
 
 	space = &model.Space{
-		Name:        in.Name,
-		Description: in.Description,
+		Name:         in.Name,
+		Description:  in.Description,
+		StaleAfter:   in.StaleAfter,
+		OfflineAfter: in.OfflineAfter,
+	}
+
+	if err := g.checkThresholds(space); err != nil {
+		return 0, err
 	}
 
 	if err := g.repo.Space().Create(ctx, space); err != nil {
@@ -32,21 +40,199 @@ func (g *guard) CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64,
 }
 
 // ListSpace is the request to list spaces
-func (g *guard) ListSpace(ctx context.Context) (ListSpaceResponse, error) {
-	spaces, err := g.repo.Space().List(ctx)
+func (g *guard) ListSpace(ctx context.Context, in *ListSpaceRequest) (*ListSpaceResponse, error) {
+	spaces, total, err := g.repo.Space().List(ctx, &repo.SpaceFilter{Name: in.Name}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list spaces: %w", err)
 	}
 
-	response := ListSpaceResponse{}
+	response := &ListSpaceResponse{
+		Total:  total,
+		Spaces: make([]*ListSpaceVO, 0, len(spaces)),
+	}
 	for _, space := range spaces {
-		response = append(response, &ListSpaceVO{
-			ID:          space.ID,
-			Name:        space.Name,
-			Description: space.Description,
-			CreatedAt:   space.CreatedAt,
+		response.Spaces = append(response.Spaces, &ListSpaceVO{
+			ID:           space.ID,
+			Name:         space.Name,
+			Description:  space.Description,
+			StaleAfter:   space.StaleAfter,
+			OfflineAfter: space.OfflineAfter,
+			CreatedAt:    space.CreatedAt,
 		})
 	}
 
 	return response, nil
 }
+
+// checkThresholds checks the effective heartbeat thresholds of the
+// space, e.g. a stale_after over the default offline_after is refused
+func (g *guard) checkThresholds(space *model.Space) error {
+	staleAfter, offlineAfter := g.thresholds(space)
+	if offlineAfter <= staleAfter {
+		return paramError("offline_after %ds must be greater than stale_after %ds", offlineAfter, staleAfter)
+	}
+	return nil
+}
+
+// UpdateSpace updates the name, description and heartbeat thresholds of a space
+func (g *guard) UpdateSpace(ctx context.Context, in *UpdateSpaceRequest) error {
+	space, err := g.repo.Space().GetByID(ctx, in.SpaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get space by id: %w", err)
+	}
+
+	if space == nil {
+		return errors.ErrSpaceNotFound
+	}
+
+	if in.Name != nil && *in.Name != space.Name {
+		exist, err := g.repo.Space().GetByName(ctx, *in.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get space by name: %w", err)
+		}
+
+		if exist != nil {
+			return errors.ErrSpaceNameAlreadyExists
+		}
+
+		space.Name = *in.Name
+	}
+
+	if in.Description != nil {
+		space.Description = *in.Description
+	}
+
+	if in.StaleAfter != nil {
+		space.StaleAfter = *in.StaleAfter
+	}
+
+	if in.OfflineAfter != nil {
+		space.OfflineAfter = *in.OfflineAfter
+	}
+
+	if err := g.checkThresholds(space); err != nil {
+		return err
+	}
+
+	if err := g.repo.Space().Update(ctx, space); err != nil {
+		return fmt.Errorf("failed to update space: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSpace deletes a space. A space with nodes or roles is only
+// deleted when force is set, then its roles and nodes are deleted too.
+func (g *guard) DeleteSpace(ctx context.Context, in *DeleteSpaceRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		space, err := tx.Space().GetByID(ctx, in.SpaceID)
+		if err != nil {
+			return fmt.Errorf("failed to get space by id: %w", err)
+		}
+
+		if space == nil {
+			return errors.ErrSpaceNotFound
+		}
+
+		roles, roleTotal, err := tx.Role().List(ctx, &repo.RoleFilter{SpaceID: space.ID}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list roles: %w", err)
+		}
+
+		nodes, nodeTotal, err := tx.Node().List(ctx, &repo.NodeFilter{SpaceID: space.ID}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list nodes: %w", err)
+		}
+
+		if (roleTotal > 0 || nodeTotal > 0) && !in.Force {
+			return errors.ErrSpaceNotEmpty
+		}
+
+		for _, role := range roles {
+			if err := deleteRole(ctx, tx, role.ID); err != nil {
+				return err
+			}
+		}
+
+		for _, node := range nodes {
+			if err := deleteNode(ctx, tx, node.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Space().RemoveAdmin(ctx, space.ID); err != nil {
+			return fmt.Errorf("failed to remove space admins: %w", err)
+		}
+
+		if err := tx.Space().Delete(ctx, space.ID); err != nil {
+			return fmt.Errorf("failed to delete space: %w", err)
+		}
+
+		slog.Info("space deleted", "space_id", space.ID, "roles", roleTotal, "nodes", nodeTotal)
+		return nil
+	})
+}
+
+// ListSpaceAdmin lists the admins of the space
+func (g *guard) ListSpaceAdmin(ctx context.Context, spaceID int64) (*ListSpaceAdminResponse, error) {
+	users, err := g.repo.Space().ListAdmin(ctx, spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list space admins: %w", err)
+	}
+
+	var resp = &ListSpaceAdminResponse{
+		Admins: make([]*RoleUserListVO, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.Admins = append(resp.Admins, &RoleUserListVO{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			State:    user.State,
+		})
+	}
+
+	return resp, nil
+}
+
+// AddSpaceAdmin adds the admins to the space, the admins are notified
+// of the break-glass access and review it
+func (g *guard) AddSpaceAdmin(ctx context.Context, in *AddSpaceAdminRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		space, err := tx.Space().GetByID(ctx, in.SpaceID)
+		if err != nil {
+			return fmt.Errorf("failed to get space by id: %w", err)
+		}
+
+		if space == nil {
+			return errors.ErrSpaceNotFound
+		}
+
+		for _, userID := range in.UserIDs {
+			user, err := tx.User().GetByID(ctx, userID)
+			if err != nil {
+				return fmt.Errorf("failed to get user by id: %w", err)
+			}
+
+			if user == nil {
+				return errors.ErrUserNotFound
+			}
+
+			if err := tx.Space().AddAdmin(ctx, space.ID, user.ID); err != nil {
+				return fmt.Errorf("failed to add space admin: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "add space admin", "space", space.Name, "user_ids", in.UserIDs)
+		return nil
+	})
+}
+
+// RemoveSpaceAdmin removes the admins from the space
+func (g *guard) RemoveSpaceAdmin(ctx context.Context, in *RemoveSpaceAdminRequest) error {
+	if err := g.repo.Space().RemoveAdmin(ctx, in.SpaceID, in.UserIDs...); err != nil {
+		return fmt.Errorf("failed to remove space admin: %w", err)
+	}
+
+	return nil
+}
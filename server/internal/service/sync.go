@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
+)
+
+// maxReportError is the max length of the error reported by the node
+const maxReportError = 1024
+
+// Report saves the state of the managed files reported by the node after
+// a run, an event is emitted when a file drifts from what the node received
+func (g *guard) Report(ctx context.Context, in *ReportRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		node, err := tx.Node().GetByUniqueID(ctx, in.UniqueID)
+		if err != nil {
+			return fmt.Errorf("failed to get node by unique id: %w", err)
+		}
+
+		if node == nil {
+			return errors.ErrNodeNotFound
+		}
+
+		previous, err := tx.Node().ListArtifact(ctx, node.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list node artifacts: %w", err)
+		}
+
+		drifted := make(map[string]bool, len(previous))
+		for _, a := range previous {
+			drifted[a.Kind] = a.Drifted()
+		}
+
+		for _, r := range in.Artifacts {
+			artifact := &model.NodeArtifact{
+				NodeID:   node.ID,
+				Kind:     string(r.Kind),
+				Received: r.Received,
+				Applied:  r.Applied,
+				Error:    truncate(r.Error, maxReportError),
+			}
+
+			if err := tx.Node().SaveArtifact(ctx, artifact); err != nil {
+				return fmt.Errorf("failed to save node artifact: %w", err)
+			}
+
+			if !artifact.Drifted() || drifted[artifact.Kind] {
+				continue
+			}
+
+			message := fmt.Sprintf("%s of node %s is not what the node received", artifact.Kind, node.Name)
+			if artifact.Error != "" {
+				message += ": " + artifact.Error
+			}
+
+			if err := emit(ctx, tx, &model.Event{
+				Type:    model.EventNodeDrift,
+				SpaceID: node.SpaceID,
+				NodeID:  node.ID,
+				Message: message,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// ListSpaceSync compares the files reported by the nodes of the space with
+// what the server serves now
+func (g *guard) ListSpaceSync(ctx context.Context, in *ListSpaceSyncRequest) (*ListSpaceSyncResponse, error) {
+	space, err := g.repo.Space().GetByID(ctx, in.SpaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get space by id: %w", err)
+	}
+
+	if space == nil {
+		return nil, errors.ErrSpaceNotFound
+	}
+
+	nodes, _, err := g.repo.Node().List(ctx, &repo.NodeFilter{SpaceID: space.ID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	if in.NodeID > 0 {
+		nodes = slices.DeleteFunc(nodes, func(n *model.Node) bool { return n.ID != in.NodeID })
+	}
+
+	kinds := dto.Artifacts
+	if in.Kind != "" {
+		kinds = []dto.Artifact{dto.Artifact(in.Kind)}
+	}
+
+	nodeIDs := make([]int64, 0, len(nodes))
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+
+	artifacts, err := g.repo.Node().ListArtifact(ctx, nodeIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node artifacts: %w", err)
+	}
+
+	reported := make(map[int64]map[string]*model.NodeArtifact, len(nodes))
+	for _, a := range artifacts {
+		if reported[a.NodeID] == nil {
+			reported[a.NodeID] = make(map[string]*model.NodeArtifact)
+		}
+		reported[a.NodeID][a.Kind] = a
+	}
+
+	resp := &ListSpaceSyncResponse{
+		Summary: make([]*SyncSummaryVO, 0, len(kinds)),
+		Nodes:   make([]*NodeSyncVO, 0, len(nodes)),
+	}
+	summary := make(map[dto.Artifact]*SyncSummaryVO, len(kinds))
+	for _, kind := range kinds {
+		summary[kind] = &SyncSummaryVO{Kind: kind}
+		resp.Summary = append(resp.Summary, summary[kind])
+	}
+
+	d := &digester{g: g, krls: make(map[string]string)}
+	for _, node := range nodes {
+		vo := &NodeSyncVO{NodeID: node.ID, Name: node.Name, Artifacts: make([]*ArtifactSyncVO, 0, len(kinds))}
+		matched := in.Status == ""
+		for _, kind := range kinds {
+			current, err := d.digest(ctx, node, kind)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute %s of node %d: %w", kind, node.ID, err)
+			}
+
+			a := reported[node.ID][string(kind)]
+			item := &ArtifactSyncVO{Kind: kind, Status: syncStatus(a, current), Current: current}
+			if a != nil {
+				item.Received, item.Applied, item.Error, item.ReportedAt = a.Received, a.Applied, a.Error, a.ReportedAt
+			}
+
+			summary[kind].add(item.Status)
+			matched = matched || string(item.Status) == in.Status
+			vo.Artifacts = append(vo.Artifacts, item)
+		}
+
+		if matched {
+			resp.Nodes = append(resp.Nodes, vo)
+		}
+	}
+
+	return resp, nil
+}
+
+// syncStatus compares the reported file with the current digest
+func syncStatus(a *model.NodeArtifact, current string) model.SyncStatus {
+	switch {
+	case a == nil:
+		return model.SyncUnknown
+	case a.Applied == current:
+		return model.SyncSynced
+	case a.Drifted():
+		return model.SyncDrift
+	default:
+		return model.SyncLagging
+	}
+}
+
+// digester computes the digests of what the nodes are served now, the
+// KRLs are generated once for every set of the revoked keys
+type digester struct {
+	g    *guard
+	krls map[string]string
+}
+
+func (d *digester) digest(ctx context.Context, node *model.Node, kind dto.Artifact) (string, error) {
+	switch kind {
+	case dto.ArtifactCA:
+		return dto.DigestCA(string(d.g.GetCA(ctx))), nil
+	case dto.ArtifactPrincipals:
+		principals, _, err := d.g.nodeMembers(ctx, node.ID)
+		if err != nil {
+			return "", err
+		}
+		return dto.DigestPrincipals(principals), nil
+	case dto.ArtifactAuthorizedKeys:
+		_, keys, err := d.g.nodeMembers(ctx, node.ID)
+		if err != nil {
+			return "", err
+		}
+		return dto.DigestAuthorizedKeys(keys), nil
+	case dto.ArtifactKRL:
+		return d.krl(ctx, node.ID)
+	}
+
+	return "", fmt.Errorf("unknown artifact %s", kind)
+}
+
+func (d *digester) krl(ctx context.Context, nodeID int64) (string, error) {
+	serials, err := d.g.repo.Role().ListRevokedKeys(ctx, nodeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list revoked keys: %w", err)
+	}
+
+	if len(serials) == 0 {
+		return dto.DigestKRL(nil), nil
+	}
+
+	slices.Sort(serials)
+	keys := make([]string, 0, len(serials))
+	for _, serial := range serials {
+		keys = append(keys, strconv.FormatInt(serial, 10))
+	}
+	key := strings.Join(keys, ",")
+
+	if digest, ok := d.krls[key]; ok {
+		return digest, nil
+	}
+
+	krl, err := d.g.certificateSigner.RevokeKeys(serials...)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke keys: %w", err)
+	}
+
+	d.krls[key] = dto.DigestKRL(krl)
+	return d.krls[key], nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/repo"
 	"github.com/sysarmor/guard/server/internal/service/errors"
 )
 
@@ -22,9 +24,11 @@ func (g *guard) CreateUser(ctx context.Context, in *CreateUserRequest) (int64, e
 	}
 
 	user = &model.User{
-		Username: in.Username,
-		Email:    in.Email,
-		PubKey:   in.PublicKey,
+		Username:  in.Username,
+		Email:     in.Email,
+		PubKey:    in.PublicKey,
+		State:     model.UserStateActive,
+		ExpiresAt: in.ExpiresAt,
 	}
 
 	if err := g.repo.User().Create(ctx, user); err != nil {
@@ -52,29 +56,40 @@ func (g *guard) UpdateUserPublicKey(ctx context.Context, in *UpdateUserPublicKey
 	}
 
 	// revoke all certs, because the public key is changed
-	if err := g.repo.User().RevokeAllCerts(ctx, in.UserID); err != nil {
+	revoked, err := g.repo.User().RevokeAllCerts(ctx, in.UserID)
+	if err != nil {
 		return fmt.Errorf("failed to revoke all certs: %w", err)
 	}
+	g.countRevoked(revokeReasonPublicKey, revoked)
 
 	slog.Info("update user public key", "username", user.Username)
 	return nil
 }
 
 // ListUser lists users
-func (g *guard) ListUser(ctx context.Context, in *ListUserRequest) (ListUserResponse, error) {
-	users, err := g.repo.User().List(ctx, in.Offset(), in.Limit)
+func (g *guard) ListUser(ctx context.Context, in *ListUserRequest) (*ListUserResponse, error) {
+	users, total, err := g.repo.User().List(ctx, &repo.UserFilter{
+		Name:  in.Name,
+		Email: in.Email,
+		State: in.State,
+	}, in.ListOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	var resp = make(ListUserResponse, 0, len(users))
+	var resp = &ListUserResponse{
+		Total: total,
+		Users: make([]*UserListVO, 0, len(users)),
+	}
 	for _, user := range users {
-		resp = append(resp, &UserListVO{
+		resp.Users = append(resp.Users, &UserListVO{
 			ID:        user.ID,
 			Username:  user.Username,
 			Email:     user.Email,
-			Ban:       user.Ban,
+			State:     user.State,
+			ExpiresAt: user.ExpiresAt,
 			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
 		})
 	}
 
@@ -97,7 +112,8 @@ func (g *guard) GetUser(ctx context.Context, id int64) (*GetUserResponse, error)
 		Username:  user.Username,
 		Email:     user.Email,
 		PubKey:    user.PubKey,
-		Ban:       user.Ban,
+		State:     user.State,
+		ExpiresAt: user.ExpiresAt,
 		CreatedAt: user.CreatedAt,
 		UpdateAt:  user.UpdatedAt,
 	}, nil
@@ -118,14 +134,214 @@ func (g *guard) GetUserByEmail(ctx context.Context, email string) (*GetUserRespo
 		Username:  user.Username,
 		Email:     user.Email,
 		PubKey:    user.PubKey,
-		Ban:       user.Ban,
+		State:     user.State,
+		ExpiresAt: user.ExpiresAt,
 		CreatedAt: user.CreatedAt,
 		UpdateAt:  user.UpdatedAt,
 	}, nil
 }
 
-// BanUser bans a user
+// UpdateUser updates the username, email and expires_at of a user
+func (g *guard) UpdateUser(ctx context.Context, in *UpdateUserRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		user, err := tx.User().GetByID(ctx, in.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if in.Username != nil {
+			user.Username = *in.Username
+		}
+
+		if in.Email != nil && *in.Email != user.Email {
+			exist, err := tx.User().GetByEmail(ctx, *in.Email)
+			if err != nil {
+				return fmt.Errorf("failed to get user by email: %w", err)
+			}
+
+			if exist != nil {
+				return errors.ErrUserAlreadyExists
+			}
+
+			// the email is the principal of the certs, revoke them
+			revoked, err := tx.User().RevokeAllCerts(ctx, user.ID)
+			if err != nil {
+				return fmt.Errorf("failed to revoke all certs: %w", err)
+			}
+			g.countRevoked(revokeReasonEmail, revoked)
+
+			slog.Info("update user email", "username", user.Username, "from", user.Email, "to", *in.Email)
+			user.Email = *in.Email
+		}
+
+		if in.ExpiresAt != nil {
+			user.ExpiresAt = *in.ExpiresAt
+
+			// extending or clearing the expires_at reactivates the expired user,
+			// the certs revoked on expiry are not restored
+			if user.State == model.UserStateExpired && (user.ExpiresAt == 0 || user.ExpiresAt > time.Now().Unix()) {
+				if err := tx.User().UpdateState(ctx, user.ID, model.UserStateActive); err != nil {
+					return fmt.Errorf("failed to reactivate user: %w", err)
+				}
+
+				slog.Info("reactivate user", "username", user.Username, "expires_at", user.ExpiresAt)
+			}
+		}
+
+		if err := tx.User().Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteUser removes a user from all roles, revokes all certs and deletes
+// the user. The revoked certs are kept for auditing.
+func (g *guard) DeleteUser(ctx context.Context, id int64) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		user, err := tx.User().GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if err := tx.Role().RemoveUserByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from roles: %w", err)
+		}
+
+		if err := tx.AccessRequest().RemoveApproverByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from approvers: %w", err)
+		}
+
+		if err := tx.Space().RemoveAdminByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from space admins: %w", err)
+		}
+
+		if err := tx.Group().RemoveUserByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from groups: %w", err)
+		}
+
+		revoked, err := tx.User().RevokeAllCerts(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to revoke all certs: %w", err)
+		}
+		g.countRevoked(revokeReasonDelete, revoked)
+
+		if err := tx.User().Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+
+		slog.Info("delete user", "username", user.Username, "email", user.Email)
+		return nil
+	})
+}
+
+// BanUser bans a user, the user is removed from all roles
+// and all certs of the user are revoked
 func (g *guard) BanUser(ctx context.Context, id int64) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		user, err := tx.User().GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		// remove user from all roles
+		if err := tx.Role().RemoveUserByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from roles: %w", err)
+		}
+
+		if err := tx.AccessRequest().RemoveApproverByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from approvers: %w", err)
+		}
+
+		if err := tx.Space().RemoveAdminByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from space admins: %w", err)
+		}
+
+		if err := tx.Group().RemoveUserByUserID(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove user from groups: %w", err)
+		}
+
+		revoked, err := tx.User().RevokeAllCerts(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to revoke all certs: %w", err)
+		}
+		g.countRevoked(revokeReasonBan, revoked)
+
+		if err := tx.User().UpdateState(ctx, id, model.UserStateBanned); err != nil {
+			return fmt.Errorf("failed to ban user: %w", err)
+		}
+
+		slog.Info("ban user", "username", user.Username)
+		return nil
+	})
+}
+
+// UnbanUser unbans a user. The certs revoked by the ban and the role
+// memberships are not restored, a new cert must be granted.
+func (g *guard) UnbanUser(ctx context.Context, id int64) error {
+	return g.setUserState(ctx, id, model.UserStateBanned, model.UserStateActive)
+}
+
+// SuspendUser suspends an active or expired user, all certs of the user
+// are revoked. Unlike the ban, the role memberships are kept, so the
+// access is restored once the user is resumed.
+func (g *guard) SuspendUser(ctx context.Context, id int64) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		user, err := tx.User().GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if user.IsBanned() {
+			return errors.ErrUserBanned
+		}
+
+		if user.State == model.UserStateSuspended {
+			return nil
+		}
+
+		revoked, err := tx.User().RevokeAllCerts(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to revoke all certs: %w", err)
+		}
+		g.countRevoked(revokeReasonSuspend, revoked)
+
+		if err := tx.User().UpdateState(ctx, id, model.UserStateSuspended); err != nil {
+			return fmt.Errorf("failed to suspend user: %w", err)
+		}
+
+		slog.Info("suspend user", "username", user.Username)
+		return nil
+	})
+}
+
+// ResumeUser resumes a suspended user, the revoked certs are not
+// restored. If the user has passed the expires_at, it's expired
+// by the next sweep.
+func (g *guard) ResumeUser(ctx context.Context, id int64) error {
+	return g.setUserState(ctx, id, model.UserStateSuspended, model.UserStateActive)
+}
+
+// setUserState changes the state of the user from the state to the state,
+// it does nothing if the user is not in the from state
+func (g *guard) setUserState(ctx context.Context, id int64, from, to model.UserState) error {
 	user, err := g.repo.User().GetByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get user by id: %w", err)
@@ -135,16 +351,49 @@ func (g *guard) BanUser(ctx context.Context, id int64) error {
 		return errors.ErrUserNotFound
 	}
 
-	// remove user from all roles
-	if err := g.repo.Role().RemoveUserByUserID(ctx, id); err != nil {
-		return fmt.Errorf("failed to remove user from roles: %w", err)
+	if user.State != from {
+		return nil
+	}
+
+	if err := g.repo.User().UpdateState(ctx, id, to); err != nil {
+		return fmt.Errorf("failed to update user state: %w", err)
 	}
 
-	if err := g.repo.User().Ban(ctx, id); err != nil {
-		return fmt.Errorf("failed to ban user: %w", err)
+	slog.Info("update user state", "username", user.Username, "from", from, "to", to)
+	return nil
+}
+
+// expireUsers expires the active users which passed the expires_at,
+// all certs of the users are revoked
+func (g *guard) expireUsers(ctx context.Context, now int64) error {
+	users, err := g.repo.User().ListExpired(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list expired users: %w", err)
+	}
+
+	for _, user := range users {
+		err := g.transaction(ctx, func(tx repo.Repo) error {
+			revoked, err := tx.User().RevokeAllCerts(ctx, user.ID)
+			if err != nil {
+				return fmt.Errorf("failed to revoke all certs: %w", err)
+			}
+			g.countRevoked(revokeReasonExpired, revoked)
+
+			if err := tx.User().UpdateState(ctx, user.ID, model.UserStateExpired); err != nil {
+				return fmt.Errorf("failed to expire user: %w", err)
+			}
+
+			return emit(ctx, tx, &model.Event{
+				Type:    model.EventUserExpired,
+				UserID:  user.ID,
+				Message: fmt.Sprintf("user %s expired", user.Email),
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire user %s: %w", user.Email, err)
+		}
 	}
 
-	slog.Info("ban user", "username", user.Username)
 	return nil
 }
 
@@ -159,10 +408,15 @@ func (g *guard) GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCert
 		return nil, errors.ErrUserNotFound
 	}
 
-	if user.Ban {
+	if user.IsBanned() {
 		return nil, errors.ErrUserBanned
 	}
 
+	now := time.Now().Unix()
+	if !user.IsActive(now) {
+		return nil, errors.ErrUserInactive
+	}
+
 	userCert := &model.UserCert{
 		UserID:    user.ID,
 		Cert:      "",
@@ -176,10 +430,24 @@ func (g *guard) GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCert
 
 	stateDate := in.StartDate
 	if stateDate == 0 {
-		stateDate = time.Now().Unix()
+		stateDate = now
 	}
 	endDate := stateDate + in.Effect
 
+	// the cert can not outlive the user
+	if user.ExpiresAt > 0 && endDate > user.ExpiresAt {
+		endDate = user.ExpiresAt
+	}
+
+	// nor the temporary memberships of the user
+	membershipEnd, err := g.membershipEnd(ctx, user.ID, in.RoleID, stateDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if membershipEnd > 0 && endDate > membershipEnd {
+		endDate = membershipEnd
+	}
+
 	cert, err := g.certificateSigner.SignCert(
 		[]byte(g.getPassphrase(ctx)), []byte(user.PubKey),
 		uint64(userCert.ID), user.Email, user.Email,
@@ -193,7 +461,134 @@ func (g *guard) GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCert
 		return nil, fmt.Errorf("failed to update user cert: %w", err)
 	}
 
+	g.countIssued()
 	return &GrantCertResponse{
 		Cert: string(cert),
 	}, nil
 }
+
+// ListUserCert lists the certs granted to a user, the newest first
+func (g *guard) ListUserCert(ctx context.Context, userID int64) (*ListUserCertResponse, error) {
+	user, err := g.repo.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	certs, err := g.repo.User().ListCerts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certs: %w", err)
+	}
+
+	resp := &ListUserCertResponse{Certs: make([]*UserCertVO, 0, len(certs))}
+	for i := len(certs) - 1; i >= 0; i-- {
+		resp.Certs = append(resp.Certs, &UserCertVO{
+			ID:        certs[i].ID,
+			IsRevoked: certs[i].IsRevoked,
+			CreatedAt: certs[i].CreatedAt,
+			UpdatedAt: certs[i].UpdateAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// RevokeCert revokes the given certs of a user, or all certs of the user
+// if no cert is given. The revoked certs are added to the KRL of the nodes.
+func (g *guard) RevokeCert(ctx context.Context, in *RevokeCertRequest) error {
+	return g.transaction(ctx, func(tx repo.Repo) error {
+		user, err := tx.User().GetByID(ctx, in.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user by id: %w", err)
+		}
+
+		if user == nil {
+			return errors.ErrUserNotFound
+		}
+
+		if len(in.CertIDs) == 0 {
+			revoked, err := tx.User().RevokeAllCerts(ctx, user.ID)
+			if err != nil {
+				return fmt.Errorf("failed to revoke all certs: %w", err)
+			}
+			g.countRevoked(revokeReasonAPI, revoked)
+
+			slog.Info("revoke all certs", "username", user.Username)
+			return nil
+		}
+
+		certs, err := tx.User().ListCerts(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list certs: %w", err)
+		}
+
+		owned := make(map[int64]*model.UserCert, len(certs))
+		for _, cert := range certs {
+			owned[cert.ID] = cert
+		}
+
+		var revoked int64
+		for _, id := range in.CertIDs {
+			cert, ok := owned[id]
+			if !ok {
+				return paramError("cert %d does not belong to the user", id)
+			}
+
+			if cert.IsRevoked {
+				continue
+			}
+
+			if err := tx.User().RevokeCert(ctx, id); err != nil {
+				return fmt.Errorf("failed to revoke cert: %w", err)
+			}
+			cert.IsRevoked = true
+			revoked++
+		}
+		g.countRevoked(revokeReasonAPI, revoked)
+
+		slog.Info("revoke certs", "username", user.Username, "certs", in.CertIDs)
+		return nil
+	})
+}
+
+// membershipEnd returns the time when the memberships of the user which
+// grant the access between start and end end. Only the membership in the
+// role is taken if roleID is not 0. It's 0 if one of them is permanent or
+// there is none, the roles inherited from groups are permanent.
+func (g *guard) membershipEnd(ctx context.Context, userID, roleID, start, end int64) (int64, error) {
+	groupRoles, err := g.repo.Group().ListRoleByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list roles of user by group: %w", err)
+	}
+
+	if len(groupRoles) > 0 && (roleID == 0 || slices.Contains(groupRoles, roleID)) {
+		return 0, nil
+	}
+
+	roleUsers, err := g.repo.Role().ListRoleUserByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list role users by user id: %w", err)
+	}
+
+	var last int64
+	for _, roleUser := range roleUsers {
+		if roleID != 0 && roleUser.RoleID != roleID {
+			continue
+		}
+
+		// the membership does not grant the access to the cert
+		if roleUser.ValidFrom >= end || roleUser.IsTemporary() && roleUser.ValidUntil <= start {
+			continue
+		}
+
+		if !roleUser.IsTemporary() {
+			return 0, nil
+		}
+		last = max(last, roleUser.ValidUntil)
+	}
+
+	return last, nil
+}
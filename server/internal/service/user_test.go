@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+func TestMembershipEnd(t *testing.T) {
+	now := time.Now().Unix()
+	hour := int64(3600)
+
+	tests := []struct {
+		name       string
+		members    []*model.RoleUser
+		groupRoles []int64
+		roleID     int64
+		expected   int64
+	}{
+		{name: "no membership", expected: 0},
+		{
+			name:     "temporary",
+			members:  []*model.RoleUser{{RoleID: 1, ValidFrom: now, ValidUntil: now + hour}},
+			expected: now + hour,
+		},
+		{
+			name: "latest temporary",
+			members: []*model.RoleUser{
+				{RoleID: 1, ValidFrom: now, ValidUntil: now + hour},
+				{RoleID: 2, ValidFrom: now, ValidUntil: now + 2*hour},
+			},
+			expected: now + 2*hour,
+		},
+		{
+			name: "permanent",
+			members: []*model.RoleUser{
+				{RoleID: 1, ValidFrom: now, ValidUntil: now + hour},
+				{RoleID: 2, ValidFrom: now},
+			},
+			expected: 0,
+		},
+		{
+			name: "permanent of other role",
+			members: []*model.RoleUser{
+				{RoleID: 1, ValidFrom: now, ValidUntil: now + hour},
+				{RoleID: 2, ValidFrom: now},
+			},
+			roleID:   1,
+			expected: now + hour,
+		},
+		{
+			name:       "group role of other role",
+			members:    []*model.RoleUser{{RoleID: 1, ValidFrom: now, ValidUntil: now + hour}},
+			groupRoles: []int64{2},
+			roleID:     1,
+			expected:   now + hour,
+		},
+		{
+			name:       "group role",
+			members:    []*model.RoleUser{{RoleID: 1, ValidFrom: now, ValidUntil: now + hour}},
+			groupRoles: []int64{1},
+			roleID:     1,
+			expected:   0,
+		},
+		{
+			name:       "any group role",
+			members:    []*model.RoleUser{{RoleID: 1, ValidFrom: now, ValidUntil: now + hour}},
+			groupRoles: []int64{2},
+			expected:   0,
+		},
+		{
+			name: "permanent after the cert",
+			members: []*model.RoleUser{
+				{RoleID: 1, ValidFrom: now, ValidUntil: now + hour},
+				{RoleID: 2, ValidFrom: now + 48*hour},
+			},
+			expected: now + hour,
+		},
+		{
+			name: "ended",
+			members: []*model.RoleUser{
+				{RoleID: 1, ValidFrom: now - 2*hour, ValidUntil: now - hour},
+				{RoleID: 2, ValidFrom: now, ValidUntil: now + hour},
+			},
+			expected: now + hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the user 1 inherits the group roles from the group 1
+			db := newMemDB(1)
+			db.data.groups = []model.Group{{ID: 1, Name: "team"}}
+			db.data.groupUsers = []model.GroupUser{{ID: 1, GroupID: 1, UserID: 1}}
+			for _, roleID := range tt.groupRoles {
+				db.data.roleGroups = append(db.data.roleGroups, model.RoleGroup{ID: db.data.nextID(), RoleID: roleID, GroupID: 1})
+			}
+			for _, member := range tt.members {
+				member.ID, member.UserID = db.data.nextID(), 1
+				db.data.roleUsers = append(db.data.roleUsers, *member)
+			}
+			g := &guard{repo: db}
+
+			end, err := g.membershipEnd(context.Background(), 1, tt.roleID, now, now+24*hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if end != tt.expected {
+				t.Fatalf("expected %d, got %d", tt.expected, end)
+			}
+		})
+	}
+}
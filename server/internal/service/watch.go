@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
+)
+
+// watcher wakes the watches on the changes, the channel is closed and
+// replaced on every change, so that all the watches waiting on it wake
+type watcher struct {
+	mu     sync.Mutex
+	ch     chan struct{}
+	closed bool
+}
+
+func newWatcher() *watcher {
+	return &watcher{ch: make(chan struct{})}
+}
+
+// wait returns the channel closed on the next change, false if the
+// watcher is closed
+func (w *watcher) wait() (<-chan struct{}, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.ch, !w.closed
+}
+
+func (w *watcher) broadcast() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	close(w.ch)
+	w.ch = make(chan struct{})
+}
+
+// close wakes the watches for the last time, the following ones fail
+func (w *watcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	w.closed = true
+	close(w.ch)
+}
+
+// Listen wakes the watches on the changes of the database until ctx
+// is done, then the watches fail so that the server can shut down
+func (g *guard) Listen(ctx context.Context) error {
+	defer g.watcher.close()
+
+	notified := make(chan struct{}, 1)
+	go g.coalesce(ctx, notified)
+
+	return g.repo.Listen(ctx, func() {
+		select {
+		case notified <- struct{}{}:
+		default:
+			// the pending one covers this change
+		}
+	})
+}
+
+// coalesce wakes the watches at most once per coalesce interval, and only
+// if the version of the data served to the nodes is changed, so that the
+// watches do not check their nodes on every write of a burst
+func (g *guard) coalesce(ctx context.Context, notified <-chan struct{}) {
+	var last int64 = -1
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-notified:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(g.watch.Coalesce):
+		}
+
+		// the notifications meanwhile are covered by the version read after
+		select {
+		case <-notified:
+		default:
+		}
+
+		version, err := g.repo.Version(ctx)
+		if err != nil {
+			// the watches check their nodes themselves
+			slog.ErrorContext(ctx, "failed to get version", "error", err)
+			version = -1
+		} else if version == last {
+			continue
+		}
+
+		last = version
+		g.watcher.broadcast()
+	}
+}
+
+// Watch waits until the version of the files served to the node is
+// different from in.Version, or the watch times out. The watches wake on
+// the coalesced changes of the database and check the version of their nodes.
+func (g *guard) Watch(ctx context.Context, in *WatchRequest) (*WatchResponse, error) {
+	node, err := g.nodeByUniqueID(ctx, in.UniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(g.watch.Timeout)
+	defer timer.Stop()
+
+	for {
+		// wait before reading the version, so that no change is missed
+		changed, ok := g.watcher.wait()
+		if !ok {
+			return nil, errors.ErrWatchUnavailable
+		}
+
+		version, err := g.nodeVersion(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+
+		if in.Version == "" || version != in.Version {
+			return &WatchResponse{Version: version, Changed: in.Version != ""}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		case <-timer.C:
+			// the memberships starting on time change the files without
+			// a write, they are found when the watches time out
+			version, err := g.nodeVersion(ctx, node)
+			if err != nil {
+				return nil, err
+			}
+			return &WatchResponse{Version: version, Changed: version != in.Version}, nil
+		}
+	}
+}
+
+// nodeVersion returns the version of the files served to the node, it's
+// the digest of the CA, the principals, the authorized keys and the
+// revoked keys. The version of the KRL is used instead of the KRL,
+// generating it is expensive.
+func (g *guard) nodeVersion(ctx context.Context, node *model.Node) (string, error) {
+	principals, keys, err := g.nodeMembers(ctx, node.ID)
+	if err != nil {
+		return "", err
+	}
+
+	krl, err := g.krlVersion(ctx, node.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return g.digestNode(principals, keys, krl), nil
+}
+
+// digestNode returns the digest of the files served to the node, the keys
+// are sorted in place, their order does not matter to sshd
+func (g *guard) digestNode(principals PrincipalList, keys []string, krlVersion string) string {
+	slices.Sort(keys)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n%s\n", dto.DigestCA(string(g.publicKey)),
+		dto.DigestPrincipals(principals), dto.DigestAuthorizedKeys(keys), krlVersion)
+	return hex.EncodeToString(hash.Sum(nil))
+}
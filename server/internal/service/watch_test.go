@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatcherCoalesce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := newMemDB()
+	g := &guard{repo: r, watcher: newWatcher(), watch: WatchConfig{Coalesce: 50 * time.Millisecond}}
+	go g.Listen(ctx) // nolint
+
+	// changed reports whether the watches wake in the time
+	changed := func(notify func()) bool {
+		ch, _ := g.watcher.wait()
+		notify()
+
+		select {
+		case <-ch:
+			return true
+		case <-time.After(200 * time.Millisecond):
+			return false
+		}
+	}
+
+	burst := func() {
+		for i := 0; i < 100; i++ {
+			r.version.Add(1)
+			r.notify <- struct{}{}
+		}
+	}
+
+	if !changed(burst) {
+		t.Fatal("expected the watches woken by the burst")
+	}
+
+	if versions := r.versions.Load(); versions > 2 {
+		t.Fatalf("expected the burst coalesced, got %d version reads", versions)
+	}
+
+	// the notification of the connection re-established, nothing changed
+	if changed(func() { r.notify <- struct{}{} }) {
+		t.Fatal("expected the watches not woken without a change")
+	}
+
+	if !changed(burst) {
+		t.Fatal("expected the watches woken by the burst")
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sysarmor/guard/server/internal/controller"
 	"github.com/sysarmor/guard/server/internal/repo/postgres"
@@ -16,61 +20,101 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// Main is the entry of the server
-func Main(ctx context.Context) (close func() error, err error) {
+// Main is the entry of the server, it runs until ctx is done and the
+// server is shut down, or the server fails
+func Main(ctx context.Context) error {
 	var configPath string
 	flag.StringVar(&configPath, "config", "config.yaml", "config file path")
 	flag.Parse()
 
 	cfg, err := loadConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("get config: %w", err)
+		return fmt.Errorf("get config: %w", err)
 	}
 
 	repo, err := postgres.New(ctx, &cfg.Postgres)
 	if err != nil {
-		return nil, fmt.Errorf("new postgres: %w", err)
+		return fmt.Errorf("new postgres: %w", err)
 	}
 
 	svc, err := service.New(cfg.Services, repo)
 	if err != nil {
-		return nil, fmt.Errorf("new service: %w", err)
+		return fmt.Errorf("new service: %w", err)
 	}
 
-	r := route.New(controller.New(svc))
-	close = func() error {
-		return r.Close()
-	}
+	r := route.New(controller.New(cfg.Controller, svc))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		if err := r.Run(cfg.Addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("run: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		sweep(gCtx, svc, cfg.Services.SweepInterval)
+		return nil
+	})
 
-	go func() {
-		if err := r.Run(cfg.Addr); err != nil {
-			slog.ErrorContext(ctx, "run", "error", err)
+	g.Go(func() error {
+		// the watches fall back to the timeouts if the listener fails
+		if err := svc.Listen(gCtx); err != nil {
+			slog.ErrorContext(ctx, "listen", "error", err)
 		}
-	}()
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gCtx.Done()
+		slog.InfoContext(ctx, "shutting down", "delay", cfg.Shutdown.Delay, "timeout", cfg.Shutdown.Timeout)
+
+		// ctx is done, the shutdown has its own deadline
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Delay+cfg.Shutdown.Timeout)
+		defer cancel()
 
-	return close, nil
+		return r.Shutdown(shutdownCtx, cfg.Shutdown.Delay)
+	})
+
+	return g.Wait()
+}
+
+// sweep runs the periodic jobs of the service until ctx is done
+func sweep(ctx context.Context, svc service.Guard, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.Sweep(ctx); err != nil {
+				slog.ErrorContext(ctx, "sweep", "error", err)
+			}
+		}
+	}
 }
 
 func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	close, err := Main(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, "main", "error", err)
-		return // nolint
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(ctx, os.Args[2:]); err != nil {
+				slog.ErrorContext(ctx, os.Args[1], "error", err)
+				cancel()
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	g, gCtx := errgroup.WithContext(ctx)
-	g.Go(func() error {
-		<-gCtx.Done()
-		if close != nil {
-			return close()
-		}
-		return nil
-	})
-	if err := g.Wait(); err != nil {
-		slog.ErrorContext(ctx, "exit", "error", err)
+	if err := Main(ctx); err != nil {
+		slog.ErrorContext(ctx, "main", "error", err)
+		cancel()
+		os.Exit(1)
 	}
 	slog.InfoContext(ctx, "exit")
 }
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"github.com/sysarmor/guard/server/internal/model"
+	"github.com/sysarmor/guard/server/internal/service"
+	"github.com/sysarmor/guard/server/pkg/apis/dto"
+)
+
+type Labels = model.Labels
+type UserState = model.UserState
+type NodeHealth = model.NodeHealth
+type SyncStatus = model.SyncStatus
+type Artifact = dto.Artifact
+
+const (
+	UserStateActive    = model.UserStateActive
+	UserStateSuspended = model.UserStateSuspended
+	UserStateExpired   = model.UserStateExpired
+	UserStateBanned    = model.UserStateBanned
+
+	NodeHealthy = model.NodeHealthy
+	NodeStale   = model.NodeStale
+	NodeOffline = model.NodeOffline
+
+	SyncSynced  = model.SyncSynced
+	SyncLagging = model.SyncLagging
+	SyncDrift   = model.SyncDrift
+	SyncUnknown = model.SyncUnknown
+
+	ArtifactCA             = dto.ArtifactCA
+	ArtifactPrincipals     = dto.ArtifactPrincipals
+	ArtifactKRL            = dto.ArtifactKRL
+	ArtifactAuthorizedKeys = dto.ArtifactAuthorizedKeys
+)
+
+type PageRequest = service.PageRequest
+
+type CreateUserRequest = service.CreateUserRequest
+type ListUserRequest = service.ListUserRequest
+type ListUserResponse = service.ListUserResponse
+type UserListVO = service.UserListVO
+type GetUserResponse = service.GetUserResponse
+type UpdateUserRequest = service.UpdateUserRequest
+type UpdateUserPublicKeyRequest = service.UpdateUserPublicKeyRequest
+type GrantCertRequest = service.GrantCertRequest
+type GrantCertResponse = service.GrantCertResponse
+type UserCertVO = service.UserCertVO
+type ListUserCertResponse = service.ListUserCertResponse
+type RevokeCertRequest = service.RevokeCertRequest
+
+type CreateSpaceRequest = service.CreateSpaceRequest
+type ListSpaceRequest = service.ListSpaceRequest
+type ListSpaceResponse = service.ListSpaceResponse
+type ListSpaceVO = service.ListSpaceVO
+type UpdateSpaceRequest = service.UpdateSpaceRequest
+type DeleteSpaceRequest = service.DeleteSpaceRequest
+
+type CreateNodeRequest = service.CreateNodeRequest
+type CreateNodeResponse = service.CreateNodeResponse
+type ListNodeRequest = service.ListNodeRequest
+type ListNodeResponse = service.ListNodeResponse
+type ListNodeVO = service.ListNodeVO
+type UpdateNodeRequest = service.UpdateNodeRequest
+type ListNodeHeartbeatRequest = service.ListNodeHeartbeatRequest
+type ListNodeHeartbeatResponse = service.ListNodeHeartbeatResponse
+type SpaceHealthVO = service.SpaceHealthVO
+type FleetHealthResponse = service.FleetHealthResponse
+type ListSpaceSyncRequest = service.ListSpaceSyncRequest
+type ListSpaceSyncResponse = service.ListSpaceSyncResponse
+type NodeSyncVO = service.NodeSyncVO
+type ArtifactSyncVO = service.ArtifactSyncVO
+type SyncSummaryVO = service.SyncSummaryVO
+
+type CreateRoleRequest = service.CreateRoleRequest
+type ListRoleRequest = service.ListRoleRequest
+type ListRoleResponse = service.ListRoleResponse
+type ListRoleVO = service.ListRoleVO
+type UpdateRoleRequest = service.UpdateRoleRequest
+type AddNodeToRoleRequest = service.AddNodeToRoleRequest
+type RoleNodeListRequest = service.RoleNodeListRequest
+type RoleNodeRequest = service.RoleNodeRequest
+type ListRoleNodeRequest = service.ListRoleNodeRequest
+type ListRoleNodeResponse = service.ListRoleNodeResponse
+type RoleNodeListVO = service.RoleNodeListVO
+type RemoveNodeFromRoleRequest = service.RemoveNodeFromRoleRequest
+type AddRoleSelectorRequest = service.AddRoleSelectorRequest
+type ListRoleSelectorResponse = service.ListRoleSelectorResponse
+type RoleSelectorVO = service.RoleSelectorVO
+type RemoveRoleSelectorRequest = service.RemoveRoleSelectorRequest
+type AddUserToRoleRequest = service.AddUserToRoleRequest
+type ListRoleUserRequest = service.ListRoleUserRequest
+type ListRoleUserResponse = service.ListRoleUserResponse
+type RoleUserListVO = service.RoleUserListVO
+type RemoveUserFromRoleRequest = service.RemoveUserFromRoleRequest
+type AddGroupToRoleRequest = service.AddGroupToRoleRequest
+type ListRoleGroupResponse = service.ListRoleGroupResponse
+type RemoveGroupFromRoleRequest = service.RemoveGroupFromRoleRequest
+
+type CreateGroupRequest = service.CreateGroupRequest
+type ListGroupRequest = service.ListGroupRequest
+type ListGroupResponse = service.ListGroupResponse
+type GroupVO = service.GroupVO
+type UpdateGroupRequest = service.UpdateGroupRequest
+type AddUserToGroupRequest = service.AddUserToGroupRequest
+type ListGroupUserRequest = service.ListGroupUserRequest
+type ListGroupUserResponse = service.ListGroupUserResponse
+type GroupUserVO = service.GroupUserVO
+type RemoveUserFromGroupRequest = service.RemoveUserFromGroupRequest
@@ -0,0 +1,72 @@
+// Package admin is the client of the guard management api, the requests
+// and the responses are the DTOs of the service
+package admin
+
+import (
+	"context"
+)
+
+// Guard is the management api of guard. The role api is addressed by the
+// space of the role like the http routes, space id 0 addresses the global
+// roles.
+type Guard interface {
+	CreateUser(ctx context.Context, in *CreateUserRequest) (int64, error)
+	ListUser(ctx context.Context, in *ListUserRequest) (*ListUserResponse, error)
+	GetUser(ctx context.Context, id int64) (*GetUserResponse, error)
+	GetUserByEmail(ctx context.Context, email string) (*GetUserResponse, error)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest) error
+	DeleteUser(ctx context.Context, id int64) error
+	BanUser(ctx context.Context, id int64) error
+	UnbanUser(ctx context.Context, id int64) error
+	SuspendUser(ctx context.Context, id int64) error
+	ResumeUser(ctx context.Context, id int64) error
+	UpdateUserPublicKey(ctx context.Context, in *UpdateUserPublicKeyRequest) error
+	GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCertResponse, error)
+	ListUserCert(ctx context.Context, userID int64) (*ListUserCertResponse, error)
+	// RevokeCert revokes the given certs, or all certs of the user if
+	// no cert id is given
+	RevokeCert(ctx context.Context, in *RevokeCertRequest) error
+
+	CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64, error)
+	ListSpace(ctx context.Context, in *ListSpaceRequest) (*ListSpaceResponse, error)
+	UpdateSpace(ctx context.Context, in *UpdateSpaceRequest) error
+	DeleteSpace(ctx context.Context, in *DeleteSpaceRequest) error
+
+	CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateNodeResponse, error)
+	ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeResponse, error)
+	UpdateNode(ctx context.Context, in *UpdateNodeRequest) error
+	DeleteNode(ctx context.Context, spaceID, nodeID int64) error
+	ListNodeHeartbeat(ctx context.Context, in *ListNodeHeartbeatRequest) (*ListNodeHeartbeatResponse, error)
+	// FleetHealth counts the nodes of every space by the health
+	FleetHealth(ctx context.Context) (*FleetHealthResponse, error)
+	// ListSpaceSync compares the files reported by the nodes of the
+	// space with what the server serves now
+	ListSpaceSync(ctx context.Context, in *ListSpaceSyncRequest) (*ListSpaceSyncResponse, error)
+
+	// CreateRole, ListRole and UpdateRole use the global role api if
+	// the space id of the request is 0 or Global is set
+	CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, error)
+	ListRole(ctx context.Context, in *ListRoleRequest) (*ListRoleResponse, error)
+	UpdateRole(ctx context.Context, in *UpdateRoleRequest) error
+	DeleteRole(ctx context.Context, spaceID, roleID int64) error
+	AddNodeToRole(ctx context.Context, spaceID int64, in *AddNodeToRoleRequest) error
+	ListRoleNode(ctx context.Context, spaceID int64, in *ListRoleNodeRequest) (*ListRoleNodeResponse, error)
+	RemoveNodeFromRole(ctx context.Context, spaceID int64, in *RemoveNodeFromRoleRequest) error
+	AddRoleSelector(ctx context.Context, spaceID int64, in *AddRoleSelectorRequest) (int64, error)
+	ListRoleSelector(ctx context.Context, spaceID, roleID int64) (*ListRoleSelectorResponse, error)
+	RemoveRoleSelector(ctx context.Context, spaceID int64, in *RemoveRoleSelectorRequest) error
+	AddUserToRole(ctx context.Context, spaceID int64, in *AddUserToRoleRequest) error
+	ListRoleUser(ctx context.Context, spaceID int64, in *ListRoleUserRequest) (*ListRoleUserResponse, error)
+	RemoveUserFromRole(ctx context.Context, spaceID int64, in *RemoveUserFromRoleRequest) error
+	AddGroupToRole(ctx context.Context, spaceID int64, in *AddGroupToRoleRequest) error
+	ListRoleGroup(ctx context.Context, spaceID, roleID int64) (*ListRoleGroupResponse, error)
+	RemoveGroupFromRole(ctx context.Context, spaceID int64, in *RemoveGroupFromRoleRequest) error
+
+	CreateGroup(ctx context.Context, in *CreateGroupRequest) (int64, error)
+	ListGroup(ctx context.Context, in *ListGroupRequest) (*ListGroupResponse, error)
+	UpdateGroup(ctx context.Context, in *UpdateGroupRequest) error
+	DeleteGroup(ctx context.Context, groupID int64) error
+	AddUserToGroup(ctx context.Context, in *AddUserToGroupRequest) error
+	ListGroupUser(ctx context.Context, in *ListGroupUserRequest) (*ListGroupUserResponse, error)
+	RemoveUserFromGroup(ctx context.Context, in *RemoveUserFromGroupRequest) error
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"fmt"
+
+	serviceerrors "github.com/sysarmor/guard/server/internal/service/errors"
+	"github.com/sysarmor/guard/server/pkg/errors"
+)
+
+// The errors returned by the api, they are matched by the code with
+// errors.Is, e.g. errors.Is(err, admin.ErrUserNotFound)
+var (
+	ErrInvalidParam           = errors.New(serviceerrors.ParamError, "invalid parameter")
+	ErrSpaceNotFound          = serviceerrors.ErrSpaceNotFound
+	ErrNodeNotFound           = serviceerrors.ErrNodeNotFound
+	ErrRoleNotFound           = serviceerrors.ErrRoleNotFound
+	ErrUserNotFound           = serviceerrors.ErrUserNotFound
+	ErrGroupNotFound          = serviceerrors.ErrGroupNotFound
+	ErrSpaceNameAlreadyExists = serviceerrors.ErrSpaceNameAlreadyExists
+	ErrGroupNameAlreadyExists = serviceerrors.ErrGroupNameAlreadyExists
+	ErrUserAlreadyExists      = serviceerrors.ErrUserAlreadyExists
+	ErrUserBanned             = serviceerrors.ErrUserBanned
+	ErrUserInactive           = serviceerrors.ErrUserInactive
+	ErrSpaceNotEmpty          = serviceerrors.ErrSpaceNotEmpty
+	ErrAccountInUse           = serviceerrors.ErrAccountInUse
+	ErrTooManyRequests        = serviceerrors.ErrTooManyRequests
+	ErrUnauthorized           = serviceerrors.ErrUnauthorized
+	ErrForbidden              = serviceerrors.ErrForbidden
+)
+
+func paramError(format string, args ...any) error {
+	return errors.New(serviceerrors.ParamError, fmt.Sprintf(format, args...))
+}
+
+// StatusError is returned if the response is not a guard error,
+// e.g. the request can not be bound or the server fails
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
@@ -0,0 +1,1404 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sysarmor/guard/server/internal/model"
+)
+
+// FakeGuard is an in-memory Guard for the tests. It validates the requests
+// and keeps the objects and the memberships like the server, the operators
+// are not checked and the lists are ordered by id.
+type FakeGuard struct {
+	mu     sync.Mutex
+	lastID int64
+
+	users      map[int64]*GetUserResponse
+	spaces     map[int64]*ListSpaceVO
+	nodes      map[int64]*ListNodeVO
+	nodeSpace  map[int64]int64
+	roles      map[int64]*ListRoleVO
+	groups     map[int64]*GroupVO
+	selectors  map[int64]*fakeSelector
+	roleNodes  map[int64][]fakeRoleNode
+	roleUsers  map[int64]map[int64]*fakeMembership
+	roleGroups map[int64]map[int64]struct{}
+	groupUsers map[int64]map[int64]int64
+	certs      map[int64]*fakeCert
+	heartbeats map[int64][]int64
+}
+
+var _ Guard = (*FakeGuard)(nil)
+
+type fakeSelector struct {
+	roleID int64
+	RoleSelectorVO
+}
+
+type fakeRoleNode struct {
+	nodeID  int64
+	account string
+}
+
+type fakeCert struct {
+	userID int64
+	UserCertVO
+}
+
+type fakeMembership struct {
+	validFrom, validUntil, joinedAt int64
+}
+
+// init creates the maps, so that the zero value is ready to use
+func (g *FakeGuard) init() {
+	if g.users != nil {
+		return
+	}
+
+	g.users = make(map[int64]*GetUserResponse)
+	g.spaces = make(map[int64]*ListSpaceVO)
+	g.nodes = make(map[int64]*ListNodeVO)
+	g.nodeSpace = make(map[int64]int64)
+	g.roles = make(map[int64]*ListRoleVO)
+	g.groups = make(map[int64]*GroupVO)
+	g.selectors = make(map[int64]*fakeSelector)
+	g.roleNodes = make(map[int64][]fakeRoleNode)
+	g.roleUsers = make(map[int64]map[int64]*fakeMembership)
+	g.roleGroups = make(map[int64]map[int64]struct{})
+	g.groupUsers = make(map[int64]map[int64]int64)
+	g.certs = make(map[int64]*fakeCert)
+	g.heartbeats = make(map[int64][]int64)
+}
+
+// lock locks the guard and validates the request
+func (g *FakeGuard) lock(in any) (unlock func(), err error) {
+	if v, ok := in.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	g.mu.Lock()
+	g.init()
+	return g.mu.Unlock, nil
+}
+
+func (g *FakeGuard) nextID() int64 {
+	g.lastID++
+	return g.lastID
+}
+
+func now() int64 {
+	return time.Now().Unix()
+}
+
+func contains(s, substr string) bool {
+	return substr == "" || strings.Contains(s, substr)
+}
+
+// matchLabels reports whether the labels have all the labels of the selector
+func matchLabels(selector, labels Labels) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// list returns the values ordered by id which pass the filter
+func list[T any](m map[int64]T, filter func(T) bool) []T {
+	res := make([]T, 0, len(m))
+	for _, id := range slices.Sorted(maps.Keys(m)) {
+		if filter(m[id]) {
+			res = append(res, m[id])
+		}
+	}
+	return res
+}
+
+// page returns the items of the page and the total
+func page[T any](items []T, pr *PageRequest) (int64, []T) {
+	total := int64(len(items))
+	offset := min(pr.Offset(), total)
+	return total, items[offset:min(offset+pr.Limit, total)]
+}
+
+func (g *FakeGuard) CreateUser(ctx context.Context, in *CreateUserRequest) (int64, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	for _, user := range g.users {
+		if user.Email == in.Email {
+			return 0, ErrUserAlreadyExists
+		}
+	}
+
+	user := &GetUserResponse{
+		ID:        g.nextID(),
+		Username:  in.Username,
+		Email:     in.Email,
+		PubKey:    in.PublicKey,
+		State:     UserStateActive,
+		ExpiresAt: in.ExpiresAt,
+		CreatedAt: now(),
+		UpdateAt:  now(),
+	}
+	g.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (g *FakeGuard) ListUser(ctx context.Context, in *ListUserRequest) (*ListUserResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	users := list(g.users, func(u *GetUserResponse) bool {
+		return contains(u.Username, in.Name) && contains(u.Email, in.Email) &&
+			(in.State == "" || string(u.State) == in.State)
+	})
+
+	total, users := page(users, &in.PageRequest)
+	resp := &ListUserResponse{Total: total, Users: make([]*UserListVO, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, &UserListVO{
+			ID:        u.ID,
+			Username:  u.Username,
+			Email:     u.Email,
+			State:     u.State,
+			ExpiresAt: u.ExpiresAt,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdateAt,
+		})
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) GetUser(ctx context.Context, id int64) (*GetUserResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	user, ok := g.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	clone := *user
+	return &clone, nil
+}
+
+func (g *FakeGuard) GetUserByEmail(ctx context.Context, email string) (*GetUserResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	for _, user := range g.users {
+		if user.Email == email {
+			clone := *user
+			return &clone, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (g *FakeGuard) UpdateUser(ctx context.Context, in *UpdateUserRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := g.users[in.UserID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if in.Email != nil {
+		for _, u := range g.users {
+			if u.ID != user.ID && u.Email == *in.Email {
+				return ErrUserAlreadyExists
+			}
+		}
+		user.Email = *in.Email
+	}
+	if in.Username != nil {
+		user.Username = *in.Username
+	}
+	if in.ExpiresAt != nil {
+		user.ExpiresAt = *in.ExpiresAt
+		if user.State == UserStateExpired && (user.ExpiresAt == 0 || user.ExpiresAt > now()) {
+			user.State = UserStateActive
+		}
+	}
+	user.UpdateAt = now()
+	return nil
+}
+
+func (g *FakeGuard) DeleteUser(ctx context.Context, id int64) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, ok := g.users[id]; !ok {
+		return ErrUserNotFound
+	}
+
+	delete(g.users, id)
+	for _, members := range g.roleUsers {
+		delete(members, id)
+	}
+	for _, members := range g.groupUsers {
+		delete(members, id)
+	}
+	return nil
+}
+
+// setUserState changes the state of the user if it is in one of the from states
+func (g *FakeGuard) setUserState(id int64, to UserState, from ...UserState) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	user, ok := g.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if !slices.Contains(from, user.State) {
+		return paramError("user is %s, can not be %s", user.State, to)
+	}
+
+	user.State = to
+	user.UpdateAt = now()
+	if to == UserStateBanned || to == UserStateSuspended {
+		g.revokeCerts(id)
+	}
+	if to == UserStateBanned {
+		// banned users are removed from the roles and the groups
+		for _, members := range g.roleUsers {
+			delete(members, id)
+		}
+		for _, members := range g.groupUsers {
+			delete(members, id)
+		}
+	}
+	return nil
+}
+
+func (g *FakeGuard) BanUser(ctx context.Context, id int64) error {
+	return g.setUserState(id, UserStateBanned, UserStateActive, UserStateSuspended, UserStateExpired)
+}
+
+func (g *FakeGuard) UnbanUser(ctx context.Context, id int64) error {
+	return g.setUserState(id, UserStateActive, UserStateBanned)
+}
+
+func (g *FakeGuard) SuspendUser(ctx context.Context, id int64) error {
+	return g.setUserState(id, UserStateSuspended, UserStateActive)
+}
+
+func (g *FakeGuard) ResumeUser(ctx context.Context, id int64) error {
+	return g.setUserState(id, UserStateActive, UserStateSuspended)
+}
+
+func (g *FakeGuard) UpdateUserPublicKey(ctx context.Context, in *UpdateUserPublicKeyRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := g.users[in.UserID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.PubKey = in.PublicKey
+	user.UpdateAt = now()
+	g.revokeCerts(user.ID)
+	return nil
+}
+
+func (g *FakeGuard) GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCertResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := g.users[in.UserID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	switch user.State {
+	case UserStateActive:
+	case UserStateBanned:
+		return nil, ErrUserBanned
+	default:
+		return nil, ErrUserInactive
+	}
+
+	id := g.nextID()
+	g.certs[id] = &fakeCert{userID: user.ID, UserCertVO: UserCertVO{ID: id, CreatedAt: now()}}
+	return &GrantCertResponse{Cert: fmt.Sprintf("fake-cert %d %s", id, user.Email)}, nil
+}
+
+func (g *FakeGuard) ListUserCert(ctx context.Context, userID int64) (*ListUserCertResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, ok := g.users[userID]; !ok {
+		return nil, ErrUserNotFound
+	}
+
+	certs := list(g.certs, func(c *fakeCert) bool { return c.userID == userID })
+	resp := &ListUserCertResponse{Certs: make([]*UserCertVO, 0, len(certs))}
+	for i := len(certs) - 1; i >= 0; i-- {
+		vo := certs[i].UserCertVO
+		resp.Certs = append(resp.Certs, &vo)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) RevokeCert(ctx context.Context, in *RevokeCertRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := g.users[in.UserID]; !ok {
+		return ErrUserNotFound
+	}
+
+	if len(in.CertIDs) == 0 {
+		g.revokeCerts(in.UserID)
+		return nil
+	}
+
+	for _, id := range in.CertIDs {
+		if cert, ok := g.certs[id]; !ok || cert.userID != in.UserID {
+			return paramError("cert %d does not belong to the user", id)
+		}
+	}
+	for _, id := range in.CertIDs {
+		g.certs[id].IsRevoked, g.certs[id].UpdatedAt = true, now()
+	}
+	return nil
+}
+
+// revokeCerts revokes all certs of the user
+func (g *FakeGuard) revokeCerts(userID int64) {
+	for _, cert := range g.certs {
+		if cert.userID == userID && !cert.IsRevoked {
+			cert.IsRevoked, cert.UpdatedAt = true, now()
+		}
+	}
+}
+
+func (g *FakeGuard) CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	for _, space := range g.spaces {
+		if space.Name == in.Name {
+			return 0, ErrSpaceNameAlreadyExists
+		}
+	}
+
+	space := &ListSpaceVO{Name: in.Name, Description: in.Description,
+		StaleAfter: in.StaleAfter, OfflineAfter: in.OfflineAfter, CreatedAt: now()}
+	if err := checkThresholds(space); err != nil {
+		return 0, err
+	}
+
+	space.ID = g.nextID()
+	g.spaces[space.ID] = space
+	return space.ID, nil
+}
+
+func (g *FakeGuard) ListSpace(ctx context.Context, in *ListSpaceRequest) (*ListSpaceResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	spaces := list(g.spaces, func(s *ListSpaceVO) bool { return contains(s.Name, in.Name) })
+	resp := &ListSpaceResponse{}
+	resp.Total, spaces = page(spaces, &in.PageRequest)
+	for _, space := range spaces {
+		clone := *space
+		resp.Spaces = append(resp.Spaces, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) UpdateSpace(ctx context.Context, in *UpdateSpaceRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	space, ok := g.spaces[in.SpaceID]
+	if !ok {
+		return ErrSpaceNotFound
+	}
+
+	if in.Name != nil {
+		for _, s := range g.spaces {
+			if s.ID != space.ID && s.Name == *in.Name {
+				return ErrSpaceNameAlreadyExists
+			}
+		}
+		space.Name = *in.Name
+	}
+	if in.Description != nil {
+		space.Description = *in.Description
+	}
+
+	updated := *space
+	if in.StaleAfter != nil {
+		updated.StaleAfter = *in.StaleAfter
+	}
+	if in.OfflineAfter != nil {
+		updated.OfflineAfter = *in.OfflineAfter
+	}
+	if err := checkThresholds(&updated); err != nil {
+		return err
+	}
+	space.StaleAfter, space.OfflineAfter = updated.StaleAfter, updated.OfflineAfter
+	return nil
+}
+
+func (g *FakeGuard) DeleteSpace(ctx context.Context, in *DeleteSpaceRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := g.spaces[in.SpaceID]; !ok {
+		return ErrSpaceNotFound
+	}
+
+	var nodes, roles []int64
+	for id, spaceID := range g.nodeSpace {
+		if spaceID == in.SpaceID {
+			nodes = append(nodes, id)
+		}
+	}
+	for id, role := range g.roles {
+		if !role.Global && role.SpaceID == in.SpaceID {
+			roles = append(roles, id)
+		}
+	}
+
+	if (len(nodes) > 0 || len(roles) > 0) && !in.Force {
+		return ErrSpaceNotEmpty
+	}
+
+	for _, id := range nodes {
+		g.deleteNode(id)
+	}
+	for _, id := range roles {
+		g.deleteRole(id)
+	}
+	delete(g.spaces, in.SpaceID)
+	return nil
+}
+
+func (g *FakeGuard) CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateNodeResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := g.spaces[in.SpaceID]; !ok {
+		return nil, ErrSpaceNotFound
+	}
+
+	accounts := in.Accounts
+	if len(accounts) == 0 {
+		accounts = []string{"root"}
+	}
+
+	node := &ListNodeVO{
+		ID:          g.nextID(),
+		Name:        in.Name,
+		Description: in.Description,
+		IP:          in.IP,
+		Accounts:    slices.Clone(accounts),
+		Labels:      maps.Clone(in.Labels),
+		Health:      NodeOffline,
+		CreatedAt:   now(),
+	}
+	node.UniqueID = fmt.Sprintf("fake-node-%d", node.ID)
+	g.nodes[node.ID] = node
+	g.nodeSpace[node.ID] = in.SpaceID
+
+	return &CreateNodeResponse{
+		ID:       node.ID,
+		UniqueID: node.UniqueID,
+		Secret:   fmt.Sprintf("fake-secret-%d", node.ID),
+	}, nil
+}
+
+func (g *FakeGuard) ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	selector, err := model.ParseLabels(in.Labels)
+	if err != nil {
+		return nil, paramError("%s", err)
+	}
+
+	g.updateHealth()
+	nodes := list(g.nodes, func(n *ListNodeVO) bool {
+		return g.nodeSpace[n.ID] == in.SpaceID && contains(n.Name, in.Name) &&
+			(in.Health == "" || string(n.Health) == in.Health) &&
+			(in.Account == "" || slices.Contains(n.Accounts, in.Account)) &&
+			(in.HeartbeatFrom == 0 || n.LastHeartbeat >= in.HeartbeatFrom) &&
+			(in.HeartbeatTo == 0 || n.LastHeartbeat <= in.HeartbeatTo) &&
+			matchLabels(selector, n.Labels)
+	})
+
+	resp := &ListNodeResponse{}
+	resp.Total, nodes = page(nodes, &in.PageRequest)
+	for _, node := range nodes {
+		clone := *node
+		resp.Nodes = append(resp.Nodes, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) UpdateNode(ctx context.Context, in *UpdateNodeRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	node, ok := g.nodes[in.NodeID]
+	if !ok || g.nodeSpace[in.NodeID] != in.SpaceID {
+		return ErrNodeNotFound
+	}
+
+	if in.Accounts != nil {
+		for roleID, bindings := range g.roleNodes {
+			kept := slices.DeleteFunc(slices.Clone(bindings), func(b fakeRoleNode) bool {
+				return b.nodeID == node.ID && !slices.Contains(in.Accounts, b.account)
+			})
+			if len(kept) != len(bindings) && !in.Force {
+				return ErrAccountInUse
+			}
+			g.roleNodes[roleID] = kept
+		}
+		node.Accounts = slices.Clone(in.Accounts)
+	}
+
+	if in.Name != nil {
+		node.Name = *in.Name
+	}
+	if in.Description != nil {
+		node.Description = *in.Description
+	}
+	if in.IP != nil {
+		node.IP = *in.IP
+	}
+	if in.Labels != nil {
+		node.Labels = maps.Clone(in.Labels)
+	}
+	return nil
+}
+
+func (g *FakeGuard) DeleteNode(ctx context.Context, spaceID, nodeID int64) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, ok := g.nodes[nodeID]; !ok || g.nodeSpace[nodeID] != spaceID {
+		return ErrNodeNotFound
+	}
+
+	g.deleteNode(nodeID)
+	return nil
+}
+
+// the default heartbeat thresholds of the server in seconds, and the
+// number of the heartbeats kept per node
+const (
+	fakeStaleAfter   = 600
+	fakeOfflineAfter = 3600
+	fakeHistory      = 20
+)
+
+// thresholds returns the effective heartbeat thresholds of the space
+func thresholds(space *ListSpaceVO) (staleAfter, offlineAfter int64) {
+	staleAfter, offlineAfter = space.StaleAfter, space.OfflineAfter
+	if staleAfter == 0 {
+		staleAfter = fakeStaleAfter
+	}
+	if offlineAfter == 0 {
+		offlineAfter = fakeOfflineAfter
+	}
+	return staleAfter, offlineAfter
+}
+
+func checkThresholds(space *ListSpaceVO) error {
+	staleAfter, offlineAfter := thresholds(space)
+	if offlineAfter <= staleAfter {
+		return paramError("offline_after %ds must be greater than stale_after %ds", offlineAfter, staleAfter)
+	}
+	return nil
+}
+
+// updateHealth computes the health of the nodes, the server does it
+// periodically, the fake does it before reading the nodes
+func (g *FakeGuard) updateHealth() {
+	at := now()
+	for id, node := range g.nodes {
+		staleAfter, offlineAfter := thresholds(g.spaces[g.nodeSpace[id]])
+		switch {
+		case node.LastHeartbeat >= at-staleAfter:
+			node.Health = NodeHealthy
+		case node.LastHeartbeat >= at-offlineAfter:
+			node.Health = NodeStale
+		default:
+			node.Health = NodeOffline
+		}
+	}
+}
+
+// Heartbeat records a successful sync of the node at the time, it's the
+// heartbeat sent by the client daemon to the server
+func (g *FakeGuard) Heartbeat(nodeID int64, at time.Time) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	node, ok := g.nodes[nodeID]
+	if !ok {
+		return ErrNodeNotFound
+	}
+
+	node.LastHeartbeat = max(node.LastHeartbeat, at.Unix())
+	heartbeats := append(g.heartbeats[nodeID], at.Unix())
+	slices.Sort(heartbeats)
+	g.heartbeats[nodeID] = heartbeats[max(0, len(heartbeats)-fakeHistory):]
+	return nil
+}
+
+func (g *FakeGuard) ListNodeHeartbeat(ctx context.Context, in *ListNodeHeartbeatRequest) (*ListNodeHeartbeatResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	node, ok := g.nodes[in.NodeID]
+	if !ok || g.nodeSpace[in.NodeID] != in.SpaceID {
+		return nil, ErrNodeNotFound
+	}
+
+	g.updateHealth()
+	staleAfter, offlineAfter := thresholds(g.spaces[in.SpaceID])
+	heartbeats := slices.Clone(g.heartbeats[node.ID])
+	slices.Reverse(heartbeats)
+	return &ListNodeHeartbeatResponse{
+		NodeID:        node.ID,
+		Health:        node.Health,
+		LastHeartbeat: node.LastHeartbeat,
+		StaleAfter:    staleAfter,
+		OfflineAfter:  offlineAfter,
+		Heartbeats:    append([]int64{}, heartbeats...),
+	}, nil
+}
+
+func (g *FakeGuard) FleetHealth(ctx context.Context) (*FleetHealthResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	g.updateHealth()
+	resp := &FleetHealthResponse{Spaces: make([]*SpaceHealthVO, 0, len(g.spaces))}
+	for _, space := range list(g.spaces, func(*ListSpaceVO) bool { return true }) {
+		vo := &SpaceHealthVO{SpaceID: space.ID, SpaceName: space.Name}
+		vo.StaleAfter, vo.OfflineAfter = thresholds(space)
+		for id, node := range g.nodes {
+			if g.nodeSpace[id] != space.ID {
+				continue
+			}
+			vo.Total++
+			switch node.Health {
+			case NodeHealthy:
+				vo.Healthy++
+			case NodeStale:
+				vo.Stale++
+			default:
+				vo.Offline++
+			}
+		}
+		resp.Spaces = append(resp.Spaces, vo)
+	}
+	return resp, nil
+}
+
+// ListSpaceSync returns the files of the nodes as unknown, the fake has
+// no clients to report them
+func (g *FakeGuard) ListSpaceSync(ctx context.Context, in *ListSpaceSyncRequest) (*ListSpaceSyncResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := g.spaces[in.SpaceID]; !ok {
+		return nil, ErrSpaceNotFound
+	}
+
+	kinds := []Artifact{ArtifactCA, ArtifactPrincipals, ArtifactKRL, ArtifactAuthorizedKeys}
+	if in.Kind != "" {
+		kinds = []Artifact{Artifact(in.Kind)}
+	}
+
+	nodes := list(g.nodes, func(n *ListNodeVO) bool {
+		return g.nodeSpace[n.ID] == in.SpaceID && (in.NodeID == 0 || n.ID == in.NodeID)
+	})
+
+	resp := &ListSpaceSyncResponse{Summary: make([]*SyncSummaryVO, 0, len(kinds)), Nodes: []*NodeSyncVO{}}
+	for _, kind := range kinds {
+		resp.Summary = append(resp.Summary, &SyncSummaryVO{Kind: kind, Unknown: int64(len(nodes))})
+	}
+
+	if in.Status != "" && in.Status != string(SyncUnknown) {
+		return resp, nil
+	}
+
+	for _, node := range nodes {
+		vo := &NodeSyncVO{NodeID: node.ID, Name: node.Name}
+		for _, kind := range kinds {
+			vo.Artifacts = append(vo.Artifacts, &ArtifactSyncVO{Kind: kind, Status: SyncUnknown})
+		}
+		resp.Nodes = append(resp.Nodes, vo)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) deleteNode(id int64) {
+	delete(g.nodes, id)
+	delete(g.nodeSpace, id)
+	delete(g.heartbeats, id)
+	for roleID, bindings := range g.roleNodes {
+		g.roleNodes[roleID] = slices.DeleteFunc(bindings, func(b fakeRoleNode) bool { return b.nodeID == id })
+	}
+}
+
+// role returns the role of the space, or the global role if the space id is 0
+func (g *FakeGuard) role(spaceID, roleID int64) (*ListRoleVO, error) {
+	role, ok := g.roles[roleID]
+	if !ok || role.Global != (spaceID == 0) || (!role.Global && role.SpaceID != spaceID) {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (g *FakeGuard) CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, error) {
+	in.Global = in.Global || in.SpaceID == 0
+	unlock, err := g.lock(in)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if _, ok := g.spaces[in.SpaceID]; !ok && !in.Global {
+		return 0, ErrSpaceNotFound
+	}
+
+	role := &ListRoleVO{
+		ID:          g.nextID(),
+		Name:        in.Name,
+		Description: in.Description,
+		BreakGlass:  in.BreakGlass,
+		Global:      in.Global,
+		CreatedAt:   now(),
+	}
+	if !in.Global {
+		role.SpaceID = in.SpaceID
+	}
+	g.roles[role.ID] = role
+	return role.ID, nil
+}
+
+func (g *FakeGuard) ListRole(ctx context.Context, in *ListRoleRequest) (*ListRoleResponse, error) {
+	in.Global = in.Global || in.SpaceID == 0
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	roles := list(g.roles, func(r *ListRoleVO) bool {
+		return r.Global == in.Global && (in.Global || r.SpaceID == in.SpaceID) &&
+			contains(r.Name, in.Name) && (in.BreakGlass == nil || r.BreakGlass == *in.BreakGlass)
+	})
+
+	resp := &ListRoleResponse{}
+	resp.Total, roles = page(roles, &in.PageRequest)
+	for _, role := range roles {
+		clone := *role
+		resp.Roles = append(resp.Roles, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) UpdateRole(ctx context.Context, in *UpdateRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	role, err := g.role(in.SpaceID, in.RoleID)
+	if err != nil {
+		return err
+	}
+
+	if in.Name != nil {
+		role.Name = *in.Name
+	}
+	if in.Description != nil {
+		role.Description = *in.Description
+	}
+	if in.BreakGlass != nil {
+		role.BreakGlass = *in.BreakGlass
+	}
+	return nil
+}
+
+func (g *FakeGuard) DeleteRole(ctx context.Context, spaceID, roleID int64) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, err := g.role(spaceID, roleID); err != nil {
+		return err
+	}
+
+	g.deleteRole(roleID)
+	return nil
+}
+
+func (g *FakeGuard) deleteRole(id int64) {
+	delete(g.roles, id)
+	delete(g.roleNodes, id)
+	delete(g.roleUsers, id)
+	delete(g.roleGroups, id)
+	maps.DeleteFunc(g.selectors, func(_ int64, s *fakeSelector) bool { return s.roleID == id })
+}
+
+func (g *FakeGuard) AddNodeToRole(ctx context.Context, spaceID int64, in *AddNodeToRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	role, err := g.role(spaceID, in.RoleID)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range in.Nodes {
+		node, ok := g.nodes[req.NodeID]
+		if req.NodeID == 0 {
+			node, ok = nil, false
+			for _, n := range g.nodes {
+				if n.UniqueID == req.UniqueID {
+					node, ok = n, true
+				}
+			}
+		}
+
+		if !ok || (!role.Global && g.nodeSpace[node.ID] != role.SpaceID) {
+			return ErrNodeNotFound
+		}
+
+		account := req.Account
+		if account == "" {
+			account = node.Accounts[0]
+		}
+		if !slices.Contains(node.Accounts, account) {
+			return paramError("account %s is not in the node %s", account, node.Name)
+		}
+
+		binding := fakeRoleNode{nodeID: node.ID, account: account}
+		if !slices.Contains(g.roleNodes[role.ID], binding) {
+			g.roleNodes[role.ID] = append(g.roleNodes[role.ID], binding)
+		}
+	}
+	return nil
+}
+
+func (g *FakeGuard) ListRoleNode(ctx context.Context, spaceID int64, in *ListRoleNodeRequest) (*ListRoleNodeResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	role, err := g.role(spaceID, in.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.updateHealth()
+	nodes := make([]*RoleNodeListVO, 0)
+	add := func(node *ListNodeVO, account string, dynamic bool) {
+		if !contains(node.Name, in.Name) || (in.Account != "" && account != in.Account) ||
+			(in.HeartbeatFrom != 0 && node.LastHeartbeat < in.HeartbeatFrom) ||
+			(in.HeartbeatTo != 0 && node.LastHeartbeat > in.HeartbeatTo) ||
+			(in.Dynamic != nil && *in.Dynamic != dynamic) {
+			return
+		}
+
+		nodes = append(nodes, &RoleNodeListVO{
+			ID:            node.ID,
+			SpaceID:       g.nodeSpace[node.ID],
+			UniqueID:      node.UniqueID,
+			Name:          node.Name,
+			Description:   node.Description,
+			IP:            node.IP,
+			Account:       account,
+			Labels:        maps.Clone(node.Labels),
+			LastHeartbeat: node.LastHeartbeat,
+			Health:        node.Health,
+			Dynamic:       dynamic,
+		})
+	}
+
+	for _, binding := range g.roleNodes[role.ID] {
+		add(g.nodes[binding.nodeID], binding.account, false)
+	}
+
+	for _, selector := range list(g.selectors, func(s *fakeSelector) bool { return s.roleID == role.ID }) {
+		for _, node := range list(g.nodes, func(n *ListNodeVO) bool {
+			return (role.Global || g.nodeSpace[n.ID] == role.SpaceID) && matchLabels(selector.Selector, n.Labels)
+		}) {
+			account := selector.Account
+			if account == "" {
+				account = node.Accounts[0]
+			}
+			if slices.Contains(node.Accounts, account) {
+				add(node, account, true)
+			}
+		}
+	}
+
+	resp := &ListRoleNodeResponse{}
+	resp.Total, resp.Nodes = page(nodes, &in.PageRequest)
+	return resp, nil
+}
+
+func (g *FakeGuard) RemoveNodeFromRole(ctx context.Context, spaceID int64, in *RemoveNodeFromRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	g.roleNodes[in.RoleID] = slices.DeleteFunc(g.roleNodes[in.RoleID], func(b fakeRoleNode) bool {
+		return slices.Contains(in.NodeIDs, b.nodeID)
+	})
+	return nil
+}
+
+func (g *FakeGuard) AddRoleSelector(ctx context.Context, spaceID int64, in *AddRoleSelectorRequest) (int64, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return 0, err
+	}
+
+	selector := &fakeSelector{roleID: in.RoleID, RoleSelectorVO: RoleSelectorVO{
+		ID:        g.nextID(),
+		Selector:  maps.Clone(in.Selector),
+		Account:   in.Account,
+		CreatedAt: now(),
+	}}
+	g.selectors[selector.ID] = selector
+	return selector.ID, nil
+}
+
+func (g *FakeGuard) ListRoleSelector(ctx context.Context, spaceID, roleID int64) (*ListRoleSelectorResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, err := g.role(spaceID, roleID); err != nil {
+		return nil, err
+	}
+
+	resp := &ListRoleSelectorResponse{Selectors: make([]*RoleSelectorVO, 0)}
+	for _, selector := range list(g.selectors, func(s *fakeSelector) bool { return s.roleID == roleID }) {
+		clone := selector.RoleSelectorVO
+		resp.Selectors = append(resp.Selectors, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) RemoveRoleSelector(ctx context.Context, spaceID int64, in *RemoveRoleSelectorRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	maps.DeleteFunc(g.selectors, func(id int64, s *fakeSelector) bool {
+		return s.roleID == in.RoleID && slices.Contains(in.SelectorIDs, id)
+	})
+	return nil
+}
+
+func (g *FakeGuard) AddUserToRole(ctx context.Context, spaceID int64, in *AddUserToRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	for _, id := range in.UserIDs {
+		user, ok := g.users[id]
+		if !ok {
+			return ErrUserNotFound
+		}
+		if user.State == UserStateBanned {
+			return ErrUserBanned
+		}
+	}
+
+	if g.roleUsers[in.RoleID] == nil {
+		g.roleUsers[in.RoleID] = make(map[int64]*fakeMembership)
+	}
+	for _, id := range in.UserIDs {
+		g.roleUsers[in.RoleID][id] = &fakeMembership{validFrom: in.ValidFrom, validUntil: in.ValidUntil, joinedAt: now()}
+	}
+	return nil
+}
+
+func (g *FakeGuard) ListRoleUser(ctx context.Context, spaceID int64, in *ListRoleUserRequest) (*ListRoleUserResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return nil, err
+	}
+
+	members := make(map[int64]*RoleUserListVO)
+	member := func(id int64) *RoleUserListVO {
+		if vo, ok := members[id]; ok {
+			return vo
+		}
+
+		user := g.users[id]
+		vo := &RoleUserListVO{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			State:     user.State,
+			ExpiresAt: user.ExpiresAt,
+		}
+		members[id] = vo
+		return vo
+	}
+
+	for id, m := range g.roleUsers[in.RoleID] {
+		vo := member(id)
+		vo.Direct, vo.ValidFrom, vo.ValidUntil, vo.JoinedAt = true, m.validFrom, m.validUntil, m.joinedAt
+	}
+	for groupID := range g.roleGroups[in.RoleID] {
+		for id, joinedAt := range g.groupUsers[groupID] {
+			vo := member(id)
+			vo.Groups = append(vo.Groups, g.groups[groupID].Name)
+			if vo.JoinedAt == 0 {
+				vo.JoinedAt = joinedAt
+			}
+		}
+	}
+
+	users := list(members, func(u *RoleUserListVO) bool {
+		slices.Sort(u.Groups)
+		return contains(u.Username, in.Name) && contains(u.Email, in.Email) &&
+			(in.State == "" || string(u.State) == in.State)
+	})
+
+	resp := &ListRoleUserResponse{}
+	resp.Total, resp.Users = page(users, &in.PageRequest)
+	return resp, nil
+}
+
+func (g *FakeGuard) RemoveUserFromRole(ctx context.Context, spaceID int64, in *RemoveUserFromRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	for _, id := range in.UserIDs {
+		delete(g.roleUsers[in.RoleID], id)
+	}
+	return nil
+}
+
+func (g *FakeGuard) AddGroupToRole(ctx context.Context, spaceID int64, in *AddGroupToRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	for _, id := range in.GroupIDs {
+		if _, ok := g.groups[id]; !ok {
+			return ErrGroupNotFound
+		}
+	}
+
+	if g.roleGroups[in.RoleID] == nil {
+		g.roleGroups[in.RoleID] = make(map[int64]struct{})
+	}
+	for _, id := range in.GroupIDs {
+		g.roleGroups[in.RoleID][id] = struct{}{}
+	}
+	return nil
+}
+
+func (g *FakeGuard) ListRoleGroup(ctx context.Context, spaceID, roleID int64) (*ListRoleGroupResponse, error) {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, err := g.role(spaceID, roleID); err != nil {
+		return nil, err
+	}
+
+	resp := &ListRoleGroupResponse{Groups: make([]*GroupVO, 0)}
+	for _, group := range list(g.groups, func(group *GroupVO) bool {
+		_, ok := g.roleGroups[roleID][group.ID]
+		return ok
+	}) {
+		clone := *group
+		resp.Groups = append(resp.Groups, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) RemoveGroupFromRole(ctx context.Context, spaceID int64, in *RemoveGroupFromRoleRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := g.role(spaceID, in.RoleID); err != nil {
+		return err
+	}
+
+	for _, id := range in.GroupIDs {
+		delete(g.roleGroups[in.RoleID], id)
+	}
+	return nil
+}
+
+func (g *FakeGuard) CreateGroup(ctx context.Context, in *CreateGroupRequest) (int64, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	for _, group := range g.groups {
+		if group.Name == in.Name {
+			return 0, ErrGroupNameAlreadyExists
+		}
+	}
+
+	group := &GroupVO{ID: g.nextID(), Name: in.Name, Description: in.Description, CreatedAt: now()}
+	g.groups[group.ID] = group
+	return group.ID, nil
+}
+
+func (g *FakeGuard) ListGroup(ctx context.Context, in *ListGroupRequest) (*ListGroupResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	groups := list(g.groups, func(group *GroupVO) bool { return contains(group.Name, in.Name) })
+	resp := &ListGroupResponse{}
+	resp.Total, groups = page(groups, &in.PageRequest)
+	for _, group := range groups {
+		clone := *group
+		resp.Groups = append(resp.Groups, &clone)
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) UpdateGroup(ctx context.Context, in *UpdateGroupRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	group, ok := g.groups[in.GroupID]
+	if !ok {
+		return ErrGroupNotFound
+	}
+
+	if in.Name != nil {
+		for _, other := range g.groups {
+			if other.ID != group.ID && other.Name == *in.Name {
+				return ErrGroupNameAlreadyExists
+			}
+		}
+		group.Name = *in.Name
+	}
+	if in.Description != nil {
+		group.Description = *in.Description
+	}
+	return nil
+}
+
+func (g *FakeGuard) DeleteGroup(ctx context.Context, groupID int64) error {
+	unlock, _ := g.lock(nil)
+	defer unlock()
+
+	if _, ok := g.groups[groupID]; !ok {
+		return ErrGroupNotFound
+	}
+
+	delete(g.groups, groupID)
+	delete(g.groupUsers, groupID)
+	for _, groups := range g.roleGroups {
+		delete(groups, groupID)
+	}
+	return nil
+}
+
+func (g *FakeGuard) AddUserToGroup(ctx context.Context, in *AddUserToGroupRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := g.groups[in.GroupID]; !ok {
+		return ErrGroupNotFound
+	}
+
+	for _, id := range in.UserIDs {
+		user, ok := g.users[id]
+		if !ok {
+			return ErrUserNotFound
+		}
+		if user.State == UserStateBanned {
+			return ErrUserBanned
+		}
+	}
+
+	if g.groupUsers[in.GroupID] == nil {
+		g.groupUsers[in.GroupID] = make(map[int64]int64)
+	}
+	for _, id := range in.UserIDs {
+		if _, ok := g.groupUsers[in.GroupID][id]; !ok {
+			g.groupUsers[in.GroupID][id] = now()
+		}
+	}
+	return nil
+}
+
+func (g *FakeGuard) ListGroupUser(ctx context.Context, in *ListGroupUserRequest) (*ListGroupUserResponse, error) {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := g.groups[in.GroupID]; !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	users := list(g.users, func(u *GetUserResponse) bool {
+		_, ok := g.groupUsers[in.GroupID][u.ID]
+		return ok && contains(u.Username, in.Name) && contains(u.Email, in.Email)
+	})
+
+	resp := &ListGroupUserResponse{}
+	resp.Total, users = page(users, &in.PageRequest)
+	for _, u := range users {
+		resp.Users = append(resp.Users, &GroupUserVO{
+			ID:       u.ID,
+			Username: u.Username,
+			Email:    u.Email,
+			State:    u.State,
+			JoinedAt: g.groupUsers[in.GroupID][u.ID],
+		})
+	}
+	return resp, nil
+}
+
+func (g *FakeGuard) RemoveUserFromGroup(ctx context.Context, in *RemoveUserFromGroupRequest) error {
+	unlock, err := g.lock(in)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := g.groups[in.GroupID]; !ok {
+		return ErrGroupNotFound
+	}
+
+	for _, id := range in.UserIDs {
+		delete(g.groupUsers[in.GroupID], id)
+	}
+	return nil
+}
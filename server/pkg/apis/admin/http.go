@@ -0,0 +1,375 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sysarmor/guard/server/pkg/errors"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+
+	apiPrefix = "/api/v1/guard"
+)
+
+// HTTPGuard is the client of the management api, it authenticates by
+// the api token
+type HTTPGuard struct {
+	tgt   *url.URL
+	token string
+
+	client *http.Client
+}
+
+var _ Guard = (*HTTPGuard)(nil)
+
+// NewHTTPGuard creates the client, the token can be empty if the
+// server has no api token configured
+func NewHTTPGuard(address, token string) (*HTTPGuard, error) {
+	url, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	return &HTTPGuard{
+		tgt:   url,
+		token: token,
+
+		client: http.DefaultClient,
+	}, nil
+}
+
+// WithHTTPClient replaces the http client, e.g. to set the timeout
+func (g *HTTPGuard) WithHTTPClient(client *http.Client) *HTTPGuard {
+	g.client = client
+	return g
+}
+
+func (g *HTTPGuard) CreateUser(ctx context.Context, in *CreateUserRequest) (int64, error) {
+	return call[int64](ctx, g, http.MethodPost, "/user", nil, in)
+}
+
+func (g *HTTPGuard) ListUser(ctx context.Context, in *ListUserRequest) (*ListUserResponse, error) {
+	return call[*ListUserResponse](ctx, g, http.MethodGet, "/users", encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) GetUser(ctx context.Context, id int64) (*GetUserResponse, error) {
+	return call[*GetUserResponse](ctx, g, http.MethodGet, fmt.Sprintf("/user/%d", id), nil, nil)
+}
+
+func (g *HTTPGuard) GetUserByEmail(ctx context.Context, email string) (*GetUserResponse, error) {
+	return call[*GetUserResponse](ctx, g, http.MethodGet, "/user", url.Values{"email": {email}}, nil)
+}
+
+func (g *HTTPGuard) UpdateUser(ctx context.Context, in *UpdateUserRequest) error {
+	return g.exec(ctx, http.MethodPatch, fmt.Sprintf("/user/%d", in.UserID), nil, in)
+}
+
+func (g *HTTPGuard) DeleteUser(ctx context.Context, id int64) error {
+	return g.exec(ctx, http.MethodDelete, fmt.Sprintf("/user/%d", id), nil, nil)
+}
+
+func (g *HTTPGuard) BanUser(ctx context.Context, id int64) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/user/%d/ban", id), nil, nil)
+}
+
+func (g *HTTPGuard) UnbanUser(ctx context.Context, id int64) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/user/%d/unban", id), nil, nil)
+}
+
+func (g *HTTPGuard) SuspendUser(ctx context.Context, id int64) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/user/%d/suspend", id), nil, nil)
+}
+
+func (g *HTTPGuard) ResumeUser(ctx context.Context, id int64) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/user/%d/resume", id), nil, nil)
+}
+
+func (g *HTTPGuard) UpdateUserPublicKey(ctx context.Context, in *UpdateUserPublicKeyRequest) error {
+	return g.exec(ctx, http.MethodPut, fmt.Sprintf("/user/%d/publicKey", in.UserID), nil, in.PublicKey)
+}
+
+func (g *HTTPGuard) GrantCert(ctx context.Context, in *GrantCertRequest) (*GrantCertResponse, error) {
+	return call[*GrantCertResponse](ctx, g, http.MethodPost, fmt.Sprintf("/user/%d/cert", in.UserID), nil, in)
+}
+
+func (g *HTTPGuard) ListUserCert(ctx context.Context, userID int64) (*ListUserCertResponse, error) {
+	return call[*ListUserCertResponse](ctx, g, http.MethodGet, fmt.Sprintf("/user/%d/cert", userID), nil, nil)
+}
+
+func (g *HTTPGuard) RevokeCert(ctx context.Context, in *RevokeCertRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/user/%d/cert/revoke", in.UserID), nil, in)
+}
+
+func (g *HTTPGuard) CreateSpace(ctx context.Context, in *CreateSpaceRequest) (int64, error) {
+	return call[int64](ctx, g, http.MethodPost, "/space", nil, in)
+}
+
+func (g *HTTPGuard) ListSpace(ctx context.Context, in *ListSpaceRequest) (*ListSpaceResponse, error) {
+	return call[*ListSpaceResponse](ctx, g, http.MethodGet, "/space", encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) UpdateSpace(ctx context.Context, in *UpdateSpaceRequest) error {
+	return g.exec(ctx, http.MethodPatch, fmt.Sprintf("/space/%d", in.SpaceID), nil, in)
+}
+
+func (g *HTTPGuard) DeleteSpace(ctx context.Context, in *DeleteSpaceRequest) error {
+	return g.exec(ctx, http.MethodDelete, fmt.Sprintf("/space/%d", in.SpaceID), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) CreateNode(ctx context.Context, in *CreateNodeRequest) (*CreateNodeResponse, error) {
+	return call[*CreateNodeResponse](ctx, g, http.MethodPost, fmt.Sprintf("/space/%d/node", in.SpaceID), nil, in)
+}
+
+func (g *HTTPGuard) ListNode(ctx context.Context, in *ListNodeRequest) (*ListNodeResponse, error) {
+	return call[*ListNodeResponse](ctx, g, http.MethodGet, fmt.Sprintf("/space/%d/node", in.SpaceID), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) UpdateNode(ctx context.Context, in *UpdateNodeRequest) error {
+	var query url.Values
+	if in.Force {
+		query = url.Values{"force": {"true"}}
+	}
+	return g.exec(ctx, http.MethodPatch, fmt.Sprintf("/space/%d/node/%d", in.SpaceID, in.NodeID), query, in)
+}
+
+func (g *HTTPGuard) DeleteNode(ctx context.Context, spaceID, nodeID int64) error {
+	return g.exec(ctx, http.MethodDelete, fmt.Sprintf("/space/%d/node/%d", spaceID, nodeID), nil, nil)
+}
+
+func (g *HTTPGuard) ListNodeHeartbeat(ctx context.Context, in *ListNodeHeartbeatRequest) (*ListNodeHeartbeatResponse, error) {
+	return call[*ListNodeHeartbeatResponse](ctx, g, http.MethodGet,
+		fmt.Sprintf("/space/%d/node/%d/heartbeat", in.SpaceID, in.NodeID), nil, nil)
+}
+
+func (g *HTTPGuard) FleetHealth(ctx context.Context) (*FleetHealthResponse, error) {
+	return call[*FleetHealthResponse](ctx, g, http.MethodGet, "/fleet/health", nil, nil)
+}
+
+func (g *HTTPGuard) ListSpaceSync(ctx context.Context, in *ListSpaceSyncRequest) (*ListSpaceSyncResponse, error) {
+	return call[*ListSpaceSyncResponse](ctx, g, http.MethodGet, fmt.Sprintf("/space/%d/sync", in.SpaceID), encodeQuery(in), nil)
+}
+
+// rolePath returns the path of the roles of the space, or the global
+// roles if the space id is 0
+func rolePath(spaceID int64) string {
+	if spaceID == 0 {
+		return "/global_role"
+	}
+	return fmt.Sprintf("/space/%d/role", spaceID)
+}
+
+func roleSpace(spaceID int64, global bool) int64 {
+	if global {
+		return 0
+	}
+	return spaceID
+}
+
+func (g *HTTPGuard) CreateRole(ctx context.Context, in *CreateRoleRequest) (int64, error) {
+	return call[int64](ctx, g, http.MethodPost, rolePath(roleSpace(in.SpaceID, in.Global)), nil, in)
+}
+
+func (g *HTTPGuard) ListRole(ctx context.Context, in *ListRoleRequest) (*ListRoleResponse, error) {
+	return call[*ListRoleResponse](ctx, g, http.MethodGet, rolePath(roleSpace(in.SpaceID, in.Global)), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) UpdateRole(ctx context.Context, in *UpdateRoleRequest) error {
+	return g.exec(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", rolePath(in.SpaceID), in.RoleID), nil, in)
+}
+
+func (g *HTTPGuard) DeleteRole(ctx context.Context, spaceID, roleID int64) error {
+	return g.exec(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", rolePath(spaceID), roleID), nil, nil)
+}
+
+func (g *HTTPGuard) AddNodeToRole(ctx context.Context, spaceID int64, in *AddNodeToRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/node", rolePath(spaceID), in.RoleID), nil, in.Nodes)
+}
+
+func (g *HTTPGuard) ListRoleNode(ctx context.Context, spaceID int64, in *ListRoleNodeRequest) (*ListRoleNodeResponse, error) {
+	return call[*ListRoleNodeResponse](ctx, g, http.MethodGet,
+		fmt.Sprintf("%s/%d/node", rolePath(spaceID), in.RoleID), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) RemoveNodeFromRole(ctx context.Context, spaceID int64, in *RemoveNodeFromRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/node/batch/delete", rolePath(spaceID), in.RoleID), nil, in.NodeIDs)
+}
+
+func (g *HTTPGuard) AddRoleSelector(ctx context.Context, spaceID int64, in *AddRoleSelectorRequest) (int64, error) {
+	return call[int64](ctx, g, http.MethodPost, fmt.Sprintf("%s/%d/selector", rolePath(spaceID), in.RoleID), nil, in)
+}
+
+func (g *HTTPGuard) ListRoleSelector(ctx context.Context, spaceID, roleID int64) (*ListRoleSelectorResponse, error) {
+	return call[*ListRoleSelectorResponse](ctx, g, http.MethodGet,
+		fmt.Sprintf("%s/%d/selector", rolePath(spaceID), roleID), nil, nil)
+}
+
+func (g *HTTPGuard) RemoveRoleSelector(ctx context.Context, spaceID int64, in *RemoveRoleSelectorRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/selector/batch/delete", rolePath(spaceID), in.RoleID), nil, in.SelectorIDs)
+}
+
+func (g *HTTPGuard) AddUserToRole(ctx context.Context, spaceID int64, in *AddUserToRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/user", rolePath(spaceID), in.RoleID), encodeQuery(in), in.UserIDs)
+}
+
+func (g *HTTPGuard) ListRoleUser(ctx context.Context, spaceID int64, in *ListRoleUserRequest) (*ListRoleUserResponse, error) {
+	return call[*ListRoleUserResponse](ctx, g, http.MethodGet,
+		fmt.Sprintf("%s/%d/user", rolePath(spaceID), in.RoleID), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) RemoveUserFromRole(ctx context.Context, spaceID int64, in *RemoveUserFromRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/user/batch/delete", rolePath(spaceID), in.RoleID), nil, in.UserIDs)
+}
+
+func (g *HTTPGuard) AddGroupToRole(ctx context.Context, spaceID int64, in *AddGroupToRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/group", rolePath(spaceID), in.RoleID), nil, in.GroupIDs)
+}
+
+func (g *HTTPGuard) ListRoleGroup(ctx context.Context, spaceID, roleID int64) (*ListRoleGroupResponse, error) {
+	return call[*ListRoleGroupResponse](ctx, g, http.MethodGet,
+		fmt.Sprintf("%s/%d/group", rolePath(spaceID), roleID), nil, nil)
+}
+
+func (g *HTTPGuard) RemoveGroupFromRole(ctx context.Context, spaceID int64, in *RemoveGroupFromRoleRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("%s/%d/group/batch/delete", rolePath(spaceID), in.RoleID), nil, in.GroupIDs)
+}
+
+func (g *HTTPGuard) CreateGroup(ctx context.Context, in *CreateGroupRequest) (int64, error) {
+	return call[int64](ctx, g, http.MethodPost, "/group", nil, in)
+}
+
+func (g *HTTPGuard) ListGroup(ctx context.Context, in *ListGroupRequest) (*ListGroupResponse, error) {
+	return call[*ListGroupResponse](ctx, g, http.MethodGet, "/group", encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) UpdateGroup(ctx context.Context, in *UpdateGroupRequest) error {
+	return g.exec(ctx, http.MethodPatch, fmt.Sprintf("/group/%d", in.GroupID), nil, in)
+}
+
+func (g *HTTPGuard) DeleteGroup(ctx context.Context, groupID int64) error {
+	return g.exec(ctx, http.MethodDelete, fmt.Sprintf("/group/%d", groupID), nil, nil)
+}
+
+func (g *HTTPGuard) AddUserToGroup(ctx context.Context, in *AddUserToGroupRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/group/%d/user", in.GroupID), nil, in.UserIDs)
+}
+
+func (g *HTTPGuard) ListGroupUser(ctx context.Context, in *ListGroupUserRequest) (*ListGroupUserResponse, error) {
+	return call[*ListGroupUserResponse](ctx, g, http.MethodGet, fmt.Sprintf("/group/%d/user", in.GroupID), encodeQuery(in), nil)
+}
+
+func (g *HTTPGuard) RemoveUserFromGroup(ctx context.Context, in *RemoveUserFromGroupRequest) error {
+	return g.exec(ctx, http.MethodPost, fmt.Sprintf("/group/%d/user/batch/delete", in.GroupID), nil, in.UserIDs)
+}
+
+// exec calls the api without a response body
+func (g *HTTPGuard) exec(ctx context.Context, method, path string, query url.Values, body any) error {
+	_, err := call[json.RawMessage](ctx, g, method, path, query, body)
+	return err
+}
+
+// call sends the body as json to the path under the api prefix, and
+// decodes the response into T
+func call[T any](ctx context.Context, g *HTTPGuard, method, path string, query url.Values, body any) (T, error) {
+	var t T
+
+	url := *g.tgt
+	url.Path = strings.TrimSuffix(url.Path, "/") + apiPrefix + path
+	url.RawQuery = query.Encode()
+
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return t, fmt.Errorf("failed to encode request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), r)
+	if err != nil {
+		return t, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.token != "" {
+		req.Header.Set(HeaderAuthorization, "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return t, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return t, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return t, decodeError(resp.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return t, nil
+}
+
+// decodeError decodes the guard error of the response, the other
+// responses are returned as StatusError
+func decodeError(status int, body []byte) error {
+	var e errors.Error
+	if err := json.Unmarshal(body, &e); err != nil || e.Code == 0 {
+		return &StatusError{StatusCode: status}
+	}
+
+	e.HTTPCode = &status
+	return &e
+}
+
+// encodeQuery encodes the fields with the form tag of the request,
+// the zero values are omitted
+func encodeQuery(in any) url.Values {
+	query := url.Values{}
+	encodeFields(query, reflect.Indirect(reflect.ValueOf(in)))
+	return query
+}
+
+func encodeFields(query url.Values, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if field.Anonymous {
+			encodeFields(query, reflect.Indirect(value))
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		if name == "" || name == "-" || value.IsZero() {
+			continue
+		}
+
+		value = reflect.Indirect(value)
+		switch value.Kind() {
+		case reflect.String:
+			query.Set(name, value.String())
+		case reflect.Bool:
+			query.Set(name, strconv.FormatBool(value.Bool()))
+		case reflect.Int, reflect.Int64, reflect.Int32:
+			query.Set(name, strconv.FormatInt(value.Int(), 10))
+		}
+	}
+}
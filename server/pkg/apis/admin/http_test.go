@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPGuard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAuthorization) != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrUnauthorized) // nolint
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/guard/user/1":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrUserNotFound) // nolint
+		case "/api/v1/guard/users":
+			if q := r.URL.Query(); q.Get("name") != "alice" || q.Get("page") != "2" || q.Has("email") {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(&ListUserResponse{Total: 1}) // nolint
+		case "/api/v1/guard/global_role":
+			w.Write([]byte("3")) // nolint
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	g, err := NewHTTPGuard(srv.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.GetUser(ctx, 1); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := g.ListUser(ctx, &ListUserRequest{PageRequest: PageRequest{Page: 2}, Name: "alice"})
+	if err != nil || resp.Total != 1 {
+		t.Fatalf("unexpected response: %+v, %v", resp, err)
+	}
+
+	if id, err := g.CreateRole(ctx, &CreateRoleRequest{Name: "audit"}); err != nil || id != 3 {
+		t.Fatalf("unexpected response: %d, %v", id, err)
+	}
+
+	var status *StatusError
+	if err := g.DeleteUser(ctx, 2); !errors.As(err, &status) || status.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g.token = "invalid"
+	if _, err := g.GetUser(ctx, 1); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFakeGuard(t *testing.T) {
+	ctx := context.Background()
+	g := &FakeGuard{}
+
+	userID, err := g.CreateUser(ctx, &CreateUserRequest{Username: "alice", Email: "alice@example.com", PublicKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.CreateUser(ctx, &CreateUserRequest{Username: "alice", Email: "alice@example.com", PublicKey: "key"}); !errors.Is(err, ErrUserAlreadyExists) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := g.CreateUser(ctx, &CreateUserRequest{Email: "bob@example.com"}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spaceID, err := g.CreateSpace(ctx, &CreateSpaceRequest{Name: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := g.CreateNode(ctx, &CreateNodeRequest{SpaceID: spaceID, Name: "web-1", IP: "10.0.0.1", Accounts: []string{"ubuntu"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleID, err := g.CreateRole(ctx, &CreateRoleRequest{SpaceID: spaceID, Name: "ops"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddNodeToRole(ctx, spaceID, &AddNodeToRoleRequest{RoleID: roleID,
+		Nodes: RoleNodeListRequest{{NodeID: node.ID, Account: "ubuntu"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddUserToRole(ctx, spaceID, &AddUserToRoleRequest{RoleID: roleID, UserIDs: []int64{userID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the role is not global
+	if err := g.DeleteRole(ctx, 0, roleID); !errors.Is(err, ErrRoleNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := g.DeleteSpace(ctx, &DeleteSpaceRequest{SpaceID: spaceID}); !errors.Is(err, ErrSpaceNotEmpty) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := g.GrantCert(ctx, &GrantCertRequest{UserID: userID, Effect: 3600}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.SuspendUser(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	certs, err := g.ListUserCert(ctx, userID)
+	if err != nil || len(certs.Certs) != 1 || !certs.Certs[0].IsRevoked {
+		t.Fatalf("unexpected response: %+v, %v", certs, err)
+	}
+	if _, err := g.GrantCert(ctx, &GrantCertRequest{UserID: userID, Effect: 3600}); !errors.Is(err, ErrUserInactive) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	users, err := g.ListRoleUser(ctx, spaceID, &ListRoleUserRequest{RoleID: roleID})
+	if err != nil || users.Total != 1 || !users.Users[0].Direct {
+		t.Fatalf("unexpected response: %+v, %v", users, err)
+	}
+	if err := g.Heartbeat(node.ID, time.Now().Add(-15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	fleet, err := g.FleetHealth(ctx)
+	if err != nil || len(fleet.Spaces) != 1 || fleet.Spaces[0].Stale != 1 || fleet.Spaces[0].StaleAfter != 600 {
+		t.Fatalf("unexpected response: %+v, %v", fleet, err)
+	}
+}
@@ -0,0 +1,91 @@
+package apis
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache keeps the last responses of the sync endpoints with their etags,
+// the unchanged responses are not sent again by the server. The cache is
+// best effort, the failures are ignored and the responses are fetched.
+type Cache interface {
+	Get(key string) (etag string, body []byte, ok bool)
+	Set(key, etag string, body []byte)
+}
+
+type cacheItem struct {
+	etag string
+	body []byte
+}
+
+// memoryCache is the default cache of HTTPGuard
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string]cacheItem
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{items: make(map[string]cacheItem)}
+}
+
+func (c *memoryCache) Get(key string) (string, []byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	return item.etag, item.body, ok
+}
+
+func (c *memoryCache) Set(key, etag string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = cacheItem{etag: etag, body: body}
+}
+
+// FileCache keeps the responses in the files of Dir, one file per key
+// with the etag in the first line. It outlives the process, e.g. the
+// client runs every sync in a new process.
+type FileCache struct {
+	Dir string
+}
+
+func (c *FileCache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *FileCache) Get(key string) (string, []byte, bool) {
+	content, err := os.ReadFile(c.file(key))
+	if err != nil {
+		return "", nil, false
+	}
+
+	etag, body, ok := bytes.Cut(content, []byte("\n"))
+	if !ok || len(etag) == 0 {
+		return "", nil, false
+	}
+	return string(etag), body, true
+}
+
+func (c *FileCache) Set(key, etag string, body []byte) {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return
+	}
+
+	// write to a temporary file and rename, the readers never see
+	// a partial file
+	file := c.file(key)
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte(etag+"\n"), body...), 0600); err != nil {
+		return
+	}
+
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp) // nolint
+	}
+}
@@ -0,0 +1,17 @@
+package dto
+
+// Bundle is all the files served to the node, read from one snapshot of
+// the database, so that they are consistent with each other
+type Bundle struct {
+	// Version is the version of the database the bundle is read from, it
+	// increases on every change, a bundle with a greater version is newer
+	Version int64 `json:"version"`
+	// Digest identifies the content of the bundle, it's the version of
+	// the watches of the node
+	Digest     string        `json:"digest"`
+	CA         string        `json:"ca"`
+	Principals PrincipalList `json:"principals"`
+	// KRL is encoded in base64, it's empty if no key is revoked
+	KRL            string   `json:"krl"`
+	AuthorizedKeys []string `json:"authorized_keys"`
+}
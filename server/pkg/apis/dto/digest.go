@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// Artifact is a kind of the files managed by the client on the node
+type Artifact string
+
+const (
+	// ArtifactCA is the trusted user CA keys file
+	ArtifactCA Artifact = "ca"
+	// ArtifactPrincipals is the authorized principals directory
+	ArtifactPrincipals Artifact = "principals"
+	// ArtifactKRL is the revoked keys file
+	ArtifactKRL Artifact = "krl"
+	// ArtifactAuthorizedKeys is the authorized keys file
+	ArtifactAuthorizedKeys Artifact = "authorized_keys"
+)
+
+// Artifacts are all the kinds of the managed files
+var Artifacts = []Artifact{ArtifactCA, ArtifactPrincipals, ArtifactKRL, ArtifactAuthorizedKeys}
+
+// Valid reports whether the artifact is a known kind
+func (a Artifact) Valid() bool {
+	return slices.Contains(Artifacts, a)
+}
+
+// The digests are computed the same way by the server from what it serves
+// and by the client from what it received and what it reads back from
+// the files, so that they can be compared.
+
+// Digest returns the hex sha256 of the content
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestCA returns the digest of the trusted user CA keys file
+func DigestCA(ca string) string {
+	return Digest([]byte(ca))
+}
+
+// krlMagic and the offset of the generated_date in the KRL header, see
+// PROTOCOL.krl of openssh
+const (
+	krlMagic         = "SSHKRL\n\x00"
+	krlGeneratedDate = len(krlMagic) + 4 + 8
+)
+
+// DigestKRL returns the digest of the KRL, the generated date of the
+// header is ignored, so the same revocations have the same digest
+func DigestKRL(krl []byte) string {
+	if len(krl) >= krlGeneratedDate+8 && bytes.HasPrefix(krl, []byte(krlMagic)) {
+		krl = bytes.Clone(krl)
+		clear(krl[krlGeneratedDate : krlGeneratedDate+8])
+	}
+	return Digest(krl)
+}
+
+// DigestPrincipals returns the digest of the principals, the roles and
+// the principals of each role are sorted
+func DigestPrincipals(list PrincipalList) string {
+	roles := slices.Clone(list)
+	slices.SortFunc(roles, func(a, b *Principals) int { return strings.Compare(a.Role, b.Role) })
+
+	var buf bytes.Buffer
+	for _, p := range roles {
+		buf.WriteString(p.Role)
+		buf.WriteByte('\n')
+		for _, principal := range slices.Sorted(slices.Values(p.Principals)) {
+			buf.WriteString(principal)
+			buf.WriteByte('\n')
+		}
+		buf.WriteByte('\n')
+	}
+	return Digest(buf.Bytes())
+}
+
+// AuthorizedKeysContent returns the content of the authorized keys file,
+// one key per line
+func AuthorizedKeysContent(keys []string) []byte {
+	var buf bytes.Buffer
+	for _, key := range keys {
+		buf.WriteString(key)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// DigestAuthorizedKeys returns the digest of the authorized keys file
+func DigestAuthorizedKeys(keys []string) string {
+	return Digest(AuthorizedKeysContent(keys))
+}
+
+// Report is the state of the managed files reported by the client after
+// each run
+type Report struct {
+	Artifacts []*ArtifactReport `json:"artifacts"`
+}
+
+// ArtifactReport is the state of a managed file on the node
+type ArtifactReport struct {
+	Kind Artifact `json:"kind"`
+	// Received is the digest of the content served to the client,
+	// empty if the client failed to get it
+	Received string `json:"received"`
+	// Applied is the digest of the file read back after the run,
+	// it differs from Received if the file was not written
+	Applied string `json:"applied"`
+	// Error is the error of the run, empty if succeeded
+	Error string `json:"error,omitempty"`
+}
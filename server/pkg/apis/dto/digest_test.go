@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func krl(version, generatedDate uint64) []byte {
+	buf := bytes.NewBufferString(krlMagic)
+	binary.Write(buf, binary.BigEndian, uint32(1))     // format version
+	binary.Write(buf, binary.BigEndian, version)       // krl version
+	binary.Write(buf, binary.BigEndian, generatedDate) // generated date
+	buf.WriteString("sections")
+	return buf.Bytes()
+}
+
+func TestDigestKRL(t *testing.T) {
+	t.Run("GeneratedDate", func(t *testing.T) {
+		if DigestKRL(krl(1, 1000)) != DigestKRL(krl(1, 2000)) {
+			t.Fatal("the generated date should be ignored")
+		}
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		if DigestKRL(krl(1, 1000)) == DigestKRL(krl(2, 1000)) {
+			t.Fatal("the different KRLs should have different digests")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if DigestKRL(nil) != Digest(nil) {
+			t.Fatal("the empty KRL should be the digest of the empty file")
+		}
+	})
+}
+
+func TestDigestPrincipals(t *testing.T) {
+	a := PrincipalList{
+		{Role: "root", Principals: []string{"alice", "bob"}},
+		{Role: "deploy", Principals: []string{"carol"}},
+	}
+	b := PrincipalList{
+		{Role: "deploy", Principals: []string{"carol"}},
+		{Role: "root", Principals: []string{"bob", "alice"}},
+	}
+	if DigestPrincipals(a) != DigestPrincipals(b) {
+		t.Fatal("the order of the roles and the principals should be ignored")
+	}
+
+	c := PrincipalList{
+		{Role: "root", Principals: []string{"alice"}},
+		{Role: "deploy", Principals: []string{"bob", "carol"}},
+	}
+	if DigestPrincipals(a) == DigestPrincipals(c) {
+		t.Fatal("the principals of different roles should have different digests")
+	}
+}
@@ -0,0 +1,10 @@
+package dto
+
+// Watch is the result of a watch of the node. Version identifies the
+// content of the files served to the node, it's passed to the next watch.
+type Watch struct {
+	Version string `json:"version"`
+	// Changed is set if the version is different from the one passed
+	// to the watch, the node should sync the files now
+	Changed bool `json:"changed"`
+}
@@ -11,4 +11,12 @@ type Guard interface {
 	GetPrincipals(ctx context.Context) ([]*dto.Principals, error)
 	GetKRL(ctx context.Context) (string, error)
 	GetAuthorizedKeys(ctx context.Context) ([]string, error)
+	// GetBundle gets all the files of the node from one snapshot, they
+	// are consistent with each other
+	GetBundle(ctx context.Context) (*dto.Bundle, error)
+	// Report reports the digests of the managed files after a run
+	Report(ctx context.Context, report *dto.Report) error
+	// Watch waits until the files of the node on the server are different
+	// from the version, an empty version returns the current one at once
+	Watch(ctx context.Context, version string) (*dto.Watch, error)
 }
@@ -35,3 +35,35 @@ func (g *FakeGuard) GetAuthorizedKeys(ctx context.Context) ([]string, error) {
 		"fake-authorized",
 	}, nil
 }
+
+// GetBundle returns the fake files above in one bundle
+func (g *FakeGuard) GetBundle(ctx context.Context) (*dto.Bundle, error) {
+	ca, _ := g.GetCA(ctx)
+	principals, _ := g.GetPrincipals(ctx)
+	krl, _ := g.GetKRL(ctx)
+	keys, _ := g.GetAuthorizedKeys(ctx)
+
+	return &dto.Bundle{
+		Version:        1,
+		Digest:         "fake-version",
+		CA:             ca,
+		Principals:     principals,
+		KRL:            krl,
+		AuthorizedKeys: keys,
+	}, nil
+}
+
+func (g *FakeGuard) Report(ctx context.Context, report *dto.Report) error {
+	return nil
+}
+
+// Watch returns the fake version at once, then waits until ctx is done
+// since the fake files never change
+func (g *FakeGuard) Watch(ctx context.Context, version string) (*dto.Watch, error) {
+	if version == "" {
+		return &dto.Watch{Version: "fake-version"}, nil
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
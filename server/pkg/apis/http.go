@@ -1,6 +1,7 @@
 package apis
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -19,11 +20,14 @@ type HTTPGuard struct {
 	nodeSecret string
 
 	client *http.Client
+	cache  Cache
 }
 
 const (
-	HeaderTimestamp = "X-Timestamp"
-	HeaderSignature = "X-Signature"
+	HeaderTimestamp   = "X-Timestamp"
+	HeaderSignature   = "X-Signature"
+	HeaderETag        = "ETag"
+	HeaderIfNoneMatch = "If-None-Match"
 )
 
 func NewHTTPGuard(address string, nodeID, nodeSecret string) (*HTTPGuard, error) {
@@ -38,67 +42,129 @@ func NewHTTPGuard(address string, nodeID, nodeSecret string) (*HTTPGuard, error)
 		nodeSecret: nodeSecret,
 
 		client: http.DefaultClient,
+		cache:  newMemoryCache(),
 	}, nil
 }
 
+// SetCache replaces the in-memory cache of the responses
+func (g *HTTPGuard) SetCache(cache Cache) {
+	g.cache = cache
+}
+
 func (g *HTTPGuard) GetCA(ctx context.Context) (string, error) {
-	url := *g.tgt
-	url.Path = "/api/v1/guard/ca"
-	url.RawQuery = "nodeID=" + g.nodeID
+	return get[string](ctx, g, "/api/v1/guard/ca")
+}
 
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    &url,
-	}
+func (g *HTTPGuard) GetPrincipals(ctx context.Context) ([]*dto.Principals, error) {
+	return get[[]*dto.Principals](ctx, g, "/api/v1/guard/principals")
+}
 
-	resp, err := g.do(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("failed to do request: %w", err)
-	}
+func (g *HTTPGuard) GetKRL(ctx context.Context) (string, error) {
+	return get[string](ctx, g, "/api/v1/guard/krl")
+}
 
-	return decodeResp[string](g.nodeSecret, resp)
+func (g *HTTPGuard) GetAuthorizedKeys(ctx context.Context) ([]string, error) {
+	return get[[]string](ctx, g, "/api/v1/guard/authorized_keys")
 }
 
-func (g *HTTPGuard) GetPrincipals(ctx context.Context) ([]*dto.Principals, error) {
+func (g *HTTPGuard) GetBundle(ctx context.Context) (*dto.Bundle, error) {
+	return get[*dto.Bundle](ctx, g, "/api/v1/guard/bundle")
+}
+
+// get gets the path with the etag of the cached response, the cached
+// body is used if the server responds 304
+func get[T any](ctx context.Context, g *HTTPGuard, path string) (T, error) {
+	var t T
+
 	url := *g.tgt
-	url.Path = "/api/v1/guard/principals"
+	url.Path = path
 	url.RawQuery = "nodeID=" + g.nodeID
 
 	req := &http.Request{
 		Method: http.MethodGet,
 		URL:    &url,
+		Header: make(http.Header),
+	}
+
+	key := g.nodeID + " " + path
+	etag, cached, ok := g.cache.Get(key)
+	if ok {
+		req.Header.Set(HeaderIfNoneMatch, etag)
 	}
 
 	resp, err := g.do(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %w", err)
+		return t, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		// the 304 has no body, the etag is signed instead
+		if err := validateSignature([]byte(resp.Header.Get(HeaderETag)), g.nodeSecret, resp.Header.Get(HeaderSignature)); err != nil {
+			return t, fmt.Errorf("failed to validate response: %w", err)
+		}
+		body = cached
+	case resp.StatusCode == http.StatusOK:
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return t, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if err := validateSignature(body, g.nodeSecret, resp.Header.Get(HeaderSignature)); err != nil {
+			return t, fmt.Errorf("failed to validate response: %w", err)
+		}
+
+		if etag := resp.Header.Get(HeaderETag); etag != "" {
+			g.cache.Set(key, etag, body)
+		}
+	default:
+		return t, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, &t); err != nil {
+		return t, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return decodeResp[[]*dto.Principals](g.nodeSecret, resp)
+	return t, nil
 }
 
-func (g *HTTPGuard) GetKRL(ctx context.Context) (string, error) {
+func (g *HTTPGuard) Report(ctx context.Context, report *dto.Report) error {
 	url := *g.tgt
-	url.Path = "/api/v1/guard/krl"
+	url.Path = "/api/v1/guard/report"
 	url.RawQuery = "nodeID=" + g.nodeID
 
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
 	req := &http.Request{
-		Method: http.MethodGet,
+		Method: http.MethodPost,
 		URL:    &url,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   io.NopCloser(bytes.NewReader(body)),
 	}
+	req.ContentLength = int64(len(body))
 
 	resp, err := g.do(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to do request: %w", err)
+		return fmt.Errorf("failed to do request: %w", err)
 	}
 
-	return decodeResp[string](g.nodeSecret, resp)
+	_, err = decodeResp[struct{}](g.nodeSecret, resp)
+	return err
 }
 
-func (g *HTTPGuard) GetAuthorizedKeys(ctx context.Context) ([]string, error) {
+// Watch holds the request until the server notices a change or times out,
+// ctx should not have a shorter deadline than the watch timeout of the server
+func (g *HTTPGuard) Watch(ctx context.Context, version string) (*dto.Watch, error) {
+	query := url.Values{"nodeID": {g.nodeID}, "version": {version}}
+
 	url := *g.tgt
-	url.Path = "/api/v1/guard/authorized_keys"
-	url.RawQuery = "nodeID=" + g.nodeID
+	url.Path = "/api/v1/guard/watch"
+	url.RawQuery = query.Encode()
 
 	req := &http.Request{
 		Method: http.MethodGet,
@@ -110,7 +176,7 @@ func (g *HTTPGuard) GetAuthorizedKeys(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("failed to do request: %w", err)
 	}
 
-	return decodeResp[[]string](g.nodeSecret, resp)
+	return decodeResp[*dto.Watch](g.nodeSecret, resp)
 }
 
 func (g *HTTPGuard) do(ctx context.Context, req *http.Request) (*http.Response, error) {
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/sysarmor/guard/server/pkg/helper"
 	"golang.org/x/crypto/ssh"
@@ -16,6 +17,10 @@ import (
 type Certificate struct {
 	privateKey []byte
 	publicKey  []byte
+
+	// checked is set once the private key is decrypted and matches
+	// the public key, the keys are not changed after New
+	checked atomic.Bool
 }
 
 func New(privateKey, publicKey []byte) *Certificate {
@@ -25,6 +30,35 @@ func New(privateKey, publicKey []byte) *Certificate {
 	}
 }
 
+// Check checks the CA is usable, the private key must be decrypted by the
+// passphrase and match the public key, and ssh-keygen must be installed
+// to generate the KRL
+func (c *Certificate) Check(passphrase []byte) error {
+	if !c.checked.Load() {
+		caSigner, err := ssh.ParsePrivateKeyWithPassphrase(c.privateKey, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to parse private key: %w", err)
+		}
+
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey(c.publicKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse public key: %w", err)
+		}
+
+		if !bytes.Equal(caSigner.PublicKey().Marshal(), pubKey.Marshal()) {
+			return fmt.Errorf("private key does not match the public key")
+		}
+
+		c.checked.Store(true)
+	}
+
+	if _, err := witchSSHKeyGen(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 var extensions = []string{
 	"permit-X11-forwarding",
 	"permit-agent-forwarding",
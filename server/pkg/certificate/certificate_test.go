@@ -136,3 +136,29 @@ func TestRevokeKeys(t *testing.T) {
 		}
 	})
 }
+
+func TestCheck(t *testing.T) {
+	passphrase := []byte("123456")
+
+	caPrivateKey, caPublicKey, err := generateKeyPair(2048, "", passphrase)
+	if err != nil {
+		t.Fatalf("failed to generate CA key pair: %v", err)
+	}
+
+	_, otherPublicKey, err := generateKeyPair(2048, "", passphrase)
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	if err := New(caPrivateKey, caPublicKey).Check([]byte("wrong")); err == nil {
+		t.Fatal("wrong passphrase should fail")
+	}
+
+	if err := New(caPrivateKey, otherPublicKey).Check(passphrase); err == nil {
+		t.Fatal("mismatched public key should fail")
+	}
+
+	if err := New(caPrivateKey, caPublicKey).Check(passphrase); err != nil {
+		t.Fatalf("failed to check: %v", err)
+	}
+}
@@ -38,3 +38,10 @@ func NewWithHTTPCode(httpCode, code int, message string) error {
 		Message:  message,
 	}
 }
+
+// Is reports whether the target is an Error with the same code, so that
+// the errors decoded from the api responses match the predefined errors
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
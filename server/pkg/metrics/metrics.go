@@ -0,0 +1,367 @@
+// Package metrics is a minimal implementation of the prometheus metrics,
+// it has the counters, the histograms and the gauges computed at the
+// scrape time, and writes them in the prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the buckets of the latencies in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector writes the samples of the metrics in the text format
+type Collector interface {
+	Collect(ctx context.Context, w io.Writer) error
+}
+
+// Registry is a list of the collectors, it's also a collector, so that
+// a registry can be registered to another one
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// Default is the registry of the metrics defined by the packages
+var Default = NewRegistry()
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register registers the collectors to the default registry
+func Register(cs ...Collector) {
+	Default.Register(cs...)
+}
+
+// Register registers the collectors, they are written in the order
+func (r *Registry) Register(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, cs...)
+}
+
+// Collect writes the samples of all collectors, the failed collectors
+// are skipped and their errors are returned
+func (r *Registry) Collect(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, c := range collectors {
+		// buffered, so that a failed collector writes nothing
+		var buf bytes.Buffer
+		if err := c.Collect(ctx, &buf); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ServeHTTP writes the samples in the text format, the errors of the
+// collectors are logged and the other samples are still written
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var buf bytes.Buffer
+	if err := r.Collect(ctx, &buf); err != nil {
+		slog.WarnContext(ctx, "failed to collect metrics", "error", err)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w) // nolint
+}
+
+// desc is the name, the help and the label names of a metric
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) writeHeader(w io.Writer) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, help, d.name, d.typ)
+}
+
+// writeSample writes a sample, extra is the label added to the labels of
+// the metric, e.g. le of the histogram buckets
+func (d *desc) writeSample(w io.Writer, suffix string, values []string, extra []string, v float64) {
+	io.WriteString(w, d.name+suffix) // nolint
+
+	names, all := d.labels, values
+	if len(extra) == 2 {
+		names, all = append(names[:len(names):len(names)], extra[0]), append(values[:len(values):len(values)], extra[1])
+	}
+
+	if len(names) > 0 {
+		io.WriteString(w, "{") // nolint
+		for i, name := range names {
+			if i > 0 {
+				io.WriteString(w, ",") // nolint
+			}
+			fmt.Fprintf(w, `%s="%s"`, name, escapeLabel(all[i]))
+		}
+		io.WriteString(w, "}") // nolint
+	}
+
+	fmt.Fprintf(w, " %s\n", formatFloat(v))
+}
+
+func (d *desc) checkValues(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", d.name, len(d.labels), len(values)))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// vec is the children of a metric keyed by the label values
+type vec[T any] struct {
+	desc
+
+	mu       sync.Mutex
+	children map[string]*child[T]
+	newT     func() *T
+}
+
+type child[T any] struct {
+	values []string
+	metric *T
+}
+
+func newVec[T any](d desc, newT func() *T) *vec[T] {
+	return &vec[T]{desc: d, children: make(map[string]*child[T]), newT: newT}
+}
+
+func (v *vec[T]) with(values []string) *T {
+	v.checkValues(values)
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.children[key]
+	if !ok {
+		c = &child[T]{values: append([]string(nil), values...), metric: v.newT()}
+		v.children[key] = c
+	}
+	return c.metric
+}
+
+// sorted returns the children ordered by the label values
+func (v *vec[T]) sorted() []*child[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	children := make([]*child[T], 0, len(keys))
+	for _, key := range keys {
+		children = append(children, v.children[key])
+	}
+	return children
+}
+
+// Counter is a value which only goes up
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v to the counter, v must not be negative
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter can not decrease")
+	}
+
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) get() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// CounterVec is the counters partitioned by the labels
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounterVec returns a counter vector, the name should end with _total
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(desc{name: name, help: help, typ: "counter", labels: labels}, func() *Counter {
+		return &Counter{}
+	})}
+}
+
+// With returns the counter of the label values
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values)
+}
+
+func (v *CounterVec) Collect(ctx context.Context, w io.Writer) error {
+	v.writeHeader(w)
+	for _, c := range v.sorted() {
+		v.writeSample(w, "", c.values, nil, c.metric.get())
+	}
+	return nil
+}
+
+// Histogram counts the observations in the buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Observe adds an observation to the histogram
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// snapshot returns the cumulative counts of the buckets, the sum and
+// the count of the observations
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative := make([]uint64, len(h.counts))
+	var n uint64
+	for i, c := range h.counts {
+		n += c
+		cumulative[i] = n
+	}
+	return cumulative, h.sum, h.count
+}
+
+// HistogramVec is the histograms partitioned by the labels
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec returns a histogram vector, buckets are the upper bounds
+// of the buckets, the +Inf bucket is added implicitly
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &HistogramVec{
+		vec: newVec(desc{name: name, help: help, typ: "histogram", labels: labels}, func() *Histogram {
+			return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		}),
+		buckets: buckets,
+	}
+}
+
+// With returns the histogram of the label values
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values)
+}
+
+func (v *HistogramVec) Collect(ctx context.Context, w io.Writer) error {
+	v.writeHeader(w)
+	for _, c := range v.sorted() {
+		counts, sum, count := c.metric.snapshot()
+		for i, upper := range v.buckets {
+			v.writeSample(w, "_bucket", c.values, []string{"le", formatFloat(upper)}, float64(counts[i]))
+		}
+		v.writeSample(w, "_bucket", c.values, []string{"le", "+Inf"}, float64(count))
+		v.writeSample(w, "_sum", c.values, nil, sum)
+		v.writeSample(w, "_count", c.values, nil, float64(count))
+	}
+	return nil
+}
+
+// GaugeFunc is a gauge vector computed at the scrape time, e.g. the
+// number of the objects in the database
+type GaugeFunc struct {
+	desc
+	fn func(ctx context.Context, observe func(v float64, values ...string)) error
+}
+
+// NewGaugeFunc returns a gauge vector, fn is called by every scrape and
+// calls observe with the value of every label values
+func NewGaugeFunc(name, help string, labels []string,
+	fn func(ctx context.Context, observe func(v float64, values ...string)) error) *GaugeFunc {
+	return &GaugeFunc{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, fn: fn}
+}
+
+func (g *GaugeFunc) Collect(ctx context.Context, w io.Writer) error {
+	type sample struct {
+		values []string
+		v      float64
+	}
+
+	var samples []sample
+	err := g.fn(ctx, func(v float64, values ...string) {
+		g.checkValues(values)
+		samples = append(samples, sample{values: values, v: v})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collect %s: %w", g.name, err)
+	}
+
+	g.writeHeader(w)
+	for _, s := range samples {
+		g.writeSample(w, "", s.values, nil, s.v)
+	}
+	return nil
+}
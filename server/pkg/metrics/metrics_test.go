@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	return w.Body.String()
+}
+
+func TestRegistry(t *testing.T) {
+	requests := NewCounterVec("test_requests_total", "Number of the requests", "route", "status")
+	requests.With("/b", "200").Inc()
+	requests.With("/a", "500").Add(2)
+	requests.With("/a", "500").Inc()
+	requests.With(`/"q"`, "200").Inc()
+
+	latency := NewHistogramVec("test_latency_seconds", "Latency\nof the requests", []float64{1, 0.1}, "route")
+	latency.With("/a").Observe(0.05)
+	latency.With("/a").Observe(0.5)
+	latency.With("/a").Observe(3)
+
+	nodes := NewGaugeFunc("test_nodes", "Number of the nodes", []string{"space"},
+		func(ctx context.Context, observe func(v float64, values ...string)) error {
+			observe(3, "prod")
+			observe(0, "dev")
+			return nil
+		})
+
+	failed := NewGaugeFunc("test_failed", "Always fails", nil,
+		func(ctx context.Context, observe func(v float64, values ...string)) error {
+			observe(1)
+			return errors.New("db is down")
+		})
+
+	inner := NewRegistry()
+	inner.Register(nodes)
+
+	r := NewRegistry()
+	r.Register(requests, latency, failed, inner)
+
+	want := `# HELP test_requests_total Number of the requests
+# TYPE test_requests_total counter
+test_requests_total{route="/\"q\"",status="200"} 1
+test_requests_total{route="/a",status="500"} 3
+test_requests_total{route="/b",status="200"} 1
+# HELP test_latency_seconds Latency\nof the requests
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/a",le="0.1"} 1
+test_latency_seconds_bucket{route="/a",le="1"} 2
+test_latency_seconds_bucket{route="/a",le="+Inf"} 3
+test_latency_seconds_sum{route="/a"} 3.55
+test_latency_seconds_count{route="/a"} 3
+# HELP test_nodes Number of the nodes
+# TYPE test_nodes gauge
+test_nodes{space="prod"} 3
+test_nodes{space="dev"} 0
+`
+	if got := scrape(t, r); got != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelValues(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("mismatched label values should panic")
+		}
+	}()
+
+	NewCounterVec("test_total", "", "a", "b").With("x")
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LockoutConfig is the configuration of the lockout
+type LockoutConfig struct {
+	// Threshold is the number of failures in the window to lock the key,
+	// if it is 0, the lockout is disabled
+	Threshold int `yaml:"threshold"`
+	// Window is the duration to count the failures
+	Window time.Duration `yaml:"window"`
+	// Duration is the duration to lock the key
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Validate checks the config, the zero window and duration default
+// to 5m and 15m
+func (c *LockoutConfig) Validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+
+	if c.Window < 0 || c.Duration < 0 {
+		return fmt.Errorf("window and duration must not be negative")
+	}
+
+	if c.Window == 0 {
+		c.Window = 5 * time.Minute
+	}
+
+	if c.Duration == 0 {
+		c.Duration = 15 * time.Minute
+	}
+
+	return nil
+}
+
+type failure struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// Lockout locks a key temporarily after repeated failures,
+// e.g. invalid signatures or invalid api tokens
+type Lockout struct {
+	mu       sync.Mutex
+	cfg      LockoutConfig
+	failures map[string]*failure
+
+	now func() time.Time
+}
+
+// NewLockout returns a new lockout, the config must be validated
+func NewLockout(cfg LockoutConfig) *Lockout {
+	return &Lockout{
+		cfg:      cfg,
+		failures: make(map[string]*failure),
+		now:      time.Now,
+	}
+}
+
+// Enabled reports whether the lockout is enabled
+func (l *Lockout) Enabled() bool {
+	return l != nil && l.cfg.Threshold > 0
+}
+
+// Locked reports whether the key is locked and the remaining duration
+func (l *Lockout) Locked(key string) (bool, time.Duration) {
+	if !l.Enabled() {
+		return false, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return false, 0
+	}
+
+	remaining := f.lockedUntil.Sub(l.now())
+	if remaining <= 0 {
+		return false, 0
+	}
+
+	return true, remaining
+}
+
+// Fail records a failure of the key, it returns true if
+// the key is locked after the failure
+func (l *Lockout) Fail(key string) bool {
+	if !l.Enabled() {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.expire(now)
+
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.first) > l.cfg.Window {
+		f = &failure{first: now}
+		l.failures[key] = f
+	}
+
+	f.count++
+	if f.count >= l.cfg.Threshold {
+		f.lockedUntil = now.Add(l.cfg.Duration)
+		return true
+	}
+
+	return false
+}
+
+// Reset clears the failures of the key
+func (l *Lockout) Reset(key string) {
+	if !l.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
+
+// expire removes the failures which are out of window and not locked
+func (l *Lockout) expire(now time.Time) {
+	for key, f := range l.failures {
+		if now.Sub(f.first) > l.cfg.Window && now.After(f.lockedUntil) {
+			delete(l.failures, key)
+		}
+	}
+}
+
+// LockState is the state of a key in the lockout
+type LockState struct {
+	Key         string `json:"key"`
+	Failures    int    `json:"failures"`
+	LockedUntil int64  `json:"locked_until"`
+}
+
+// Snapshot returns the state of the keys which have failures
+func (l *Lockout) Snapshot() []LockState {
+	states := make([]LockState, 0)
+	if !l.Enabled() {
+		return states
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.expire(l.now())
+	for key, f := range l.failures {
+		state := LockState{Key: key, Failures: f.count}
+		if !f.lockedUntil.IsZero() {
+			state.LockedUntil = f.lockedUntil.Unix()
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Failures > states[j].Failures
+	})
+
+	return states
+}
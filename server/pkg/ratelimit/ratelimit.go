@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultMaxKeys is the number of keys kept before idle buckets are pruned
+const defaultMaxKeys = 10000
+
+// Config is the configuration of a token bucket limiter
+type Config struct {
+	// Rate is the number of tokens added per second,
+	// if it is 0, the limiter is disabled
+	Rate float64 `yaml:"rate"`
+	// Burst is the max number of tokens in the bucket
+	Burst int `yaml:"burst"`
+}
+
+// Validate checks the config, the zero burst defaults to the rate
+func (c *Config) Validate() error {
+	if c.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+
+	if c.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+
+	if c.Rate == 0 && c.Burst > 0 {
+		return fmt.Errorf("burst requires a rate")
+	}
+
+	if c.Burst == 0 {
+		c.Burst = int(math.Ceil(c.Rate))
+	}
+
+	return nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket limiter keyed by an arbitrary string,
+// e.g. node id, source ip or api token
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	maxKeys int
+	buckets map[string]*bucket
+
+	now func() time.Time
+}
+
+// New returns a new limiter, the config must be validated
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		rate:    cfg.Rate,
+		burst:   float64(cfg.Burst),
+		maxKeys: defaultMaxKeys,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Enabled reports whether the limiter is enabled
+func (l *Limiter) Enabled() bool {
+	return l != nil && l.rate > 0
+}
+
+// Allow takes a token from the bucket of the key, if there is no token
+// left, it returns false and the duration to wait for the next token
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if !l.Enabled() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxKeys {
+			l.prune(now)
+		}
+
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	l.refill(b, now)
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+}
+
+// prune removes the buckets which are full, they are the same as
+// a new bucket, so there is no need to keep them
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// BucketState is the state of a bucket
+type BucketState struct {
+	Key    string  `json:"key"`
+	Tokens float64 `json:"tokens"`
+}
+
+// Snapshot returns the state of the buckets which are not full
+func (l *Limiter) Snapshot() []BucketState {
+	states := make([]BucketState, 0)
+	if !l.Enabled() {
+		return states
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			continue
+		}
+
+		states = append(states, BucketState{Key: key, Tokens: b.tokens})
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Tokens < states[j].Tokens
+	})
+
+	return states
+}
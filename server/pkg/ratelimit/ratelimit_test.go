@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("Burst", func(t *testing.T) {
+		c := &clock{t: time.Unix(1000, 0)}
+		l := New(Config{Rate: 1, Burst: 3})
+		l.now = c.now
+
+		for i := 0; i < 3; i++ {
+			if ok, _ := l.Allow("node"); !ok {
+				t.Fatalf("request %d should be allowed", i)
+			}
+		}
+
+		ok, wait := l.Allow("node")
+		if ok {
+			t.Fatalf("request should be throttled")
+		}
+		if wait != time.Second {
+			t.Fatalf("unexpected wait: %s", wait)
+		}
+
+		// other keys are not affected
+		if ok, _ := l.Allow("other"); !ok {
+			t.Fatalf("other key should be allowed")
+		}
+
+		c.add(time.Second)
+		if ok, _ := l.Allow("node"); !ok {
+			t.Fatalf("request should be allowed after refill")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		l := New(Config{})
+		for i := 0; i < 100; i++ {
+			if ok, _ := l.Allow("node"); !ok {
+				t.Fatalf("disabled limiter should allow all requests")
+			}
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		c := &clock{t: time.Unix(1000, 0)}
+		l := New(Config{Rate: 1, Burst: 1})
+		l.now = c.now
+		l.maxKeys = 2
+
+		l.Allow("a")
+		l.Allow("b")
+		c.add(time.Second)
+		l.Allow("c")
+
+		if len(l.buckets) != 1 {
+			t.Fatalf("full buckets should be pruned, got %d", len(l.buckets))
+		}
+
+		if states := l.Snapshot(); len(states) != 1 || states[0].Key != "c" {
+			t.Fatalf("unexpected snapshot: %v", states)
+		}
+	})
+}
+
+func TestLockout(t *testing.T) {
+	c := &clock{t: time.Unix(1000, 0)}
+	l := NewLockout(LockoutConfig{Threshold: 3, Window: time.Minute, Duration: 10 * time.Minute})
+	l.now = c.now
+
+	if l.Fail("node") || l.Fail("node") {
+		t.Fatalf("node should not be locked before threshold")
+	}
+
+	if !l.Fail("node") {
+		t.Fatalf("node should be locked at threshold")
+	}
+
+	locked, remaining := l.Locked("node")
+	if !locked || remaining != 10*time.Minute {
+		t.Fatalf("unexpected lock state: %v %s", locked, remaining)
+	}
+
+	c.add(10 * time.Minute)
+	if locked, _ := l.Locked("node"); locked {
+		t.Fatalf("node should be unlocked after duration")
+	}
+
+	// failures out of window are not counted
+	l.Fail("other")
+	l.Fail("other")
+	c.add(2 * time.Minute)
+	if l.Fail("other") {
+		t.Fatalf("failures out of window should be reset")
+	}
+
+	l.Reset("other")
+	if states := l.Snapshot(); len(states) != 0 {
+		t.Fatalf("unexpected snapshot: %v", states)
+	}
+}
@@ -1,9 +1,13 @@
 package route
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/console"
 	"github.com/sysarmor/guard/server/internal/controller"
 )
 
@@ -26,52 +30,168 @@ func New(cc *controller.Guard) *Route {
 
 func (r *Route) Register() {
 	e := gin.Default()
+	e.Use(r.cc.Instrument)
 	r.server.Handler = e
 
-	sg := e.Group("/api/v1/guard", r.cc.IsAllowedNode, r.cc.Signature, r.cc.UpdateNodeLastHeartbeat)
+	sg := e.Group("/api/v1/guard", r.cc.RateLimitIP, r.cc.RateLimitNode,
+		r.cc.IsAllowedNode, r.cc.Signature, r.cc.UpdateNodeLastHeartbeat)
 	{
 		sg.GET("/ca", r.cc.GetCA)
 		sg.GET("/principals", r.cc.GetPrincipals)
 		sg.GET("/krl", r.cc.GetKRL)
 		sg.GET("/authorized_keys", r.cc.GetAuthorizedKeys)
+		sg.GET("/bundle", r.cc.GetBundle)
 	}
 
-	space := e.Group("/api/v1/guard/space")
+	// the reports and the watches are not syncs, they do not count as heartbeats
+	ng := e.Group("/api/v1/guard", r.cc.RateLimitIP, r.cc.RateLimitNode,
+		r.cc.IsAllowedNode, r.cc.Signature)
+	{
+		ng.POST("/report", r.cc.Report)
+		ng.GET("/watch", r.cc.Watch)
+	}
+
+	admin := []gin.HandlerFunc{r.cc.RateLimitIP, r.cc.IsAdmin, r.cc.RateLimitToken}
+
+	e.GET("/healthz", r.cc.Healthz)
+	e.GET("/readyz", r.cc.Readyz)
+	e.GET("/metrics", r.cc.RateLimitIP, r.cc.IsAdmin, r.cc.Metrics)
+
+	// the console has no data, it calls the admin api below with the token
+	e.GET("/console/*filepath", r.cc.RateLimitIP, console.Handler("/console"))
+
+	ratelimit := e.Group("/api/v1/guard/ratelimit", admin...)
+	{
+		ratelimit.GET("", r.cc.GetRateLimitState)
+	}
+
+	event := e.Group("/api/v1/guard/event", admin...)
+	{
+		event.GET("", r.cc.ListEvent)
+	}
+
+	state := e.Group("/api/v1/guard", append(admin, r.cc.IsSuperAdmin)...)
+	{
+		state.POST("/apply", r.cc.Apply)
+		state.GET("/export", r.cc.Export)
+	}
+
+	access := e.Group("/api/v1/guard/access", admin...)
+	{
+		access.GET("/node/:nodeID", r.cc.ListNodeAccess)
+		access.GET("/user/:userID", r.cc.ListUserAccess)
+	}
+
+	space := e.Group("/api/v1/guard/space", admin...)
 	{
 		space.GET("", r.cc.ListSpace)
 		space.POST("", r.cc.CreateSpace)
+		space.PATCH("/:spaceID", r.cc.UpdateSpace)
+		space.DELETE("/:spaceID", r.cc.DeleteSpace)
+		space.GET("/:spaceID/admin", r.cc.ListSpaceAdmin)
+		space.POST("/:spaceID/admin", r.cc.AddSpaceAdmin)
+		space.POST("/:spaceID/admin/batch/delete", r.cc.BatchRemoveSpaceAdmin)
+		space.GET("/:spaceID/sync", r.cc.ListSpaceSync)
 	}
 
-	user := e.Group("/api/v1/guard")
+	user := e.Group("/api/v1/guard", admin...)
 	{
 		user.POST("/user", r.cc.CreateUser)
 		user.GET("/users", r.cc.ListUser)
 		user.GET("/user", r.cc.QueryUser)
 		user.GET("/user/:userID", r.cc.GetUser)
+		user.PATCH("/user/:userID", r.cc.UpdateUser)
+		user.DELETE("/user/:userID", r.cc.DeleteUser)
 		user.POST("/user/:userID/ban", r.cc.BanUser)
+		user.POST("/user/:userID/unban", r.cc.UnbanUser)
+		user.POST("/user/:userID/suspend", r.cc.SuspendUser)
+		user.POST("/user/:userID/resume", r.cc.ResumeUser)
 		user.PUT("/user/:userID/publicKey", r.cc.UpdateUserPublicKey)
 		user.POST("/user/:userID/cert", r.cc.GrantCert)
+		user.GET("/user/:userID/cert", r.cc.ListUserCert)
+		user.POST("/user/:userID/cert/revoke", r.cc.RevokeCert)
 	}
 
-	node := e.Group("/api/v1/guard/space/:spaceID/node")
+	node := e.Group("/api/v1/guard/space/:spaceID/node", admin...)
 	{
 		node.GET("", r.cc.ListNode)
 		node.POST("", r.cc.CreateNode)
+		node.PATCH("/:nodeID", r.cc.UpdateNode)
 		node.DELETE("/:nodeID", r.cc.DeleteNode)
+		node.GET("/:nodeID/heartbeat", r.cc.ListNodeHeartbeat)
+	}
+
+	fleet := e.Group("/api/v1/guard/fleet", admin...)
+	{
+		fleet.GET("/health", r.cc.FleetHealth)
 	}
 
-	role := e.Group("/api/v1/guard/space/:spaceID/role")
+	role := e.Group("/api/v1/guard/space/:spaceID/role", append(admin, r.cc.RoleInSpace)...)
 	{
 		role.GET("", r.cc.ListRole)
 		role.POST("", r.cc.CreateRole)
+		role.PATCH("/:roleID", r.cc.UpdateRole)
 		role.DELETE("/:roleID", r.cc.DeleteRole)
-		role.POST("/:roleID/node", r.cc.AddNodeToRole)
-		role.GET("/:roleID/node", r.cc.ListRoleNode)
-		role.POST("/:roleID/node/batch/delete", r.cc.BatchRemoveNodeFromRole)
-		role.POST("/:roleID/user", r.cc.AddUserToRole)
-		role.GET("/:roleID/user", r.cc.ListRoleUser)
-		role.POST("/:roleID/user/batch/delete", r.cc.BatchRemoveUserFromRole)
+		r.registerRoleMember(role)
+	}
+
+	globalRole := e.Group("/api/v1/guard/global_role", append(admin, r.cc.GlobalRole)...)
+	{
+		globalRole.GET("", r.cc.ListGlobalRole)
+		globalRole.POST("", r.cc.CreateGlobalRole)
+		globalRole.PATCH("/:roleID", r.cc.UpdateGlobalRole)
+		globalRole.DELETE("/:roleID", r.cc.DeleteRole)
+		r.registerRoleMember(globalRole)
+	}
+
+	group := e.Group("/api/v1/guard/group", admin...)
+	{
+		group.GET("", r.cc.ListGroup)
+		group.POST("", r.cc.CreateGroup)
+		group.PATCH("/:groupID", r.cc.UpdateGroup)
+		group.DELETE("/:groupID", r.cc.DeleteGroup)
+		group.POST("/:groupID/user", r.cc.AddUserToGroup)
+		group.GET("/:groupID/user", r.cc.ListGroupUser)
+		group.POST("/:groupID/user/batch/delete", r.cc.BatchRemoveUserFromGroup)
+	}
+
+	accessRequest := e.Group("/api/v1/guard/access_request", admin...)
+	{
+		accessRequest.POST("", r.cc.CreateAccessRequest)
+		accessRequest.GET("", r.cc.ListAccessRequest)
+		accessRequest.GET("/:requestID", r.cc.GetAccessRequest)
+		accessRequest.POST("/:requestID/approve", r.cc.ApproveAccessRequest)
+		accessRequest.POST("/:requestID/deny", r.cc.DenyAccessRequest)
+		accessRequest.POST("/:requestID/cancel", r.cc.CancelAccessRequest)
 	}
+
+	breakGlass := e.Group("/api/v1/guard/break_glass", admin...)
+	{
+		breakGlass.POST("", r.cc.BreakGlass)
+		breakGlass.GET("", r.cc.ListBreakGlass)
+		breakGlass.POST("/:id/ack", r.cc.AckBreakGlass)
+	}
+}
+
+// registerRoleMember registers the nodes, selectors, users, groups and
+// approvers api of the roles, they are shared by the space roles and the
+// global roles
+func (r *Route) registerRoleMember(g *gin.RouterGroup) {
+	g.POST("/:roleID/node", r.cc.AddNodeToRole)
+	g.GET("/:roleID/node", r.cc.ListRoleNode)
+	g.POST("/:roleID/node/batch/delete", r.cc.BatchRemoveNodeFromRole)
+	g.POST("/:roleID/selector", r.cc.AddRoleSelector)
+	g.GET("/:roleID/selector", r.cc.ListRoleSelector)
+	g.POST("/:roleID/selector/batch/delete", r.cc.BatchRemoveRoleSelector)
+	g.POST("/:roleID/user", r.cc.AddUserToRole)
+	g.GET("/:roleID/user", r.cc.ListRoleUser)
+	g.POST("/:roleID/user/batch/delete", r.cc.BatchRemoveUserFromRole)
+	g.GET("/:roleID/approver", r.cc.ListRoleApprover)
+	g.POST("/:roleID/approver", r.cc.AddApproverToRole)
+	g.POST("/:roleID/approver/batch/delete", r.cc.BatchRemoveApproverFromRole)
+	g.POST("/:roleID/group", r.cc.AddGroupToRole)
+	g.GET("/:roleID/group", r.cc.ListRoleGroup)
+	g.POST("/:roleID/group/batch/delete", r.cc.BatchRemoveGroupFromRole)
 }
 
 func (r *Route) Run(addr string) error {
@@ -79,6 +199,22 @@ func (r *Route) Run(addr string) error {
 	return r.server.ListenAndServe()
 }
 
-func (r *Route) Close() error {
-	return r.server.Close()
+// Shutdown stops the server gracefully. The readiness fails first and
+// the server keeps serving for delay, so that the load balancer notices
+// it, then the listener is closed and the in-flight requests are drained
+// until ctx is done, the remaining connections are closed after that.
+func (r *Route) Shutdown(ctx context.Context, delay time.Duration) error {
+	r.cc.Drain()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+
+	if err := r.server.Shutdown(ctx); err != nil {
+		r.server.Close() // nolint
+		return fmt.Errorf("failed to drain the requests: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,144 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sysarmor/guard/server/internal/controller"
+	"github.com/sysarmor/guard/server/internal/service"
+)
+
+// readyService blocks the first readiness check until release is closed,
+// started is closed when it's blocked
+type readyService struct {
+	service.Guard
+
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *readyService) Ready(ctx context.Context) *service.ReadyResponse {
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+	return &service.ReadyResponse{Ready: true, Checks: map[string]string{"database": "ok"}}
+}
+
+// serve runs the route on a random port, get requests the path on a new
+// connection and returns the status
+func serve(t *testing.T, svc service.Guard) (r *Route, get func(path string) (int, error), served chan error) {
+	gin.SetMode(gin.TestMode)
+	r = New(controller.New(controller.Config{}, svc))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served = make(chan error, 1)
+	go func() { served <- r.server.Serve(ln) }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get = func(path string) (int, error) {
+		resp, err := client.Get("http://" + ln.Addr().String() + path)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	return r, get, served
+}
+
+// eventually polls fn until it's true or the deadline
+func eventually(t *testing.T, msg string, fn func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !fn(); {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestShutdown checks the readiness fails during the delay, then the
+// listener is closed and the in-flight request is finished before the
+// shutdown returns
+func TestShutdown(t *testing.T) {
+	svc := &readyService{started: make(chan struct{}), release: make(chan struct{})}
+	r, get, served := serve(t, svc)
+
+	inflight := make(chan int, 1)
+	go func() {
+		code, _ := get("/readyz")
+		inflight <- code
+	}()
+	<-svc.started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- r.Shutdown(context.Background(), 200*time.Millisecond) }()
+
+	eventually(t, "expected the readiness failed while draining", func() bool {
+		code, err := get("/readyz")
+		return err == nil && code == http.StatusServiceUnavailable
+	})
+	if code, err := get("/healthz"); err != nil || code != http.StatusOK {
+		t.Fatalf("expected the requests served during the delay, got %d %v", code, err)
+	}
+
+	eventually(t, "expected the listener closed after the delay", func() bool {
+		_, err := get("/healthz")
+		return err != nil
+	})
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected the shutdown waits for the in-flight request, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(svc.release)
+	if code := <-inflight; code != http.StatusOK {
+		t.Fatalf("expected the in-flight request finished, got %d", code)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expected the server closed, got %v", err)
+	}
+}
+
+// TestShutdownTimeout checks the in-flight request is cut off when it's
+// not finished before the deadline of the shutdown
+func TestShutdownTimeout(t *testing.T) {
+	svc := &readyService{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(svc.release)
+	r, get, _ := serve(t, svc)
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := get("/readyz")
+		inflight <- err
+	}()
+	<-svc.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := r.Shutdown(ctx, 0); err == nil {
+		t.Fatal("expected the shutdown failed to drain the request")
+	}
+	if err := <-inflight; err == nil {
+		t.Fatal("expected the in-flight request cut off")
+	}
+}